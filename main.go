@@ -6,9 +6,507 @@ package main
 // https://sta.c64.org/cbm64mem.html
 // https://www.c64-wiki.com/wiki/Reset_(Process)
 
+import (
+	"bufio"
+	"emulator/pkg/arc"
+	"emulator/pkg/asm"
+	"emulator/pkg/common"
+	"emulator/pkg/disasm"
+	"emulator/pkg/link"
+	"emulator/pkg/profile"
+	"emulator/pkg/trace"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
 
+// Commands available from the command line, e.g. emulator disasm -load 0xC000 rom.bin
+// Without a command, the emulator starts the monitor.
+var commands = map[string]func(args []string) error{
+    "monitor": monitorMain,
+    "disasm": disassemble,
+    "asm": assemble,
+    "link": linkObjects,
+    "trace": traceProgram,
+    "profile": profileProgram,
+}
 
+func usage(){
+    fmt.Fprintln(os.Stderr, "usage: emulator [command] [arguments]")
+    fmt.Fprintln(os.Stderr, "commands:")
+    fmt.Fprintln(os.Stderr, "  monitor  interactive machine language monitor, the default")
+    fmt.Fprintln(os.Stderr, "  disasm   disassemble a binary file loaded at a given address")
+    fmt.Fprintln(os.Stderr, "  asm      assemble a source file into a program, or an object with -obj")
+    fmt.Fprintln(os.Stderr, "  link     link objects into an image, placed by a memory layout config")
+    fmt.Fprintln(os.Stderr, "  trace    run a program and log each instruction, in the format of the nestest log")
+    fmt.Fprintln(os.Stderr, "  profile  run a program and report where it spends its cycles")
+}
 
 func main() {
 
+    command, args := monitorMain, []string(nil)
+
+    if len(os.Args) > 1 {
+
+        var ok bool
+        if command, ok = commands[os.Args[1]]; !ok {
+            usage()
+            os.Exit(2)
+        }
+
+        args = os.Args[2:]
+    }
+
+    if err := command(args); err != nil {
+        fmt.Fprintln(os.Stderr, "emulator:", err)
+        os.Exit(1)
+    }
+}
+
+// disassemble loads a binary file at its load address and prints its listing.
+// With -prg, the first two bytes of the file are the load address, like LoadProgram expects.
+func disassemble(args []string) error{
+
+    flags := flag.NewFlagSet("disasm", flag.ContinueOnError)
+
+    load := flags.String("load", "0x0000", "load address of the file")
+    start := flags.String("start", "", "first address to disassemble, the load address by default")
+    end := flags.String("end", "", "last address to disassemble, the end of the file by default")
+    prg := flags.Bool("prg", false, "the file starts with its load address")
+    cmos := flags.Bool("cmos", false, "decode 65C02 opcodes")
+    bytes := flags.Bool("bytes", false, "show the raw bytes of each instruction")
+    cycles := flags.Bool("cycles", false, "show the cycles of each instruction")
+
+    if err := flags.Parse(args); err != nil {
+        return err
+    }
+
+    if flags.NArg() != 1 {
+        return fmt.Errorf("disasm: expected one file, got %d", flags.NArg())
+    }
+
+    data, err := os.ReadFile(flags.Arg(0))
+    if err != nil {
+        return err
+    }
+
+    loadAddress, err := common.ParseAddress(*load)
+    if err != nil {
+        return err
+    }
+
+    if *prg {
+
+        if len(data) < 2 {
+            return fmt.Errorf("disasm: %s is too short to hold a load address", flags.Arg(0))
+        }
+
+        loadAddress = uint16(data[0]) | uint16(data[1]) << 8
+        data = data[2:]
+    }
+
+    if len(data) == 0 {
+        return fmt.Errorf("disasm: %s is empty", flags.Arg(0))
+    }
+
+    if int(loadAddress) + len(data) > arc.MaxMem {
+        return fmt.Errorf("disasm: %d bytes don't fit at $%04X", len(data), loadAddress)
+    }
+
+    memory := &arc.Memory{}
+    copy(memory.Data[loadAddress:], data)
+
+    first, last := loadAddress, loadAddress + uint16(len(data) - 1)
+
+    if *start != "" {
+        if first, err = common.ParseAddress(*start); err != nil {
+            return err
+        }
+    }
+
+    if *end != "" {
+        if last, err = common.ParseAddress(*end); err != nil {
+            return err
+        }
+    }
+
+    options := disasm.Options{CMOS: *cmos, Bytes: *bytes, Cycles: *cycles}
+
+    return disasm.Write(os.Stdout, memory, first, last, options)
+}
+
+// assemble assembles a source file into a binary LoadProgram accepts,
+// or into an object file for the linker with -obj.
+func assemble(args []string) error{
+
+    flags := flag.NewFlagSet("asm", flag.ContinueOnError)
+
+    output := flags.String("o", "", "output file, the source file with the .bin or .o extension by default")
+    object := flags.Bool("obj", false, "assemble an object for the linker")
+    cmos := flags.Bool("cmos", false, "accept 65C02 instructions")
+
+    if err := flags.Parse(args); err != nil {
+        return err
+    }
+
+    if flags.NArg() != 1 {
+        return fmt.Errorf("asm: expected one source file, got %d", flags.NArg())
+    }
+
+    source := flags.Arg(0)
+    assembler := &asm.Assembler{CMOS: *cmos}
+
+    if *object {
+
+        o, err := assembler.AssembleObjectFile(source)
+        if err != nil {
+            return err
+        }
+
+        return o.WriteFile(outputPath(*output, source, ".o"))
+    }
+
+    program, err := assembler.AssembleFile(source)
+    if err != nil {
+        return err
+    }
+
+    return os.WriteFile(outputPath(*output, source, ".bin"), program.Binary(), 0644)
+}
+
+// outputPath is the output file given, or the source file with another extension.
+func outputPath(output, source, extension string) string{
+
+    if output != "" {
+        return output
+    }
+
+    return strings.TrimSuffix(source, filepath.Ext(source)) + extension
+}
+
+// linkObjects links object files into an image. The image is written in the format
+// LoadProgram accepts, or as a ROM covering a range of addresses with -rom.
+func linkObjects(args []string) error{
+
+    flags := flag.NewFlagSet("link", flag.ContinueOnError)
+
+    config := flags.String("config", "", "memory layout config, mapping segments to ranges of addresses")
+    output := flags.String("o", "a.bin", "output file")
+    mapFile := flags.String("map", "", "write the map of the image to this file")
+    rom := flags.String("rom", "", "write a ROM covering this range, like 0xE000-0xFFFF")
+    fill := flags.String("fill", "0xFF", "value of the bytes of the ROM not covered by the image")
+
+    if err := flags.Parse(args); err != nil {
+        return err
+    }
+
+    if *config == "" || flags.NArg() == 0 {
+        return fmt.Errorf("link: expected -config and object files")
+    }
+
+    layout, err := link.ReadConfig(*config)
+    if err != nil {
+        return err
+    }
+
+    var objects []*asm.Object
+
+    for _, path := range flags.Args() {
+
+        object, err := asm.ReadObject(path)
+        if err != nil {
+            return err
+        }
+
+        objects = append(objects, object)
+    }
+
+    image, err := link.Link(layout, objects...)
+    if err != nil {
+        return err
+    }
+
+    if *mapFile != "" {
+
+        file, err := os.Create(*mapFile)
+        if err != nil {
+            return err
+        }
+
+        err = image.WriteMap(file)
+        if closeErr := file.Close(); err == nil {
+            err = closeErr
+        }
+
+        if err != nil {
+            return err
+        }
+    }
+
+    if *rom == "" {
+        return os.WriteFile(*output, image.Binary(), 0644)
+    }
+
+    start, end, err := common.ParseAddressRange(*rom)
+    if err != nil {
+        return fmt.Errorf("link: ROM %v", err)
+    }
+
+    fillValue, err := common.ParseAddress(*fill)
+    if err != nil || fillValue > 0xFF {
+        return fmt.Errorf("link: invalid fill value %s", *fill)
+    }
+
+    return os.WriteFile(*output, image.ROM(start, end, byte(fillValue)), 0644)
+}
+
+// addressRanges is a flag that can be repeated, each time with a range of addresses.
+type addressRanges []trace.Range
+
+func (ranges *addressRanges) String() string{
+    return fmt.Sprint(*ranges)
+}
+
+func (ranges *addressRanges) Set(text string) error{
+
+    start, end, err := common.ParseAddressRange(text)
+    if err != nil {
+        return err
+    }
+
+    *ranges = append(*ranges, trace.Range{Start: start, End: end})
+
+    return nil
+}
+
+// loadProgram returns a CPU with the program of a file loaded, ready to run it from pc,
+// or from the load address when pc is empty. Without load, the file starts with its
+// load address, like LoadProgram expects. Errors start with the name of the command.
+func loadProgram(command, path, load, pc string, cmos bool) (*arc.CPU, error){
+
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    variant := arc.NMOS6502
+    if cmos {
+        variant = arc.CMOS65C02
+    }
+
+    cpu := arc.NewCPU(variant)
+
+    if load == "" {
+
+        if len(data) < 2 {
+            return nil, fmt.Errorf("%s: %s is too short to hold a load address", command, path)
+        }
+
+        cpu.LoadProgram(data)
+
+    } else {
+
+        address, err := common.ParseAddress(load)
+        if err != nil {
+            return nil, err
+        }
+
+        if int(address) + len(data) > arc.MaxMem {
+            return nil, fmt.Errorf("%s: %s doesn't fit in memory at $%04X", command, path, address)
+        }
+
+        copy(cpu.Memory.Data[address:], data)
+        cpu.PC = address
+    }
+
+    if pc != "" {
+        if cpu.PC, err = common.ParseAddress(pc); err != nil {
+            return nil, err
+        }
+    }
+
+    return cpu, nil
+}
+
+// traceProgram runs a program for a number of cycles, logging each instruction.
+// Without -load, the file starts with its load address, like LoadProgram expects.
+// nestest, for instance, is traced with:
+//
+//	emulator trace -load 0xC000 -offset 7 -cycles 26554 nestest.prg
+func traceProgram(args []string) error{
+
+    flags := flag.NewFlagSet("trace", flag.ContinueOnError)
+
+    load := flags.String("load", "", "load address of the file, which starts with it by default")
+    pc := flags.String("pc", "", "address the execution starts at, the load address by default")
+    cycles := flags.Int("cycles", 1_000_000, "cycles to run")
+    offset := flags.Uint64("offset", 0, "added to the cycles of the trace, nestest starts at 7")
+    output := flags.String("o", "", "trace file, the standard output by default")
+    cmos := flags.Bool("cmos", false, "emulate the 65C02")
+
+    var ranges addressRanges
+    flags.Var(&ranges, "range", "only trace the instructions in this range, like 0xC000-0xCFFF, can be repeated")
+
+    if err := flags.Parse(args); err != nil {
+        return err
+    }
+
+    if flags.NArg() != 1 {
+        return fmt.Errorf("trace: expected one program, got %d", flags.NArg())
+    }
+
+    cpu, err := loadProgram("trace", flags.Arg(0), *load, *pc, *cmos)
+    if err != nil {
+        return err
+    }
+
+    var w io.Writer = os.Stdout
+
+    if *output != "" {
+
+        file, err := os.Create(*output)
+        if err != nil {
+            return err
+        }
+        defer file.Close()
+
+        w = file
+    }
+
+    buffered := bufio.NewWriter(w)
+    tracer := trace.New(buffered, trace.Options{Ranges: ranges, CycleOffset: *offset})
+    cpu.Tracer = tracer
+
+    // A fault ends the trace, it's reported once the trace is written
+    _, runErr := cpu.Run(*cycles)
+
+    if err := tracer.Err(); err != nil {
+        return err
+    }
+
+    if err := buffered.Flush(); err != nil {
+        return err
+    }
+
+    return runErr
+}
+
+// profileProgram runs a program for a number of cycles, then reports the addresses
+// where it spent the most cycles, or writes the whole profile as CSV with -csv.
+//
+//	emulator profile -load 0x0200 -labels program.map -top 10 program.bin
+func profileProgram(args []string) error{
+
+    flags := flag.NewFlagSet("profile", flag.ContinueOnError)
+
+    load := flags.String("load", "", "load address of the file, which starts with it by default")
+    pc := flags.String("pc", "", "address the execution starts at, the load address by default")
+    cycles := flags.Int("cycles", 1_000_000, "cycles to run")
+    cmos := flags.Bool("cmos", false, "emulate the 65C02")
+    labels := flags.String("labels", "", "label file naming the addresses, like a map file of the linker")
+    order := flags.String("sort", "cycles", "order of the report: cycles, executions or address")
+    top := flags.Int("top", 20, "addresses reported, 0 for all of them")
+    output := flags.String("csv", "", "write the whole profile to this CSV file instead")
+    calls := flags.Bool("calls", false, "report the cycles of the routines, and the stack anomalies, instead of the addresses")
+    folded := flags.String("folded", "", "write the call stacks to this file, in the folded format of flame graphs")
+
+    if err := flags.Parse(args); err != nil {
+        return err
+    }
+
+    if flags.NArg() != 1 {
+        return fmt.Errorf("profile: expected one program, got %d", flags.NArg())
+    }
+
+    sortOrder, err := profile.ParseOrder(*order)
+    if err != nil {
+        return err
+    }
+
+    var names *profile.Labels
+
+    if *labels != "" {
+        if names, err = profile.ReadLabels(*labels); err != nil {
+            return err
+        }
+    }
+
+    cpu, err := loadProgram("profile", flags.Arg(0), *load, *pc, *cmos)
+    if err != nil {
+        return err
+    }
+
+    profiler := arc.NewProfiler()
+    cpu.Profiler = profiler
+
+    if *calls || *folded != "" {
+        profiler.Calls = arc.NewCallGraph()
+    }
+
+    // A fault ends the profile, it's reported once the profile is written
+    _, runErr := cpu.Run(*cycles)
+
+    report := profile.NewReport(profiler, names)
+    report.Sort(sortOrder)
+
+    if *output != "" {
+        if err := writeFile(*output, report.WriteCSV); err != nil {
+            return err
+        }
+    }
+
+    if *folded != "" {
+
+        err := writeFile(*folded, func(w io.Writer) error{
+            return profile.WriteFolded(w, profiler.Calls, names)
+        })
+
+        if err != nil {
+            return err
+        }
+    }
+
+    switch {
+    case *calls:
+
+        if err := profile.WriteRoutines(os.Stdout, profile.Routines(profiler.Calls, names), *top); err != nil {
+            return err
+        }
+
+        anomalies, count := profiler.Calls.Anomalies()
+        if count > 0 {
+            fmt.Printf("%d stack anomalies, the first ones:\n", count)
+        }
+
+        for _, anomaly := range anomalies {
+            fmt.Println(anomaly)
+        }
+
+    case *output == "":
+
+        if err := report.WriteTable(os.Stdout, *top); err != nil {
+            return err
+        }
+    }
+
+    return runErr
+}
+
+// writeFile creates a file and writes it with write.
+func writeFile(path string, write func(w io.Writer) error) error{
+
+    file, err := os.Create(path)
+    if err != nil {
+        return err
+    }
+
+    err = write(file)
+    if closeErr := file.Close(); err == nil {
+        err = closeErr
+    }
+
+    return err
 }
@@ -0,0 +1,790 @@
+package main
+
+import (
+	"bufio"
+	"emulator/pkg/arc"
+	"emulator/pkg/disasm"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// monitor is an interactive machine language monitor, in the spirit of Wozmon and of
+// the monitor of VICE. It reads one command per line, so it can run scripts too.
+// Addresses and values are hexadecimal, with or without $, counts are decimal.
+type monitor struct {
+    cpu *arc.CPU
+
+    in io.Reader
+    out io.Writer
+
+    // Printed before reading each command, when a person is typing them
+    prompt string
+
+    // Cycles executed since the monitor started
+    cycles int
+
+    // Cycles go runs at most, when no breakpoint is reached
+    limit int
+
+    // Where m and d continue when they're not given an address
+    nextMemory uint16
+    nextDisassembly uint16
+}
+
+// monitorCommand is a command of the monitor, which can also be called by its alias.
+type monitorCommand struct {
+    name string
+    alias string
+    arguments string
+    help string
+    run func(m *monitor, args []string) error
+}
+
+var monitorCommands []monitorCommand
+
+// Set by init, since help lists the commands
+func init(){
+
+    monitorCommands = []monitorCommand{
+        {"help", "?", "", "list the commands", (*monitor).help},
+        {"load", "l", "file [address]", "load a binary file at address, or at the address in its first two bytes", (*monitor).load},
+        {"mem", "m", "[start [end]]", "examine memory", (*monitor).memory},
+        {"write", ">", "address value...", "modify memory", (*monitor).write},
+        {"regs", "r", "[register=value...]", "show the registers, or set A, X, Y, SP, PC, P or a flag like C=1", (*monitor).registers},
+        {"disasm", "d", "[start [end]]", "disassemble, from PC by default", (*monitor).disassemble},
+        {"step", "z", "[count]", "execute count instructions, one by default", (*monitor).step},
+        {"go", "g", "[address]", "run from address or PC until a breakpoint", (*monitor).run},
+        {"break", "b", "[address [if cond]]", "set a breakpoint, or list them with their hits", (*monitor).setBreakpoint},
+        {"watch", "w", "[r|w|rw start [end] [if cond]]", "stop after reads, writes or both of a range", (*monitor).setWatchpoint},
+        {"delete", "bd", "number", "delete a breakpoint or a watchpoint", (*monitor).deleteBreakpoint},
+        {"back", "zb", "[count]", "go back count instructions, one by default", (*monitor).back},
+        {"lastwrite", "lw", "address", "go back to the last instruction writing address", (*monitor).lastWrite},
+        {"quit", "q", "", "leave the monitor", (*monitor).quit},
+    }
+}
+
+// errQuit is returned by the quit command to stop the monitor.
+var errQuit = errors.New("quit")
+
+// defaultLimit is enough for a few seconds of emulation.
+const defaultLimit = 100_000_000
+
+func newMonitor(cpu *arc.CPU, in io.Reader, out io.Writer) *monitor{
+
+    if cpu.Debugger == nil {
+        cpu.Debugger = arc.NewDebugger()
+    }
+
+    if cpu.History == nil {
+        cpu.History = arc.NewHistory(arc.DefaultHistorySize, arc.DefaultSnapshotInterval)
+    }
+
+    return &monitor{
+        cpu: cpu,
+        in: in,
+        out: out,
+        limit: defaultLimit,
+        nextDisassembly: cpu.PC,
+    }
+}
+
+// monitorMain starts the monitor on the standard input: emulator monitor [-cmos] [file [address]]
+func monitorMain(args []string) error{
+
+    flags := flag.NewFlagSet("monitor", flag.ContinueOnError)
+
+    cmos := flags.Bool("cmos", false, "emulate the 65C02")
+    limit := flags.Int("limit", defaultLimit, "cycles go runs at most without reaching a breakpoint")
+
+    if err := flags.Parse(args); err != nil {
+        return err
+    }
+
+    variant := arc.NMOS6502
+    if *cmos {
+        variant = arc.CMOS65C02
+    }
+
+    m := newMonitor(arc.NewCPU(variant), os.Stdin, os.Stdout)
+    m.limit = *limit
+
+    if info, err := os.Stdin.Stat(); err == nil && info.Mode() & os.ModeCharDevice != 0 {
+        m.prompt = ". "
+    }
+
+    if flags.NArg() > 0 {
+        if err := m.load(flags.Args()); err != nil {
+            return err
+        }
+    }
+
+    return m.loop()
+}
+
+// loop reads and runs commands until the input ends or quit is called.
+// Errors of the commands are printed, and the monitor goes on.
+func (m *monitor) loop() error{
+
+    scanner := bufio.NewScanner(m.in)
+
+    for {
+        fmt.Fprint(m.out, m.prompt)
+
+        if !scanner.Scan() {
+            return scanner.Err()
+        }
+
+        // Scripts can have comments
+        line := scanner.Text()
+        if comment := strings.Index(line, ";"); comment != -1 {
+            line = line[:comment]
+        }
+
+        fields := strings.Fields(line)
+        if len(fields) == 0 {
+            continue
+        }
+
+        command := findCommand(fields[0])
+        if command == nil {
+            fmt.Fprintf(m.out, "unknown command %s, ? lists them\n", fields[0])
+            continue
+        }
+
+        err := command.run(m, fields[1:])
+
+        if err == errQuit {
+            return nil
+        }
+
+        if err != nil {
+            fmt.Fprintln(m.out, "error:", err)
+        }
+    }
+}
+
+func findCommand(name string) *monitorCommand{
+
+    name = strings.ToLower(name)
+
+    for i := range monitorCommands {
+        if monitorCommands[i].name == name || monitorCommands[i].alias == name {
+            return &monitorCommands[i]
+        }
+    }
+
+    return nil
+}
+
+func (m *monitor) help(args []string) error{
+
+    for _, command := range monitorCommands {
+        usage := strings.TrimSpace(command.name + " " + command.arguments)
+        fmt.Fprintf(m.out, "  %-2s %-36s %s\n", command.alias, usage, command.help)
+    }
+
+    fmt.Fprintln(m.out, "addresses and values are hexadecimal, counts are decimal")
+    fmt.Fprintln(m.out, "conditions are expressions like A == $10 && mem[$20] > 3, where numbers without $ are decimal")
+
+    return nil
+}
+
+func (m *monitor) quit(args []string) error{
+    return errQuit
+}
+
+// parseHex reads a hexadecimal number written $C000, 0xC000 or C000, up to max.
+func parseHex(text string, max uint64) (uint64, error){
+
+    digits := strings.TrimPrefix(text, "$")
+    if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+        digits = digits[2:]
+    }
+
+    value, err := strconv.ParseUint(digits, 16, 64)
+    if err != nil || value > max {
+        return 0, fmt.Errorf("invalid value %q", text)
+    }
+
+    return value, nil
+}
+
+func parseWord(text string) (uint16, error){
+
+    value, err := parseHex(text, 0xFFFF)
+    return uint16(value), err
+}
+
+func parseByte(text string) (byte, error){
+
+    value, err := parseHex(text, 0xFF)
+    return byte(value), err
+}
+
+// parseRange reads the optional start and end arguments of m and d.
+// Without an end, it returns the start and false.
+func parseRange(args []string, next uint16) (start, end uint16, hasEnd bool, err error){
+
+    if len(args) > 2 {
+        return 0, 0, false, fmt.Errorf("expected a start and an end address")
+    }
+
+    start = next
+
+    if len(args) > 0 {
+        if start, err = parseWord(args[0]); err != nil {
+            return
+        }
+    }
+
+    if len(args) > 1 {
+
+        if end, err = parseWord(args[1]); err != nil {
+            return
+        }
+
+        if end < start {
+            return 0, 0, false, fmt.Errorf("range $%04X-$%04X ends before it starts", start, end)
+        }
+
+        hasEnd = true
+    }
+
+    return
+}
+
+// poke writes data to memory. Buses that can load data, like the ones of package memmap,
+// let the monitor patch ROMs too.
+func (m *monitor) poke(address uint16, data []byte){
+
+    // Going back must not undo the change
+    m.cpu.History.Checkpoint()
+
+    bus := m.cpu.AddressSpace()
+
+    if l, ok := bus.(interface{ Load(uint16, []byte) }); ok {
+        l.Load(address, data)
+        return
+    }
+
+    for i, value := range data {
+        bus.Write(address + uint16(i), value)
+    }
+}
+
+func (m *monitor) load(args []string) error{
+
+    if len(args) < 1 || len(args) > 2 {
+        return fmt.Errorf("load needs a file name, and optionally an address")
+    }
+
+    data, err := os.ReadFile(args[0])
+    if err != nil {
+        return err
+    }
+
+    var address uint16
+
+    // Without an address, the file starts with it, like LoadProgram expects
+    if len(args) == 1 {
+
+        if len(data) < 2 {
+            return fmt.Errorf("%s is too short to hold a load address", args[0])
+        }
+
+        address = uint16(data[0]) | uint16(data[1]) << 8
+        data = data[2:]
+        m.cpu.PC = address
+
+    }else if address, err = parseWord(args[1]); err != nil {
+        return err
+    }
+
+    if int(address) + len(data) > arc.MaxMem {
+        return fmt.Errorf("%d bytes don't fit at $%04X", len(data), address)
+    }
+
+    m.poke(address, data)
+    m.nextMemory, m.nextDisassembly = address, address
+
+    fmt.Fprintf(m.out, "loaded %d bytes at $%04X\n", len(data), address)
+
+    return nil
+}
+
+// memory dumps 16 bytes per line, with their characters.
+func (m *monitor) memory(args []string) error{
+
+    start, end, hasEnd, err := parseRange(args, m.nextMemory)
+    if err != nil {
+        return err
+    }
+
+    if !hasEnd {
+        end = start + 0x7F
+        if end < start {
+            end = 0xFFFF
+        }
+    }
+
+    bus := m.cpu.AddressSpace()
+
+    for line := int(start); line <= int(end); line += 16 {
+
+        var hex, text strings.Builder
+
+        for address := line; address < line + 16 && address <= int(end); address++ {
+
+            value := bus.Read(uint16(address))
+            fmt.Fprintf(&hex, " %02X", value)
+
+            if value >= 0x20 && value < 0x7F {
+                text.WriteByte(value)
+            }else{
+                text.WriteByte('.')
+            }
+        }
+
+        fmt.Fprintf(m.out, "$%04X %-48s  %s\n", line, hex.String(), text.String())
+    }
+
+    m.nextMemory = end + 1
+
+    return nil
+}
+
+func (m *monitor) write(args []string) error{
+
+    if len(args) < 2 {
+        return fmt.Errorf("write needs an address and values")
+    }
+
+    address, err := parseWord(args[0])
+    if err != nil {
+        return err
+    }
+
+    data := make([]byte, len(args) - 1)
+
+    for i, arg := range args[1:] {
+        if data[i], err = parseByte(arg); err != nil {
+            return err
+        }
+    }
+
+    m.poke(address, data)
+
+    return nil
+}
+
+// registers shows the registers like PrintStatus, or sets them: r A=10 PC=E000 C=1
+func (m *monitor) registers(args []string) error{
+
+    if len(args) == 0 {
+        m.cpu.WriteStatus(m.out)
+        fmt.Fprintf(m.out, "Cycles: %d\n", m.cycles)
+        return nil
+    }
+
+    for _, arg := range args {
+
+        name, text, ok := strings.Cut(arg, "=")
+        if !ok {
+            return fmt.Errorf("expected register=value, got %q", arg)
+        }
+
+        if err := m.setRegister(strings.ToUpper(name), text); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+func (m *monitor) setRegister(name, text string) error{
+
+    cpu := m.cpu
+
+    if name == "PC" {
+
+        pc, err := parseWord(text)
+        if err != nil {
+            return err
+        }
+
+        cpu.PC = pc
+        m.nextDisassembly = pc
+
+        return nil
+    }
+
+    value, err := parseByte(text)
+    if err != nil {
+        return err
+    }
+
+    flags := map[string]*uint{
+        "C": &cpu.PS.C, "Z": &cpu.PS.Z, "I": &cpu.PS.I, "D": &cpu.PS.D,
+        "B": &cpu.PS.B, "V": &cpu.PS.V, "N": &cpu.PS.N,
+    }
+
+    switch name {
+    case "A":
+        cpu.A = value
+    case "X":
+        cpu.X = value
+    case "Y":
+        cpu.Y = value
+    case "SP", "S":
+        cpu.SP = value
+    case "P", "PS":
+        cpu.PS = cpu.ByteToPS(value)
+    default:
+
+        bit, ok := flags[name]
+        if !ok {
+            return fmt.Errorf("unknown register %s", name)
+        }
+
+        if value > 1 {
+            return fmt.Errorf("flag %s can only be 0 or 1", name)
+        }
+
+        *bit = uint(value)
+    }
+
+    return nil
+}
+
+func (m *monitor) cmos() bool{
+    return m.cpu.Variant == arc.CMOS65C02
+}
+
+// disassemble lists the instructions of a range, or 16 instructions from where it stopped.
+func (m *monitor) disassemble(args []string) error{
+
+    start, end, hasEnd, err := parseRange(args, m.nextDisassembly)
+    if err != nil {
+        return err
+    }
+
+    options := disasm.Options{CMOS: m.cmos(), Bytes: true}
+    bus := m.cpu.AddressSpace()
+
+    if hasEnd {
+        m.nextDisassembly = end + 1
+        return disasm.Write(m.out, bus, start, end, options)
+    }
+
+    address := start
+
+    for i := 0; i < 16; i++ {
+
+        ins := disasm.Decode(bus, address, options.CMOS)
+        fmt.Fprintln(m.out, ins.Format(options))
+
+        address += uint16(len(ins.Bytes))
+    }
+
+    m.nextDisassembly = address
+
+    return nil
+}
+
+// status is the one line summary of the registers printed after each step.
+func (m *monitor) status() string{
+
+    cpu := m.cpu
+
+    return fmt.Sprintf("A:%02X X:%02X Y:%02X SP:%02X NV-BDIZC:%08b", cpu.A, cpu.X, cpu.Y, cpu.SP, cpu.PSToByte())
+}
+
+// execute runs one instruction. It returns false when the CPU can't go on:
+// it faulted, a jam is a fault too, it hit a watchpoint, or it waits for an interrupt.
+func (m *monitor) execute(trace bool) (bool, error){
+
+    result, err := m.cpu.Step()
+    m.cycles += result.Cycles
+
+    if trace && result.Mnemonic != "" {
+        ins := disasm.Decode(m.cpu.AddressSpace(), result.PC, m.cmos())
+        fmt.Fprintf(m.out, "%-32s %s\n", ins.Format(disasm.Options{CMOS: m.cmos(), Bytes: true}), m.status())
+    }
+
+    m.nextDisassembly = m.cpu.PC
+
+    var hit *arc.Hit
+
+    switch {
+    case errors.As(err, &hit):
+        fmt.Fprintln(m.out, hit)
+        return false, nil
+
+    case err != nil:
+        return false, err
+
+    case result.Mnemonic == "":
+        fmt.Fprintf(m.out, "CPU waiting for an interrupt at $%04X\n", m.cpu.PC)
+        return false, nil
+    }
+
+    return true, nil
+}
+
+func (m *monitor) step(args []string) error{
+
+    count := 1
+
+    if len(args) > 0 {
+
+        var err error
+        if count, err = strconv.Atoi(args[0]); err != nil || count < 1 {
+            return fmt.Errorf("invalid count %q", args[0])
+        }
+    }
+
+    for i := 0; i < count; i++ {
+
+        ok, err := m.execute(true)
+        if !ok || err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+// run executes until the CPU stops at a breakpoint or a watchpoint, or after limit cycles.
+// The CPU debugger doesn't stop at the breakpoint it stopped at last, so go can resume from it.
+func (m *monitor) run(args []string) error{
+
+    if len(args) > 1 {
+        return fmt.Errorf("go takes an address at most")
+    }
+
+    if len(args) == 1 {
+
+        pc, err := parseWord(args[0])
+        if err != nil {
+            return err
+        }
+
+        m.cpu.PC = pc
+    }
+
+    cycles, err := m.cpu.Run(m.limit)
+    m.cycles += cycles
+    m.nextDisassembly = m.cpu.PC
+
+    var hit *arc.Hit
+
+    switch {
+    case errors.As(err, &hit):
+        fmt.Fprintln(m.out, hit)
+        m.cpu.WriteStatus(m.out)
+
+    case err != nil:
+        return err
+
+    case m.cpu.Waiting():
+        fmt.Fprintf(m.out, "CPU waiting for an interrupt at $%04X\n", m.cpu.PC)
+
+    default:
+        fmt.Fprintf(m.out, "stopped at $%04X after %d cycles without reaching a breakpoint\n", m.cpu.PC, cycles)
+    }
+
+    return nil
+}
+
+// rewound shows where the CPU went back to, after back and lastwrite.
+func (m *monitor) rewound(steps int, cycles uint64){
+
+    m.cycles -= int(cycles - m.cpu.Cycles())
+    m.nextDisassembly = m.cpu.PC
+
+    ins := disasm.Decode(m.cpu.AddressSpace(), m.cpu.PC, m.cmos())
+
+    fmt.Fprintf(m.out, "back %d steps, to %d cycles\n", steps, m.cpu.Cycles())
+    fmt.Fprintf(m.out, "%-32s %s\n", ins.Format(disasm.Options{CMOS: m.cmos(), Bytes: true}), m.status())
+}
+
+// back goes back count steps, a step being an instruction or the servicing of an interrupt.
+func (m *monitor) back(args []string) error{
+
+    count := 1
+
+    if len(args) > 0 {
+
+        var err error
+        if count, err = strconv.Atoi(args[0]); err != nil || count < 1 {
+            return fmt.Errorf("invalid count %q", args[0])
+        }
+    }
+
+    cycles := m.cpu.Cycles()
+
+    if err := m.cpu.Rewind(count); err != nil {
+        return err
+    }
+
+    m.rewound(count, cycles)
+
+    return nil
+}
+
+// lastWrite goes back before the last instruction writing an address.
+func (m *monitor) lastWrite(args []string) error{
+
+    if len(args) != 1 {
+        return fmt.Errorf("lastwrite needs an address")
+    }
+
+    address, err := parseWord(args[0])
+    if err != nil {
+        return err
+    }
+
+    cycles := m.cpu.Cycles()
+
+    steps, err := m.cpu.RewindToWrite(address)
+    if err != nil {
+        return err
+    }
+
+    m.rewound(steps, cycles)
+
+    return nil
+}
+
+// condition parses the optional "if condition" ending the arguments of break and watch.
+func condition(args []string) ([]string, *arc.Condition, error){
+
+    for i, arg := range args {
+
+        if strings.EqualFold(arg, "if") {
+
+            c, err := arc.ParseCondition(strings.Join(args[i + 1:], " "))
+
+            return args[:i], c, err
+        }
+    }
+
+    return args, nil, nil
+}
+
+// listBreakpoints prints the breakpoints and watchpoints, with their hit counts.
+func (m *monitor) listBreakpoints(){
+
+    for _, b := range m.cpu.Debugger.Breakpoints() {
+
+        state := ""
+        if !b.Enabled {
+            state = ", disabled"
+        }
+
+        fmt.Fprintf(m.out, "%v (%d hits%s)\n", b, b.Hits, state)
+    }
+}
+
+func (m *monitor) setBreakpoint(args []string) error{
+
+    if len(args) == 0 {
+        m.listBreakpoints()
+        return nil
+    }
+
+    args, c, err := condition(args)
+    if err != nil {
+        return err
+    }
+
+    if len(args) != 1 {
+        return fmt.Errorf("break takes an address, optionally followed by if and a condition")
+    }
+
+    address, err := parseWord(args[0])
+    if err != nil {
+        return err
+    }
+
+    b := m.cpu.Debugger.Break(address)
+    b.Condition = c
+
+    fmt.Fprintln(m.out, b)
+
+    return nil
+}
+
+// watchKinds are the kinds of watchpoint, by the name watch takes.
+var watchKinds = map[string]arc.BreakpointKind{
+    "r": arc.WatchRead,
+    "w": arc.WatchWrite,
+    "rw": arc.WatchAccess,
+}
+
+var errWatchUsage = errors.New("watch takes r, w or rw, and an address or a range, optionally followed by if and a condition")
+
+func (m *monitor) setWatchpoint(args []string) error{
+
+    if len(args) == 0 {
+        m.listBreakpoints()
+        return nil
+    }
+
+    args, c, err := condition(args)
+    if err != nil {
+        return err
+    }
+
+    if len(args) < 2 || len(args) > 3 {
+        return errWatchUsage
+    }
+
+    kind, ok := watchKinds[strings.ToLower(args[0])]
+    if !ok {
+        return errWatchUsage
+    }
+
+    start, end, hasEnd, err := parseRange(args[1:], 0)
+    if err != nil {
+        return err
+    }
+
+    if !hasEnd {
+        end = start
+    }
+
+    b, err := m.cpu.Debugger.Add(kind, start, end)
+    if err != nil {
+        return err
+    }
+
+    b.Condition = c
+
+    fmt.Fprintln(m.out, b)
+
+    return nil
+}
+
+func (m *monitor) deleteBreakpoint(args []string) error{
+
+    if len(args) != 1 {
+        return fmt.Errorf("delete needs the number of a breakpoint")
+    }
+
+    id, err := strconv.Atoi(args[0])
+    if err != nil {
+        return fmt.Errorf("invalid breakpoint number %q", args[0])
+    }
+
+    if !m.cpu.Debugger.Delete(id) {
+        return fmt.Errorf("no breakpoint %d", id)
+    }
+
+    return nil
+}
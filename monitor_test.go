@@ -0,0 +1,217 @@
+package main
+
+import (
+	"emulator/pkg/arc"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// RunMonitor runs a script of monitor commands and returns what the monitor printed.
+func RunMonitor(t *testing.T, cpu *arc.CPU, script string) string{
+
+    t.Helper()
+
+    var out strings.Builder
+
+    m := newMonitor(cpu, strings.NewReader(script), &out)
+    m.limit = 10000
+
+    if err := m.loop(); err != nil {
+        t.Fatal(err)
+    }
+
+    return out.String()
+}
+
+func CheckOutput(t *testing.T, output string, expected ...string){
+
+    t.Helper()
+
+    for _, text := range expected {
+        if !strings.Contains(output, text) {
+            t.Errorf("The output should contain %q:\n%s", text, output)
+        }
+    }
+}
+
+// LDX #3, DEX, BNE back to DEX, BRK, followed by "HI"
+const countdown = "> 0200 A2 03 CA D0 FD 00 48 49\n"
+
+func TestMonitorWritesExaminesAndDisassembles(t *testing.T){
+
+    cpu := arc.NewCPU(arc.NMOS6502)
+
+    output := RunMonitor(t, cpu, countdown + `
+        m 0200 0207     ; examine
+        d $0200 $0205
+`)
+
+    CheckOutput(t, output,
+        "$0200  A2 03 CA D0 FD 00 48 49                          ......HI",
+        "$0200  A2 03     LDX #$03",
+        "$0203  D0 FD     BNE $0202",
+        "$0205  00        BRK",
+    )
+
+    if cpu.Memory.Data[0x0206] != 'H' {
+        t.Error("The memory should have been written")
+    }
+}
+
+func TestMonitorSetsRegisters(t *testing.T){
+
+    cpu := arc.NewCPU(arc.NMOS6502)
+
+    output := RunMonitor(t, cpu, "r A=10 x=$20 Y=0x30 SP=F0 PC=C000 P=00 C=1 N=1\nr\n")
+
+    if cpu.A != 0x10 || cpu.X != 0x20 || cpu.Y != 0x30 || cpu.SP != 0xF0 || cpu.PC != 0xC000 {
+        t.Error("Registers not set: ", cpu.Registers())
+    }
+
+    CheckOutput(t, output, "PC: $C000  A: $10  X: $20  Y: $30  SP: $F0", "PS: $81  NV-BDIZC 10000001", "Cycles: 0")
+
+    output = RunMonitor(t, cpu, "r Q=1\nr C=2\nr A\n")
+
+    CheckOutput(t, output, "unknown register Q", "flag C can only be 0 or 1", "expected register=value")
+}
+
+func TestMonitorStepsAndRunsToBreakpoints(t *testing.T){
+
+    cpu := arc.NewCPU(arc.NMOS6502)
+
+    output := RunMonitor(t, cpu, countdown + `
+        r PC=0200
+        z 2
+        b 0205
+        g
+        r
+`)
+
+    CheckOutput(t, output,
+        "$0200  A2 03     LDX #$03        A:00 X:03 Y:00",
+        "$0202  CA        DEX             A:00 X:02 Y:00",
+        "breakpoint 1 at $0205, after 12 cycles",
+        "PC: $0205",
+        // 2 + 2, then DEX and BNE taken twice, and DEX and BNE not taken
+        "Cycles: 16",
+    )
+
+    // Going on from a breakpoint doesn't stop at it again
+    output = RunMonitor(t, cpu, "b\nbd 1\nbd 1\ng 0200\n")
+
+    CheckOutput(t, output, "breakpoint 1 at $0205 (1 hits)", "no breakpoint 1", "without reaching a breakpoint")
+}
+
+func TestMonitorStopsAtConditionsAndWatchpoints(t *testing.T){
+
+    cpu := arc.NewCPU(arc.NMOS6502)
+
+    output := RunMonitor(t, cpu, countdown + `
+        b 0203 if X == 1
+        g 0200
+        w w 0300 0301
+        > 0203 8E 00 03 D0 FA 00
+        r X=3
+        g 0202
+        z 3
+        b
+`)
+
+    CheckOutput(t, output,
+        "breakpoint 1 at $0203 if X == 1",
+        "breakpoint 1 at $0203, after 9 cycles",
+        "X: $01",
+        "write watchpoint 2 at $0300-$0301",
+        "write watchpoint 2: $02 written at $0300 by the instruction at $0203, after 6 cycles",
+        "$0203  8E 00 03  STX $0300",
+        "write watchpoint 2: $01 written at $0300",
+        "breakpoint 1 at $0203 if X == 1 (1 hits)",
+        "write watchpoint 2 at $0300-$0301 (2 hits)",
+    )
+
+    output = RunMonitor(t, cpu, "b 0200 if Q\nw x 0300\nw r 0300 0200\nw if A == 1\nw r if A == 1\n")
+
+    CheckOutput(t, output, "unknown name Q", "watch takes r, w or rw", "ends before it starts")
+
+    if strings.Count(output, "watch takes r, w or rw") != 3 {
+        t.Errorf("A watch without address should be refused:\n%s", output)
+    }
+}
+
+func TestMonitorStopsWhenTheCPUJams(t *testing.T){
+
+    cpu := arc.NewCPU(arc.NMOS6502)
+
+    output := RunMonitor(t, cpu, "> 0300 EA 02\ng 0300\n")
+
+    CheckOutput(t, output, "error: cpu jammed: opcode $02 at $0301")
+}
+
+func TestMonitorLoadsFiles(t *testing.T){
+
+    dir := t.TempDir()
+
+    raw := filepath.Join(dir, "raw.bin")
+    program := filepath.Join(dir, "program.bin")
+
+    if err := os.WriteFile(raw, []byte{1, 2, 3}, 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := os.WriteFile(program, []byte{0x00, 0x10, 0xA9, 0x42}, 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    cpu := arc.NewCPU(arc.NMOS6502)
+
+    output := RunMonitor(t, cpu, "l " + raw + " 0400\nload " + program + "\nz\nl missing.bin\n")
+
+    CheckOutput(t, output, "loaded 3 bytes at $0400", "loaded 2 bytes at $1000", "LDA #$42", "error: open missing.bin")
+
+    if cpu.Memory.Data[0x0402] != 3 || cpu.A != 0x42 {
+        t.Error("The files should have been loaded, and the program run")
+    }
+}
+
+func TestMonitorStopsAtQuit(t *testing.T){
+
+    cpu := arc.NewCPU(arc.NMOS6502)
+
+    output := RunMonitor(t, cpu, "?\nfoo\nq\nr A=10\n")
+
+    CheckOutput(t, output, "list the commands", "unknown command foo")
+
+    if cpu.A == 0x10 {
+        t.Error("Commands after quit shouldn't run")
+    }
+}
+
+func TestMonitorGoesBack(t *testing.T){
+
+    cpu := arc.NewCPU(arc.NMOS6502)
+
+    // LDX #3, STX $10, DEX, BNE back to STX, BRK
+    output := RunMonitor(t, cpu, `> 0200 A2 03 86 10 CA D0 FB 00
+        r PC=0200
+        z 5
+        zb 2
+        lw 10
+        zb 5
+        r
+`)
+
+    CheckOutput(t, output,
+        "back 2 steps, to 7 cycles",
+        "$0205  D0 FB     BNE $0202",
+        "back 2 steps, to 2 cycles",
+        "$0202  86 10     STX $10         A:00 X:03",
+        "error: can't rewind 5 steps, the history has 1",
+        "Cycles: 2",
+    )
+
+    if cpu.Memory.Data[0x10] != 0 {
+        t.Error("The write should have been undone")
+    }
+}
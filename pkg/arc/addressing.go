@@ -0,0 +1,86 @@
+package arc
+
+// Addressing modes. Each helper fetches the operand of the instruction and returns
+// the effective address, leaving the PC on the next instruction.
+// The cycles are not counted here, they come from the opcode table: the indexed modes
+// only record whether a page was crossed, since only some instructions pay for it.
+
+// Fetches ZeroPage Address when in Addressing Mode - Zero Page 
+func (cpu *CPU) AddressZeroPage() uint16{
+
+    return uint16(cpu.FetchByte())
+}
+
+// Fetches ZeroPage Address when in Addressing Mode - Zero Page with X offset
+func (cpu *CPU) AddressZeroPageX() uint16{
+
+    // Wrap Around
+    return uint16(cpu.FetchByte() + cpu.X)
+}
+
+// Fetches ZeroPage Address when in Addressing Mode - Zero Page with Y offset
+func (cpu *CPU) AddressZeroPageY() uint16{
+
+    // Wrap Around
+    return uint16(cpu.FetchByte() + cpu.Y)
+}
+
+// Fetches Absolute Address when in Addressing Mode - Absolute
+func (cpu *CPU) AddressAbsolute() uint16{
+
+    return cpu.FetchWord()
+}
+
+// Fetches Absolute Address when in Addressing Mode - Absolute with X offset
+func (cpu *CPU) AddressAbsoluteX() uint16{
+
+    return cpu.indexed(cpu.FetchWord(), cpu.X)
+}
+
+// Fetches Absolute Address when in Addressing Mode - Absolute with Y offset
+func (cpu *CPU) AddressAbsoluteY() uint16{
+
+    return cpu.indexed(cpu.FetchWord(), cpu.Y)
+}
+
+// The X register is added to the zero page address, and the effective address
+// is read from the resulting zero page address.
+// Both bytes of the pointer are in the zero page.
+func (cpu *CPU) AddressIndirectX() uint16{
+
+    return cpu.readZeroPageWord(cpu.FetchByte() + cpu.X)
+}
+
+// The effective address is read from the zero page address, then the Y register is added to it.
+func (cpu *CPU) AddressIndirectY() uint16{
+
+    return cpu.indexed(cpu.readZeroPageWord(cpu.FetchByte()), cpu.Y)
+}
+
+// addressIndirectZeroPage reads the effective address from the zero page address
+// held in the instruction, without adding any register. 65C02 only.
+func (cpu *CPU) addressIndirectZeroPage() uint16{
+
+    return cpu.readZeroPageWord(cpu.FetchByte())
+}
+
+// readZeroPageWord reads a pointer from the zero page. A pointer at $FF takes
+// its high byte from $00, not from $0100.
+func (cpu *CPU) readZeroPageWord(address byte) uint16{
+
+    return uint16(cpu.read(uint16(address))) | uint16(cpu.read(uint16(address + 1))) << 8
+}
+
+// indexed adds index to address.
+// "+1 if page crossed": a page boundary is crossed if the high byte of the original address is
+// different from the high byte of the calculated address after adding the index.
+func (cpu *CPU) indexed(address uint16, index byte) uint16{
+
+    target := address + uint16(index)
+
+    if (target >> 8) != (address >> 8) {
+        cpu.pageCrossed = true
+    }
+
+    return target
+}
@@ -0,0 +1,320 @@
+package arc
+
+import (
+	"emulator/pkg/instructions"
+	"fmt"
+)
+
+// CallGraph follows the subroutine calls of a program with a shadow call stack:
+// JSR and RTS, and interrupts, BRK included, and RTI. The cycles of each instruction
+// go to the routine running it, in the tree of the paths of calls leading to it.
+// The CPU updates it while it runs when the Calls field of its Profiler is set.
+//
+// Programs don't always return the way they called. A routine can drop its return
+// address to return to the caller of its caller, change it to skip inline parameters,
+// or push an address and RTS to it to jump. The shadow stack resynchronizes with the
+// real one instead of being corrupted, and records an anomaly each time.
+type CallGraph struct {
+
+    // Root is the code running outside of any call, named after the address the graph
+    // started at. It's set by the first instruction executed.
+    Root *CallNode
+
+    stack []callFrame
+
+    anomalies []StackAnomaly
+    anomalyCount uint64
+}
+
+// CallNode is a routine called along a path of calls, from the Root.
+// A recursive routine has a node for each depth.
+type CallNode struct {
+
+    // Routine is the address the routine was called at, or the one of the interrupt handler.
+    Routine uint16
+    Interrupt bool
+
+    Parent *CallNode
+
+    // Children in the order they were first called.
+    Children []*CallNode
+
+    // Calls counts the times the routine was entered along this path,
+    // Cycles the cycles spent in the routine itself, without its callees.
+    Calls uint64
+    Cycles uint64
+
+    children map[callKey]*CallNode
+}
+
+type callKey struct {
+    routine uint16
+    interrupt bool
+}
+
+// callFrame is an entry of the shadow stack, with what the return should restore.
+type callFrame struct {
+    node *CallNode
+    returnAddress uint16
+
+    // SP before the call, which is the SP after the return.
+    returnSP byte
+}
+
+// AnomalyKind tells how a program didn't return the way it called.
+type AnomalyKind int
+
+const (
+    // ModifiedReturn is a return from the running routine to an address other than
+    // the one after its call, or with RTI from a JSR, or RTS from an interrupt.
+    ModifiedReturn AnomalyKind = iota
+
+    // SkippedFrames is a return from several routines at once, after the routines
+    // running below the one returning dropped their return addresses.
+    SkippedFrames
+
+    // FakeReturn is an RTS or an RTI that doesn't return from any routine,
+    // like an RTS to an address pushed on the stack to jump to it.
+    FakeReturn
+
+    // DroppedFrames is the stack pointer moving above return addresses without
+    // returning, by TXS or by pulling them. The routines are considered returned.
+    DroppedFrames
+)
+
+func (kind AnomalyKind) String() string{
+
+    switch kind {
+    case ModifiedReturn:
+        return "modified return"
+    case SkippedFrames:
+        return "skipped frames"
+    case FakeReturn:
+        return "fake return"
+    case DroppedFrames:
+        return "dropped frames"
+    }
+
+    return fmt.Sprintf("AnomalyKind(%d)", int(kind))
+}
+
+// StackAnomaly describes an instruction that didn't use the stack the way calls do.
+type StackAnomaly struct {
+    Kind AnomalyKind
+
+    // PC is the address of the instruction, Target where it went.
+    PC uint16
+    Target uint16
+
+    // Expected is the return address of the routine returning, for ModifiedReturn.
+    Expected uint16
+
+    // Frames counts the routines left.
+    Frames int
+}
+
+func (a StackAnomaly) String() string{
+
+    switch a.Kind {
+    case ModifiedReturn:
+        return fmt.Sprintf("%v at $%04X: to $%04X instead of $%04X", a.Kind, a.PC, a.Target, a.Expected)
+
+    case SkippedFrames, DroppedFrames:
+        return fmt.Sprintf("%v at $%04X: %d routines left, going to $%04X", a.Kind, a.PC, a.Frames, a.Target)
+    }
+
+    return fmt.Sprintf("%v at $%04X: to $%04X", a.Kind, a.PC, a.Target)
+}
+
+// maxAnomalies is the number of anomalies a CallGraph keeps, jump tables using RTS can make plenty.
+const maxAnomalies = 100
+
+func NewCallGraph() *CallGraph{
+    return &CallGraph{}
+}
+
+// Anomalies returns the first anomalies, and how many were found.
+func (g *CallGraph) Anomalies() ([]StackAnomaly, uint64){
+    return g.anomalies, g.anomalyCount
+}
+
+// Stack returns the routines being run, from the outermost one, the Root excluded.
+func (g *CallGraph) Stack() []*CallNode{
+
+    nodes := make([]*CallNode, len(g.stack))
+    for i := range g.stack {
+        nodes[i] = g.stack[i].node
+    }
+
+    return nodes
+}
+
+// Clear resets the counts and the anomalies. The routines being run stay on the stack,
+// so their returns are still matched.
+func (g *CallGraph) Clear(){
+
+    g.anomalies = nil
+    g.anomalyCount = 0
+
+    if g.Root == nil {
+        return
+    }
+
+    g.Root = &CallNode{Routine: g.Root.Routine, Calls: 1}
+
+    node := g.Root
+    for i := range g.stack {
+        node = node.child(g.stack[i].node.Routine, g.stack[i].node.Interrupt)
+        g.stack[i].node = node
+    }
+}
+
+// InclusiveCycles returns the cycles spent in the routine and the routines it called.
+func (n *CallNode) InclusiveCycles() uint64{
+
+    cycles := n.Cycles
+    for _, child := range n.Children {
+        cycles += child.InclusiveCycles()
+    }
+
+    return cycles
+}
+
+// child returns the node of routine called from n, adding it on the first call.
+func (n *CallNode) child(routine uint16, interrupt bool) *CallNode{
+
+    key := callKey{routine, interrupt}
+
+    if child, ok := n.children[key]; ok {
+        return child
+    }
+
+    if n.children == nil {
+        n.children = map[callKey]*CallNode{}
+    }
+
+    child := &CallNode{Routine: routine, Interrupt: interrupt, Parent: n}
+    n.children[key] = child
+    n.Children = append(n.Children, child)
+
+    return child
+}
+
+// current returns the node of the routine running.
+func (g *CallGraph) current() *CallNode{
+
+    if len(g.stack) == 0 {
+        return g.Root
+    }
+
+    return g.stack[len(g.stack) - 1].node
+}
+
+func (g *CallGraph) report(a StackAnomaly){
+
+    g.anomalyCount++
+
+    if len(g.anomalies) < maxAnomalies {
+        g.anomalies = append(g.anomalies, a)
+    }
+}
+
+// call pushes a frame for the routine at the PC of cpu, returning to returnAddress with returnSP.
+func (g *CallGraph) call(cpu *CPU, pc, returnAddress uint16, returnSP byte, interrupt bool){
+
+    // The frames whose return address the call overwrites were dropped
+    g.drop(pc, cpu.PC, returnSP)
+
+    node := g.current().child(cpu.PC, interrupt)
+    node.Calls++
+
+    g.stack = append(g.stack, callFrame{node: node, returnAddress: returnAddress, returnSP: returnSP})
+}
+
+// drop removes the frames whose return address is no longer on the stack,
+// the stack pointer being sp, and reports them.
+func (g *CallGraph) drop(pc, target uint16, sp byte){
+
+    // A return address is dropped as soon as its first byte is
+    n := len(g.stack)
+    for n > 0 && int(g.stack[n - 1].returnSP) <= int(sp) + 1 {
+        n--
+    }
+
+    if n < len(g.stack) {
+        g.report(StackAnomaly{Kind: DroppedFrames, PC: pc, Target: target, Frames: len(g.stack) - n})
+        g.stack = g.stack[:n]
+    }
+}
+
+// ret matches a return of cpu, now at the address returned to, with the shadow stack.
+func (g *CallGraph) ret(cpu *CPU, pc uint16, interrupt bool){
+
+    // The frame of the routine returning is the one whose SP is restored
+    i := len(g.stack) - 1
+    for i >= 0 && g.stack[i].returnSP < cpu.SP {
+        i--
+    }
+
+    if i < 0 || g.stack[i].returnSP != cpu.SP {
+
+        g.report(StackAnomaly{Kind: FakeReturn, PC: pc, Target: cpu.PC})
+
+        // The return addresses above SP were pulled by the fake return
+        g.stack = g.stack[:i + 1]
+        return
+    }
+
+    frame := &g.stack[i]
+
+    switch {
+    case i < len(g.stack) - 1:
+        g.report(StackAnomaly{Kind: SkippedFrames, PC: pc, Target: cpu.PC, Frames: len(g.stack) - i})
+
+    case frame.returnAddress != cpu.PC || frame.node.Interrupt != interrupt:
+        g.report(StackAnomaly{Kind: ModifiedReturn, PC: pc, Target: cpu.PC, Expected: frame.returnAddress})
+    }
+
+    g.stack = g.stack[:i]
+}
+
+// step accounts for the instruction at pc, executed with sp as the stack pointer,
+// which took cycles.
+func (g *CallGraph) step(cpu *CPU, pc uint16, sp byte, cycles int){
+
+    if g.Root == nil {
+        g.Root = &CallNode{Routine: pc, Calls: 1}
+    }
+
+    // The instruction belongs to the routine running it, calls and returns included
+    g.current().Cycles += uint64(cycles)
+
+    switch cpu.opcode {
+    case instructions.INS_JSR_ABS:
+        g.call(cpu, pc, pc + 3, sp, false)
+
+    case instructions.INS_BRK_IMP:
+        g.call(cpu, pc, pc + 2, sp, true)
+
+    case instructions.INS_RTS_IMP:
+        g.ret(cpu, pc, false)
+
+    case instructions.INS_RTI_IMP:
+        g.ret(cpu, pc, true)
+
+    case instructions.INS_TXS_IMP:
+        g.drop(pc, cpu.PC, cpu.SP)
+    }
+}
+
+// interrupt accounts for the interrupt sequence interrupting the instruction at pc,
+// with sp as the stack pointer. Its cycles go to the handler.
+func (g *CallGraph) interrupt(cpu *CPU, pc uint16, sp byte, cycles int){
+
+    if g.Root == nil {
+        g.Root = &CallNode{Routine: pc, Calls: 1}
+    }
+
+    g.call(cpu, pc, pc, sp, true)
+    g.current().Cycles += uint64(cycles)
+}
@@ -0,0 +1,80 @@
+package arc
+
+// The instructions of the 65C02 which are either new or behave differently
+// from the NMOS 6502, see the "65C02" section of the instructions package.
+// Their handlers are built by the dispatch table from the operations below.
+
+// BIT immediate: there's no memory value to copy bits 6 and 7 from, so only Z is affected
+func bitImmediate(cpu *CPU){
+
+    if (cpu.A & cpu.FetchByte()) == 0 {
+        cpu.PS.Z = set
+    }else {
+        cpu.PS.Z = cleared
+    }
+}
+
+// RMB: reset a bit of a zero page value
+func resetBit(bit byte) func(cpu *CPU, value byte) byte{
+
+    return func(cpu *CPU, value byte) byte{
+        return value &^ (1 << bit)
+    }
+}
+
+// SMB: set a bit of a zero page value
+func setBit(bit byte) func(cpu *CPU, value byte) byte{
+
+    return func(cpu *CPU, value byte) byte{
+        return value | (1 << bit)
+    }
+}
+
+// BBR & BBS: branch if a bit of a zero page value is cleared or set
+func branchOnBit(bit byte, condition uint) handler{
+
+    return func(cpu *CPU){
+
+        memValue := cpu.read(cpu.AddressZeroPage())
+
+        cpu.BranchIf(uint((memValue >> bit) & 0x01), condition)
+    }
+}
+
+// testBits sets the flags like BIT does: Z if A AND value is zero,
+// V and N to bits 6 and 7 of value.
+func testBits(cpu *CPU, value byte){
+
+    if (cpu.A & value) == 0 {
+        cpu.PS.Z = set
+    }else {
+        cpu.PS.Z = cleared
+    }
+
+    cpu.PS.V = uint((value >> 6) & 0x01)
+    cpu.PS.N = uint(value >> 7)
+}
+
+// TRB: Z is set like BIT does, then the bits set in A are cleared in value
+func testAndResetBits(cpu *CPU, value byte) byte{
+
+    if (cpu.A & value) == 0 {
+        cpu.PS.Z = set
+    }else {
+        cpu.PS.Z = cleared
+    }
+
+    return value &^ cpu.A
+}
+
+// TSB: Z is set like BIT does, then the bits set in A are set in value
+func testAndSetBits(cpu *CPU, value byte) byte{
+
+    if (cpu.A & value) == 0 {
+        cpu.PS.Z = set
+    }else {
+        cpu.PS.Z = cleared
+    }
+
+    return value | cpu.A
+}
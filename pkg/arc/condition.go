@@ -0,0 +1,295 @@
+package arc
+
+import (
+	"emulator/pkg/common"
+	"fmt"
+	"strings"
+)
+
+// Condition is an expression over the registers, the flags and the memory,
+// deciding whether a breakpoint stops the CPU, like
+//
+//	A == $10 && mem[$20] > 3
+//
+// The registers are A, X, Y, SP (or S), PC and P (or PS), the flags C, Z, I, D, B, V and N,
+// and mem[address] is the byte at address. Names aren't case sensitive.
+// Numbers and operators are the ones of the assembler, see common.BinaryOperators:
+// numbers are decimal, hexadecimal with $, or binary with %, and the operators are the
+// ones of C, with the same precedence. Hexadecimal can also be written with 0x, like the
+// addresses of the monitor, and since a condition can't fail while the CPU runs,
+// dividing by zero gives 0. The unary < and > of the assembler aren't supported.
+// Comparisons give 1 or 0. The condition holds when it evaluates to anything but 0.
+type Condition struct {
+    text string
+    expression expression
+}
+
+// expression is a compiled expression, read gives access to the memory without going through watchpoints.
+type expression func(cpu *CPU, read func(uint16) byte) int
+
+// ParseCondition compiles text into a Condition.
+func ParseCondition(text string) (*Condition, error){
+
+    p := &conditionParser{text: text}
+
+    e, err := p.binary(0)
+    if err == nil {
+        p.skipSpaces()
+        if p.pos < len(p.text) {
+            err = p.errorf("unexpected %q", p.text[p.pos:])
+        }
+    }
+
+    if err != nil {
+        return nil, err
+    }
+
+    return &Condition{text: strings.TrimSpace(text), expression: e}, nil
+}
+
+// String returns the text the condition was parsed from.
+func (c *Condition) String() string{
+    return c.text
+}
+
+// Evaluate returns the value of the condition for the current state of cpu.
+// It reads the memory through cpu.AddressSpace().
+func (c *Condition) Evaluate(cpu *CPU) int{
+    return c.expression(cpu, cpu.AddressSpace().Read)
+}
+
+// Holds reports whether the condition is true for the current state of cpu.
+func (c *Condition) Holds(cpu *CPU) bool{
+    return c.Evaluate(cpu) != 0
+}
+
+type conditionParser struct {
+    text string
+    pos int
+}
+
+func (p *conditionParser) errorf(format string, args ...interface{}) error{
+    return fmt.Errorf("condition %q: %s", p.text, fmt.Sprintf(format, args...))
+}
+
+func (p *conditionParser) skipSpaces(){
+
+    for p.pos < len(p.text) && (p.text[p.pos] == ' ' || p.text[p.pos] == '\t') {
+        p.pos++
+    }
+}
+
+// binary parses the operators of the given precedence level and the ones above it.
+func (p *conditionParser) binary(level int) (expression, error){
+
+    if level == len(common.BinaryOperators) {
+        return p.unary()
+    }
+
+    x, err := p.binary(level + 1)
+    if err != nil {
+        return nil, err
+    }
+
+    for {
+        p.skipSpaces()
+
+        operator := common.MatchOperator(p.text[p.pos:], common.BinaryOperators[level])
+        if operator == nil {
+            return x, nil
+        }
+
+        p.pos += len(operator.Symbol)
+
+        y, err := p.binary(level + 1)
+        if err != nil {
+            return nil, err
+        }
+
+        left, right := x, y
+
+        switch operator.Symbol {
+        case "&&":
+            x = func(cpu *CPU, read func(uint16) byte) int{
+                return common.Truth(left(cpu, read) != 0 && right(cpu, read) != 0)
+            }
+
+        case "||":
+            x = func(cpu *CPU, read func(uint16) byte) int{
+                return common.Truth(left(cpu, read) != 0 || right(cpu, read) != 0)
+            }
+
+        default:
+            apply := operator.Apply
+
+            // Dividing by zero gives 0
+            x = func(cpu *CPU, read func(uint16) byte) int{
+                n, _ := apply(left(cpu, read), right(cpu, read))
+                return n
+            }
+        }
+    }
+}
+
+func (p *conditionParser) unary() (expression, error){
+
+    p.skipSpaces()
+
+    if p.pos == len(p.text) {
+        return nil, p.errorf("unexpected end")
+    }
+
+    operator := p.text[p.pos]
+
+    switch operator {
+    case '-', '~', '!':
+
+        p.pos++
+
+        x, err := p.unary()
+        if err != nil {
+            return nil, err
+        }
+
+        return func(cpu *CPU, read func(uint16) byte) int{
+
+            switch operator {
+            case '-':
+                return -x(cpu, read)
+            case '~':
+                return ^x(cpu, read)
+            }
+
+            return common.Truth(x(cpu, read) == 0)
+        }, nil
+    }
+
+    return p.primary()
+}
+
+func (p *conditionParser) primary() (expression, error){
+
+    c := p.text[p.pos]
+
+    switch {
+    case c == '(':
+
+        p.pos++
+
+        x, err := p.binary(0)
+        if err != nil {
+            return nil, err
+        }
+
+        if err := p.expect(')'); err != nil {
+            return nil, err
+        }
+
+        return x, nil
+
+    case c == '$' || c == '%' || (c >= '0' && c <= '9'):
+        return p.number()
+
+    case isLetter(c):
+        return p.name()
+    }
+
+    return nil, p.errorf("unexpected %q", p.text[p.pos:])
+}
+
+func (p *conditionParser) expect(c byte) error{
+
+    p.skipSpaces()
+
+    if p.pos == len(p.text) || p.text[p.pos] != c {
+        return p.errorf("expected %c", c)
+    }
+
+    p.pos++
+
+    return nil
+}
+
+func isLetter(c byte) bool{
+    return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+func isAlphanumeric(c byte) bool{
+    return isLetter(c) || (c >= '0' && c <= '9')
+}
+
+func (p *conditionParser) number() (expression, error){
+
+    start := p.pos
+    for p.pos < len(p.text) && (isAlphanumeric(p.text[p.pos]) || (p.pos == start && (p.text[p.pos] == '$' || p.text[p.pos] == '%'))) {
+        p.pos++
+    }
+
+    text := p.text[start:p.pos]
+
+    digits := text
+    if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+        digits = "$" + text[2:]
+    }
+
+    n, err := common.ParseNumber(digits)
+    if err != nil {
+        return nil, p.errorf("invalid number %s", text)
+    }
+
+    return func(*CPU, func(uint16) byte) int{
+        return int(n)
+    }, nil
+}
+
+func (p *conditionParser) name() (expression, error){
+
+    start := p.pos
+    for p.pos < len(p.text) && isAlphanumeric(p.text[p.pos]) {
+        p.pos++
+    }
+
+    name := strings.ToLower(p.text[start:p.pos])
+
+    var e expression
+
+    switch name {
+    case "a": e = func(cpu *CPU, _ func(uint16) byte) int{ return int(cpu.A) }
+    case "x": e = func(cpu *CPU, _ func(uint16) byte) int{ return int(cpu.X) }
+    case "y": e = func(cpu *CPU, _ func(uint16) byte) int{ return int(cpu.Y) }
+    case "sp", "s": e = func(cpu *CPU, _ func(uint16) byte) int{ return int(cpu.SP) }
+    case "pc": e = func(cpu *CPU, _ func(uint16) byte) int{ return int(cpu.PC) }
+    case "p", "ps": e = func(cpu *CPU, _ func(uint16) byte) int{ return int(cpu.PSToByte()) }
+    case "c": e = func(cpu *CPU, _ func(uint16) byte) int{ return int(cpu.PS.C) }
+    case "z": e = func(cpu *CPU, _ func(uint16) byte) int{ return int(cpu.PS.Z) }
+    case "i": e = func(cpu *CPU, _ func(uint16) byte) int{ return int(cpu.PS.I) }
+    case "d": e = func(cpu *CPU, _ func(uint16) byte) int{ return int(cpu.PS.D) }
+    case "b": e = func(cpu *CPU, _ func(uint16) byte) int{ return int(cpu.PS.B) }
+    case "v": e = func(cpu *CPU, _ func(uint16) byte) int{ return int(cpu.PS.V) }
+    case "n": e = func(cpu *CPU, _ func(uint16) byte) int{ return int(cpu.PS.N) }
+
+    case "mem":
+
+        if err := p.expect('['); err != nil {
+            return nil, err
+        }
+
+        address, err := p.binary(0)
+        if err != nil {
+            return nil, err
+        }
+
+        if err := p.expect(']'); err != nil {
+            return nil, err
+        }
+
+        e = func(cpu *CPU, read func(uint16) byte) int{
+            return int(read(uint16(address(cpu, read))))
+        }
+
+    default:
+        return nil, p.errorf("unknown name %s", p.text[start:p.pos])
+    }
+
+    return e, nil
+}
@@ -1,10 +1,10 @@
 package arc
 
 import (
-	"emulator/pkg/common"
-	"emulator/pkg/instructions"
 	"fmt"
+	"io"
 	"log"
+	"os"
 )
 
 // Typical of the PS:
@@ -20,1748 +20,571 @@ import (
 
 const MaxMem = 1024 * 64
 
-type flags interface{
-    testIfSet()
-}
-
-type Memory struct {
-
-   Data [MaxMem]byte
-}
-
-// These variables are used for checking flags
-const cleared = 0
-const set = 1
+// Vectors are fixed 16-bit addresses at the top of memory holding the address
+// the CPU jumps to when the corresponding event occurs, stored little endian.
+const (
+    NMIVector = 0xFFFA
+    ResetVector = 0xFFFC
 
+    // BRK shares its vector with the hardware IRQ.
+    IRQVector = 0xFFFE
+)
 
+// Bits of the PS byte which are not backed by a real flag on the chip.
+// They only exist in the copy of the PS pushed to the stack.
+const breakBit = 1 << 4
+const unusedBit = 1 << 5
 
-func (mem *Memory) Initialise(){
-    
-    for i := 0; i < MaxMem; i++{
-        mem.Data[i] = 0
-    }
-}
-type ProcessorStatus struct {
-    C uint // Carry flag
-    Z uint // Zero flag
-    I uint // Interrupt Disable flag
-    D uint // Decimal mode flag
-    B uint // Break command flag
-    U uint // This bit is reserved for future use and should always be set to 1 when writing to the PSR.
-    V uint // Overflow flag
-    N uint // Negative flag
+type flags interface{
+    testIfSet()
 }
 
-type CPU struct {
-
-    // Program Counter points to the next instruction.
-    PC uint16 
-
-    // Stack Pointer is a 8-bit register that holds the low 8bits of the next free
-    // location on the stack. Pushing bytes to the stack causes the Stack Pointer to be 
-    // decremented. The CPU does not detect if the stack is overflowed by excessive pushing or
-    // pulling operations and will most likely result in the program crashing.
-    // Goes from $0100 to $01FF, it's fixed.
-    SP byte  
-
-    // Accumulator register is used for all arithmetical and logical operations.
-    // Exception made for intcrements and decrements. The contents of the accumulator
-    // can be stored and retrieved either from memory or the stack.
-    A byte
-
-    // The X register is most commonly used to hold counters and offsets for accessing.
-    // It has one special purpose, which is to get a copy of the SP or change its value.
-    X byte
-
-    // The Y register is similar to the X register but has no special functions.
-    Y byte
-
-    // The Processor Status is a 8-bit status which holds a bunch of bits, that get set in it after operations.
-    PS ProcessorStatus
-
-    Memory Memory
+// Bus connects the CPU to memory and devices.
+// Every access of the CPU goes through it: instruction and operand fetches,
+// reads, writes and stack operations. Memory mapped devices can be wired to the CPU
+// by implementing a Bus that routes some addresses to them.
+type Bus interface {
+    Read(address uint16) byte
+    Write(address uint16, value byte)
 }
 
-// TODO: How ugly is this?
-var getFlagName = make(map[*uint]string)
-
-func (cpu *CPU) Reset(resetVector uint16){
-
-    // Reset procedure does not follow accurate Commodor 64, it acts like a computer that's like a 
-    // Commodor 64.
-
-    // Reset vector address
-    cpu.PC = resetVector
-
-    // Clear all flags
-    cpu.PS.C = 0
-    cpu.PS.Z = 0
-    cpu.PS.I = 0
-    cpu.PS.D = 0
-    cpu.PS.B = 0
-    cpu.PS.U = 0
-    cpu.PS.V = 0
-    cpu.PS.N = 0
-
-    // TODO: pretty sure this shouldn't be done here
-    getFlagName[&cpu.PS.C] = "Carry flag"
-    getFlagName[&cpu.PS.Z] = "Zero flag"
-    getFlagName[&cpu.PS.I] = "Interrupt disable flag"
-    getFlagName[&cpu.PS.D] = "Decimal flag"
-    getFlagName[&cpu.PS.B] = "Break command flag"
-    getFlagName[&cpu.PS.U] = "Unused flag"
-    getFlagName[&cpu.PS.V] = "Overflow flag"
-    getFlagName[&cpu.PS.N] = "Negative flag"
-
-    // After the Reset, there's 9 post-reset cycles, which execute three fake push into the stack.
-    // The final SP is therefore 00 - 1 = FF, FF - 1 = FE, FF - 1 = FD
-    // https://www.c64-wiki.com/wiki/Reset_(Process)
-    cpu.SP = 0xFD
-
-    // Not sure if we want this to happen for now.
-    cpu.A = 0
-    cpu.X = 0
-    cpu.Y = 0
+// Memory is a plain 64K RAM, it's the Bus used by the CPU unless another one is set.
+type Memory struct {
 
-    cpu.Memory.Initialise()
+   Data [MaxMem]byte
 }
 
-
-func (cpu *CPU) PrintStatus(){
-    fmt.Println("PC:", cpu.PC)
-    fmt.Println("SP:", cpu.SP)
-    fmt.Println("PS:", cpu.PS)
+func (mem *Memory) Read(address uint16) byte{
+    return mem.Data[address]
 }
 
+func (mem *Memory) Write(address uint16, value byte){
+    mem.Data[address] = value
+}
 
+// These variables are used for checking flags
+const cleared = 0
+const set = 1
 
-// Execute runs the fetch-decode loop.
-// It fetches the instruction byte and then, based on the opcode fetched, 
-// executes the corresponding instruction.
-// It returns the number of cycles used, for Testing purposes.
-func (cpu *CPU) Execute( cycles int ) ( cyclesUsed int) {
-
-    // At the beginning, initialise cyclesUsed as the number of cycles passed when calling the
-    // method Execute().
-    cyclesUsed = cycles
-
-    // Can we get stuck in infinite loop if we pass more cycles than expected?
-    // Not for now because since memory is initialised to 0, if we try to fetch a 
-    // byte from one more cell memory where we are not supposed to be, it fetches 0 and
-    // exits the switch loop with the default case
-    for cycles > 0 {
-
-
-        // Fetch instruction, takes up one clock cycle
-        // PC++
-        ins := cpu.FetchByte(&cycles)
-        
-        // Decode instruction
-        switch (ins) {
-
-
-            // Execute operations based on the instruction opcode
-        case instructions.INS_LDA_IM:
-
-            cpu.A = cpu.FetchByte(&cycles)
-
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-
-            // Total cycles: 2
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_LDA_ZP:
-
-            // First byte is the ZeroPage address
-            // Second byte is the value to load
-
-            // First cycle to fetch the instruction
-            // Second cycle to fetch the address
-            // The third cycle to read the data from the address
-            zeroPageAddress := cpu.AddressZeroPage(&cycles)
-            
-            LoadRegisterAndSetStatusFlags(cpu, &cycles, zeroPageAddress, &cpu.A)
-
-            // Total cycles: 3
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_LDA_ZPX:
-
-            zeroPageAddress := cpu.AddressZeroPageX(&cycles)
-
-            LoadRegisterAndSetStatusFlags(cpu, &cycles, zeroPageAddress, &cpu.A)
-
-            // Total cycles: 4
-            // Total bytes: 2
-            break;
-        case instructions.INS_LDA_ABS:
-
-            // Fetch the target location using a full 16 bit address
-            targetAddress := cpu.AddressAbsolute(&cycles)
-
-            LoadRegisterAndSetStatusFlags(cpu, &cycles, targetAddress, &cpu.A)
-
-            // Total cycles: 4
-            // Total bytes: 3
-            break;
-        case instructions.INS_LDA_ABSX:
-
-            // TODO: cycles count it's not right
-            // Fetch 16-bit address
-            targetAddress := cpu.AddressAbsoluteX(&cycles)
-
-            LoadRegisterAndSetStatusFlags(cpu, &cycles, targetAddress, &cpu.A)
-
-            // Total cycles: 4(+1 if page crossed)
-            // Total bytes: 3
-            break;
-        case instructions.INS_LDA_ABSY:
-
-            targetAddress := cpu.AddressAbsoluteY(&cycles)
-
-            LoadRegisterAndSetStatusFlags(cpu, &cycles, targetAddress, &cpu.A)
-
-            // Total cycles: 4(+1 if page crossed)
-            // Total bytes: 3
-            break;
-        case instructions.INS_LDA_INDX:
-
-            // In this mode the X register is used to offset the zero page vector,
-            // used to determine the effective address.
-            // Put another way, the vector is chosen by adding the value in the X register,
-            // to the given zero page address.
-            // The resulting zero page address is the vector from which the effective address is read.
-            // Weird stuff.
-
-            // Example:
-            // LDX #$04
-            // LDA ($02, X)
-
-            // In the above case X is loaded with four, so the vector is calculated with 
-            // $02 + $04 = $06 (resulting vector)
-            // If the zero page memory $06 contains: 00 80, then the effective address from the vector (06)
-            // would be $8000
-
-            // This takes 4 cycles
-            effectiveAddress := cpu.AddressIndirectX(&cycles)
-
-            // This takes 1 cycle
-            LoadRegisterAndSetStatusFlags(cpu, &cycles,effectiveAddress, &cpu.A)
-
-            // Total cycles: 6
-            // Total bytes: 2
-            break;
-        case instructions.INS_LDA_INDY:
-
-            // This takes 3+1 cycles
-            effectiveAddress := cpu.AddressIndirectY(&cycles)
-
-            // This takes 1 cycle
-            LoadRegisterAndSetStatusFlags(cpu, &cycles,effectiveAddress, &cpu.A)
-
-            // Total cycles: 5(+1 if page crossed)
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_LDX_IM:
-
-            // Load value into X
-            cpu.X = cpu.FetchByte(&cycles)
-
-            // Set LDX status flags
-            SetZeroAndNegativeFlags(cpu, cpu.X)
-
-            // Total cycles: 2
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_LDX_ZP:
-
-            // First byte is the ZeroPage address
-            // Second byte is the value to load
-
-            // First cycle to fetch the instruction
-            // Second cycle to fetch the address
-            // The third cycle to read the data from the address
-            zeroPageAddress := cpu.AddressZeroPage(&cycles)
-            
-            LoadRegisterAndSetStatusFlags(cpu, &cycles, zeroPageAddress, &cpu.X)
-
-            // Total cycles: 3
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_LDX_ZPY:
-
-            zeroPageAddress := cpu.AddressZeroPageY(&cycles)
-
-            LoadRegisterAndSetStatusFlags(cpu, &cycles, zeroPageAddress, &cpu.X)
-            // Total cycles: 4
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_LDX_ABS:
-
-            // Fetch the target location using a full 16 bit address
-            targetAddress := cpu.AddressAbsolute(&cycles)
-
-            LoadRegisterAndSetStatusFlags(cpu, &cycles, targetAddress, &cpu.X)
-
-            // Total cycles: 4
-            // Total bytes: 3
-            break;
-
-        case instructions.INS_LDX_ABSY:
-
-            // Fetch 16-bit address
-            targetAddress := cpu.AddressAbsoluteY(&cycles)
-
-            LoadRegisterAndSetStatusFlags(cpu, &cycles, targetAddress, &cpu.X)
-
-            // Total cycles: 4(+1 if page crossed)
-            // Total bytes: 3
-            break;
-
-        case instructions.INS_LDY_IM:
-
-            // Load value into Y
-            cpu.Y = cpu.FetchByte(&cycles)
-
-            // Set LDX status flags
-            SetZeroAndNegativeFlags(cpu, cpu.Y)
-
-            // Total cycles: 2
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_LDY_ZP:
-
-            // First byte is the ZeroPage address
-            // Second byte is the value to load
-
-            // First cycle to fetch the instruction
-            // Second cycle to fetch the address
-            // The third cycle to read the data from the address
-            zeroPageAddress := cpu.AddressZeroPage(&cycles)
-            
-            LoadRegisterAndSetStatusFlags(cpu, &cycles, zeroPageAddress, &cpu.Y)
-
-            // Total cycles: 3
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_LDY_ZPX:
-
-            zeroPageAddress := cpu.AddressZeroPageX(&cycles)
-            
-            LoadRegisterAndSetStatusFlags(cpu, &cycles, zeroPageAddress, &cpu.Y)
-
-            // Total cycles: 4
-            // Total bytes: 2
-            break;
-        case instructions.INS_LDY_ABS:
-
-            // Fetch the target location using a full 16 bit address
-            targetAddress := cpu.AddressAbsolute(&cycles)
-
-            LoadRegisterAndSetStatusFlags(cpu, &cycles, targetAddress, &cpu.Y)
-            // Total cycles: 4
-            // Total bytes: 3
-            break;
-
-        case instructions.INS_LDY_ABSX:
-
-            // Fetch the target location using a full 16 bit address
-            targetAddress := cpu.AddressAbsoluteX(&cycles)
-
-            LoadRegisterAndSetStatusFlags(cpu, &cycles, targetAddress, &cpu.Y)
-
-            // Total cycles: 4(+1 if page crossed)
-            // Total bytes: 3
-            break;
-
-        case instructions.INS_STA_ZP:
-
-            zeroPageAddress := cpu.AddressZeroPage(&cycles)
-
-            cpu.WriteByte(&cycles, cpu.A, zeroPageAddress)
-            
-            // Total cycles: 3
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_STA_ZPX:
-
-            zeroPageAddress := cpu.AddressZeroPageX(&cycles)
-
-            cpu.WriteByte(&cycles, cpu.A, zeroPageAddress)
-
-            // Total cycles: 4
-            // Total bytes: 2
-
-            break;
-
-        case instructions.INS_STA_ABS:
-
-            // Fetch the target location using a full 16 bit address
-            targetAddress := cpu.AddressAbsolute(&cycles)
-
-            cpu.WriteByte(&cycles, cpu.A, targetAddress)
-
-            // Total cycles: 4
-            // Total bytes: 3
-            break;
-
-        case instructions.INS_STA_ABSX:
-
-            // Why does this take 5 cycles flat? Weird
-
-            // Fetch the target location using a full 16 bit address
-            targetAddress := cpu.FetchWord(&cycles)
-
-            // Add X to the target address
-            // AddRegValueToTarget16Address() not used because totale cycles amount to 5, 
-            // independently from page crossing.
-            targetAddress += uint16(cpu.X)
-            cycles--
-
-            cpu.WriteByte(&cycles, cpu.A, targetAddress)
-
-            // Total cycles: 5
-            // Total bytes: 3
-            break;
-
-        case instructions.INS_STA_ABSY:
-
-            // Fetch the target location using a full 16 bit address
-            targetAddress := cpu.FetchWord(&cycles)
-
-            // Add Y to the target address
-            // AddRegValueToTarget16Address() not used because totale cycles amount to 5, 
-            // independently from page crossing.
-            targetAddress += uint16(cpu.Y)
-            cycles--
-
-            cpu.WriteByte(&cycles, cpu.A, targetAddress)
-
-            // Total cycles: 5
-            // Total bytes: 3
-            break;
-
-
-        case instructions.INS_STA_INDX:
-
-            // In this mode the X register is used to offste the zero page vector,
-            // used to determine the effective address.
-            // Put another way, the vector is chosen by adding the value in the X register,
-            // to the given zero page address.
-            // The resulting zero page address is the vector from which the effective address is read.
-            // Weird stuff.
-
-            // Example:
-            // LDX #$04
-            // LDA ($02, X)
-
-            // In the above case X is loaded with four, so the vector is calculated with 
-            // $02 + $04 = $06 (resulting vector)
-            // If the zero page memory $06 contains: 00 80, then the effective address from the vector (06)
-            // would be $8000
-
-            effectiveAddress := cpu.AddressIndirectX(&cycles)
-
-            cpu.WriteByte(&cycles, cpu.A, effectiveAddress)
-
-            // Total cycles: 6
-            // Total bytes: 2
-            break;
-        case instructions.INS_STA_INDY:
-
-            // Fetch the Zero Page Address
-            zeroPageAddress := cpu.AddressZeroPage(&cycles)
-
-            effectiveAddress := cpu.ReadWord(&cycles, zeroPageAddress)
-
-            // Add Y to the Zero Page Address
-            // AddRegValueToTarget16Address() not used because totale cycles amount to 6, 
-            // independently from page crossing.
-            effectiveAddress += uint16(cpu.Y)
-            cycles--
-
-            cpu.WriteByte(&cycles, cpu.A, effectiveAddress)
-
-            // Total cycles: 6
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_STX_ZP:
-
-            zeroPageAddress := cpu.FetchByte(&cycles)
-
-            cpu.WriteByte(&cycles, cpu.X, uint16(zeroPageAddress))
-            
-            // Total cycles: 3
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_STX_ZPY:
-
-            zeroPageAddress := cpu.FetchByte(&cycles)
-
-            // TODO: handle address overflow
-            // Wrap Around
-            zeroPageAddress = byte(uint16(cpu.Y + zeroPageAddress) % 0x100)
-            cycles--
-
-            cpu.WriteByte(&cycles, cpu.X, uint16(zeroPageAddress))
-
-            // Total cycles: 4
-            // Total bytes: 2
-
-            break;
-
-        case instructions.INS_STX_ABS:
-
-            // Fetch the target location using a full 16 bit address
-            targetAddress := cpu.FetchWord(&cycles)
-
-            cpu.WriteByte(&cycles, cpu.X, targetAddress)
-
-            // Total cycles: 4
-            // Total bytes: 3
-            break;
-
-        case instructions.INS_STY_ZP:
-
-            zeroPageAddress := cpu.FetchByte(&cycles)
-
-            cpu.WriteByte(&cycles, cpu.Y, uint16(zeroPageAddress))
-            
-            // Total cycles: 3
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_STY_ZPX:
-
-            zeroPageAddress := cpu.FetchByte(&cycles)
-
-            // TODO: handle address overflow
-            // Wrap Around
-            zeroPageAddress = byte(uint16(cpu.X + zeroPageAddress) % 0x100)
-            cycles--
-
-            cpu.WriteByte(&cycles, cpu.Y, uint16(zeroPageAddress))
-
-            // Total cycles: 4
-            // Total bytes: 2
-
-            break;
-
-        case instructions.INS_STY_ABS:
-
-            // Fetch the target location using a full 16 bit address
-            targetAddress := cpu.FetchWord(&cycles)
-
-            cpu.WriteByte(&cycles, cpu.Y, targetAddress)
-
-            // Total cycles: 4
-            // Total bytes: 3
-            break;
-
-        case instructions.INS_TAX_IMP:
-
-            // Copy the current contents of the accumulator into the X register and sets the zero and negative flags as appropriate.
-            // Implicit:
-            // For many 6502 instructions the source and destination of the information to be manipulated
-            // is implied directly by the function of the instruction itself and no further operand needs to be specified.
-            // Operations like 'Clear Carry Flag' (CLC) and 'Return from Subroutine' (RTS) are implicit.
-
-            cpu.X = cpu.A
-            cycles--
-
-            SetZeroAndNegativeFlags(cpu, cpu.X)
-
-
-
-
-            // Total cycles: 2
-            // Total bytes: 1
-            break;
-
-        case instructions.INS_TAY_IMP:
-
-            // Copy the current contents of the accumulator into the X register and sets the zero and negative flags as appropriate.
-            // Implicit:
-            // For many 6502 instructions the source and destination of the information to be manipulated
-            // is implied directly by the function of the instruction itself and no further operand needs to be specified.
-            // Operations like 'Clear Carry Flag' (CLC) and 'Return from Subroutine' (RTS) are implicit.
-
-            cpu.Y = cpu.A
-            cycles--
-
-            SetZeroAndNegativeFlags(cpu, cpu.Y)
-
-
-
-
-            // Total cycles: 2
-            // Total bytes: 1
-            break;
-
-        case instructions.INS_TXA_IMP:
-
-            // Copy the current contents of the accumulator into the X register and sets the zero and negative flags as appropriate.
-            // Implicit:
-            // For many 6502 instructions the source and destination of the information to be manipulated
-            // is implied directly by the function of the instruction itself and no further operand needs to be specified.
-            // Operations like 'Clear Carry Flag' (CLC) and 'Return from Subroutine' (RTS) are implicit.
-
-            cpu.A = cpu.X
-            cycles--
-
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-
-
-
-
-            // Total cycles: 2
-            // Total bytes: 1
-            break;
-
-        case instructions.INS_TYA_IMP:
-
-            // Copy the current contents of the accumulator into the X register and sets the zero and negative flags as appropriate.
-            // Implicit:
-            // For many 6502 instructions the source and destination of the information to be manipulated
-            // is implied directly by the function of the instruction itself and no further operand needs to be specified.
-            // Operations like 'Clear Carry Flag' (CLC) and 'Return from Subroutine' (RTS) are implicit.
-
-            cpu.A = cpu.Y
-            cycles--
-
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-
-            // Total cycles: 2
-            // Total bytes: 1
-            break;
-
-        case instructions.INS_TSX_IMP:
-
-            // Copy the current contents of the accumulator into the X register and sets the zero and negative flags as appropriate.
-            // Implicit:
-            // For many 6502 instructions the source and destination of the information to be manipulated
-            // is implied directly by the function of the instruction itself and no further operand needs to be specified.
-            // Operations like 'Clear Carry Flag' (CLC) and 'Return from Subroutine' (RTS) are implicit.
-
-            cpu.X = cpu.SP
-            cycles--
-
-            SetZeroAndNegativeFlags(cpu, cpu.X)
-
-            // Total cycles: 2
-            // Total bytes: 1
-            break;
-
-        case instructions.INS_TXS_IMP:
-
-            // Copy the current contents of the accumulator into the X register and sets the zero and negative flags as appropriate.
-            // Implicit:
-            // For many 6502 instructions the source and destination of the information to be manipulated
-            // is implied directly by the function of the instruction itself and no further operand needs to be specified.
-            // Operations like 'Clear Carry Flag' (CLC) and 'Return from Subroutine' (RTS) are implicit.
-
-            cpu.SP = cpu.X
-            cycles--
-
-            // Total cycles: 2
-            // Total bytes: 1
-            break;
-
-        case instructions.INS_PHA_IMP:
-
-            cpu.PushByteToStack(&cycles, cpu.A)
-
-            cycles--
-
-            // Total cycles: 3
-            // Total bytes: 1
-            break;
-
-        case instructions.INS_PHP_IMP:
-
-            cpu.PushByteToStack(&cycles, cpu.PSToByte())
-            cycles--
-
-            // Total cycles: 3
-            // Total bytes: 1
-            break;
-
-        case instructions.INS_PLA_IMP:
-
-            cpu.A = cpu.PopByteFromStack(&cycles)
-
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-
-            cycles-=2
-
-            // Total cycles: 4
-            // Total bytes: 1
-            break;
-
-        case instructions.INS_PLP_IMP:
-
-            PSByte := cpu.PopByteFromStack(&cycles)
-            cpu.PS = cpu.ByteToPS(PSByte)
-
-            cycles-=2
-
-            // Total cycles: 4
-            // Total bytes: 1
-            break;
-
-        case instructions.INS_JSR_ABS:
-
-            // Example:
-            // I read opcode at FF00. PC is now FF01
-            // targetAddress := cpu.FetchWord(&cycles)
-            // I read 00 at FF01 and 80 at FF02. PC is now FF03
-            // I store PC - 1 = FF02 in the SP which is FD
-            // Which means 02 at 01FD and FF at 01FC
-            // PC is 8000 
-
-            // Fetch the targetMemoryAddress, which is where we have to jump to
-            targetAddress := cpu.FetchWord(&cycles)
-
-            // This takes 2 cycles
-            cpu.PushWordToStack(&cycles, cpu.PC-1)
-
-            cpu.PC = targetAddress
-            cycles--
-
-            // Total cycles: 6
-            // Total bytes: 3
-
-            break;
-
-        case instructions.INS_RTS_IMP:
-            cpu.PC = cpu.PopWordFromStack(&cycles)
-
-            // This is necessary since we want to Execute next instruction in the next loop iteration
-            // If I don't increase the PC, it will run the same execution stored in the SP
-            cpu.PC++
-
-            // TODO: why is this necessary to reach a total of 6 cycles? What happens?
-
-            // Total cycles: 6
-            // Total bytes: 1
-            cycles -=3
-            break;
-        case instructions.INS_JMP_ABS:
-
-            cpu.PC = cpu.AddressAbsolute(&cycles)
-
-            // Total cycles: 3
-            // Total bytes: 3
-            break;
-
-        case instructions.INS_JMP_IND:
-
-            targetAddress := cpu.AddressAbsolute(&cycles)
-
-            cpu.PC = cpu.ReadWord(&cycles, targetAddress)
-
-            // Total cycles: 5
-            // Total bytes: 3
-            break;
-
-        case instructions.INS_AND_IM:
-
-            cpu.A = cpu.A & cpu.FetchByte(&cycles)
-
-            // Total cycles: 2
-            // Total bytes: 2
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_AND_ZP:
-
-            zeroPageAddress := cpu.AddressZeroPage(&cycles)
-
-            cpu.A = cpu.A & cpu.ReadByte(&cycles, zeroPageAddress)
-
-            // Total cycles: 3
-            // Total bytes: 2
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_AND_ZPX:
-
-            zeroPageAddress := cpu.AddressZeroPageX(&cycles)
-
-            cpu.A = cpu.A & cpu.ReadByte(&cycles, zeroPageAddress)
-
-            // Total cycles: 4
-            // Total bytes: 2
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-        
-        case instructions.INS_AND_ABS:
-
-            absoluteAddress := cpu.AddressAbsolute(&cycles)
-
-            cpu.A = cpu.A & cpu.ReadByte(&cycles, absoluteAddress)
-
-            // Total cycles: 4
-            // Total bytes: 3
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_AND_ABSX:
-
-            absoluteAddress := cpu.AddressAbsoluteX(&cycles)
-
-            cpu.A = cpu.A & cpu.ReadByte(&cycles, absoluteAddress)
-
-            // Total cycles: 4+1
-            // Total bytes: 3
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_AND_ABSY:
-
-            absoluteAddress := cpu.AddressAbsoluteY(&cycles)
-
-            cpu.A = cpu.A & cpu.ReadByte(&cycles, absoluteAddress)
-
-            // Total cycles: 4+1
-            // Total bytes: 3
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_AND_INDX:
-
-            effectiveAddress := cpu.AddressIndirectX(&cycles)
-
-            cpu.A = cpu.A & cpu.ReadByte(&cycles, effectiveAddress)
-
-            // Total cycles: 6
-            // Total bytes: 2
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_AND_INDY:
-
-            effectiveAddress := cpu.AddressIndirectY(&cycles)
-
-            cpu.A = cpu.A & cpu.ReadByte(&cycles, effectiveAddress)
-
-            // Total cycles: 5+1
-            // Total bytes: 2
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_EOR_IM:
-
-            cpu.A = cpu.A ^ cpu.FetchByte(&cycles)
-
-            // Total cycles: 2
-            // Total bytes: 2
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_EOR_ZP:
-
-            zeroPageAddress := cpu.AddressZeroPage(&cycles)
-
-            cpu.A = cpu.A ^ cpu.ReadByte(&cycles, zeroPageAddress)
-
-            // Total cycles: 3
-            // Total bytes: 2
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_EOR_ZPX:
-
-            zeroPageAddress := cpu.AddressZeroPageX(&cycles)
-
-            cpu.A = cpu.A ^ cpu.ReadByte(&cycles, zeroPageAddress)
-
-            // Total cycles: 4
-            // Total bytes: 2
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-        
-        case instructions.INS_EOR_ABS:
-
-            absoluteAddress := cpu.AddressAbsolute(&cycles)
-
-            cpu.A = cpu.A ^ cpu.ReadByte(&cycles, absoluteAddress)
-
-            // Total cycles: 4
-            // Total bytes: 3
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_EOR_ABSX:
-
-            absoluteAddress := cpu.AddressAbsoluteX(&cycles)
-
-            cpu.A = cpu.A ^ cpu.ReadByte(&cycles, absoluteAddress)
-
-            // Total cycles: 4+1
-            // Total bytes: 3
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_EOR_ABSY:
-
-            absoluteAddress := cpu.AddressAbsoluteY(&cycles)
-
-            cpu.A = cpu.A ^ cpu.ReadByte(&cycles, absoluteAddress)
-
-            // Total cycles: 4+1
-            // Total bytes: 3
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_EOR_INDX:
-
-            effectiveAddress := cpu.AddressIndirectX(&cycles)
-
-            cpu.A = cpu.A ^ cpu.ReadByte(&cycles, effectiveAddress)
-
-            // Total cycles: 6
-            // Total bytes: 2
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_EOR_INDY:
-
-            effectiveAddress := cpu.AddressIndirectY(&cycles)
-
-            cpu.A = cpu.A ^ cpu.ReadByte(&cycles, effectiveAddress)
-
-            // Total cycles: 5+1
-            // Total bytes: 2
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_ORA_IM:
-
-            cpu.A = cpu.A | cpu.FetchByte(&cycles)
-
-            // Total cycles: 2
-            // Total bytes: 2
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_ORA_ZP:
-
-            zeroPageAddress := cpu.AddressZeroPage(&cycles)
-
-            cpu.A = cpu.A | cpu.ReadByte(&cycles, zeroPageAddress)
-
-            // Total cycles: 3
-            // Total bytes: 2
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_ORA_ZPX:
-
-            zeroPageAddress := cpu.AddressZeroPageX(&cycles)
-
-            cpu.A = cpu.A | cpu.ReadByte(&cycles, zeroPageAddress)
-
-            // Total cycles: 4
-            // Total bytes: 2
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-        
-        case instructions.INS_ORA_ABS:
-
-            absoluteAddress := cpu.AddressAbsolute(&cycles)
-
-            cpu.A = cpu.A | cpu.ReadByte(&cycles, absoluteAddress)
-
-            // Total cycles: 4
-            // Total bytes: 3
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_ORA_ABSX:
-
-            absoluteAddress := cpu.AddressAbsoluteX(&cycles)
-
-            cpu.A = cpu.A | cpu.ReadByte(&cycles, absoluteAddress)
-
-            // Total cycles: 4+1
-            // Total bytes: 3
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_ORA_ABSY:
-
-            absoluteAddress := cpu.AddressAbsoluteY(&cycles)
-
-            cpu.A = cpu.A | cpu.ReadByte(&cycles, absoluteAddress)
-
-            // Total cycles: 4+1
-            // Total bytes: 3
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_ORA_INDX:
-
-            effectiveAddress := cpu.AddressIndirectX(&cycles)
-
-            cpu.A = cpu.A | cpu.ReadByte(&cycles, effectiveAddress)
-
-            // Total cycles: 6
-            // Total bytes: 2
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-
-        case instructions.INS_ORA_INDY:
-
-            effectiveAddress := cpu.AddressIndirectY(&cycles)
-
-            cpu.A = cpu.A | cpu.ReadByte(&cycles, effectiveAddress)
-
-            // Total cycles: 5+1
-            // Total bytes: 2
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, cpu.A)
-            break;
-        
-        case instructions.INS_BIT_ZP:
-
-            zeropageAddress := cpu.AddressZeroPage(&cycles)
-
-            memValue := cpu.ReadByte(&cycles, zeropageAddress)
-
-            if (cpu.A & memValue) == 0 {
-                cpu.PS.Z = 1
-            }else {
-                cpu.PS.Z = 0
-            }
-
-            // Flag V is set to bit 6 of the memory value
-            cpu.PS.V = uint((memValue << 1) >> 7)
-
-
-            // Flag N is set to bit 7 of the memory value
-            cpu.PS.N = uint(memValue >> 7)
-
-            // Total cycles: 3
-            // Total bytes: 2
-            break;
-        case instructions.INS_BIT_ABS:
-
-            targetAddress := cpu.AddressAbsolute(&cycles)
-
-            memValue := cpu.ReadByte(&cycles, targetAddress)
-
-            if (cpu.A & memValue) == 0 {
-                cpu.PS.Z = 1
-            }else {
-                cpu.PS.Z = 0
-            }
-
-            // Flag V is set to bit 6 of the memory value
-            cpu.PS.V = uint((memValue << 1) >> 7)
-
-
-            // Flag N is set to bit 7 of the memory value
-            cpu.PS.N = uint(memValue >> 7)
-
-            // Total cycles: 4
-            // Total bytes: 3
-            break;
-        case instructions.INS_INC_ZP:
-
-            zeroPageAddress := cpu.AddressZeroPage(&cycles)
-
-            memValue := cpu.ReadByte(&cycles, zeroPageAddress)
-
-            memValue++
-
-            cycles--
-
-            cpu.WriteByte(&cycles, memValue, zeroPageAddress)
-
-            SetZeroAndNegativeFlags(cpu, memValue)
-
-            // Total cycles: 5
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_INC_ZPX:
-
-            zeroPageAddress := cpu.AddressZeroPageX(&cycles)
-
-            memValue := cpu.ReadByte(&cycles, zeroPageAddress)
-
-            memValue++
-
-            cycles--
-
-            cpu.WriteByte(&cycles, memValue, zeroPageAddress)
-
-            SetZeroAndNegativeFlags(cpu, memValue)
-
-            // Total cycles: 6
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_INC_ABS:
-
-            targetAddress := cpu.AddressAbsolute(&cycles)
-
-            memValue := cpu.ReadByte(&cycles, targetAddress)
-
-            memValue++
-
-            cycles--
-
-            cpu.WriteByte(&cycles, memValue, targetAddress)
-
-            SetZeroAndNegativeFlags(cpu, memValue)
-
-            // Total cycles: 6
-            // Total bytes: 3
-            break;
-
-        case instructions.INS_INC_ABSX:
-
-            targetAddress := cpu.AddressAbsolute(&cycles)
-
-            targetAddress += uint16(cpu.X)
-            cycles--
-
-            memValue := cpu.ReadByte(&cycles, targetAddress)
-            memValue++
-
-            cycles--
-
-            cpu.WriteByte(&cycles, memValue, targetAddress)
-
-            SetZeroAndNegativeFlags(cpu, memValue)
-
-            // Total cycles: 7
-            // Total bytes: 3
-            break;
-
-        case instructions.INS_INX_IMP:
-
-            cpu.X++
-            cycles--
-
-            SetZeroAndNegativeFlags(cpu, cpu.X)
-
-            // Total cycles: 2
-            // Total bytes: 1
-            break;
-        case instructions.INS_INY_IMP:
-
-            cpu.Y += 1
-            cycles--
-            SetZeroAndNegativeFlags(cpu, cpu.Y)
-            // Total cycles: 2
-            // Total bytes: 1
-            break;
-
-        case instructions.INS_DEC_ZP:
-
-            zeroPageAddress := cpu.AddressZeroPage(&cycles)
-
-            memValue := cpu.ReadByte(&cycles, zeroPageAddress)
-
-            memValue--
-
-            cycles--
-
-            cpu.WriteByte(&cycles, memValue, zeroPageAddress)
-
-            SetZeroAndNegativeFlags(cpu, memValue)
-
-            // Total cycles: 5
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_DEC_ZPX:
-
-            zeroPageAddress := cpu.AddressZeroPageX(&cycles)
-
-            memValue := cpu.ReadByte(&cycles, zeroPageAddress)
-
-            memValue--
-
-            cycles--
-
-            cpu.WriteByte(&cycles, memValue, zeroPageAddress)
-
-            SetZeroAndNegativeFlags(cpu, memValue)
-
-            // Total cycles: 6
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_DEC_ABS:
-
-            targetAddress := cpu.AddressAbsolute(&cycles)
-
-            memValue := cpu.ReadByte(&cycles, targetAddress)
-
-            memValue--
-
-            cycles--
-
-            cpu.WriteByte(&cycles, memValue, targetAddress)
-
-            SetZeroAndNegativeFlags(cpu, memValue)
-
-            // Total cycles: 6
-            // Total bytes: 3
-            break;
-
-        case instructions.INS_DEC_ABSX:
-
-            targetAddress := cpu.AddressAbsolute(&cycles)
-
-            targetAddress += uint16(cpu.X)
-            cycles--
-
-            memValue := cpu.ReadByte(&cycles, targetAddress)
-            memValue--
-
-            cycles--
-
-            cpu.WriteByte(&cycles, memValue, targetAddress)
-
-            SetZeroAndNegativeFlags(cpu, memValue)
-
-            // Total cycles: 7
-            // Total bytes: 3
-            break;
-
-        case instructions.INS_DEX_IMP:
-
-            cpu.X -= 1
-            cycles--
-            SetZeroAndNegativeFlags(cpu, cpu.X)
-            // Total cycles: 2
-            // Total bytes: 1
-            break;
-        case instructions.INS_DEY_IMP:
-
-            cpu.Y -= 1
-            cycles--
-            SetZeroAndNegativeFlags(cpu, cpu.Y)
-            // Total cycles: 2
-            // Total bytes: 1
-            break;
-
-        case instructions.INS_BEQ_REL:
-
-            cpu.BranchIf(cpu.PS.Z, set, &cycles)
-
-
-            // Total cycles: 2(+1 if branch succeeds, +2 if to a new page)
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_BNE_REL:
-
-            cpu.BranchIf(cpu.PS.Z, cleared, &cycles)
-
-            // Total cycles: 2(+1 if branch succeeds, +2 if to a new page)
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_BCC_REL:
-
-            // Branch if carry flag is clear
-            cpu.BranchIf(cpu.PS.C, cleared, &cycles)
-
-            // Total cycles: 2(+1 if branch succeeds, +2 if to a new page)
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_BCS_REL:
-
-            // Branch if carry flag is set
-            cpu.BranchIf(cpu.PS.C, set, &cycles)
-
-            // Total cycles: 2(+1 if branch succeeds, +2 if to a new page)
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_BPL_REL:
-
-            // Branch if negative flag is clear
-            cpu.BranchIf(cpu.PS.N, cleared, &cycles)
-
-            // Total cycles: 2(+1 if branch succeeds, +2 if to a new page)
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_BMI_REL:
-
-            // Branch if negative flag is set
-            cpu.BranchIf(cpu.PS.N, set, &cycles)
-
-            // Total cycles: 2(+1 if branch succeeds, +2 if to a new page)
-            // Total bytes: 2
-            break;
-
-        case instructions.INS_BVC_REL:
 
-            // Branch if overflow flag is clear
-            cpu.BranchIf(cpu.PS.V, cleared, &cycles)
 
-            // Total cycles: 2(+1 if branch succeeds, +2 if to a new page)
-            // Total bytes: 2
-            break;
+func (mem *Memory) Initialise(){
+    
+    for i := 0; i < MaxMem; i++{
+        mem.Data[i] = 0
+    }
+}
+type ProcessorStatus struct {
+    C uint // Carry flag
+    Z uint // Zero flag
+    I uint // Interrupt Disable flag
+    D uint // Decimal mode flag
+    B uint // Break command flag
+    U uint // This bit is reserved for future use and should always be set to 1 when writing to the PSR.
+    V uint // Overflow flag
+    N uint // Negative flag
+}
 
-        case instructions.INS_BVS_REL:
+// Variant selects which chip of the 6502 family the CPU behaves like.
+type Variant int
 
-            // Branch if overflow flag is set
-            cpu.BranchIf(cpu.PS.V, set, &cycles)
+const (
+    // NMOS6502 is the original 6502, with its bugs and its illegal opcodes.
+    NMOS6502 Variant = iota
 
-            // Total cycles: 2(+1 if branch succeeds, +2 if to a new page)
-            // Total bytes: 2
-            break;
+    // CMOS65C02 is the WDC 65C02. It adds new instructions and addressing modes
+    // in place of the illegal opcodes, which become NOPs, and fixes some of the NMOS bugs.
+    CMOS65C02
+)
 
-            //TODO: BRK instruction
+func (variant Variant) String() string{
 
-        case instructions.INS_CLC_IMP:
+    switch variant {
+    case NMOS6502:
+        return "6502"
+    case CMOS65C02:
+        return "65C02"
+    }
 
-            cpu.PS.C = cleared
-            cycles--
+    return fmt.Sprintf("Variant(%d)", int(variant))
+}
 
-            // Total cycles: 2
-            // Total bytes: 1
-            break; 
+type CPU struct {
 
-        case instructions.INS_CLD_IMP:
+    // Program Counter points to the next instruction.
+    PC uint16 
 
-            cpu.PS.D = cleared
-            cycles--
+    // Stack Pointer is a 8-bit register that holds the low 8bits of the next free
+    // location on the stack. Pushing bytes to the stack causes the Stack Pointer to be 
+    // decremented. The CPU does not detect if the stack is overflowed by excessive pushing or
+    // pulling operations and will most likely result in the program crashing.
+    // Goes from $0100 to $01FF, it's fixed.
+    SP byte  
 
-            // Total cycles: 2
-            // Total bytes: 1
-            break; 
+    // Accumulator register is used for all arithmetical and logical operations.
+    // Exception made for intcrements and decrements. The contents of the accumulator
+    // can be stored and retrieved either from memory or the stack.
+    A byte
 
-        case instructions.INS_CLI_IMP:
+    // The X register is most commonly used to hold counters and offsets for accessing.
+    // It has one special purpose, which is to get a copy of the SP or change its value.
+    X byte
 
-            cpu.PS.I = cleared
-            cycles--
+    // The Y register is similar to the X register but has no special functions.
+    Y byte
 
-            // Total cycles: 2
-            // Total bytes: 1
-            break; 
+    // The Processor Status is a 8-bit status which holds a bunch of bits, that get set in it after operations.
+    PS ProcessorStatus
 
-        case instructions.INS_CLV_IMP:
+    // Interrupt lines driven by external hardware, see interrupts.go.
+    // IRQ is level triggered, so we only keep the current level of the line.
+    // NMI is edge triggered, so we also remember that an edge was seen
+    // until the interrupt gets serviced.
+    irq bool
+    nmi bool
+    nmiPending bool
 
-            cpu.PS.V = cleared
-            cycles--
+    // Variant is the chip the CPU emulates, NMOS6502 when not set.
+    // It's meant to be chosen with NewCPU and not changed afterwards.
+    Variant Variant
 
-            // Total cycles: 2
-            // Total bytes: 1
-            break; 
+    // Strict rejects the illegal opcodes of the NMOS 6502 as unknown opcodes,
+    // for programs that must only use documented instructions.
+    Strict bool
 
-        case instructions.INS_SEC_IMP:
+    // Fault raised during the current instruction, reported by Run once the instruction is over.
+    fault *Fault
 
-            cpu.PS.C = set
-            cycles--
+    // Address and opcode of the instruction being executed, to report faults.
+    instructionPC uint16
+    opcode byte
 
-            // Total cycles: 2
-            // Total bytes: 1
-            break; 
+    // Set while the opcode or an operand is fetched, so watchpoints tell fetches from data reads.
+    fetching bool
 
-        case instructions.INS_SED_IMP:
+    // Cycles taken by the current instruction on top of the ones listed in the opcode table.
+    // pageCrossed is set by the indexed addressing modes, and only costs a cycle
+    // to the instructions that have a page crossing penalty.
+    pageCrossed bool
+    extraCycles int
 
-            cpu.PS.D = set
-            cycles--
+    // Set after a JAM instruction, or STP on the 65C02.
+    // The CPU stops executing until the next Reset.
+    jammed bool
 
-            // Total cycles: 2
-            // Total bytes: 1
-            break; 
-                
-        case instructions.INS_SEI_IMP:
+    // Set by WAI on the 65C02, the CPU sleeps until an interrupt line is asserted.
+    waiting bool
 
-            cpu.PS.I = set
-            cycles--
+    // Memory is the RAM used when Bus is not set.
+    Memory Memory
 
-            // Total cycles: 2
-            // Total bytes: 1
-            break; 
+    // Bus the CPU is wired to. When nil, the CPU uses its own Memory.
+    Bus Bus
 
-        case instructions.INS_NOP_IMP:
+    // Debugger, when set, holds breakpoints that stop Run, see debug.go.
+    Debugger *Debugger
 
-            cpu.PC++
-            cycles--
-            // Total cycles: 2
-            // Total bytes: 1
-            break;
+    // Tracer, when set, is called before each instruction is executed.
+    Tracer Tracer
 
-        case instructions.INS_ADC_IM:
+    // History, when set, records the execution so the CPU can go back in time, see history.go.
+    History *History
 
-            memValue := cpu.FetchByte(&cycles)
-            AddWithCarryAndSetSignOverflow(cpu, memValue)
+    // Profiler, when set, counts the executions and the cycles of each instruction.
+    Profiler *Profiler
 
-            SetZeroAndNegativeFlags(cpu, cpu.A)
+    // Cycles executed since the last Reset, updated when Run returns.
+    cycles uint64
+}
 
-            // Total cycles: 2
-            // Total bytes: 2
-            break;
+// Tracer follows the execution of a CPU, like the tracer of the trace package.
+type Tracer interface {
 
-        case instructions.INS_ADC_ZP:
+    // Trace is called with the PC on the instruction about to be executed,
+    // and the cycles executed since the last Reset.
+    // It must not modify the CPU.
+    Trace(cpu *CPU, cycles uint64)
+}
 
-            zeroPageAddress := cpu.AddressZeroPage(&cycles)
-            memValue := cpu.ReadByte(&cycles, zeroPageAddress)
-            AddWithCarryAndSetSignOverflow(cpu, memValue)
+// bus returns the Bus every memory access goes through.
+func (cpu *CPU) bus() Bus{
 
-            SetZeroAndNegativeFlags(cpu, cpu.A)
+    if cpu.Bus != nil {
+        return cpu.Bus
+    }
 
-            // Total cycles: 3
-            // Total bytes: 2
-            break;
-        case instructions.INS_ADC_ZPX:
+    return &cpu.Memory
+}
 
-            zeroPageAddress := cpu.AddressZeroPageX(&cycles)
-            memValue := cpu.ReadByte(&cycles, zeroPageAddress)
-            AddWithCarryAndSetSignOverflow(cpu, memValue)
+// AddressSpace returns the Bus the CPU reads and writes, which is its own Memory
+// when no Bus is set. It's handy for tools like the disassembler.
+// Its accesses are never caught by watchpoints.
+func (cpu *CPU) AddressSpace() Bus{
 
-            SetZeroAndNegativeFlags(cpu, cpu.A)
+    bus := cpu.bus()
 
-            // Total cycles: 4
-            // Total bytes: 2
-            break;
-        case instructions.INS_ADC_ABS:
+    // The History wraps the Bus installed by the Debugger, if any
+    if cpu.History != nil && bus == &cpu.History.recorder {
+        bus = cpu.History.recorder.Bus
+    }
 
-            targetAddress := cpu.AddressAbsolute(&cycles)
-            memValue := cpu.ReadByte(&cycles, targetAddress)
-            AddWithCarryAndSetSignOverflow(cpu, memValue)
+    if cpu.Debugger != nil && bus == &cpu.Debugger.watcher {
+        bus = cpu.Debugger.watcher.Bus
+    }
 
-            SetZeroAndNegativeFlags(cpu, cpu.A)
+    return bus
+}
 
-            // Total cycles: 4
-            // Total bytes: 3
-            break;
-        case instructions.INS_ADC_ABSX:
+// read and write are the fast path of every memory access, they skip the Bus
+// interface when the CPU uses its own Memory.
+func (cpu *CPU) read(address uint16) byte{
 
-            targetAddress := cpu.AddressAbsoluteX(&cycles)
-            memValue := cpu.ReadByte(&cycles, targetAddress)
-            AddWithCarryAndSetSignOverflow(cpu, memValue)
+    if cpu.Bus == nil {
+        return cpu.Memory.Data[address]
+    }
 
-            SetZeroAndNegativeFlags(cpu, cpu.A)
+    return cpu.Bus.Read(address)
+}
 
-            // Total cycles: 4(+1 if page crossed)
-            // Total bytes: 3
-            break;
-        case instructions.INS_ADC_ABSY:
+func (cpu *CPU) write(address uint16, value byte){
 
-            targetAddress := cpu.AddressAbsoluteY(&cycles)
-            memValue := cpu.ReadByte(&cycles, targetAddress)
-            AddWithCarryAndSetSignOverflow(cpu, memValue)
+    if cpu.Bus == nil {
+        cpu.Memory.Data[address] = value
+        return
+    }
 
-            SetZeroAndNegativeFlags(cpu, cpu.A)
+    cpu.Bus.Write(address, value)
+}
 
-            // Total cycles: 4(+1 if page crossed)
-            // Total bytes: 3
-            break;
-        case instructions.INS_ADC_INDX:
+// NewCPU returns a CPU emulating variant, reset with the PC at the reset vector address.
+func NewCPU(variant Variant) *CPU{
 
-            targetAddress := cpu.AddressIndirectX(&cycles)
-            memValue := cpu.ReadByte(&cycles, targetAddress)
-            AddWithCarryAndSetSignOverflow(cpu, memValue)
+    cpu := &CPU{Variant: variant}
+    cpu.Reset(ResetVector)
 
-            SetZeroAndNegativeFlags(cpu, cpu.A)
+    return cpu
+}
 
-            // Total cycles: 6
-            // Total bytes: 2
-            break;
-        case instructions.INS_ADC_INDY:
+// TODO: How ugly is this?
+var getFlagName = make(map[*uint]string)
 
-            targetAddress := cpu.AddressIndirectY(&cycles)
-            memValue := cpu.ReadByte(&cycles, targetAddress)
-            AddWithCarryAndSetSignOverflow(cpu, memValue)
+func (cpu *CPU) Reset(resetVector uint16){
 
-            SetZeroAndNegativeFlags(cpu, cpu.A)
+    // Reset procedure does not follow accurate Commodor 64, it acts like a computer that's like a 
+    // Commodor 64.
 
-            // Total cycles: 5(+1 if page crossed)
-            // Total bytes: 2
-            break;
+    // Reset vector address
+    cpu.PC = resetVector
 
-            
-        case instructions.INS_CMP_IM:
+    // Clear all flags
+    cpu.PS.C = 0
+    cpu.PS.Z = 0
+    cpu.PS.I = 0
+    cpu.PS.D = 0
+    cpu.PS.B = 0
+    cpu.PS.U = 0
+    cpu.PS.V = 0
+    cpu.PS.N = 0
 
-            // CMP instruction performs a comparison between A register and value held in memory, 
-            // by doint an unsigned subtracting the value from the Accumulator
-            // It then sets proper flags
-            
-            compareRegisterWithValueAndSetFlags(cpu, cpu.A, cpu.FetchByte(&cycles))
+    // TODO: pretty sure this shouldn't be done here
+    getFlagName[&cpu.PS.C] = "Carry flag"
+    getFlagName[&cpu.PS.Z] = "Zero flag"
+    getFlagName[&cpu.PS.I] = "Interrupt disable flag"
+    getFlagName[&cpu.PS.D] = "Decimal flag"
+    getFlagName[&cpu.PS.B] = "Break command flag"
+    getFlagName[&cpu.PS.U] = "Unused flag"
+    getFlagName[&cpu.PS.V] = "Overflow flag"
+    getFlagName[&cpu.PS.N] = "Negative flag"
 
-            // Total cycles: 2
-            // Total bytes: 2
-            break;
+    // After the Reset, there's 9 post-reset cycles, which execute three fake push into the stack.
+    // The final SP is therefore 00 - 1 = FF, FF - 1 = FE, FF - 1 = FD
+    // https://www.c64-wiki.com/wiki/Reset_(Process)
+    cpu.SP = 0xFD
 
-        case instructions.INS_CMP_ZP:
+    // A NMI edge seen before the reset is lost.
+    cpu.nmiPending = false
+    cpu.jammed = false
+    cpu.waiting = false
+    cpu.cycles = 0
 
-            zeroPageAddress := cpu.AddressZeroPage(&cycles)
-            
-            compareRegisterWithValueAndSetFlags(cpu, cpu.A, cpu.ReadByte(&cycles, zeroPageAddress))
+    // The memory may change, the History can't go back before the reset without a snapshot
+    if cpu.History != nil {
+        cpu.History.Checkpoint()
+    }
 
-            // Total cycles: 3
-            // Total bytes: 2
-            break;
-        case instructions.INS_CMP_ZPX:
+    // Not sure if we want this to happen for now.
+    cpu.A = 0
+    cpu.X = 0
+    cpu.Y = 0
 
-            zeroPageAddress := cpu.AddressZeroPageX(&cycles)
-            compareRegisterWithValueAndSetFlags(cpu, cpu.A, cpu.ReadByte(&cycles, zeroPageAddress))
+    // Only the built-in Memory is cleared, a Bus keeps its content
+    // since it may hold ROMs and devices.
+    cpu.Memory.Initialise()
+}
 
-            // Total cycles: 4
-            // Total bytes: 2
-            break;
 
-        case instructions.INS_CMP_ABS:
+// Jammed reports whether the CPU executed a JAM instruction and is stuck until the next Reset.
+func (cpu *CPU) Jammed() bool{
+    return cpu.jammed
+}
 
-            zeroPageAddress := cpu.AddressAbsolute(&cycles)
-            compareRegisterWithValueAndSetFlags(cpu, cpu.A, cpu.ReadByte(&cycles, zeroPageAddress))
+// Cycles returns the number of cycles executed since the last Reset.
+func (cpu *CPU) Cycles() uint64{
+    return cpu.cycles
+}
 
-            // Total cycles: 4
-            // Total bytes: 3
-            break;
+// Waiting reports whether the CPU executed a WAI and sleeps until an interrupt line is asserted.
+func (cpu *CPU) Waiting() bool{
+    return cpu.waiting
+}
 
-        case instructions.INS_CMP_ABSX:
+// PrintStatus prints the registers to the standard output.
+func (cpu *CPU) PrintStatus(){
+    cpu.WriteStatus(os.Stdout)
+}
 
-            targetAddress := cpu.AddressAbsoluteX(&cycles)
-            compareRegisterWithValueAndSetFlags(cpu, cpu.A, cpu.ReadByte(&cycles, targetAddress))
+// WriteStatus writes the registers to w, with the flags in the order of the status byte:
+//
+//	PC: $E000  A: $00  X: $00  Y: $00  SP: $FD
+//	PS: $20  NV-BDIZC 00100000
+func (cpu *CPU) WriteStatus(w io.Writer){
 
-            // Total cycles: 4(+1 if page crossed)
-            // Total bytes: 3
-            break;
+    fmt.Fprintf(w, "PC: $%04X  A: $%02X  X: $%02X  Y: $%02X  SP: $%02X\n", cpu.PC, cpu.A, cpu.X, cpu.Y, cpu.SP)
 
-        case instructions.INS_CMP_ABSY:
+    ps := cpu.PSToByte()
+    fmt.Fprintf(w, "PS: $%02X  NV-BDIZC %08b\n", ps, ps)
+}
 
-            targetAddress := cpu.AddressAbsoluteY(&cycles)
-            compareRegisterWithValueAndSetFlags(cpu, cpu.A, cpu.ReadByte(&cycles, targetAddress))
 
-            // Total cycles: 4(+1 if page crossed)
-            // Total bytes: 3
-            break;
 
-        case instructions.INS_CMP_INDX:
+// Execute runs the fetch-decode loop.
+// It fetches the instruction byte and then, based on the opcode fetched, 
+// executes the corresponding instruction.
+// It returns the number of cycles used, for Testing purposes.
+// A fault stops the execution and gets logged, use Run to handle it.
+// Reaching a breakpoint stops it too, without logging: Debugger.LastHit tells which one.
+func (cpu *CPU) Execute( cycles int ) ( cyclesUsed int) {
 
-            targetAddress := cpu.AddressIndirectX(&cycles)
-            compareRegisterWithValueAndSetFlags(cpu, cpu.A, cpu.ReadByte(&cycles, targetAddress))
+    cyclesUsed, err := cpu.Run(cycles)
 
-            // Total cycles: 6
-            // Total bytes: 2
-            break;
+    if _, hit := err.(*Hit); err != nil && !hit {
+        log.Println(err)
+    }
 
-        case instructions.INS_CMP_INDY:
+    return
+}
 
-            targetAddress := cpu.AddressIndirectY(&cycles)
-            compareRegisterWithValueAndSetFlags(cpu, cpu.A, cpu.ReadByte(&cycles, targetAddress))
-            
-            // Total cycles: 5(+1 if page crossed)
-            // Total bytes: 2
-            break;
+// Run is like Execute, but it stops as soon as the guest program makes the CPU fault,
+// and returns a *Fault describing it, along with the number of cycles used until then.
+// The CPU state is left as it was when the fault happened, so it can be inspected,
+// and the execution can be resumed with another call to Run, if that makes sense.
+// When the CPU has a Debugger, Run also stops at its breakpoints and returns a *Hit.
+func (cpu *CPU) Run( cycles int ) ( cyclesUsed int, err error) {
 
-        case instructions.INS_CMX_IM:
+    // At the beginning, initialise cyclesUsed as the number of cycles passed when calling the
+    // method Execute().
+    cyclesUsed = cycles
 
-            compareRegisterWithValueAndSetFlags(cpu, cpu.X, cpu.FetchByte(&cycles))
+    debugger := cpu.Debugger
+    if debugger != nil {
+        debugger.attach(cpu)
+    }
 
-            // Total cycles: 2
-            // Total bytes: 2
-            break;
+    history := cpu.History
+    if history != nil {
+        history.attach(cpu)
+    }
 
-        case instructions.INS_CMX_ZP:
+    // Accesses refused before this Run, like loading a program over a ROM,
+    // aren't the fault of the guest program.
+    if bus, ok := cpu.Bus.(FaultingBus); ok {
+        bus.TakeFault()
+    }
 
-            zeroPageAddress := cpu.AddressZeroPage(&cycles)
-            compareRegisterWithValueAndSetFlags(cpu, cpu.X, cpu.ReadByte(&cycles, zeroPageAddress))
-            
-            // Total cycles: 3
-            // Total bytes: 2
-            break;
+    // Each instruction takes the cycles listed in the opcode table, so the loop
+    // may use a few more cycles than requested to complete the last instruction.
+    for cycles > 0 {
 
-        case instructions.INS_CMX_ABS:
+        // Stop at the first fault raised by the previous instruction
+        if cpu.Bus != nil {
+            cpu.checkBusFault()
+        }
+        if cpu.fault != nil {
+            break
+        }
 
-            zeroPageAddress := cpu.AddressAbsolute(&cycles)
-            compareRegisterWithValueAndSetFlags(cpu, cpu.X, cpu.ReadByte(&cycles, zeroPageAddress))
+        // Stop after the instruction that hit a watchpoint
+        if debugger != nil && debugger.pending != nil {
+            break
+        }
 
-            // Total cycles: 4
-            // Total bytes: 3
-            break;
+        // A jammed CPU doesn't even service interrupts, the remaining cycles are lost.
+        if cpu.jammed {
+            cycles = 0
+            cpu.instructionPC = cpu.PC
+            cpu.fetching = true
+            cpu.opcode = cpu.bus().Read(cpu.PC)
+            cpu.fetching = false
+            cpu.fault = &Fault{Err: ErrJammed}
+            break
+        }
 
-        case instructions.INS_CMY_IM:
+        // A waiting CPU wakes up when an interrupt line is asserted, even if
+        // the IRQ is masked: in that case it just goes on with the next instruction.
+        if cpu.waiting {
 
-            compareRegisterWithValueAndSetFlags(cpu, cpu.Y, cpu.FetchByte(&cycles))
+            if !cpu.irq && !cpu.nmiPending {
+                cycles = 0
+                break
+            }
 
-            // Total cycles: 2
-            // Total bytes: 2
-            break;
+            cpu.waiting = false
+        }
 
-        case instructions.INS_CMY_ZP:
+        // Interrupts are only serviced between instructions.
+        // NMI has priority over IRQ, and can't be masked.
+        if cpu.nmiPending {
 
-            zeroPageAddress := cpu.AddressZeroPage(&cycles)
-            compareRegisterWithValueAndSetFlags(cpu, cpu.Y, cpu.ReadByte(&cycles, zeroPageAddress))
-            
-            // Total cycles: 3
-            // Total bytes: 2
-            break;
+            if history != nil {
+                history.record(cpu, cpu.cycles + uint64(cyclesUsed - cycles))
+            }
 
-        case instructions.INS_CMY_ABS:
+            cpu.nmiPending = false
 
-            targetAddress := cpu.AddressAbsolute(&cycles)
-            compareRegisterWithValueAndSetFlags(cpu, cpu.Y, cpu.ReadByte(&cycles, targetAddress))
+            pc, sp := cpu.PC, cpu.SP
+            cpu.serviceInterrupt(NMIVector)
+            cycles -= interruptCycles
 
-            // Total cycles: 4
-            // Total bytes: 3
-            break;
+            if cpu.Profiler != nil {
+                cpu.Profiler.interrupt(cpu, pc, sp)
+            }
+            continue
+        }
 
-        case instructions.INS_SBC_IM:
+        if cpu.irq && cpu.PS.I == cleared {
 
-            // The operation is unsigned but use signed to invert the number.
-            // This means a value with the 7th bit set is treated as signed
-            // to ease subtract operation.
-            // E.g. : 0x80 = -128 = 1000 0000
-            // So that A - (-128) = A + 128
-            memValue := cpu.FetchSignedByte(&cycles)
-            SubtractWithCarryAndSetSignOverflow(cpu, common.Int8AdditiveInverse(memValue))
+            if history != nil {
+                history.record(cpu, cpu.cycles + uint64(cyclesUsed - cycles))
+            }
 
-            SetZeroAndNegativeFlags(cpu, cpu.A)
+            pc, sp := cpu.PC, cpu.SP
+            cpu.serviceInterrupt(IRQVector)
+            cycles -= interruptCycles
 
-            // Total cycles: 2
-            // Total bytes: 2
-            break;
+            if cpu.Profiler != nil {
+                cpu.Profiler.interrupt(cpu, pc, sp)
+            }
+            continue
+        }
 
-        case instructions.INS_SBC_ZP:
+        if debugger != nil && debugger.breakAt(cpu) {
+            break
+        }
 
-            zeroPageAddress := cpu.AddressZeroPage(&cycles)
-            memValue := cpu.ReadByte(&cycles, zeroPageAddress)
-            SubtractWithCarryAndSetSignOverflow(cpu, common.Int8AdditiveInverse(int8(memValue)))
+        if cpu.Tracer != nil {
+            cpu.Tracer.Trace(cpu, cpu.cycles + uint64(cyclesUsed - cycles))
+        }
 
-            SetZeroAndNegativeFlags(cpu, cpu.A)
+        if history != nil {
+            history.record(cpu, cpu.cycles + uint64(cyclesUsed - cycles))
+        }
 
-            // Total cycles: 3
-            // Total bytes: 2
-            break;
-        case instructions.INS_SBC_ZPX:
+        if cpu.Profiler != nil {
 
-            zeroPageAddress := cpu.AddressZeroPageX(&cycles)
-            memValue := cpu.ReadByte(&cycles, zeroPageAddress)
-            SubtractWithCarryAndSetSignOverflow(cpu, common.Int8AdditiveInverse(int8(memValue)))
+            pc, sp := cpu.PC, cpu.SP
+            used := cpu.execute()
+            cpu.Profiler.count(cpu, pc, sp, used)
+            cycles -= used
 
-            SetZeroAndNegativeFlags(cpu, cpu.A)
+            continue
+        }
 
-            // Total cycles: 4
-            // Total bytes: 2
-            break;
-        case instructions.INS_SBC_ABS:
+        cycles -= cpu.execute()
+    }
 
-            targetAddress := cpu.AddressAbsolute(&cycles)
-            memValue := cpu.ReadByte(&cycles, targetAddress)
-            SubtractWithCarryAndSetSignOverflow(cpu, common.Int8AdditiveInverse(int8(memValue)))
+    // If the number of cycles used is correct, respectively to the instruction used, 
+    // the return should be the original value, passed when calling Execute().
+    // When testing the instruction, we make sure that the expected value returned by Execute()
+    // matches the cycles needed for the instructions, based on official documentation.
+    cyclesUsed -= cycles
+    cpu.cycles += uint64(cyclesUsed)
 
-            SetZeroAndNegativeFlags(cpu, cpu.A)
+    cpu.checkBusFault()
+    if cpu.fault != nil {
 
-            // Total cycles: 4
-            // Total bytes: 3
-            break;
-        case instructions.INS_SBC_ABSX:
+        cpu.fault.PC = cpu.instructionPC
+        cpu.fault.Opcode = cpu.opcode
+        cpu.fault.Cycles = cyclesUsed
+        err = cpu.fault
 
-            targetAddress := cpu.AddressAbsoluteX(&cycles)
-            memValue := cpu.ReadByte(&cycles, targetAddress)
-            SubtractWithCarryAndSetSignOverflow(cpu, common.Int8AdditiveInverse(int8(memValue)))
+        // The fault is reported once, a following Run goes on from the current state
+        cpu.fault = nil
 
-            SetZeroAndNegativeFlags(cpu, cpu.A)
+    }
 
-            // Total cycles: 4(+1 if page crossed)
-            // Total bytes: 3
-            break;
-        case instructions.INS_SBC_ABSY:
+    if history != nil {
+        history.detach(cpu)
+    }
 
-            targetAddress := cpu.AddressAbsoluteY(&cycles)
-            memValue := cpu.ReadByte(&cycles, targetAddress)
-            SubtractWithCarryAndSetSignOverflow(cpu, common.Int8AdditiveInverse(int8(memValue)))
+    if debugger != nil {
 
-            SetZeroAndNegativeFlags(cpu, cpu.A)
+        if hit := debugger.detach(cpu, cyclesUsed); hit != nil && err == nil {
+            err = hit
+        }
+    }
 
-            // Total cycles: 4(+1 if page crossed)
-            // Total bytes: 3
-            break;
-        case instructions.INS_SBC_INDX:
+    return
+}
 
-            targetAddress := cpu.AddressIndirectX(&cycles)
-            memValue := cpu.ReadByte(&cycles, targetAddress)
-            SubtractWithCarryAndSetSignOverflow(cpu, common.Int8AdditiveInverse(int8(memValue)))
+// execute fetches the instruction at PC, executes it through the dispatch table
+// of the CPU variant, and returns the cycles it took.
+func (cpu *CPU) execute() int{
 
-            SetZeroAndNegativeFlags(cpu, cpu.A)
+    // Fetch instruction
+    cpu.instructionPC = cpu.PC
+    cpu.opcode = cpu.FetchByte()
 
-            // Total cycles: 6
-            // Total bytes: 2
-            break;
-        case instructions.INS_SBC_INDY:
+    entry := &cpu.dispatchTable()[cpu.opcode]
 
-            targetAddress := cpu.AddressIndirectY(&cycles)
-            memValue := cpu.ReadByte(&cycles, targetAddress)
-            SubtractWithCarryAndSetSignOverflow(cpu, common.Int8AdditiveInverse(int8(memValue)))
+    if entry.illegal && cpu.Strict {
 
-            SetZeroAndNegativeFlags(cpu, cpu.A)
+        // Leave the PC on the unknown opcode, so it can be inspected
+        cpu.PC = cpu.instructionPC
+        cpu.fault = &Fault{Err: ErrUnknownOpcode}
 
-            // Total cycles: 5(+1 if page crossed)
-            // Total bytes: 2
-            break;
+        // Only the opcode fetch
+        return 1
+    }
 
+    cpu.pageCrossed = false
+    cpu.extraCycles = 0
 
-        default:
-            log.Println("At memory address: ", cpu.PC)
+    entry.execute(cpu)
 
-            // TODO: Should it stop and Fatal or just keep going till next valid instruction?
-            log.Fatalln("Unknown opcode: ", ins)
-        }
+    cycles := entry.cycles + cpu.extraCycles
+    if cpu.pageCrossed && entry.pageCross {
+        cycles++
     }
 
-    // If the number of cycles used is correct, respectively to the instruction used, 
-    // the return should be the original value, passed when calling Execute().
-    // When testing the instruction, we make sure that the expected value returned by Execute()
-    // matches the cycles needed for the instructions, based on official documentation.
-    cyclesUsed -= cycles
-
-    return
+    return cycles
 }
 
-// this takes 1 clock cycle
+// AddWithCarryAndSetSignOverflow adds memValue and the carry to the accumulator.
+// It sets carry, overflow, zero and negative flags.
+// When the decimal flag is set, the operands are treated as binary-coded decimals.
 func AddWithCarryAndSetSignOverflow(cpu *CPU, memValue byte){
 
+            if cpu.PS.D == set {
+
+                // The 65C02 takes a cycle more to fix the flags
+                if cpu.Variant == CMOS65C02 {
+                    cpu.extraCycles++
+                }
+
+                addWithCarryDecimal(cpu, memValue)
+                return
+            }
+
             signA := cpu.A >> 7
             signValue := memValue >> 7
             carryFlag := cpu.PS.C
@@ -1788,192 +611,139 @@ func AddWithCarryAndSetSignOverflow(cpu *CPU, memValue byte){
             }else {
                 cpu.PS.V = cleared
             }
+
+            SetZeroAndNegativeFlags(cpu, cpu.A)
 }
 
-// this takes 1 clock cycle
+// SubtractWithCarryAndSetSignOverflow subtracts memValue and the borrow from the accumulator.
+// It sets carry, overflow, zero and negative flags.
+// When the decimal flag is set, the operands are treated as binary-coded decimals.
 func SubtractWithCarryAndSetSignOverflow(cpu *CPU, memValue byte){
 
-            signA := cpu.A >> 7
-            signValue := memValue >> 7
-            carryFlag := cpu.PS.C
-
-            // TODO: is it correct to say carry bit is set when result exceeds 0xFF?
-            // The carry flag is set if the last operation caused an overflow from bit 7 of the result or an underflow from bit 0. 
-            if uint16(cpu.A) + uint16(memValue) + uint16(cpu.PS.C) > 0xFF || 
-               uint16(cpu.A) + uint16(memValue) + uint16(cpu.PS.C) < 0 {
-                cpu.PS.C = set
-            }else{
-                cpu.PS.C = cleared
-            }
-
-            cpu.A = cpu.A + memValue - (0x01-byte(carryFlag))
+            if cpu.PS.D == set {
 
-            signAfterAddition := cpu.A >> 7
+                // The 65C02 takes a cycle more to fix the flags
+                if cpu.Variant == CMOS65C02 {
+                    cpu.extraCycles++
+                }
 
-            // If there's overflow, set the overflow flag
-            // TODO: what happens to the overflowed value?
-            if (signA == signValue) && (signAfterAddition != signA) {
-                cpu.PS.V = set
-            }else {
-                cpu.PS.V = cleared
+                subtractWithCarryDecimal(cpu, memValue)
+                return
             }
 
-}
-
-func SetZeroAndNegativeFlags(cpu *CPU, register byte) {
+            // A - M - borrow is A + ~M + C, like the ALU computes it.
+            // The carry is set when there's no borrow, that is when the sum exceeds 0xFF.
+            result := uint16(cpu.A) + uint16(^memValue) + uint16(cpu.PS.C)
 
-            // Set Z flag if A is 0
-            if register == 0 {
-                cpu.PS.Z = set
+            if result > 0xFF {
+                cpu.PS.C = set
             }else{
-                cpu.PS.Z = cleared
+                cpu.PS.C = cleared
             }
 
-            // Set N flag if the bit 7 of A is set
-            // byte(1 << 7) is a bitmask that has the 7 bit set to 1
-            // it left-shifts the 00000001 seven positions left
-            if (register & byte(1 << 7) != 0) {
-                cpu.PS.N = set
+            // There's overflow when A and M have different signs,
+            // and the result doesn't have the sign of A
+            if (cpu.A ^ memValue) & (cpu.A ^ byte(result)) & 0x80 != 0 {
+                cpu.PS.V = set
             }else {
-                cpu.PS.N = cleared
+                cpu.PS.V = cleared
             }
-}
-
-func AddRegValueToTarget16Address(value byte, address *uint16, cycles *int){
-
-    // "+1 if page crossed": a page boundary is crossed if the high byte of the original absolute address is
-    // different from the high byte of the calculated address after adding the X register.
-    // If a page boundary is crossed, an additional cycle is required.
 
-    originalHighByte := (*address >> 8) // Add value to address *address += uint16(value)
+            cpu.A = byte(result)
 
-    *address += uint16(value)
-
-    newHighByte := (*address >> 8)
-
-
-    if originalHighByte != newHighByte {
-        *cycles--
-    }
+            SetZeroAndNegativeFlags(cpu, cpu.A)
 }
 
-func LoadRegisterAndSetStatusFlags(cpu *CPU, cycles *int, address uint16, register *byte){
-
-            // Load the data at the zeroPageAddress in the A register
-            *register = cpu.ReadByte(cycles, address)
-
-            // Set LDA status flags
-            SetZeroAndNegativeFlags(cpu, *register)
-}
+// ArithmeticShiftLeft shifts value one bit left, moving bit 7 into the carry flag.
+// It sets zero and negative flags based on the result.
+func ArithmeticShiftLeft(cpu *CPU, value byte) byte{
 
-func StoreRegistersIntoMemoryAddress(cpu *CPU, cycles *int, address uint16, register *byte){
+            cpu.PS.C = uint(value >> 7)
 
-            cpu.WriteByte(cycles, *register, address)
-}
+            value = value << 1
 
-// Fetches ZeroPage Address when in Addressing Mode - Zero Page 
-// Consumes 1 clock cycle
-func (cpu *CPU) AddressZeroPage(cycles *int) uint16{
+            SetZeroAndNegativeFlags(cpu, value)
 
-    return uint16(cpu.FetchByte(cycles))
+            return value
 }
 
-// Fetches ZeroPage Address when in Addressing Mode - Zero Page with X offset
-// Consumes 2 clock cycles
-func (cpu *CPU) AddressZeroPageX(cycles *int) uint16{
-
-            zeroPageAddress := cpu.FetchByte(cycles)
-
-            // Wrap Around
-            zeroPageAddress = byte(uint16(cpu.X + zeroPageAddress) % 0x100)
-            *cycles--
-            return uint16(zeroPageAddress)
-}
+// LogicalShiftRight shifts value one bit right, moving bit 0 into the carry flag.
+// Bit 7 is always cleared, so the negative flag always ends up cleared.
+func LogicalShiftRight(cpu *CPU, value byte) byte{
 
-// Fetches ZeroPage Address when in Addressing Mode - Zero Page with Y offset
-// Consumes 2 clock cycles
-func (cpu *CPU) AddressZeroPageY(cycles *int) uint16{
+            cpu.PS.C = uint(value & 0x01)
 
-            zeroPageAddress := cpu.FetchByte(cycles)
+            value = value >> 1
 
-            // Wrap Around
-            zeroPageAddress = byte(uint16(cpu.Y + zeroPageAddress) % 0x100)
-            *cycles--
+            SetZeroAndNegativeFlags(cpu, value)
 
-            return uint16(zeroPageAddress)
+            return value
 }
 
-// Fetches Absolute Address when in Addressing Mode - Absolute
-// Consumes 2 clock cycles
-func (cpu *CPU) AddressAbsolute(cycles *int) uint16{
+// RotateLeft shifts value one bit left, filling bit 0 with the old carry flag.
+// Bit 7 becomes the new carry flag.
+func RotateLeft(cpu *CPU, value byte) byte{
 
-            return uint16(cpu.FetchWord(cycles))
-}
-
-// Fetches Absolute Address when in Addressing Mode - Absolute with X offset
-// Consumes 2(+1) clock cycles
-func (cpu *CPU) AddressAbsoluteX(cycles *int) uint16{
+            oldCarry := byte(cpu.PS.C)
+            cpu.PS.C = uint(value >> 7)
 
-            targetAddress := uint16(cpu.FetchWord(cycles))
+            value = (value << 1) | oldCarry
 
-            // Add X value to the fetched address
-            AddRegValueToTarget16Address(cpu.X, &targetAddress, cycles)
+            SetZeroAndNegativeFlags(cpu, value)
 
-            return targetAddress
+            return value
 }
 
-// Fetches Absolute Address when in Addressing Mode - Absolute with Y offset
-func (cpu *CPU) AddressAbsoluteY(cycles *int) uint16{
-
-            targetAddress := uint16(cpu.FetchWord(cycles))
-
-            // Add Y value to the fetched address
-            AddRegValueToTarget16Address(cpu.Y, &targetAddress, cycles)
+// RotateRight shifts value one bit right, filling bit 7 with the old carry flag.
+// Bit 0 becomes the new carry flag.
+func RotateRight(cpu *CPU, value byte) byte{
 
-            return targetAddress
-}
-
-func (cpu *CPU) AddressIndirectX(cycles *int) uint16{
+            oldCarry := byte(cpu.PS.C)
+            cpu.PS.C = uint(value & 0x01)
 
-        // Fetch the Zero Page Address and add X
-        zeroPageAddress := cpu.AddressZeroPageX(cycles)
+            value = (value >> 1) | (oldCarry << 7)
 
-        // The effective address is the 
-        effectiveAddress := cpu.ReadWord(cycles, zeroPageAddress)
+            SetZeroAndNegativeFlags(cpu, value)
 
-        return effectiveAddress
+            return value
 }
 
-func (cpu *CPU) AddressIndirectY(cycles *int) uint16{
-
-        // Fetch the Zero Page Address
-        zeroPageAddress := cpu.FetchByte(cycles)
-
-        effectiveAddress := cpu.ReadWord(cycles, uint16(zeroPageAddress))
+func SetZeroAndNegativeFlags(cpu *CPU, register byte) {
 
-        // Add Y to the Effective Address
-        AddRegValueToTarget16Address(cpu.Y, &effectiveAddress, cycles)
+            // Set Z flag if A is 0
+            if register == 0 {
+                cpu.PS.Z = set
+            }else{
+                cpu.PS.Z = cleared
+            }
 
-        return effectiveAddress
+            // Set N flag if the bit 7 of A is set
+            // byte(1 << 7) is a bitmask that has the 7 bit set to 1
+            // it left-shifts the 00000001 seven positions left
+            if (register & byte(1 << 7) != 0) {
+                cpu.PS.N = set
+            }else {
+                cpu.PS.N = cleared
+            }
 }
 
-func (cpu *CPU) PushByteToStack(cycles *int, data byte) {
-    cpu.WriteByteToStack(cycles, data)
+func (cpu *CPU) PushByteToStack(data byte) {
+    cpu.WriteByteToStack(data)
 }
 
-func (cpu *CPU) PushWordToStack(cycles *int, data uint16) {
-    cpu.WriteWordToStack(cycles, data)
+func (cpu *CPU) PushWordToStack(data uint16) {
+    cpu.WriteWordToStack(data)
 }
 
-func (cpu *CPU) PopByteFromStack(cycles *int) (data byte){
+func (cpu *CPU) PopByteFromStack() (data byte){
 
-    data = cpu.ReadByteFromStack(cycles)
+    data = cpu.ReadByteFromStack()
     return
 }
 
-func (cpu *CPU) PopWordFromStack(cycles *int) (data uint16){
+func (cpu *CPU) PopWordFromStack() (data uint16){
 
-    data = cpu.ReadWordFromStack(cycles)
+    data = cpu.ReadWordFromStack()
     return
 }
 
@@ -1982,45 +752,53 @@ func (cpu *CPU) SPTo16Address(sp byte) (SP uint16){
     return
 }
 
+// BranchIf fetches the branch offset, and jumps if value meets the condition.
+// A taken branch takes one more cycle, and another one if it lands on a new page.
 // TODO: handle value and condition as bool maybe? That would mean change the PS structure
-func (cpu *CPU) BranchIf(value , condition uint, cycles *int){
+func (cpu *CPU) BranchIf(value , condition uint){
 
-            signedOffset := cpu.FetchSignedByte(cycles)
+            signedOffset := cpu.FetchSignedByte()
 
             // If value meets the condition, jump to another space in memory
             if value == condition{
 
-                *cycles--
+                cpu.extraCycles++
+                cpu.jumpRelative(signedOffset)
+            }
+}
 
-                // Add 8 signed int to uint 16. How?
-                // Cast int8 and uint16 to int16
-                // Sum 
-                // Cast back result to uint16
+// jumpRelative adds a signed offset to the PC.
+// It takes one more cycle when the target is on a new page.
+func (cpu *CPU) jumpRelative(signedOffset int8){
 
-                
-                // If original high byte is different from new high byte, there's been 
-                // a page crossing. +1 cycles
-                originalHi := cpu.PC >> 8
+            // If original high byte is different from new high byte, there's been 
+            // a page crossing. +1 cycles
+            originalHi := cpu.PC >> 8
 
-                cpu.PC = uint16(int16(signedOffset) + int16(cpu.PC))
+            // Cast int8 and uint16 to int16, sum and cast back result to uint16
+            cpu.PC = uint16(int16(signedOffset) + int16(cpu.PC))
 
-                // If to a new page, takes one cycle
-                if originalHi != cpu.PC >> 8 {
-                    *cycles--    
-                }
+            if originalHi != cpu.PC >> 8 {
+                cpu.extraCycles++
             }
 }
 
 func (cpu *CPU) PSToByte() (PS byte){
+    return cpu.PS.toByte()
+}
+
+func (ps ProcessorStatus) toByte() byte{
 
     // e.g.: 200 = 11001000
     // 00000000 | 00000000 | 00000000 | 00001000 | 00000000 | 00000000 | 01000000 | 10000000 = 11001000
-    PS = byte(cpu.PS.C) | byte(cpu.PS.Z << 1) | byte(cpu.PS.I << 2) | byte(cpu.PS.D << 3) | byte(cpu.PS.B << 4) | byte(cpu.PS.U << 5) | byte(cpu.PS.V << 6) | byte(cpu.PS.N << 7)
-
-    return
+    return byte(ps.C) | byte(ps.Z << 1) | byte(ps.I << 2) | byte(ps.D << 3) | byte(ps.B << 4) | byte(ps.U << 5) | byte(ps.V << 6) | byte(ps.N << 7)
 }
 
 func (cpu *CPU) ByteToPS(bytePS byte) (ps ProcessorStatus){
+    return statusFromByte(bytePS)
+}
+
+func statusFromByte(bytePS byte) (ps ProcessorStatus){
     
     // This is super ugly but works for now
     ps.C = uint((bytePS << 7) >> 7)
@@ -2052,7 +830,7 @@ func (cpu *CPU) LoadProgram(program []byte){
 
         if i > 1 {
 
-            cpu.Memory.Data[loadAddress] = byte
+            cpu.bus().Write(loadAddress, byte)
 
             loadAddress++
         }
@@ -2061,12 +839,12 @@ func (cpu *CPU) LoadProgram(program []byte){
 
 // Subtracts a value passed as 'memValue' from the value held by 'register'
 // And sets carry, zero and negative flags based on result
-// Carry flag if result is equal or greater than 0
+// Carry flag if register is equal or greater than memValue
 // Zero flag if result is 0
 // Negative flag is result has the 7 bit set
 func compareRegisterWithValueAndSetFlags(cpu *CPU, register, memValue uint8){
 
-            if register - memValue >= 0 {
+            if register >= memValue {
                 cpu.PS.C = set
             }else{
                 cpu.PS.C = cleared
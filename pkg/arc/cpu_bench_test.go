@@ -0,0 +1,125 @@
+package arc
+
+import (
+	"emulator/pkg/instructions"
+	"testing"
+	"time"
+)
+
+// benchmarkProgram increments the 256 bytes at $0300 forever:
+//
+//	$0200  LDX #$00
+//	$0202  LDA $0300,X
+//	$0205  ADC #$01
+//	$0207  STA $0300,X
+//	$020A  INX
+//	$020B  BNE $0202
+//	$020D  JMP $0200
+var benchmarkProgram = []byte{
+    instructions.INS_LDX_IM, 0x00,
+    instructions.INS_LDA_ABSX, 0x00, 0x03,
+    instructions.INS_ADC_IM, 0x01,
+    instructions.INS_STA_ABSX, 0x00, 0x03,
+    instructions.INS_INX_IMP,
+    instructions.INS_BNE_REL, 0xF5,
+    instructions.INS_JMP_ABS, 0x00, 0x02,
+}
+
+// Cycles run by each call to Run, about a millisecond of a 1 MHz 6502.
+const benchmarkSlice = 1000
+
+func InitBenchmark(cpu *CPU, bus Bus){
+
+    cpu.Bus = bus
+    cpu.Reset(0x0200)
+
+    for i, value := range benchmarkProgram {
+        cpu.bus().Write(0x0200 + uint16(i), value)
+    }
+}
+
+// RunBenchmark runs the benchmark program and reports the emulated clock speed,
+// in cycles per second of wall time.
+func RunBenchmark(b *testing.B, cpu *CPU){
+
+    cycles := 0
+
+    b.ResetTimer()
+    start := time.Now()
+
+    for i := 0; i < b.N; i++ {
+
+        cyclesUsed, err := cpu.Run(benchmarkSlice)
+        if err != nil {
+            b.Fatal("Benchmark program faulted: ", err)
+        }
+
+        cycles += cyclesUsed
+    }
+
+    elapsed := time.Since(start)
+    b.StopTimer()
+
+    b.ReportMetric(float64(cycles) / elapsed.Seconds() / 1e6, "MHz")
+}
+
+func BenchmarkNMOS6502(b *testing.B){
+
+    cpu := Init6502()
+    InitBenchmark(cpu, nil)
+
+    RunBenchmark(b, cpu)
+}
+
+func BenchmarkCMOS65C02(b *testing.B){
+
+    cpu := Init65C02()
+    InitBenchmark(cpu, nil)
+
+    RunBenchmark(b, cpu)
+}
+
+// Same program, with every access going through the Bus interface.
+func BenchmarkNMOS6502WithBus(b *testing.B){
+
+    cpu := Init6502()
+    InitBenchmark(cpu, &Memory{})
+
+    RunBenchmark(b, cpu)
+}
+
+// Same program, counting the executions and cycles of every address.
+func BenchmarkNMOS6502WithProfiler(b *testing.B){
+
+    cpu := Init6502()
+    InitBenchmark(cpu, nil)
+    cpu.Profiler = NewProfiler()
+
+    RunBenchmark(b, cpu)
+}
+
+func BenchmarkStep(b *testing.B){
+
+    cpu := Init6502()
+    InitBenchmark(cpu, nil)
+
+    cycles := 0
+
+    b.ResetTimer()
+    start := time.Now()
+
+    for i := 0; i < b.N; i++ {
+
+        result, err := cpu.Step()
+        if err != nil {
+            b.Fatal("Benchmark program faulted: ", err)
+        }
+
+        cycles += result.Cycles
+    }
+
+    elapsed := time.Since(start)
+    b.StopTimer()
+
+    b.ReportMetric(float64(cycles) / elapsed.Seconds() / 1e6, "MHz")
+}
@@ -0,0 +1,118 @@
+package arc
+
+import (
+	"emulator/pkg/instructions"
+	"testing"
+)
+
+// testBus is 64K of RAM with an output port mapped at $D000,
+// which records every access made by the CPU.
+type testBus struct {
+    ram Memory
+    port []byte
+    reads []uint16
+    writes []uint16
+}
+
+func (bus *testBus) Read(address uint16) byte{
+
+    bus.reads = append(bus.reads, address)
+    return bus.ram.Read(address)
+}
+
+func (bus *testBus) Write(address uint16, value byte){
+
+    bus.writes = append(bus.writes, address)
+
+    if address == 0xD000 {
+        bus.port = append(bus.port, value)
+        return
+    }
+
+    bus.ram.Write(address, value)
+}
+
+func TestCPUUsesItsOwnMemoryWhenNoBusIsSet(t *testing.T){
+
+    cpu := Init6502()
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_LDA_IM
+    cpu.Memory.Data[0xFFFD] = 0x42
+
+    cpu.Execute(2)
+
+    if cpu.A != 0x42 {
+        t.Error("A should be 0x42 but got: ", cpu.A)
+    }
+}
+
+func TestCPUWritesToMemoryMappedDeviceThroughBus(t *testing.T){
+
+    bus := &testBus{}
+
+    cpu := Init6502()
+    cpu.Bus = bus
+
+    cpu.LoadProgram([]byte{0x00, 0x10,
+        instructions.INS_LDA_IM, 0x48,
+        instructions.INS_STA_ABS, 0x00, 0xD0,
+        instructions.INS_LDA_IM, 0x49,
+        instructions.INS_STA_ABS, 0x00, 0xD0,
+    })
+
+    cpu.Execute(2+4+2+4)
+
+    if string(bus.port) != "HI" {
+        t.Error("Port should have received HI but got: ", string(bus.port))
+    }
+
+    if bus.ram.Data[0xD000] != 0 {
+        t.Error("Writes to the port shouldn't reach the RAM")
+    }
+
+    if cpu.Memory.Data[0x1000] != 0 {
+        t.Error("The CPU Memory shouldn't be used when a Bus is set")
+    }
+}
+
+func TestStackOperationsGoThroughBus(t *testing.T){
+
+    bus := &testBus{}
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+    cpu.Bus = bus
+
+    bus.ram.Data[0xFF00] = instructions.INS_JSR_ABS
+    bus.ram.Data[0xFF01] = 0x00
+    bus.ram.Data[0xFF02] = 0x80
+    bus.ram.Data[0x8000] = instructions.INS_RTS_IMP
+
+    expectedCycles := 6+6
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.PC != 0xFF03 {
+        t.Error("PC should be 0xFF03 but got: ", cpu.PC)
+    }
+
+    wantWrites := []uint16{0x01FD, 0x01FC}
+    if len(bus.writes) != len(wantWrites) || bus.writes[0] != wantWrites[0] || bus.writes[1] != wantWrites[1] {
+        t.Error("Expected stack writes at ", wantWrites, "but got: ", bus.writes)
+    }
+
+    // Opcode and operands of JSR, opcode of RTS, return address from the stack
+    wantReads := []uint16{0xFF00, 0xFF01, 0xFF02, 0x8000, 0x01FC, 0x01FD}
+    if len(bus.reads) != len(wantReads) {
+        t.Fatal("Expected reads at ", wantReads, "but got: ", bus.reads)
+    }
+    for i := range wantReads {
+        if bus.reads[i] != wantReads[i] {
+            t.Error("Expected reads at ", wantReads, "but got: ", bus.reads)
+            break
+        }
+    }
+}
@@ -0,0 +1,175 @@
+package arc
+
+import (
+	"testing"
+)
+
+func InitCallsCPU(code map[uint16][]byte) *CPU{
+
+    cpu := NewCPU(NMOS6502)
+    cpu.Reset(0x0200)
+
+    for address, bytes := range code {
+        copy(cpu.Memory.Data[address:], bytes)
+    }
+
+    cpu.Profiler = NewProfiler()
+    cpu.Profiler.Calls = NewCallGraph()
+
+    return cpu
+}
+
+// CheckNode checks the node of the routine called at address from parent, and returns it.
+func CheckNode(t *testing.T, parent *CallNode, address uint16, calls, cycles uint64) *CallNode{
+
+    t.Helper()
+
+    for _, node := range parent.Children {
+
+        if node.Routine == address {
+
+            if node.Calls != calls || node.Cycles != cycles {
+                t.Errorf("$%04X: expected %d calls and %d cycles but got %d and %d", address, calls, cycles, node.Calls, node.Cycles)
+            }
+
+            return node
+        }
+    }
+
+    t.Fatalf("$%04X isn't called from $%04X", address, parent.Routine)
+
+    return nil
+}
+
+func CheckAnomalies(t *testing.T, g *CallGraph, expected ...string){
+
+    t.Helper()
+
+    anomalies, count := g.Anomalies()
+
+    if count != uint64(len(expected)) {
+        t.Fatal("Unexpected anomalies: ", anomalies)
+    }
+
+    for i, anomaly := range anomalies {
+        if anomaly.String() != expected[i] {
+            t.Errorf("Expected %q but got %q", expected[i], anomaly)
+        }
+    }
+}
+
+func TestCallGraphFollowsCalls(t *testing.T){
+
+    cpu := InitCallsCPU(map[uint16][]byte{
+        0x0200: {0x20, 0x00, 0x03, 0x20, 0x00, 0x03, 0x4C, 0x06, 0x02}, // JSR a, JSR a, JMP *
+        0x0300: {0x20, 0x10, 0x03, 0x60},                               // a: JSR b, RTS
+        0x0310: {0xEA, 0x60},                                           // b: NOP, RTS
+        0x0320: {0x40},                                                 // NMI handler: RTI
+        NMIVector: {0x20, 0x03},
+    })
+
+    cpu.Execute(6 + 20 + 6 + 20 + 3)
+
+    g := cpu.Profiler.Calls
+
+    if g.Root.Routine != 0x0200 || g.Root.Cycles != 15 || len(g.Stack()) != 0 {
+        t.Fatal("Unexpected root: ", g.Root)
+    }
+
+    a := CheckNode(t, g.Root, 0x0300, 2, 24)
+    CheckNode(t, a, 0x0310, 2, 16)
+
+    if a.InclusiveCycles() != 40 || g.Root.InclusiveCycles() != 55 {
+        t.Error("Unexpected inclusive cycles: ", a.InclusiveCycles(), g.Root.InclusiveCycles())
+    }
+
+    // The interrupt sequence goes to the handler
+    cpu.AssertNMI()
+    cpu.Execute(7 + 6 + 3)
+
+    handler := CheckNode(t, g.Root, 0x0320, 1, 13)
+
+    if !handler.Interrupt || g.Root.InclusiveCycles() != cpu.Profiler.TotalCycles() {
+        t.Error("Unexpected handler: ", handler, g.Root.InclusiveCycles(), cpu.Profiler.TotalCycles())
+    }
+
+    CheckAnomalies(t, g)
+
+    // Clearing keeps the routines being run
+    cpu.PC = 0x0200
+    cpu.Execute(6 + 6)
+
+    if stack := g.Stack(); len(stack) != 2 || stack[1].Routine != 0x0310 {
+        t.Fatal("Expected to be in b: ", stack)
+    }
+
+    cpu.Profiler.Clear()
+    cpu.Execute(2 + 6 + 6 + 6)
+
+    a = CheckNode(t, g.Root, 0x0300, 1, 6)
+    CheckNode(t, a, 0x0310, 0, 8)
+    CheckAnomalies(t, g)
+}
+
+func TestCallGraphDetectsFakeReturns(t *testing.T){
+
+    cpu := InitCallsCPU(map[uint16][]byte{
+
+        // JSR c, JMP *
+        0x0200: {0x20, 0x00, 0x04, 0x4C, 0x03, 0x02},
+
+        // c: jumps to $0409 with RTS, then calls d
+        0x0400: {0xA9, 0x04, 0x48, 0xA9, 0x08, 0x48, 0x60, 0xEA, 0xEA, 0x20, 0x20, 0x04, 0x60},
+
+        // d: drops its return address with PLA PLA, and returns from c
+        0x0420: {0x68, 0x68, 0x60},
+    })
+
+    cpu.Execute(6 + 2 + 3 + 2 + 3 + 6 + 6 + 4 + 4 + 6 + 3)
+
+    g := cpu.Profiler.Calls
+
+    c := CheckNode(t, g.Root, 0x0400, 1, 2 + 3 + 2 + 3 + 6 + 6)
+    CheckNode(t, c, 0x0420, 1, 4 + 4 + 6)
+
+    if len(g.Stack()) != 0 || g.Root.Cycles != 6 + 3 {
+        t.Error("The program should be back to the root: ", g.Stack(), g.Root.Cycles)
+    }
+
+    CheckAnomalies(t, g,
+        "fake return at $0406: to $0409",
+        "skipped frames at $0422: 2 routines left, going to $0203",
+    )
+}
+
+func TestCallGraphDetectsStackChanges(t *testing.T){
+
+    cpu := InitCallsCPU(map[uint16][]byte{
+
+        // JSR e, an inline parameter, JSR f, JMP *
+        0x0200: {0x20, 0x00, 0x03, 0xFF, 0x20, 0x20, 0x03, 0x4C, 0x07, 0x02},
+
+        // e: skips the parameter incrementing its return address
+        0x0300: {0xBA, 0xFE, 0x01, 0x01, 0x60},
+
+        // f: resets the stack and jumps back
+        0x0320: {0xA2, 0xFD, 0x9A, 0x4C, 0x07, 0x02},
+    })
+
+    cpu.Execute(6 + 2 + 7 + 6 + 6 + 2 + 2 + 3 + 3)
+
+    g := cpu.Profiler.Calls
+
+    CheckNode(t, g.Root, 0x0300, 1, 2 + 7 + 6)
+    // The JMP after TXS is outside of f
+    CheckNode(t, g.Root, 0x0320, 1, 2 + 2)
+
+    if len(g.Stack()) != 0 {
+        t.Error("The stack should be empty: ", g.Stack())
+    }
+
+    CheckAnomalies(t, g,
+        "modified return at $0304: to $0204 instead of $0203",
+        "dropped frames at $0322: 1 routines left, going to $0323",
+    )
+}
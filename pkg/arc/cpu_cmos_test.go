@@ -0,0 +1,479 @@
+package arc
+
+import (
+	"emulator/pkg/instructions"
+	"testing"
+)
+
+func Init65C02() (cpu *CPU){
+    return NewCPU(CMOS65C02)
+}
+
+func TestNewCPUResetsWithTheChosenVariant(t *testing.T){
+
+    cpu := NewCPU(CMOS65C02)
+
+    if cpu.Variant != CMOS65C02 {
+        t.Error("Variant should be CMOS65C02 but got: ", cpu.Variant)
+    }
+
+    if cpu.PC != ResetVector || cpu.SP != 0xFD {
+        t.Error("CPU not reset correctly, PC: ", cpu.PC, "SP: ", cpu.SP)
+    }
+
+    cpu.Reset(0xFF00)
+
+    if cpu.Variant != CMOS65C02 {
+        t.Error("Reset shouldn't change the variant")
+    }
+}
+
+func TestBRABranchesAlways(t *testing.T){
+
+    cpu := Init65C02()
+    cpu.Reset(0xFF00)
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_BRA_REL
+    cpu.Memory.Data[0xFF01] = 0x10
+
+    expectedCycles := 3
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.PC != 0xFF12 {
+        t.Error("PC should be 0xFF12 but got: ", cpu.PC)
+    }
+}
+
+func TestPHXAndPLYMoveValuesThroughTheStack(t *testing.T){
+
+    cpu := Init65C02()
+    cpu.Reset(0xFF00)
+    cpu.X = 0x80
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_PHX_IMP
+    cpu.Memory.Data[0xFF01] = instructions.INS_PLY_IMP
+
+    expectedCycles := 3+4
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Y != 0x80 {
+        t.Error("Y should be 0x80 but got: ", cpu.Y)
+    }
+
+    if cpu.SP != 0xFD {
+        t.Error("SP should be 0xFD but got: ", cpu.SP)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.N)
+}
+
+func TestSTZAbsoluteXStoresZero(t *testing.T){
+
+    cpu := Init65C02()
+    cpu.X = 0x02
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_STZ_ABSX
+    cpu.Memory.Data[0xFFFD] = 0x00
+    cpu.Memory.Data[0xFFFE] = 0x20
+    cpu.Memory.Data[0x2002] = 0x42
+
+    expectedCycles := 5
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x2002] != 0 {
+        t.Error("Value at 0x2002 should be 0 but got: ", cpu.Memory.Data[0x2002])
+    }
+}
+
+func TestTSBAndTRBSetAndResetAccumulatorBits(t *testing.T){
+
+    cpu := Init65C02()
+    cpu.Reset(0xFF00)
+    cpu.A = 0x0F
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_TSB_ZP
+    cpu.Memory.Data[0xFF01] = 0x42
+    cpu.Memory.Data[0x0042] = 0xF0
+
+    expectedCycles := 5
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x0042] != 0xFF {
+        t.Error("Value at 0x0042 should be 0xFF but got: ", cpu.Memory.Data[0x0042])
+    }
+
+    // No common bits before the operation
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z)
+
+    cpu.Memory.Data[0xFF02] = instructions.INS_TRB_ABS
+    cpu.Memory.Data[0xFF03] = 0x42
+    cpu.Memory.Data[0xFF04] = 0x00
+
+    expectedCycles = 6
+    cyclesUsed = cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x0042] != 0xF0 {
+        t.Error("Value at 0x0042 should be 0xF0 but got: ", cpu.Memory.Data[0x0042])
+    }
+
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.Z)
+}
+
+func TestLDAIndirectZeroPageLoadsFromPointer(t *testing.T){
+
+    cpu := Init65C02()
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_LDA_INDZP
+    cpu.Memory.Data[0xFFFD] = 0x20
+    cpu.Memory.Data[0x0020] = 0x00
+    cpu.Memory.Data[0x0021] = 0x30
+    cpu.Memory.Data[0x3000] = 0x81
+
+    expectedCycles := 5
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x81 {
+        t.Error("A should be 0x81 but got: ", cpu.A)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.N)
+}
+
+func TestLDAIndirectZeroPageWrapsAroundTheZeroPage(t *testing.T){
+
+    cpu := Init65C02()
+
+    // The high byte of the pointer at $FF is at $00
+    cpu.Memory.Data[0xFFFC] = instructions.INS_LDA_INDZP
+    cpu.Memory.Data[0xFFFD] = 0xFF
+    cpu.Memory.Data[0x00FF] = 0x34
+    cpu.Memory.Data[0x0000] = 0x12
+    cpu.Memory.Data[0x0100] = 0x56
+    cpu.Memory.Data[0x1234] = 0x42
+    cpu.Memory.Data[0x5634] = 0x24
+
+    cpu.Execute(5)
+
+    if cpu.A != 0x42 {
+        t.Error("A should be 0x42 but got: ", cpu.A)
+    }
+}
+
+func TestBITImmediateOnlyAffectsZeroFlag(t *testing.T){
+
+    cpu := Init65C02()
+    cpu.A = 0x01
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_BIT_IM
+    cpu.Memory.Data[0xFFFD] = 0xC0
+
+    expectedCycles := 2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.N, &cpu.PS.V)
+}
+
+func TestINCAccumulatorIncrementsA(t *testing.T){
+
+    cpu := Init65C02()
+    cpu.A = 0xFF
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_INC_ACC
+
+    expectedCycles := 2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0 {
+        t.Error("A should be 0 but got: ", cpu.A)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z)
+}
+
+func TestJMPIndexedIndirectJumpsThroughTable(t *testing.T){
+
+    cpu := Init65C02()
+    cpu.X = 0x04
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_JMP_INDX
+    cpu.Memory.Data[0xFFFD] = 0x00
+    cpu.Memory.Data[0xFFFE] = 0x30
+    cpu.Memory.Data[0x3004] = 0x34
+    cpu.Memory.Data[0x3005] = 0x12
+
+    expectedCycles := 6
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.PC != 0x1234 {
+        t.Error("PC should be 0x1234 but got: ", cpu.PC)
+    }
+}
+
+func TestJMPIndirectPageBoundaryBugOnlyAffectsNMOS(t *testing.T){
+
+    for _, variant := range []Variant{NMOS6502, CMOS65C02} {
+
+        cpu := NewCPU(variant)
+
+        cpu.Memory.Data[0xFFFC] = instructions.INS_JMP_IND
+        cpu.Memory.Data[0xFFFD] = 0xFF
+        cpu.Memory.Data[0xFFFE] = 0x30
+        cpu.Memory.Data[0x30FF] = 0x34
+        cpu.Memory.Data[0x3000] = 0x56
+        cpu.Memory.Data[0x3100] = 0x12
+
+        want := uint16(0x1234)
+        expectedCycles := 6
+
+        if variant == NMOS6502 {
+            want = 0x5634
+            expectedCycles = 5
+        }
+
+        cyclesUsed := cpu.Execute(expectedCycles)
+
+        if expectedCycles != cyclesUsed {
+            t.Error("Variant ", variant, "expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+        }
+
+        if cpu.PC != want {
+            t.Error("Variant ", variant, "PC should be ", want, "but got: ", cpu.PC)
+        }
+    }
+}
+
+func TestRMBAndSMBChangeSingleBits(t *testing.T){
+
+    cpu := Init65C02()
+    cpu.Reset(0xFF00)
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_RMB7_ZP
+    cpu.Memory.Data[0xFF01] = 0x42
+    cpu.Memory.Data[0xFF02] = instructions.INS_SMB0_ZP
+    cpu.Memory.Data[0xFF03] = 0x42
+    cpu.Memory.Data[0x0042] = 0x80
+
+    expectedCycles := 5+5
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x0042] != 0x01 {
+        t.Error("Value at 0x0042 should be 0x01 but got: ", cpu.Memory.Data[0x0042])
+    }
+}
+
+func TestBBSBranchesWhenBitIsSetAndBBRDoesNot(t *testing.T){
+
+    cpu := Init65C02()
+    cpu.Reset(0xFF00)
+
+    cpu.Memory.Data[0x0042] = 0x08
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_BBR3_ZPREL
+    cpu.Memory.Data[0xFF01] = 0x42
+    cpu.Memory.Data[0xFF02] = 0x10
+    cpu.Memory.Data[0xFF03] = instructions.INS_BBS3_ZPREL
+    cpu.Memory.Data[0xFF04] = 0x42
+    cpu.Memory.Data[0xFF05] = 0x10
+
+    expectedCycles := 5+6
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.PC != 0xFF16 {
+        t.Error("PC should be 0xFF16 but got: ", cpu.PC)
+    }
+}
+
+func TestWAISleepsUntilIRQ(t *testing.T){
+
+    cpu := Init65C02()
+    cpu.Reset(0xFF00)
+    cpu.PS.I = set
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_WAI_IMP
+    cpu.Memory.Data[0xFF01] = instructions.INS_LDA_IM
+    cpu.Memory.Data[0xFF02] = 0x42
+
+    cpu.Execute(3+50)
+
+    if cpu.PC != 0xFF01 {
+        t.Error("CPU should be waiting at 0xFF01 but PC is: ", cpu.PC)
+    }
+
+    // With interrupts disabled the CPU just wakes up and goes on
+    cpu.AssertIRQ()
+
+    expectedCycles := 2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x42 {
+        t.Error("A should be 0x42 but got: ", cpu.A)
+    }
+}
+
+func TestSTPStopsTheCPU(t *testing.T){
+
+    cpu := Init65C02()
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_STP_IMP
+
+    cpu.Execute(3)
+
+    if !cpu.Jammed() {
+        t.Error("CPU should be stopped")
+    }
+}
+
+func TestNMOSIllegalOpcodesAreNOPsOn65C02(t *testing.T){
+
+    cpu := Init65C02()
+    cpu.Reset(0xFF00)
+
+    // SLO (ind,X) and JAM on the NMOS 6502
+    cpu.Memory.Data[0xFF00] = instructions.INS_SLO_INDX
+    cpu.Memory.Data[0xFF01] = instructions.INS_JAM_02
+    cpu.Memory.Data[0xFF02] = 0xFF
+    cpu.Memory.Data[0xFF03] = instructions.INS_LDA_IM
+    cpu.Memory.Data[0xFF04] = 0x42
+
+    cpuCopy := *cpu
+
+    expectedCycles := 1+2+2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x42 || cpu.X != 0 {
+        t.Error("Only LDA should change registers, A: ", cpu.A, "X: ", cpu.X)
+    }
+
+    if cpu.Jammed() {
+        t.Error("CPU shouldn't be jammed")
+    }
+
+    CheckUnmodifiedLDAFlags(cpuCopy, cpu, t)
+}
+
+func TestBRKClearsDecimalFlagOn65C02(t *testing.T){
+
+    cpu := Init65C02()
+    cpu.PS.D = set
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_BRK_IMP
+
+    cpu.Execute(7)
+
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.D)
+
+    // The pushed PS keeps it
+    if cpu.Memory.Data[0x01FB] & 0x08 == 0 {
+        t.Error("Pushed PS should have the decimal flag set")
+    }
+}
+
+func TestADCDecimalSetsZeroFlagFromResultOn65C02(t *testing.T){
+
+    cpu := Init65C02()
+    cpu.PS.D = set
+
+    // 99 + 1 = 100, with a cycle more than the NMOS 6502
+    CheckADCIMExecute(cpu, 0x99, 0x01, 0x00, 3, t)
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.C, &cpu.PS.Z)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.N)
+}
+
+func TestSBCDecimalSetsNegativeFlagFromResultOn65C02(t *testing.T){
+
+    cpu := Init65C02()
+    cpu.PS.D = set
+    cpu.PS.C = set
+
+    cpu.A = 0x00
+    cpu.Memory.Data[0xFFFC] = instructions.INS_SBC_IM
+    cpu.Memory.Data[0xFFFD] = 0x01
+
+    // 00 - 01 = 99, with borrow, and a cycle more than the NMOS 6502
+    if cyclesUsed := cpu.Execute(3); cyclesUsed != 3 {
+        t.Error("Cycles used: ", cyclesUsed, ", instead expected: ", 3)
+    }
+
+    if cpu.A != 0x99 {
+        t.Error("Accumulator should be 0x99 but got: ", cpu.A)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.N)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.C, &cpu.PS.Z)
+}
+
+// The extra cycle of decimal mode adds to the cycles of the addressing mode,
+// and only decimal mode takes it.
+func TestDecimalModeTakesOneMoreCycleOn65C02(t *testing.T){
+
+    for _, decimal := range []uint{cleared, set} {
+
+        cpu := Init65C02()
+        cpu.PS.D = decimal
+
+        cpu.Memory.Data[0xFFFC] = instructions.INS_ADC_ZP
+        cpu.Memory.Data[0xFFFD] = 0x42
+        cpu.Memory.Data[0xFFFE] = instructions.INS_SBC_ZP
+        cpu.Memory.Data[0xFFFF] = 0x42
+
+        expectedCycles := 3+3 + 2*int(decimal)
+
+        if cyclesUsed := cpu.Execute(expectedCycles); cyclesUsed != expectedCycles {
+            t.Error("D = ", decimal, ": cycles used: ", cyclesUsed, ", instead expected: ", expectedCycles)
+        }
+    }
+}
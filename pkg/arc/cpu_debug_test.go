@@ -0,0 +1,288 @@
+package arc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// debugProgram counts X down from 3, storing it at $0300:
+//
+//	$0200  LDX #$03
+//	$0202  DEX
+//	$0203  STX $0300
+//	$0206  BNE $0202
+//	$0208  JMP $0208
+var debugProgram = []byte{0xA2, 0x03, 0xCA, 0x8E, 0x00, 0x03, 0xD0, 0xFA, 0x4C, 0x08, 0x02}
+
+func InitDebugger() (*CPU, *Debugger){
+
+    cpu := Init6502()
+    cpu.Reset(0x0200)
+    copy(cpu.Memory.Data[0x0200:], debugProgram)
+
+    cpu.Debugger = NewDebugger()
+
+    return cpu, cpu.Debugger
+}
+
+func CheckHit(t *testing.T, err error, b *Breakpoint, pc uint16, cycles int) *Hit{
+
+    t.Helper()
+
+    var hit *Hit
+    if !errors.As(err, &hit) {
+        t.Fatal("Expected a breakpoint hit but got: ", err)
+    }
+
+    if hit.Breakpoint != b || hit.PC != pc || hit.Cycles != cycles {
+        t.Fatalf("Expected a hit of %v at $%04X after %d cycles but got: %v", b, pc, cycles, hit)
+    }
+
+    return hit
+}
+
+func TestRunStopsAtBreakpoints(t *testing.T){
+
+    cpu, debugger := InitDebugger()
+
+    b := debugger.Break(0x0206)
+
+    // LDX, DEX, STX
+    cycles, err := cpu.Run(100)
+    CheckHit(t, err, b, 0x0206, 8)
+
+    if cycles != 8 || cpu.PC != 0x0206 || cpu.X != 2 {
+        t.Errorf("Expected to stop before BNE after 8 cycles but got PC $%04X, X %d, %d cycles", cpu.PC, cpu.X, cycles)
+    }
+
+    // Going on doesn't stop at the same breakpoint right away: BNE taken, DEX, STX
+    _, err = cpu.Run(100)
+    CheckHit(t, err, b, 0x0206, 9)
+
+    if cpu.X != 1 || b.Hits != 2 || debugger.LastHit().Breakpoint != b {
+        t.Error("Expected a second hit with X at 1 but got: ", cpu.X, b.Hits)
+    }
+
+    if !debugger.Delete(b.ID) || debugger.Delete(b.ID) {
+        t.Error("The breakpoint should be deleted once")
+    }
+
+    if _, err := cpu.Run(100); err != nil || debugger.LastHit() != nil {
+        t.Error("Without breakpoints Run shouldn't stop but got: ", err)
+    }
+}
+
+func TestBreakpointConditionsAndIgnoreCounts(t *testing.T){
+
+    cpu, debugger := InitDebugger()
+
+    b := debugger.Break(0x0206)
+    b.Condition, _ = ParseCondition("x == 1 && mem[$0300] == 1")
+
+    _, err := cpu.Run(100)
+    CheckHit(t, err, b, 0x0206, 8 + 9)
+
+    if b.Hits != 1 {
+        t.Error("Hits shouldn't count the breakpoints whose condition doesn't hold, got: ", b.Hits)
+    }
+
+    cpu, debugger = InitDebugger()
+
+    b = debugger.Break(0x0202)
+    b.Ignore = 2
+
+    _, err = cpu.Run(100)
+    CheckHit(t, err, b, 0x0202, 2 + 9 + 9)
+
+    if cpu.X != 1 || b.Hits != 3 {
+        t.Error("The first two hits should have been ignored, X: ", cpu.X, " hits: ", b.Hits)
+    }
+
+    b.Enabled = false
+
+    if _, err := cpu.Run(100); err != nil {
+        t.Error("A disabled breakpoint shouldn't stop the CPU but got: ", err)
+    }
+}
+
+func TestRunStopsAtWatchpoints(t *testing.T){
+
+    cpu, debugger := InitDebugger()
+
+    w, _ := debugger.Add(WatchWrite, 0x0300, 0x0300)
+
+    // The instruction that writes completes
+    cycles, err := cpu.Run(100)
+    hit := CheckHit(t, err, w, 0x0203, 8)
+
+    if !hit.Write || hit.Address != 0x0300 || hit.Value != 2 || cycles != 8 || cpu.PC != 0x0206 {
+        t.Errorf("Unexpected hit %v, PC $%04X, %d cycles", hit, cpu.PC, cycles)
+    }
+
+    if !strings.Contains(hit.Error(), "write watchpoint 1: $02 written at $0300 by the instruction at $0203") {
+        t.Error("Unexpected message: ", hit)
+    }
+
+    // The watching bus is only installed during Run
+    if cpu.Bus != nil {
+        t.Error("The Bus should be restored after Run but got: ", cpu.Bus)
+    }
+
+    debugger.Delete(w.ID)
+
+    // Fetching the opcode at $0202 isn't a read
+    r, _ := debugger.Add(WatchRead, 0x0202, 0x0300)
+
+    if _, err := cpu.Run(100); err != nil || r.Hits != 0 {
+        t.Error("Fetches shouldn't hit read watchpoints but got: ", err)
+    }
+
+    // LDA $0300
+    cpu.PC = 0x0400
+    copy(cpu.Memory.Data[0x0400:], []byte{0xAD, 0x00, 0x03, 0x4C, 0x03, 0x04})
+
+    _, err = cpu.Run(100)
+    hit = CheckHit(t, err, r, 0x0400, 4)
+
+    if hit.Write || hit.Address != 0x0300 || cpu.A != 0 {
+        t.Error("Unexpected read hit: ", hit)
+    }
+}
+
+func TestWatchpointsCatchDataReadsAtThePC(t *testing.T){
+
+    cpu, debugger := InitDebugger()
+
+    // LDA $0403 reads the byte right after it, where the PC is
+    cpu.PC = 0x0400
+    copy(cpu.Memory.Data[0x0400:], []byte{0xAD, 0x03, 0x04, 0x42})
+
+    r, _ := debugger.Add(WatchRead, 0x0403, 0x0403)
+
+    _, err := cpu.Run(100)
+    hit := CheckHit(t, err, r, 0x0400, 4)
+
+    if hit.Address != 0x0403 || hit.Value != 0x42 || cpu.A != 0x42 {
+        t.Error("Unexpected read hit: ", hit)
+    }
+}
+
+// romBus is RAM up to $7FFF, and refuses the writes above.
+type romBus struct {
+    Memory
+    fault bool
+    address uint16
+}
+
+func (bus *romBus) Write(address uint16, value byte){
+
+    if address >= 0x8000 {
+        bus.fault, bus.address = true, address
+        return
+    }
+
+    bus.Memory.Write(address, value)
+}
+
+func (bus *romBus) TakeFault() (uint16, bool){
+
+    fault := bus.fault
+    bus.fault = false
+
+    return bus.address, fault
+}
+
+func TestWatchpointsGoThroughTheBus(t *testing.T){
+
+    cpu := Init6502()
+    bus := &romBus{}
+    cpu.Bus = bus
+    cpu.Reset(0x0200)
+
+    // STA $9000
+    copy(bus.Data[0x0200:], []byte{0x8D, 0x00, 0x90})
+
+    cpu.Debugger = NewDebugger()
+    w, _ := cpu.Debugger.Add(WatchAccess, 0x9000, 0x9000)
+
+    // The address fault wins over the watchpoint
+    _, err := cpu.Run(4)
+
+    if !errors.Is(err, ErrAddressFault) || w.Hits != 1 || cpu.Bus != bus {
+        t.Error("Expected an address fault but got: ", err, w.Hits)
+    }
+}
+
+func TestStepIgnoresExecutionBreakpoints(t *testing.T){
+
+    cpu, debugger := InitDebugger()
+    debugger.Break(0x0200)
+
+    if result, err := cpu.Step(); err != nil || result.Mnemonic != "LDX" {
+        t.Error("Step should execute LDX but got: ", result.Mnemonic, err)
+    }
+}
+
+func TestExecuteStopsAtBreakpoints(t *testing.T){
+
+    cpu, debugger := InitDebugger()
+    b := debugger.Break(0x0203)
+
+    if cycles := cpu.Execute(100); cycles != 4 || debugger.LastHit() == nil || debugger.LastHit().Breakpoint != b {
+        t.Error("Execute should stop at the breakpoint after 4 cycles but got: ", cycles, debugger.LastHit())
+    }
+}
+
+func TestConditions(t *testing.T){
+
+    cpu := Init6502()
+    cpu.A = 0x10
+    cpu.X = 3
+    cpu.PC = 0x1234
+    cpu.PS.C = set
+    cpu.Memory.Data[0x20] = 4
+
+    tests := map[string]int{
+        "A == $10 && mem[$20] > 3": 1,
+        "a == 16 || x == 0": 1,
+        "mem[$10 + $10] * 2 + 1": 9,
+        "-1 + 2 * (3 + 4) % 5": 3,
+        "!C | ~0 & %1010": 10,
+        "PC >> 8 == 0x12": 1,
+        "x != 3 || y": 0,
+        "1 << 4 >= 16": 1,
+        "5 / 0": 0,
+        "P & 1": 1,
+    }
+
+    for text, expected := range tests {
+
+        condition, err := ParseCondition(text)
+        if err != nil {
+            t.Error(text, ": ", err)
+            continue
+        }
+
+        if value := condition.Evaluate(cpu); value != expected {
+            t.Error(text, ": expected ", expected, " but got: ", value)
+        }
+    }
+
+    errors := map[string]string{
+        "": "unexpected end",
+        "A ==": "unexpected end",
+        "Q == 1": "unknown name Q",
+        "(A": "expected )",
+        "mem[1": "expected ]",
+        "$G": "invalid number",
+        "A B": "unexpected \"B\"",
+    }
+
+    for text, message := range errors {
+
+        if _, err := ParseCondition(text); err == nil || !strings.Contains(err.Error(), message) {
+            t.Error(text, ": expected an error containing ", message, " but got: ", err)
+        }
+    }
+}
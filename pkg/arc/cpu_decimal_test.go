@@ -0,0 +1,198 @@
+package arc
+
+import (
+	"emulator/pkg/instructions"
+	"testing"
+)
+
+func TestADCDecimalAddsTwoDigitNumbers(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.D = set
+
+    // 29 + 13 = 42
+    CheckADCIMExecute(cpu, 0x29, 0x13, 0x42, 2, t)
+
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.C, &cpu.PS.Z)
+}
+
+func TestADCDecimalSetsCarryWhenResultExceeds99(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.D = set
+    cpu.PS.C = set
+
+    // 58 + 46 + 1 = 105
+    CheckADCIMExecute(cpu, 0x58, 0x46, 0x05, 2, t)
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.C)
+}
+
+func TestADCDecimalSetsZeroFlagFromBinaryResult(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.D = set
+
+    // 99 + 1 = 100, A is 0x00 but the binary sum 0x9A is not zero
+    CheckADCIMExecute(cpu, 0x99, 0x01, 0x00, 2, t)
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.C, &cpu.PS.N)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.Z)
+}
+
+func TestSBCDecimalSubtractsTwoDigitNumbers(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.D = set
+    cpu.PS.C = set
+
+    // 42 - 13 = 29
+    CheckSBCIMExecute(cpu, 0x42, 0x13, 0x29, t)
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.C)
+}
+
+func TestSBCDecimalClearsCarryOnBorrow(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.D = set
+    cpu.PS.C = cleared
+
+    // 12 - 21 - 1 = -10, wraps around to 90
+    CheckSBCIMExecute(cpu, 0x12, 0x21, 0x90, t)
+
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.C)
+}
+
+// Checks ADC in decimal mode for every accumulator, operand and carry combination,
+// including invalid BCD digits, against the reference table.
+func TestADCDecimalMatchesReferenceForAllOperands(t *testing.T){
+
+    cpu := Init6502()
+
+    for carry := uint(0); carry <= 1; carry++ {
+        for a := 0; a < 0x100; a++ {
+            for m := 0; m < 0x100; m++ {
+
+                want := decimalADCReference(byte(a), byte(m), carry)
+
+                cpu.PC = 0xFFFC
+                cpu.A = byte(a)
+                cpu.PS.C = carry
+                cpu.PS.D = set
+                cpu.Memory.Data[0xFFFC] = instructions.INS_ADC_IM
+                cpu.Memory.Data[0xFFFD] = byte(m)
+
+                cpu.Execute(2)
+
+                CheckDecimalResult(t, "ADC", byte(a), byte(m), carry, cpu, want)
+            }
+        }
+    }
+}
+
+// Checks SBC in decimal mode for every accumulator, operand and carry combination,
+// including invalid BCD digits, against the reference table.
+func TestSBCDecimalMatchesReferenceForAllOperands(t *testing.T){
+
+    cpu := Init6502()
+
+    for carry := uint(0); carry <= 1; carry++ {
+        for a := 0; a < 0x100; a++ {
+            for m := 0; m < 0x100; m++ {
+
+                want := decimalSBCReference(byte(a), byte(m), carry)
+
+                cpu.PC = 0xFFFC
+                cpu.A = byte(a)
+                cpu.PS.C = carry
+                cpu.PS.D = set
+                cpu.Memory.Data[0xFFFC] = instructions.INS_SBC_IM
+                cpu.Memory.Data[0xFFFD] = byte(m)
+
+                cpu.Execute(2)
+
+                CheckDecimalResult(t, "SBC", byte(a), byte(m), carry, cpu, want)
+            }
+        }
+    }
+}
+
+// decimalResult is an entry of the reference table: the accumulator and the flags
+// an NMOS 6502 ends up with after a decimal mode operation.
+type decimalResult struct {
+    A byte
+    C, Z, V, N uint
+}
+
+func CheckDecimalResult(t *testing.T, mnemonic string, a, m byte, carry uint, cpu *CPU, want decimalResult){
+
+    got := decimalResult{A: cpu.A, C: cpu.PS.C, Z: cpu.PS.Z, V: cpu.PS.V, N: cpu.PS.N}
+
+    if got != want {
+        t.Errorf("%s A=$%02X M=$%02X C=%d: want %+v but got %+v", mnemonic, a, m, carry, want, got)
+    }
+}
+
+// The reference table is generated from the decimal mode algorithm used by VICE,
+// which is known to match real NMOS hardware for every input.
+// It's written independently from the emulator implementation on purpose.
+func decimalADCReference(a, m byte, carry uint) (r decimalResult){
+
+    A, M, C := uint(a), uint(m), carry
+
+    tmp := (A & 0x0F) + (M & 0x0F) + C
+    if tmp > 0x09 {
+        tmp += 0x06
+    }
+    if tmp <= 0x0F {
+        tmp = (tmp & 0x0F) + (A & 0xF0) + (M & 0xF0)
+    }else{
+        tmp = (tmp & 0x0F) + (A & 0xF0) + (M & 0xF0) + 0x10
+    }
+
+    r.Z = boolToFlag((A + M + C) & 0xFF == 0)
+    r.N = boolToFlag(tmp & 0x80 != 0)
+    r.V = boolToFlag((A ^ tmp) & 0x80 != 0 && (A ^ M) & 0x80 == 0)
+
+    if tmp & 0x1F0 > 0x90 {
+        tmp += 0x60
+    }
+
+    r.C = boolToFlag(tmp & 0xFF0 > 0xF0)
+    r.A = byte(tmp)
+
+    return
+}
+
+func decimalSBCReference(a, m byte, carry uint) (r decimalResult){
+
+    A, M, borrow := uint32(a), uint32(m), uint32(1 - carry)
+
+    tmp := A - M - borrow
+
+    tmpA := (A & 0x0F) - (M & 0x0F) - borrow
+    if tmpA & 0x10 != 0 {
+        tmpA = ((tmpA - 6) & 0x0F) | ((A & 0xF0) - (M & 0xF0) - 0x10)
+    }else{
+        tmpA = (tmpA & 0x0F) | ((A & 0xF0) - (M & 0xF0))
+    }
+    if tmpA & 0x100 != 0 {
+        tmpA -= 0x60
+    }
+
+    r.C = boolToFlag(tmp < 0x100)
+    r.Z = boolToFlag(tmp & 0xFF == 0)
+    r.N = boolToFlag(tmp & 0x80 != 0)
+    r.V = boolToFlag((A ^ tmp) & 0x80 != 0 && (A ^ M) & 0x80 != 0)
+    r.A = byte(tmpA)
+
+    return
+}
+
+func boolToFlag(b bool) uint{
+    if b {
+        return set
+    }
+    return cleared
+}
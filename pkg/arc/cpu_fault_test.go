@@ -0,0 +1,142 @@
+package arc
+
+import (
+	"emulator/pkg/instructions"
+	"errors"
+	"testing"
+)
+
+func TestRunReturnsUnknownOpcodeFaultInStrictMode(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+    cpu.Strict = true
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_LDA_IM
+    cpu.Memory.Data[0xFF01] = 0x42
+    cpu.Memory.Data[0xFF02] = instructions.INS_LAX_ZP
+
+    cyclesUsed, err := cpu.Run(10)
+
+    if !errors.Is(err, ErrUnknownOpcode) {
+        t.Fatal("Expected unknown opcode fault but got: ", err)
+    }
+
+    var fault *Fault
+    if !errors.As(err, &fault) {
+        t.Fatal("Expected a *Fault but got: ", err)
+    }
+
+    if fault.PC != 0xFF02 || fault.Opcode != instructions.INS_LAX_ZP {
+        t.Errorf("Fault should point to opcode $A7 at $FF02 but got opcode $%02X at $%04X", fault.Opcode, fault.PC)
+    }
+
+    // LDA and the opcode fetch
+    if cyclesUsed != 3 || fault.Cycles != 3 {
+        t.Error("Expected 3 cycles used but got: ", cyclesUsed, fault.Cycles)
+    }
+
+    // State is left as it was at the fault
+    if cpu.PC != 0xFF02 {
+        t.Error("PC should be left on the unknown opcode but got: ", cpu.PC)
+    }
+
+    if cpu.A != 0x42 {
+        t.Error("A should be 0x42 but got: ", cpu.A)
+    }
+}
+
+func TestRunCanBeResumedAfterAFault(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+    cpu.Strict = true
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_LAX_ZP
+
+    _, err := cpu.Run(2)
+
+    if !errors.Is(err, ErrUnknownOpcode) {
+        t.Fatal("Expected unknown opcode fault but got: ", err)
+    }
+
+    // Patch the program and go on
+    cpu.Memory.Data[0xFF00] = instructions.INS_LDA_IM
+    cpu.Memory.Data[0xFF01] = 0x42
+
+    cyclesUsed, err := cpu.Run(2)
+
+    if err != nil {
+        t.Error("Expected no fault but got: ", err)
+    }
+
+    if cyclesUsed != 2 || cpu.A != 0x42 {
+        t.Error("LDA should have been executed, cycles: ", cyclesUsed, "A: ", cpu.A)
+    }
+}
+
+func TestRunReturnsJammedFault(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_JAM_02
+
+    cyclesUsed, err := cpu.Run(10)
+
+    if !errors.Is(err, ErrJammed) {
+        t.Fatal("Expected jammed fault but got: ", err)
+    }
+
+    // A jammed CPU burns the remaining cycles
+    if cyclesUsed != 10 {
+        t.Error("Expected 10 cycles used but got: ", cyclesUsed)
+    }
+
+    // And keeps reporting the fault
+    _, err = cpu.Run(10)
+
+    var fault *Fault
+    if !errors.As(err, &fault) || fault.Err != ErrJammed {
+        t.Fatal("Expected jammed fault but got: ", err)
+    }
+
+    if fault.PC != 0xFF00 || fault.Opcode != instructions.INS_JAM_02 {
+        t.Errorf("Fault should point to opcode $02 at $FF00 but got opcode $%02X at $%04X", fault.Opcode, fault.PC)
+    }
+}
+
+func TestPCWrapsAroundPastMaxMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFFFE)
+
+    cpu.Memory.Data[0xFFFE] = instructions.INS_LDA_IM
+    cpu.Memory.Data[0xFFFF] = 0x42
+    cpu.Memory.Data[0x0000] = instructions.INS_LDX_IM
+    cpu.Memory.Data[0x0001] = 0x24
+
+    _, err := cpu.Run(4)
+
+    if err != nil {
+        t.Fatal("Expected no fault but got: ", err)
+    }
+
+    if cpu.A != 0x42 || cpu.X != 0x24 || cpu.PC != 0x0002 {
+        t.Errorf("Expected A=$42 and X=$24 with PC at $0002 but got A=$%02X X=$%02X PC=$%04X", cpu.A, cpu.X, cpu.PC)
+    }
+}
+
+func TestExecuteStopsOnFaultWithoutExiting(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Strict = true
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_LAX_ZP
+
+    cyclesUsed := cpu.Execute(10)
+
+    if cyclesUsed != 1 {
+        t.Error("Only the opcode fetch should be counted, got: ", cyclesUsed)
+    }
+}
@@ -0,0 +1,174 @@
+package arc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// The functional tests of Klaus Dormann, https://github.com/Klaus2m5/6502_65C02_functional_tests,
+// exercise every documented instruction and the interactions the tests of each opcode miss.
+// They're not part of the repository, copy the binaries to testdata to run them,
+// the tests are skipped otherwise. See testdata/README.md.
+const dormannDirectory = "testdata"
+
+// dormannTest is a test binary, and where it's loaded and started.
+type dormannTest struct {
+    file string
+    load uint16
+    start uint16
+}
+
+var (
+    // Assembled with the default configuration, the functional test reports
+    // the number of the test being run at $0200, and succeeds by trapping at $3469.
+    functionalTest = dormannTest{file: "6502_functional_test.bin", load: 0x0000, start: 0x0400}
+    functionalTestCase uint16 = 0x0200
+    functionalSuccess uint16 = 0x3469
+
+    // The decimal test leaves 0 at $000B when it succeeds, and 1 when it fails.
+    decimalTest = dormannTest{file: "6502_decimal_test.bin", load: 0x0200, start: 0x0200}
+    decimalError uint16 = 0x000B
+)
+
+// stopOpcode is STP on the 65C02, which the decimal test executes when it's over by default.
+// The NMOS 6502 would run it as DCP, so RunUntilTrap stops before it.
+const stopOpcode = 0xDB
+
+// ErrTrapLimit is returned by RunUntilTrap when the program doesn't trap in time.
+var ErrTrapLimit = errors.New("no trap reached")
+
+// RunUntilTrap executes instructions until the CPU traps, which is how the test programs end:
+// an instruction like JMP * or BNE * that leaves the PC where it was, or a STP.
+// It returns the address of the trap, or an error if the CPU faults or runs more than limit cycles.
+func RunUntilTrap(cpu *CPU, limit int) (uint16, error){
+
+    for cycles := 0; cycles < limit; {
+
+        pc := cpu.PC
+
+        if cpu.Memory.Data[pc] == stopOpcode {
+            return pc, nil
+        }
+
+        used, err := cpu.Run(1)
+        if err != nil {
+            return pc, err
+        }
+
+        if cpu.PC == pc {
+            return pc, nil
+        }
+
+        cycles += used
+    }
+
+    return cpu.PC, fmt.Errorf("%w after %d cycles, at $%04X", ErrTrapLimit, limit, cpu.PC)
+}
+
+// InitDormannTest returns a CPU of variant with the test binary loaded and ready to start,
+// the test is skipped if the binary is missing.
+func InitDormannTest(t *testing.T, variant Variant, test dormannTest) *CPU{
+
+    t.Helper()
+
+    data, err := os.ReadFile(filepath.Join(dormannDirectory, test.file))
+    if errors.Is(err, os.ErrNotExist) {
+        t.Skipf("%s is missing from %s", test.file, dormannDirectory)
+    }
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if int(test.load) + len(data) > MaxMem {
+        t.Fatalf("%s is %d bytes long, it doesn't fit in memory at $%04X", test.file, len(data), test.load)
+    }
+
+    cpu := NewCPU(variant)
+    cpu.Reset(test.start)
+    copy(cpu.Memory.Data[test.load:], data)
+
+    return cpu
+}
+
+func TestDormannFunctional(t *testing.T){
+
+    variants := map[string]Variant{"6502": NMOS6502, "65C02": CMOS65C02}
+
+    for name, variant := range variants {
+
+        t.Run(name, func(t *testing.T){
+
+            cpu := InitDormannTest(t, variant, functionalTest)
+
+            // It takes about 96 million cycles
+            pc, err := RunUntilTrap(cpu, 200_000_000)
+
+            testCase := cpu.Memory.Data[functionalTestCase]
+
+            if err != nil {
+                t.Fatalf("Test $%02X: %v", testCase, err)
+            }
+
+            if pc != functionalSuccess {
+                t.Fatalf("Test $%02X failed, trapped at $%04X", testCase, pc)
+            }
+        })
+    }
+}
+
+func TestDormannDecimal(t *testing.T){
+
+    cpu := InitDormannTest(t, NMOS6502, decimalTest)
+
+    pc, err := RunUntilTrap(cpu, 100_000_000)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // The operands and the carry of the failing operation are left in N1, N2 and Y
+    if cpu.Memory.Data[decimalError] != 0 {
+        t.Fatalf("Decimal test failed, trapped at $%04X: $%02X + $%02X with carry %d",
+            pc, cpu.Memory.Data[0x0000], cpu.Memory.Data[0x0001], cpu.Y)
+    }
+}
+
+func TestRunUntilTrap(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0x0400)
+
+    // LDX #$05, DEX, BNE $0402, then a failure trap BEQ * if the loop left X at 0
+    copy(cpu.Memory.Data[0x0400:], []byte{0xA2, 0x05, 0xCA, 0xD0, 0xFD, 0xF0, 0xFE})
+
+    if pc, err := RunUntilTrap(cpu, 1000); err != nil || pc != 0x0405 {
+        t.Errorf("Expected a trap at $0405 but got $%04X, %v", pc, err)
+    }
+
+    // An endless chain of NOPs
+    cpu.Reset(0x0400)
+
+    for i := 0x0400; i < 0x0800; i++ {
+        cpu.Memory.Data[i] = 0xEA
+    }
+
+    if _, err := RunUntilTrap(cpu, 100); !errors.Is(err, ErrTrapLimit) {
+        t.Error("Expected the limit to be reached but got: ", err)
+    }
+
+    cpu.Reset(0x0400)
+    copy(cpu.Memory.Data[0x0400:], []byte{0xEA, 0x02})
+
+    if pc, err := RunUntilTrap(cpu, 100); !errors.Is(err, ErrJammed) || pc != 0x0401 {
+        t.Error("Expected the CPU to jam at $0401 but got: ", pc, err)
+    }
+
+    cpu.Reset(0x0400)
+    copy(cpu.Memory.Data[0x0400:], []byte{0xEA, stopOpcode})
+
+    if pc, err := RunUntilTrap(cpu, 100); err != nil || pc != 0x0401 {
+        t.Error("Expected to stop at the STP at $0401 but got: ", pc, err)
+    }
+}
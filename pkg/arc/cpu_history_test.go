@@ -0,0 +1,181 @@
+package arc
+
+import (
+	"strings"
+	"testing"
+)
+
+// historyProgram stores a countdown at $20, then 7 at $21, from $0200:
+//
+//	$0200  LDX #5
+//	$0202  STX $20
+//	$0204  DEX
+//	$0205  BNE $0202
+//	$0207  LDA #7
+//	$0209  STA $21
+//	$020B  JMP $020B
+func InitHistoryCPU(size, interval int) *CPU{
+
+    cpu := NewCPU(NMOS6502)
+    cpu.Reset(0x0200)
+    copy(cpu.Memory.Data[0x0200:], []byte{0xA2, 0x05, 0x86, 0x20, 0xCA, 0xD0, 0xFB, 0xA9, 0x07, 0x85, 0x21, 0x4C, 0x0B, 0x02})
+
+    cpu.History = NewHistory(size, interval)
+
+    return cpu
+}
+
+func TestRewindGoesBackAndForth(t *testing.T){
+
+    cpu := InitHistoryCPU(100, 4)
+
+    // The state before each step
+    var states []*Snapshot
+    for i := 0; i < 30; i++ {
+        states = append(states, cpu.Snapshot())
+        cpu.Step()
+    }
+
+    if cpu.History.Len() != 30 {
+        t.Fatal("Expected 30 steps but got: ", cpu.History.Len())
+    }
+
+    for _, n := range []int{1, 5, 4} {
+
+        if err := cpu.Rewind(n); err != nil {
+            t.Fatal(err)
+        }
+
+        if expected := states[cpu.History.Len()]; *cpu.Snapshot() != *expected {
+            t.Errorf("After rewinding %d steps: %+v after %d cycles, expected %+v after %d cycles",
+                n, cpu.Registers(), cpu.Cycles(), expected.Registers, expected.Cycles)
+        }
+    }
+
+    // The execution goes forward the same way, and is recorded again
+    other := InitHistoryCPU(100, 4)
+    for i := 0; i < 20; i++ {
+        other.Step()
+    }
+
+    cpu.Execute(30)
+    other.Execute(30)
+
+    CheckSameState(t, cpu, other)
+
+    if cpu.Memory.Data[0x21] != 7 || cpu.History.Len() != other.History.Len() {
+        t.Error("The CPU should have gone forward after rewinding: ", cpu.History.Len())
+    }
+}
+
+func TestHistoryIsBounded(t *testing.T){
+
+    cpu := InitHistoryCPU(20, 8)
+    cpu.Execute(200)
+
+    if n := cpu.History.Len(); n < 12 || n > 20 {
+        t.Fatal("Expected between 12 and 20 steps but got: ", n)
+    }
+
+    err := cpu.Rewind(cpu.History.Len() + 1)
+    if err == nil || !strings.Contains(err.Error(), "can't rewind") {
+        t.Error("Expected an error but got: ", err)
+    }
+
+    if err := cpu.Rewind(cpu.History.Len()); err != nil || cpu.History.Len() != 0 {
+        t.Error("Expected to rewind every step: ", err)
+    }
+
+    cpu.History = nil
+    if err := cpu.Rewind(1); err == nil {
+        t.Error("A CPU without history can't rewind")
+    }
+}
+
+func TestRewindToWrite(t *testing.T){
+
+    cpu := InitHistoryCPU(100, 100)
+    cpu.Execute(100)
+
+    n, err := cpu.RewindToWrite(0x20)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Back before STX $20 with X = 1, the last value written
+    if cpu.PC != 0x0202 || cpu.X != 1 || cpu.Memory.Data[0x20] != 2 || cpu.Memory.Data[0x21] != 0 {
+        t.Error("Unexpected state after rewinding ", n, " steps: ", cpu.Registers(), cpu.Memory.Data[0x20])
+    }
+
+    cpu.Step()
+
+    if cpu.Memory.Data[0x20] != 1 {
+        t.Error("The write should be made again")
+    }
+
+    if _, err := cpu.RewindToWrite(0x30); err == nil || !strings.Contains(err.Error(), "no write to $0030") {
+        t.Error("Expected an error but got: ", err)
+    }
+}
+
+func TestRewindInterrupts(t *testing.T){
+
+    cpu := InitSnapshotCPU(CMOS65C02)
+    cpu.History = NewHistory(100, 10)
+    cpu.Execute(20)
+
+    before := cpu.Snapshot()
+
+    cpu.AssertNMI()
+    cpu.Execute(20)
+
+    if cpu.Memory.Data[0x11] != 1 {
+        t.Fatal("The NMI should have been serviced")
+    }
+
+    // Back before the NMI, which is serviced again
+    if _, err := cpu.RewindToWrite(0x01FD); err != nil {
+        t.Fatal(err)
+    }
+
+    if !cpu.nmiPending || cpu.Memory.Data[0x11] != 0 || cpu.Registers() != before.Registers {
+        t.Error("The NMI should be pending again: ", cpu.Registers())
+    }
+
+    cpu.Execute(20)
+
+    if cpu.Memory.Data[0x11] != 1 {
+        t.Error("The NMI should have been serviced again")
+    }
+}
+
+func TestHistoryWithDebugger(t *testing.T){
+
+    cpu := InitHistoryCPU(100, 100)
+    cpu.Debugger = NewDebugger()
+    cpu.Debugger.Add(WatchWrite, 0x21, 0x21)
+
+    cpu.Execute(100)
+
+    if hit := cpu.Debugger.LastHit(); hit == nil || hit.Address != 0x21 {
+        t.Fatal("Expected a watchpoint hit but got: ", hit)
+    }
+
+    if cpu.Bus != nil {
+        t.Error("The Bus should be restored after Run")
+    }
+
+    if _, err := cpu.RewindToWrite(0x21); err != nil || cpu.PC != 0x0209 || cpu.Memory.Data[0x21] != 0 {
+        t.Error("Expected to go back before STA $21: ", err, cpu.Registers())
+    }
+
+    // Changes made between runs are kept by a checkpoint
+    cpu.Memory.Data[0x30] = 0x42
+    cpu.History.Checkpoint()
+    cpu.Execute(2)
+    cpu.Rewind(1)
+
+    if cpu.Memory.Data[0x30] != 0x42 {
+        t.Error("The change should have been kept")
+    }
+}
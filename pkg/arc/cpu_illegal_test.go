@@ -0,0 +1,493 @@
+package arc
+
+import (
+	"emulator/pkg/instructions"
+	"testing"
+)
+
+func TestSLOZeroPageShiftsMemoryAndOrsAccumulator(t *testing.T){
+
+    cpu := Init6502()
+    cpu.A = 0x01
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_SLO_ZP
+    cpu.Memory.Data[0xFFFD] = 0x42
+    cpu.Memory.Data[0x0042] = 0xC0
+
+    expectedCycles := 5
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x0042] != 0x80 {
+        t.Error("Value at 0x0042 should be 0x80 but got: ", cpu.Memory.Data[0x0042])
+    }
+
+    if cpu.A != 0x81 {
+        t.Error("A should be 0x81 but got: ", cpu.A)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.C, &cpu.PS.N)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.Z)
+}
+
+func TestRLAAbsoluteRotatesMemoryAndAndsAccumulator(t *testing.T){
+
+    cpu := Init6502()
+    cpu.A = 0x0F
+    cpu.PS.C = set
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_RLA_ABS
+    cpu.Memory.Data[0xFFFD] = 0x00
+    cpu.Memory.Data[0xFFFE] = 0x20
+    cpu.Memory.Data[0x2000] = 0x02
+
+    expectedCycles := 6
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x2000] != 0x05 {
+        t.Error("Value at 0x2000 should be 0x05 but got: ", cpu.Memory.Data[0x2000])
+    }
+
+    if cpu.A != 0x05 {
+        t.Error("A should be 0x05 but got: ", cpu.A)
+    }
+
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.C, &cpu.PS.N, &cpu.PS.Z)
+}
+
+func TestSREAbsoluteXAlwaysTakesSevenCycles(t *testing.T){
+
+    cpu := Init6502()
+    cpu.A = 0xFF
+    cpu.X = 0x01
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_SRE_ABSX
+    cpu.Memory.Data[0xFFFD] = 0x00
+    cpu.Memory.Data[0xFFFE] = 0x20
+    cpu.Memory.Data[0x2001] = 0x03
+
+    expectedCycles := 7
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x2001] != 0x01 {
+        t.Error("Value at 0x2001 should be 0x01 but got: ", cpu.Memory.Data[0x2001])
+    }
+
+    if cpu.A != 0xFE {
+        t.Error("A should be 0xFE but got: ", cpu.A)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.C, &cpu.PS.N)
+}
+
+func TestRRAIndirectXRotatesMemoryAndAddsItWithCarry(t *testing.T){
+
+    cpu := Init6502()
+    cpu.A = 0x10
+    cpu.X = 0x04
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_RRA_INDX
+    cpu.Memory.Data[0xFFFD] = 0x20
+    cpu.Memory.Data[0x0024] = 0x00
+    cpu.Memory.Data[0x0025] = 0x30
+    cpu.Memory.Data[0x3000] = 0x05
+
+    expectedCycles := 8
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x3000] != 0x02 {
+        t.Error("Value at 0x3000 should be 0x02 but got: ", cpu.Memory.Data[0x3000])
+    }
+
+    // 0x10 + 0x02 + carry out of the rotation
+    if cpu.A != 0x13 {
+        t.Error("A should be 0x13 but got: ", cpu.A)
+    }
+
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.C, &cpu.PS.N, &cpu.PS.Z, &cpu.PS.V)
+}
+
+func TestDCPZeroPageDecrementsMemoryAndCompares(t *testing.T){
+
+    cpu := Init6502()
+    cpu.A = 0x41
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_DCP_ZP
+    cpu.Memory.Data[0xFFFD] = 0x42
+    cpu.Memory.Data[0x0042] = 0x42
+
+    expectedCycles := 5
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x0042] != 0x41 {
+        t.Error("Value at 0x0042 should be 0x41 but got: ", cpu.Memory.Data[0x0042])
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.C, &cpu.PS.Z)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.N)
+}
+
+func TestDCPClearsCarryWhenMemoryIsGreaterThanAccumulator(t *testing.T){
+
+    cpu := Init6502()
+    cpu.A = 0x10
+    cpu.PS.C = set
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_DCP_ZP
+    cpu.Memory.Data[0xFFFD] = 0x42
+    cpu.Memory.Data[0x0042] = 0x21
+
+    cpu.Execute(5)
+
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.C, &cpu.PS.Z)
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.N)
+}
+
+func TestISCIndirectYIncrementsMemoryAndSubtracts(t *testing.T){
+
+    cpu := Init6502()
+    cpu.A = 0x10
+    cpu.Y = 0x10
+    cpu.PS.C = set
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ISC_INDY
+    cpu.Memory.Data[0xFFFD] = 0x20
+    cpu.Memory.Data[0x0020] = 0x00
+    cpu.Memory.Data[0x0021] = 0x30
+    cpu.Memory.Data[0x3010] = 0x04
+
+    expectedCycles := 8
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x3010] != 0x05 {
+        t.Error("Value at 0x3010 should be 0x05 but got: ", cpu.Memory.Data[0x3010])
+    }
+
+    if cpu.A != 0x0B {
+        t.Error("A should be 0x0B but got: ", cpu.A)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.C)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.N, &cpu.PS.Z, &cpu.PS.V)
+}
+
+func TestISCWrapsMemoryAroundToZero(t *testing.T){
+
+    cpu := Init6502()
+    cpu.A = 0x10
+    cpu.PS.C = set
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ISC_ZP
+    cpu.Memory.Data[0xFFFD] = 0x42
+    cpu.Memory.Data[0x0042] = 0xFF
+
+    cpu.Execute(5)
+
+    if cpu.Memory.Data[0x0042] != 0x00 {
+        t.Error("Value at 0x0042 should be 0x00 but got: ", cpu.Memory.Data[0x0042])
+    }
+
+    // $10 - $00, without borrow
+    if cpu.A != 0x10 {
+        t.Error("A should be 0x10 but got: ", cpu.A)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.C)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.N, &cpu.PS.Z, &cpu.PS.V)
+}
+
+func TestISCBorrowsAndOverflows(t *testing.T){
+
+    cpu := Init6502()
+    cpu.A = 0x50
+    cpu.PS.C = set
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ISC_ZP
+    cpu.Memory.Data[0xFFFD] = 0x42
+    cpu.Memory.Data[0x0042] = 0x7F
+
+    cpu.Execute(5)
+
+    // $50 - $80 = $D0, 80 - (-128) doesn't fit in a signed byte
+    if cpu.A != 0xD0 {
+        t.Error("A should be 0xD0 but got: ", cpu.A)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.N, &cpu.PS.V)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.C, &cpu.PS.Z)
+}
+
+func TestUSBCSubtractsLikeSBC(t *testing.T){
+
+    cpu := Init6502()
+    cpu.A = 0x05
+    cpu.PS.C = set
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_USBC_IM
+    cpu.Memory.Data[0xFFFD] = 0x05
+    cpu.Memory.Data[0xFFFE] = instructions.INS_USBC_IM
+    cpu.Memory.Data[0xFFFF] = 0x01
+
+    expectedCycles := 2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x00 {
+        t.Error("A should be 0x00 but got: ", cpu.A)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.C, &cpu.PS.Z)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.N, &cpu.PS.V)
+
+    // $00 - $01 borrows
+    cpu.Execute(2)
+
+    if cpu.A != 0xFF {
+        t.Error("A should be 0xFF but got: ", cpu.A)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.N)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.C, &cpu.PS.Z, &cpu.PS.V)
+}
+
+func TestSAXStoresAccumulatorAndX(t *testing.T){
+
+    cpu := Init6502()
+    cpu.A = 0xF3
+    cpu.X = 0x3F
+    cpu.PS.Z = set
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_SAX_ZP
+    cpu.Memory.Data[0xFFFD] = 0x42
+
+    cpuCopy := *cpu
+
+    expectedCycles := 3
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x0042] != 0x33 {
+        t.Error("Value at 0x0042 should be 0x33 but got: ", cpu.Memory.Data[0x0042])
+    }
+
+    if cpu.PS != cpuCopy.PS {
+        t.Error("SAX shouldn't affect the PS")
+    }
+}
+
+func TestLAXAbsoluteYLoadsAccumulatorAndXWithPageCrossing(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Y = 0x01
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_LAX_ABSY
+    cpu.Memory.Data[0xFFFD] = 0xFF
+    cpu.Memory.Data[0xFFFE] = 0x20
+    cpu.Memory.Data[0x2100] = 0x80
+
+    expectedCycles := 5
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x80 || cpu.X != 0x80 {
+        t.Error("A and X should be 0x80 but got: ", cpu.A, cpu.X)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.N)
+}
+
+func TestANCCopiesNegativeFlagIntoCarry(t *testing.T){
+
+    cpu := Init6502()
+    cpu.A = 0xF0
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ANC_IM
+    cpu.Memory.Data[0xFFFD] = 0x81
+
+    cpu.Execute(2)
+
+    if cpu.A != 0x80 {
+        t.Error("A should be 0x80 but got: ", cpu.A)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.N, &cpu.PS.C)
+}
+
+func TestALRAndsThenShiftsRight(t *testing.T){
+
+    cpu := Init6502()
+    cpu.A = 0xFF
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ALR_IM
+    cpu.Memory.Data[0xFFFD] = 0x03
+
+    cpu.Execute(2)
+
+    if cpu.A != 0x01 {
+        t.Error("A should be 0x01 but got: ", cpu.A)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.C)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.N, &cpu.PS.Z)
+}
+
+func TestARRSetsCarryAndOverflowFromResultBits(t *testing.T){
+
+    cpu := Init6502()
+    cpu.A = 0xFF
+    cpu.PS.C = set
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ARR_IM
+    cpu.Memory.Data[0xFFFD] = 0x80
+
+    cpu.Execute(2)
+
+    // (0x80 >> 1) | carry = 0xC0
+    if cpu.A != 0xC0 {
+        t.Error("A should be 0xC0 but got: ", cpu.A)
+    }
+
+    // bit 6 is set, bit 5 is clear
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.C, &cpu.PS.V, &cpu.PS.N)
+}
+
+func TestSBXSubtractsFromAccumulatorAndX(t *testing.T){
+
+    cpu := Init6502()
+    cpu.A = 0x1F
+    cpu.X = 0xF3
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_SBX_IM
+    cpu.Memory.Data[0xFFFD] = 0x04
+
+    cpu.Execute(2)
+
+    if cpu.X != 0x0F {
+        t.Error("X should be 0x0F but got: ", cpu.X)
+    }
+
+    if cpu.A != 0x1F {
+        t.Error("A shouldn't change but got: ", cpu.A)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.C)
+}
+
+func TestIllegalNOPsSkipTheirOperands(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+    cpu.X = 0x01
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_NOP_IMP_1A
+    cpu.Memory.Data[0xFF01] = instructions.INS_NOP_IM_80
+    cpu.Memory.Data[0xFF02] = 0xFF
+    cpu.Memory.Data[0xFF03] = instructions.INS_NOP_ZP_04
+    cpu.Memory.Data[0xFF04] = 0xFF
+    cpu.Memory.Data[0xFF05] = instructions.INS_NOP_ZPX_14
+    cpu.Memory.Data[0xFF06] = 0xFF
+    cpu.Memory.Data[0xFF07] = instructions.INS_NOP_ABS_0C
+    cpu.Memory.Data[0xFF08] = 0xFF
+    cpu.Memory.Data[0xFF09] = 0xFF
+    cpu.Memory.Data[0xFF0A] = instructions.INS_NOP_ABSX_1C
+    cpu.Memory.Data[0xFF0B] = 0xFF
+    cpu.Memory.Data[0xFF0C] = 0x20
+    cpu.Memory.Data[0xFF0D] = instructions.INS_LDA_IM
+    cpu.Memory.Data[0xFF0E] = 0x42
+
+    cpuCopy := *cpu
+
+    // NOP absolute X crosses the page
+    expectedCycles := 2+2+3+4+4+5+2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x42 {
+        t.Error("A should be 0x42 but got: ", cpu.A)
+    }
+
+    if cpu.PC != 0xFF0F {
+        t.Error("PC should be 0xFF0F but got: ", cpu.PC)
+    }
+
+    CheckUnmodifiedlagsALL(cpuCopy, cpu, t)
+}
+
+func TestJAMHaltsTheCPUUntilReset(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_LDA_IM
+    cpu.Memory.Data[0xFF01] = 0x42
+    cpu.Memory.Data[0xFF02] = instructions.INS_JAM_02
+    cpu.Memory.Data[0xFF03] = instructions.INS_LDA_IM
+    cpu.Memory.Data[0xFF04] = 0x00
+
+    cpu.Execute(2+1)
+
+    // Not even a NMI wakes it up
+    cpu.Memory.Data[0xFFFA] = 0x03
+    cpu.Memory.Data[0xFFFB] = 0xFF
+    cpu.AssertNMI()
+
+    expectedCycles := 100
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("A jammed CPU should burn all the cycles, got: ", cyclesUsed)
+    }
+
+    if !cpu.Jammed() {
+        t.Error("CPU should be jammed")
+    }
+
+    if cpu.PC != 0xFF02 {
+        t.Error("PC should stay on the JAM opcode at 0xFF02 but got: ", cpu.PC)
+    }
+
+    if cpu.A != 0x42 {
+        t.Error("A should be 0x42 but got: ", cpu.A)
+    }
+
+    cpu.Reset(0xFF00)
+
+    if cpu.Jammed() {
+        t.Error("Reset should bring the CPU back")
+    }
+}
@@ -0,0 +1,173 @@
+package arc
+
+import (
+	"emulator/pkg/instructions"
+	"testing"
+)
+
+func TestIRQIsServicedBetweenInstructions(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+    cpu.PS.C = set
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_LDA_IM
+    cpu.Memory.Data[0xFF01] = 0x42
+    cpu.Memory.Data[0xFFFE] = 0x00
+    cpu.Memory.Data[0xFFFF] = 0x80
+
+    cpu.AssertIRQ()
+
+    expectedCycles := 7
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.PC != 0x8000 {
+        t.Error("PC should be 0x8000 but got: ", cpu.PC)
+    }
+
+    if cpu.A != 0 {
+        t.Error("LDA shouldn't have been executed, A: ", cpu.A)
+    }
+
+    // Return address is the instruction that was about to be executed
+    if cpu.Memory.Data[0x01FD] != 0xFF || cpu.Memory.Data[0x01FC] != 0x00 {
+        t.Error("Pushed return address: ", uint16(cpu.Memory.Data[0x01FC]) | (uint16(cpu.Memory.Data[0x01FD]) << 8), "but want: 0xFF00")
+    }
+
+    // U and C set, B clear: 00100001
+    if cpu.Memory.Data[0x01FB] != 0x21 {
+        t.Error("Pushed PS should be 0x21 but got: ", cpu.Memory.Data[0x01FB])
+    }
+
+    if cpu.PS.I != set {
+        t.Error("Interrupt disable flag should be set instead is clear")
+    }
+}
+
+func TestIRQIsIgnoredWhenInterruptDisableIsSet(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+    cpu.PS.I = set
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_LDA_IM
+    cpu.Memory.Data[0xFF01] = 0x42
+
+    cpu.AssertIRQ()
+
+    expectedCycles := 2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x42 {
+        t.Error("A should be 0x42 but got: ", cpu.A)
+    }
+
+    if cpu.SP != 0xFD {
+        t.Error("Nothing should have been pushed, SP: ", cpu.SP)
+    }
+}
+
+func TestIRQIsServicedAgainWhileLineStaysAsserted(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+
+    cpu.Memory.Data[0xFFFE] = 0x00
+    cpu.Memory.Data[0xFFFF] = 0x80
+
+    // Handler returns without acknowledging the device
+    cpu.Memory.Data[0x8000] = instructions.INS_RTI_IMP
+
+    cpu.AssertIRQ()
+
+    expectedCycles := 7+6+7
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.PC != 0x8000 {
+        t.Error("PC should be 0x8000 but got: ", cpu.PC)
+    }
+
+    cpu.ReleaseIRQ()
+
+    cpu.Execute(6)
+
+    if cpu.PC != 0xFF00 {
+        t.Error("After release, RTI should return to 0xFF00 but PC is: ", cpu.PC)
+    }
+}
+
+func TestNMIIsServicedEvenWhenInterruptDisableIsSet(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+    cpu.PS.I = set
+
+    cpu.Memory.Data[0xFFFA] = 0x00
+    cpu.Memory.Data[0xFFFB] = 0x90
+
+    cpu.AssertNMI()
+
+    expectedCycles := 7
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.PC != 0x9000 {
+        t.Error("PC should be 0x9000 but got: ", cpu.PC)
+    }
+
+    // U and I set, B clear: 00100100
+    if cpu.Memory.Data[0x01FB] != 0x24 {
+        t.Error("Pushed PS should be 0x24 but got: ", cpu.Memory.Data[0x01FB])
+    }
+}
+
+func TestNMIIsEdgeTriggered(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+
+    cpu.Memory.Data[0xFFFA] = 0x00
+    cpu.Memory.Data[0xFFFB] = 0x90
+    cpu.Memory.Data[0x9000] = instructions.INS_RTI_IMP
+    cpu.Memory.Data[0xFF00] = instructions.INS_LDA_IM
+    cpu.Memory.Data[0xFF01] = 0x42
+
+    cpu.AssertNMI()
+    // Asserting a line already asserted is not a new edge
+    cpu.AssertNMI()
+
+    expectedCycles := 7+6+2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x42 {
+        t.Error("NMI should be serviced once and then LDA executed, A: ", cpu.A)
+    }
+
+    cpu.ReleaseNMI()
+    cpu.AssertNMI()
+
+    cpu.Execute(7)
+
+    if cpu.PC != 0x9000 {
+        t.Error("New edge should trigger the NMI again, PC: ", cpu.PC)
+    }
+}
@@ -277,6 +277,47 @@ func TestLDAIndirectYCanLoadIntoARegisterWithPageCrossing(t *testing.T){
 }
 
 
+// The pointers of the indirect modes wrap around the zero page: a pointer at $FF
+// takes its high byte from $00, not from $0100.
+func TestLDAIndirectPointersWrapAroundTheZeroPage(t *testing.T){
+
+    for _, opcode := range []byte{instructions.INS_LDA_INDX, instructions.INS_LDA_INDY} {
+
+        cpu := Init6502()
+
+        // ($FF,X) with X = 0, and ($FF),Y with Y = 0
+        cpu.Memory.Data[0xFFFC] = opcode
+        cpu.Memory.Data[0xFFFD] = 0xFF
+        cpu.Memory.Data[0x00FF] = 0x34
+        cpu.Memory.Data[0x0000] = 0x12
+        cpu.Memory.Data[0x0100] = 0x56
+        cpu.Memory.Data[0x1234] = 0x42
+        cpu.Memory.Data[0x5634] = 0x24
+
+        cpu.Execute(5)
+
+        if cpu.A != 0x42 {
+            t.Errorf("Opcode $%02X: A should be 0x42 but got: 0x%02X", opcode, cpu.A)
+        }
+    }
+
+    // ($80,X) with X = $7F reads its pointer at $FF too
+    cpu := Init6502()
+    cpu.X = 0x7F
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_LDA_INDX
+    cpu.Memory.Data[0xFFFD] = 0x80
+    cpu.Memory.Data[0x00FF] = 0x34
+    cpu.Memory.Data[0x0000] = 0x12
+    cpu.Memory.Data[0x1234] = 0x42
+
+    cpu.Execute(6)
+
+    if cpu.A != 0x42 {
+        t.Error("A should be 0x42 but got: ", cpu.A)
+    }
+}
+
 // Test if the LDX instruction loads a value succefully into the X register
 func TestLDXImmCanLoadIntoARegister(t *testing.T){
 
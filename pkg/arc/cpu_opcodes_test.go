@@ -0,0 +1,90 @@
+package arc
+
+import (
+	"emulator/pkg/instructions"
+	"testing"
+)
+
+// stepOpcode executes opcode at $0200 with operand $0210, pointers in zero page
+// pointing to $0380 and index registers set to index.
+func stepOpcode(variant Variant, opcode byte, index byte) (result StepResult, err error){
+
+    cpu := NewCPU(variant)
+    cpu.PC = 0x0200
+    cpu.X = index
+    cpu.Y = index
+
+    cpu.Memory.Data[0x0200] = opcode
+    cpu.Memory.Data[0x0201] = 0x10
+    cpu.Memory.Data[0x0202] = 0x02
+    cpu.Memory.Data[0x0010] = 0x80
+    cpu.Memory.Data[0x0011] = 0x03
+    cpu.Memory.Data[0x000F] = 0x00
+    cpu.Memory.Data[0x0010 + uint16(index)] = 0x00
+    cpu.Memory.Data[0x0210 + uint16(index)] = 0x00
+
+    return cpu.Step()
+}
+
+// changesFlow tells whether the instruction sets the PC itself.
+func changesFlow(opcode instructions.Opcode) bool{
+
+    switch opcode.Mnemonic {
+    case "JMP", "JSR", "RTS", "RTI", "BRK":
+        return true
+    }
+
+    return opcode.Mode == instructions.Relative || opcode.Mode == instructions.ZeroPageRelative
+}
+
+// CheckOpcodeTable makes sure the CPU takes the cycles and the bytes the table says,
+// with and without page crossing.
+func CheckOpcodeTable(t *testing.T, variant Variant, table *[256]instructions.Opcode){
+
+    for code, opcode := range table {
+
+        if opcode.Mnemonic == "JAM" || opcode.Mnemonic == "STP" {
+            continue
+        }
+
+        for _, index := range []byte{0x00, 0xFF} {
+
+            result, err := stepOpcode(variant, byte(code), index)
+            if err != nil {
+                t.Errorf("$%02X %v: %v", code, opcode.Mnemonic, err)
+                continue
+            }
+
+            expectedCycles := opcode.Cycles
+            taken := result.After.PC != 0x0200 + uint16(opcode.Size)
+
+            if changesFlow(opcode) {
+
+                // BRA always branches, its cycles already count it
+                if taken && opcode.Mnemonic != "BRA" && opcode.Mnemonic[0] == 'B' && opcode.Mnemonic != "BRK" {
+                    expectedCycles++
+                }
+
+            }else if taken {
+                t.Errorf("$%02X %v %v: expected size %d but PC moved to $%04X", code, opcode.Mnemonic, opcode.Mode, opcode.Size, result.After.PC)
+            }
+
+            crossed := index == 0xFF && (opcode.Mode == instructions.AbsoluteX || opcode.Mode == instructions.AbsoluteY || opcode.Mode == instructions.IndirectIndexed)
+            if crossed && opcode.PageCross {
+                expectedCycles++
+            }
+
+            if result.Cycles != expectedCycles {
+                t.Errorf("$%02X %v %v with index $%02X: expected %d cycles but got %d", code, opcode.Mnemonic, opcode.Mode, index, expectedCycles, result.Cycles)
+            }
+        }
+    }
+}
+
+func TestNMOSOpcodeTableMatchesCPU(t *testing.T){
+    CheckOpcodeTable(t, NMOS6502, &instructions.NMOSOpcodes)
+}
+
+func TestCMOSOpcodeTableMatchesCPU(t *testing.T){
+    CheckOpcodeTable(t, CMOS65C02, &instructions.CMOSOpcodes)
+}
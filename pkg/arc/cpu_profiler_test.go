@@ -0,0 +1,43 @@
+package arc
+
+import (
+	"testing"
+)
+
+func TestProfilerCountsInstructions(t *testing.T){
+
+    cpu := InitHistoryCPU(1, 1)
+    cpu.History = nil
+    cpu.Profiler = NewProfiler()
+
+    cycles := cpu.Execute(60)
+
+    p := cpu.Profiler
+
+    // STX $20 five times, the last BNE doesn't branch
+    if p.Executions[0x0202] != 5 || p.Cycles[0x0202] != 15 || p.Executions[0x0205] != 5 || p.Cycles[0x0205] != 4 * 3 + 2 {
+        t.Error("Unexpected counts: ", p.Executions[0x0202], p.Cycles[0x0202], p.Executions[0x0205], p.Cycles[0x0205])
+    }
+
+    if p.Executions[0x0203] != 0 {
+        t.Error("Only the addresses of the instructions are counted")
+    }
+
+    if p.TotalCycles() != uint64(cycles) {
+        t.Error("The cycles should add up to those executed: ", p.TotalCycles(), cycles)
+    }
+
+    // Interrupt sequences are counted apart
+    cpu.AssertNMI()
+    cycles += cpu.Execute(10)
+
+    if p.InterruptCycles != interruptCycles || p.TotalCycles() != uint64(cycles) {
+        t.Error("Unexpected interrupt cycles: ", p.InterruptCycles, p.TotalCycles(), cycles)
+    }
+
+    p.Clear()
+
+    if p.TotalCycles() != 0 || p.Executions[0x0202] != 0 {
+        t.Error("The counts should be cleared")
+    }
+}
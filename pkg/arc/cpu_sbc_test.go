@@ -13,8 +13,9 @@ func TestSBCIMSubtractsCorrectlyZeroToZero(t *testing.T){
 
     CheckSBCIMExecute(cpu, 0x00, 0x00, 0x00, t)
 
-    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z)
-    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.V, &cpu.PS.C, &cpu.PS.N)
+    // No borrow, the carry stays set
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z, &cpu.PS.C)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.V, &cpu.PS.N)
 }
 
 func TestSBCIMSubtractsCorrectlyWithNoCarryNorOverflow(t *testing.T){
@@ -119,6 +120,38 @@ func TestSBCIMSubtractsCorrectlyWithPreviousCarryFlagClear(t *testing.T){
 }
 
 
+// The carry is set when there's no borrow, and the overflow when the result
+// of the signed subtraction doesn't fit in a byte.
+func TestSBCIMSetsCarryAndOverflowLikeASubtraction(t *testing.T){
+
+    tests := []struct {
+        a, m byte
+        carry uint
+        result byte
+        c, v uint
+    }{
+        {0x05, 0x05, 0, 0xFF, 0, 0},
+        {0x05, 0x00, 1, 0x05, 1, 0},
+        {0x05, 0x06, 1, 0xFF, 0, 0},
+        {0x50, 0x80, 1, 0xD0, 0, 1},
+        {0x00, 0x80, 1, 0x80, 0, 1},
+        {0x80, 0x01, 1, 0x7F, 1, 1},
+        {0xFF, 0xFF, 1, 0x00, 1, 0},
+    }
+
+    for _, test := range tests {
+
+        cpu := Init6502()
+        cpu.PS.C = test.carry
+
+        CheckSBCIMExecute(cpu, test.a, test.m, test.result, t)
+
+        if cpu.PS.C != test.c || cpu.PS.V != test.v {
+            t.Errorf("$%02X - $%02X with C=%d: expected C=%d V=%d but got C=%d V=%d", test.a, test.m, test.carry, test.c, test.v, cpu.PS.C, cpu.PS.V)
+        }
+    }
+}
+
 func TestSBCZPSubtractsCorrectlyZeroToZero(t *testing.T){
 
     cpu := Init6502()
@@ -126,8 +159,9 @@ func TestSBCZPSubtractsCorrectlyZeroToZero(t *testing.T){
 
     CheckSBCZPExecute(cpu, 0x00, 0x00, 0x00, t)
 
-    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z)
-    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.V, &cpu.PS.C, &cpu.PS.N)
+    // No borrow, the carry stays set
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z, &cpu.PS.C)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.V, &cpu.PS.N)
 }
 
 func TestSBCZPSubtractsCorrectlyWithNoCarryNorOverflow(t *testing.T){
@@ -238,8 +272,9 @@ func TestSBCZPXSubtractsCorrectlyZeroToZero(t *testing.T){
 
     CheckSBCZPXExecute(cpu, 0x00, 0x00, 0x00, t)
 
-    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z)
-    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.V, &cpu.PS.C, &cpu.PS.N)
+    // No borrow, the carry stays set
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z, &cpu.PS.C)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.V, &cpu.PS.N)
 }
 
 func TestSBCZPXSubtractsCorrectlyWithNoCarryNorOverflow(t *testing.T){
@@ -350,8 +385,9 @@ func TestSBCABSSubtractsCorrectlyZeroToZero(t *testing.T){
 
     CheckSBCABSExecute(cpu, 0x00, 0x00, 0x00, t)
 
-    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z)
-    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.V, &cpu.PS.C, &cpu.PS.N)
+    // No borrow, the carry stays set
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z, &cpu.PS.C)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.V, &cpu.PS.N)
 }
 
 func TestSBCABSSubtractsCorrectlyWithNoCarryNorOverflow(t *testing.T){
@@ -462,8 +498,9 @@ func TestSBCABSXSubtractsCorrectlyZeroToZero(t *testing.T){
 
     CheckSBCABSXExecute(cpu, 0x00, 0x00, 0x00, 4, t)
 
-    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z)
-    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.V, &cpu.PS.C, &cpu.PS.N)
+    // No borrow, the carry stays set
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z, &cpu.PS.C)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.V, &cpu.PS.N)
 }
 
 func TestSBCABSXSubtractsCorrectlyWithNoCarryNorOverflow(t *testing.T){
@@ -574,8 +611,9 @@ func TestSBCABSYSubtractsCorrectlyZeroToZero(t *testing.T){
 
     CheckSBCABSYExecute(cpu, 0x00, 0x00, 0x00, 4, t)
 
-    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z)
-    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.V, &cpu.PS.C, &cpu.PS.N)
+    // No borrow, the carry stays set
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z, &cpu.PS.C)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.V, &cpu.PS.N)
 }
 
 func TestSBCABSYSubtractsCorrectlyWithNoCarryNorOverflow(t *testing.T){
@@ -686,8 +724,9 @@ func TestSBCINDXSubtractsCorrectlyZeroToZero(t *testing.T){
 
     CheckSBCINDXExecute(cpu, 0x00, 0x00, 0x00, 6, t)
 
-    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z)
-    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.V, &cpu.PS.C, &cpu.PS.N)
+    // No borrow, the carry stays set
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z, &cpu.PS.C)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.V, &cpu.PS.N)
 }
 
 func TestSBCINDXSubtractsCorrectlyWithNoCarryNorOverflow(t *testing.T){
@@ -799,8 +838,9 @@ func TestSBCINDYSubtractsCorrectlyZeroToZero(t *testing.T){
 
     CheckSBCINDYExecute(cpu, 0x00, 0x00, 0x00, 5, t)
 
-    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z)
-    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.V, &cpu.PS.C, &cpu.PS.N)
+    // No borrow, the carry stays set
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.Z, &cpu.PS.C)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.V, &cpu.PS.N)
 }
 
 func TestSBCINDYSubtractsCorrectlyWithNoCarryNorOverflow(t *testing.T){
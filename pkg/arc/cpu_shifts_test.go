@@ -0,0 +1,990 @@
+package arc
+
+import (
+	"emulator/pkg/instructions"
+	"testing"
+)
+
+func TestASLAccumulatorShiftsLeftAndSetsCarry(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+    cpu.A = 0xC1
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ASL_ACC
+
+    cpuCopy := *cpu
+
+    expectedCycles := 2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x82 {
+        t.Error("A: want 0x82 but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 0 {
+        t.Error("Zero flag: want 0 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 1 {
+        t.Error("Negative flag: want 1 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestASLAccumulatorSetsZeroFlag(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+    cpu.A = 0x80
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ASL_ACC
+
+    cpuCopy := *cpu
+
+    expectedCycles := 2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x00 {
+        t.Error("A: want 0x00 but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 1 {
+        t.Error("Zero flag: want 1 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 0 {
+        t.Error("Negative flag: want 0 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestLSRAccumulatorShiftsRightAndSetsCarry(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+    cpu.A = 0x03
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_LSR_ACC
+
+    cpuCopy := *cpu
+
+    expectedCycles := 2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x01 {
+        t.Error("A: want 0x01 but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 0 {
+        t.Error("Zero flag: want 0 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 0 {
+        t.Error("Negative flag: want 0 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestLSRAccumulatorSetsZeroFlag(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+    cpu.A = 0x01
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_LSR_ACC
+
+    cpuCopy := *cpu
+
+    expectedCycles := 2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x00 {
+        t.Error("A: want 0x00 but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 1 {
+        t.Error("Zero flag: want 1 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 0 {
+        t.Error("Negative flag: want 0 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestROLAccumulatorShiftsCarryIntoBitZero(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 1
+    cpu.A = 0x40
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ROL_ACC
+
+    cpuCopy := *cpu
+
+    expectedCycles := 2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x81 {
+        t.Error("A: want 0x81 but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 0 {
+        t.Error("Carry flag: want 0 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 0 {
+        t.Error("Zero flag: want 0 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 1 {
+        t.Error("Negative flag: want 1 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestROLAccumulatorMovesBitSevenIntoCarry(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+    cpu.A = 0x80
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ROL_ACC
+
+    cpuCopy := *cpu
+
+    expectedCycles := 2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x00 {
+        t.Error("A: want 0x00 but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 1 {
+        t.Error("Zero flag: want 1 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 0 {
+        t.Error("Negative flag: want 0 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestRORAccumulatorShiftsCarryIntoBitSeven(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 1
+    cpu.A = 0x02
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ROR_ACC
+
+    cpuCopy := *cpu
+
+    expectedCycles := 2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x81 {
+        t.Error("A: want 0x81 but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 0 {
+        t.Error("Carry flag: want 0 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 0 {
+        t.Error("Zero flag: want 0 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 1 {
+        t.Error("Negative flag: want 1 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestRORAccumulatorMovesBitZeroIntoCarry(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+    cpu.A = 0x01
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ROR_ACC
+
+    cpuCopy := *cpu
+
+    expectedCycles := 2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x00 {
+        t.Error("A: want 0x00 but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 1 {
+        t.Error("Zero flag: want 1 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 0 {
+        t.Error("Negative flag: want 0 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestASLZeroPageWritesShiftedValueBackToMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ASL_ZP
+    cpu.Memory.Data[0xFFFD] = 0x42
+    cpu.Memory.Data[0x0042] = 0x41
+
+    cpuCopy := *cpu
+
+    expectedCycles := 5
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x0042] != 0x82 {
+        t.Error("Value at 0x0042 should be 0x82 but got: ", cpu.Memory.Data[0x0042])
+    }
+
+    if cpu.A != cpuCopy.A {
+        t.Error("A shouldn't be modified. Want: ", cpuCopy.A, "but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 0 {
+        t.Error("Carry flag: want 0 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 0 {
+        t.Error("Zero flag: want 0 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 1 {
+        t.Error("Negative flag: want 1 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestASLZeroPageXWritesShiftedValueBackToMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+    cpu.X = 0x90
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ASL_ZPX
+    cpu.Memory.Data[0xFFFD] = 0x80
+    cpu.Memory.Data[0x0010] = 0x41
+
+    cpuCopy := *cpu
+
+    expectedCycles := 6
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x0010] != 0x82 {
+        t.Error("Value at 0x0010 should be 0x82 but got: ", cpu.Memory.Data[0x0010])
+    }
+
+    if cpu.A != cpuCopy.A {
+        t.Error("A shouldn't be modified. Want: ", cpuCopy.A, "but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 0 {
+        t.Error("Carry flag: want 0 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 0 {
+        t.Error("Zero flag: want 0 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 1 {
+        t.Error("Negative flag: want 1 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestASLAbsoluteWritesShiftedValueBackToMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ASL_ABS
+    cpu.Memory.Data[0xFFFD] = 0x80
+    cpu.Memory.Data[0xFFFE] = 0x44
+    cpu.Memory.Data[0x4480] = 0x41
+
+    cpuCopy := *cpu
+
+    expectedCycles := 6
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x4480] != 0x82 {
+        t.Error("Value at 0x4480 should be 0x82 but got: ", cpu.Memory.Data[0x4480])
+    }
+
+    if cpu.A != cpuCopy.A {
+        t.Error("A shouldn't be modified. Want: ", cpuCopy.A, "but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 0 {
+        t.Error("Carry flag: want 0 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 0 {
+        t.Error("Zero flag: want 0 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 1 {
+        t.Error("Negative flag: want 1 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestASLAbsoluteXWritesShiftedValueBackToMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+    cpu.X = 0x11
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ASL_ABSX
+    cpu.Memory.Data[0xFFFD] = 0xF0
+    cpu.Memory.Data[0xFFFE] = 0x44
+    cpu.Memory.Data[0x4501] = 0x41
+
+    cpuCopy := *cpu
+
+    expectedCycles := 7
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x4501] != 0x82 {
+        t.Error("Value at 0x4501 should be 0x82 but got: ", cpu.Memory.Data[0x4501])
+    }
+
+    if cpu.A != cpuCopy.A {
+        t.Error("A shouldn't be modified. Want: ", cpuCopy.A, "but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 0 {
+        t.Error("Carry flag: want 0 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 0 {
+        t.Error("Zero flag: want 0 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 1 {
+        t.Error("Negative flag: want 1 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestLSRZeroPageWritesShiftedValueBackToMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_LSR_ZP
+    cpu.Memory.Data[0xFFFD] = 0x42
+    cpu.Memory.Data[0x0042] = 0x81
+
+    cpuCopy := *cpu
+
+    expectedCycles := 5
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x0042] != 0x40 {
+        t.Error("Value at 0x0042 should be 0x40 but got: ", cpu.Memory.Data[0x0042])
+    }
+
+    if cpu.A != cpuCopy.A {
+        t.Error("A shouldn't be modified. Want: ", cpuCopy.A, "but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 0 {
+        t.Error("Zero flag: want 0 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 0 {
+        t.Error("Negative flag: want 0 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestLSRZeroPageXWritesShiftedValueBackToMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+    cpu.X = 0x90
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_LSR_ZPX
+    cpu.Memory.Data[0xFFFD] = 0x80
+    cpu.Memory.Data[0x0010] = 0x81
+
+    cpuCopy := *cpu
+
+    expectedCycles := 6
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x0010] != 0x40 {
+        t.Error("Value at 0x0010 should be 0x40 but got: ", cpu.Memory.Data[0x0010])
+    }
+
+    if cpu.A != cpuCopy.A {
+        t.Error("A shouldn't be modified. Want: ", cpuCopy.A, "but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 0 {
+        t.Error("Zero flag: want 0 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 0 {
+        t.Error("Negative flag: want 0 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestLSRAbsoluteWritesShiftedValueBackToMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_LSR_ABS
+    cpu.Memory.Data[0xFFFD] = 0x80
+    cpu.Memory.Data[0xFFFE] = 0x44
+    cpu.Memory.Data[0x4480] = 0x81
+
+    cpuCopy := *cpu
+
+    expectedCycles := 6
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x4480] != 0x40 {
+        t.Error("Value at 0x4480 should be 0x40 but got: ", cpu.Memory.Data[0x4480])
+    }
+
+    if cpu.A != cpuCopy.A {
+        t.Error("A shouldn't be modified. Want: ", cpuCopy.A, "but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 0 {
+        t.Error("Zero flag: want 0 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 0 {
+        t.Error("Negative flag: want 0 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestLSRAbsoluteXWritesShiftedValueBackToMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+    cpu.X = 0x11
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_LSR_ABSX
+    cpu.Memory.Data[0xFFFD] = 0xF0
+    cpu.Memory.Data[0xFFFE] = 0x44
+    cpu.Memory.Data[0x4501] = 0x81
+
+    cpuCopy := *cpu
+
+    expectedCycles := 7
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x4501] != 0x40 {
+        t.Error("Value at 0x4501 should be 0x40 but got: ", cpu.Memory.Data[0x4501])
+    }
+
+    if cpu.A != cpuCopy.A {
+        t.Error("A shouldn't be modified. Want: ", cpuCopy.A, "but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 0 {
+        t.Error("Zero flag: want 0 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 0 {
+        t.Error("Negative flag: want 0 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestROLZeroPageWritesShiftedValueBackToMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 1
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ROL_ZP
+    cpu.Memory.Data[0xFFFD] = 0x42
+    cpu.Memory.Data[0x0042] = 0xC0
+
+    cpuCopy := *cpu
+
+    expectedCycles := 5
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x0042] != 0x81 {
+        t.Error("Value at 0x0042 should be 0x81 but got: ", cpu.Memory.Data[0x0042])
+    }
+
+    if cpu.A != cpuCopy.A {
+        t.Error("A shouldn't be modified. Want: ", cpuCopy.A, "but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 0 {
+        t.Error("Zero flag: want 0 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 1 {
+        t.Error("Negative flag: want 1 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestROLZeroPageXWritesShiftedValueBackToMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 1
+    cpu.X = 0x90
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ROL_ZPX
+    cpu.Memory.Data[0xFFFD] = 0x80
+    cpu.Memory.Data[0x0010] = 0xC0
+
+    cpuCopy := *cpu
+
+    expectedCycles := 6
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x0010] != 0x81 {
+        t.Error("Value at 0x0010 should be 0x81 but got: ", cpu.Memory.Data[0x0010])
+    }
+
+    if cpu.A != cpuCopy.A {
+        t.Error("A shouldn't be modified. Want: ", cpuCopy.A, "but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 0 {
+        t.Error("Zero flag: want 0 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 1 {
+        t.Error("Negative flag: want 1 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestROLAbsoluteWritesShiftedValueBackToMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 1
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ROL_ABS
+    cpu.Memory.Data[0xFFFD] = 0x80
+    cpu.Memory.Data[0xFFFE] = 0x44
+    cpu.Memory.Data[0x4480] = 0xC0
+
+    cpuCopy := *cpu
+
+    expectedCycles := 6
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x4480] != 0x81 {
+        t.Error("Value at 0x4480 should be 0x81 but got: ", cpu.Memory.Data[0x4480])
+    }
+
+    if cpu.A != cpuCopy.A {
+        t.Error("A shouldn't be modified. Want: ", cpuCopy.A, "but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 0 {
+        t.Error("Zero flag: want 0 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 1 {
+        t.Error("Negative flag: want 1 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestROLAbsoluteXWritesShiftedValueBackToMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 1
+    cpu.X = 0x11
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ROL_ABSX
+    cpu.Memory.Data[0xFFFD] = 0xF0
+    cpu.Memory.Data[0xFFFE] = 0x44
+    cpu.Memory.Data[0x4501] = 0xC0
+
+    cpuCopy := *cpu
+
+    expectedCycles := 7
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x4501] != 0x81 {
+        t.Error("Value at 0x4501 should be 0x81 but got: ", cpu.Memory.Data[0x4501])
+    }
+
+    if cpu.A != cpuCopy.A {
+        t.Error("A shouldn't be modified. Want: ", cpuCopy.A, "but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 0 {
+        t.Error("Zero flag: want 0 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 1 {
+        t.Error("Negative flag: want 1 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestRORZeroPageWritesShiftedValueBackToMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ROR_ZP
+    cpu.Memory.Data[0xFFFD] = 0x42
+    cpu.Memory.Data[0x0042] = 0x01
+
+    cpuCopy := *cpu
+
+    expectedCycles := 5
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x0042] != 0x00 {
+        t.Error("Value at 0x0042 should be 0x00 but got: ", cpu.Memory.Data[0x0042])
+    }
+
+    if cpu.A != cpuCopy.A {
+        t.Error("A shouldn't be modified. Want: ", cpuCopy.A, "but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 1 {
+        t.Error("Zero flag: want 1 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 0 {
+        t.Error("Negative flag: want 0 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestRORZeroPageXWritesShiftedValueBackToMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+    cpu.X = 0x90
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ROR_ZPX
+    cpu.Memory.Data[0xFFFD] = 0x80
+    cpu.Memory.Data[0x0010] = 0x01
+
+    cpuCopy := *cpu
+
+    expectedCycles := 6
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x0010] != 0x00 {
+        t.Error("Value at 0x0010 should be 0x00 but got: ", cpu.Memory.Data[0x0010])
+    }
+
+    if cpu.A != cpuCopy.A {
+        t.Error("A shouldn't be modified. Want: ", cpuCopy.A, "but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 1 {
+        t.Error("Zero flag: want 1 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 0 {
+        t.Error("Negative flag: want 0 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestRORAbsoluteWritesShiftedValueBackToMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ROR_ABS
+    cpu.Memory.Data[0xFFFD] = 0x80
+    cpu.Memory.Data[0xFFFE] = 0x44
+    cpu.Memory.Data[0x4480] = 0x01
+
+    cpuCopy := *cpu
+
+    expectedCycles := 6
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x4480] != 0x00 {
+        t.Error("Value at 0x4480 should be 0x00 but got: ", cpu.Memory.Data[0x4480])
+    }
+
+    if cpu.A != cpuCopy.A {
+        t.Error("A shouldn't be modified. Want: ", cpuCopy.A, "but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 1 {
+        t.Error("Zero flag: want 1 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 0 {
+        t.Error("Negative flag: want 0 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+func TestRORAbsoluteXWritesShiftedValueBackToMemory(t *testing.T){
+
+    cpu := Init6502()
+    cpu.PS.C = 0
+    cpu.X = 0x11
+
+    cpu.Memory.Data[0xFFFC] = instructions.INS_ROR_ABSX
+    cpu.Memory.Data[0xFFFD] = 0xF0
+    cpu.Memory.Data[0xFFFE] = 0x44
+    cpu.Memory.Data[0x4501] = 0x01
+
+    cpuCopy := *cpu
+
+    expectedCycles := 7
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.Memory.Data[0x4501] != 0x00 {
+        t.Error("Value at 0x4501 should be 0x00 but got: ", cpu.Memory.Data[0x4501])
+    }
+
+    if cpu.A != cpuCopy.A {
+        t.Error("A shouldn't be modified. Want: ", cpuCopy.A, "but got: ", cpu.A)
+    }
+
+    if cpu.PS.C != 1 {
+        t.Error("Carry flag: want 1 but got: ", cpu.PS.C)
+    }
+
+    if cpu.PS.Z != 1 {
+        t.Error("Zero flag: want 1 but got: ", cpu.PS.Z)
+    }
+
+    if cpu.PS.N != 0 {
+        t.Error("Negative flag: want 0 but got: ", cpu.PS.N)
+    }
+
+    CheckUnmodifiedShiftFlags(cpuCopy, cpu, t)
+}
+
+// Shifts and rotates only affect carry, zero and negative flags.
+func CheckUnmodifiedShiftFlags(cpuCopy CPU, cpu *CPU, t *testing.T){
+
+    if cpu.PS.I != cpuCopy.PS.I {
+        t.Error("PS.I: want: ", cpuCopy.PS.I, ", got: ", cpu.PS.I)
+    }
+
+    if cpu.PS.D != cpuCopy.PS.D {
+        t.Error("PS.D: want: ", cpuCopy.PS.D, ", got: ", cpu.PS.D)
+    }
+
+    if cpu.PS.B != cpuCopy.PS.B {
+        t.Error("PS.B: want: ", cpuCopy.PS.B, ", got: ", cpu.PS.B)
+    }
+
+    if cpu.PS.U != cpuCopy.PS.U {
+        t.Error("PS.U: want: ", cpuCopy.PS.U, ", got: ", cpu.PS.U)
+    }
+
+    if cpu.PS.V != cpuCopy.PS.V {
+        t.Error("PS.V: want: ", cpuCopy.PS.V, ", got: ", cpu.PS.V)
+    }
+}
@@ -0,0 +1,132 @@
+package arc
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// snapshotProgram increments $10 forever, from $0200:
+//
+//	$0200  INC $10
+//	$0202  JMP $0200
+//
+// and its NMI handler, at $0300, sets $11 to 1.
+func InitSnapshotCPU(variant Variant) *CPU{
+
+    cpu := NewCPU(variant)
+    cpu.Reset(0x0200)
+
+    copy(cpu.Memory.Data[0x0200:], []byte{0xE6, 0x10, 0x4C, 0x00, 0x02})
+    copy(cpu.Memory.Data[0x0300:], []byte{0xA9, 0x01, 0x85, 0x11, 0x40})
+    cpu.Memory.Data[NMIVector] = 0x00
+    cpu.Memory.Data[NMIVector + 1] = 0x03
+
+    return cpu
+}
+
+func CheckSameState(t *testing.T, cpu, other *CPU){
+
+    t.Helper()
+
+    if cpu.Registers() != other.Registers() || cpu.Cycles() != other.Cycles() {
+        t.Errorf("Registers differ: %+v after %d cycles and %+v after %d cycles", cpu.Registers(), cpu.Cycles(), other.Registers(), other.Cycles())
+    }
+
+    if cpu.AddressSpace().Read(0x10) != other.AddressSpace().Read(0x10) || cpu.AddressSpace().Read(0x11) != other.AddressSpace().Read(0x11) {
+        t.Error("Memory differs")
+    }
+}
+
+func TestSnapshotRestoresTheCPU(t *testing.T){
+
+    cpu := InitSnapshotCPU(CMOS65C02)
+    cpu.Execute(100)
+
+    // The NMI is pending when the snapshot is taken
+    cpu.AssertNMI()
+    snapshot := cpu.Snapshot()
+
+    if !snapshot.NMIPending || !snapshot.NMI || snapshot.Cycles != cpu.Cycles() || snapshot.Memory[0x10] != cpu.Memory.Data[0x10] {
+        t.Fatal("Unexpected snapshot: ", snapshot.Registers, snapshot.Cycles)
+    }
+
+    cpu.Execute(100)
+
+    // Forking from the snapshot runs the same way
+    for i := 0; i < 2; i++ {
+
+        other := InitSnapshotCPU(CMOS65C02)
+        if err := other.Restore(snapshot); err != nil {
+            t.Fatal(err)
+        }
+
+        other.Execute(100)
+
+        CheckSameState(t, cpu, other)
+
+        if other.Memory.Data[0x11] != 1 {
+            t.Error("The pending NMI should have been serviced")
+        }
+    }
+
+    if err := NewCPU(NMOS6502).Restore(snapshot); err == nil || !strings.Contains(err.Error(), "snapshot of 65C02 on 6502") {
+        t.Error("Expected a variant mismatch but got: ", err)
+    }
+}
+
+func TestSnapshotFileRoundTrip(t *testing.T){
+
+    cpu := InitSnapshotCPU(NMOS6502)
+    cpu.Execute(50)
+    cpu.AssertIRQ()
+
+    path := filepath.Join(t.TempDir(), "state.snp")
+
+    if err := cpu.Snapshot().WriteFile(path); err != nil {
+        t.Fatal(err)
+    }
+
+    snapshot, err := ReadSnapshot(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if *snapshot != *cpu.Snapshot() {
+        t.Error("The snapshot read differs from the one written")
+    }
+
+    data, _ := snapshot.MarshalBinary()
+
+    // Truncated and foreign files are detected
+    for _, bad := range [][]byte{data[:len(data) - 1], data[:len(snapshotMagic)], []byte("not a snapshot")} {
+        if err := snapshot.UnmarshalBinary(bad); err == nil {
+            t.Error("Expected an error decoding ", len(bad), " bytes")
+        }
+    }
+
+    data[len(snapshotMagic)] = 2
+    if err := snapshot.UnmarshalBinary(data); err == nil || !strings.Contains(err.Error(), "version 2") {
+        t.Error("Expected a version error but got: ", err)
+    }
+}
+
+func TestSnapshotGoesThroughTheBus(t *testing.T){
+
+    cpu := NewCPU(NMOS6502)
+    bus := &testBus{}
+    cpu.Bus = bus
+
+    bus.ram.Data[0x1234] = 0x56
+    snapshot := cpu.Snapshot()
+
+    if snapshot.Memory[0x1234] != 0x56 {
+        t.Fatal("The memory should be read through the Bus")
+    }
+
+    bus.ram.Data[0x1234] = 0
+
+    if err := cpu.Restore(snapshot); err != nil || bus.ram.Data[0x1234] != 0x56 {
+        t.Error("The memory should be restored through the Bus: ", err)
+    }
+}
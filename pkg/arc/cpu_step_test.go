@@ -0,0 +1,192 @@
+package arc
+
+import (
+	"emulator/pkg/instructions"
+	"errors"
+	"testing"
+)
+
+func TestStepExecutesOneInstruction(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+    cpu.Y = 0x04
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_LDA_INDY
+    cpu.Memory.Data[0xFF01] = 0x20
+    cpu.Memory.Data[0xFF02] = instructions.INS_LDA_IM
+    cpu.Memory.Data[0x0020] = 0xFE
+    cpu.Memory.Data[0x0021] = 0x80
+    cpu.Memory.Data[0x8102] = 0x80
+
+    result, err := cpu.Step()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if result.PC != 0xFF00 || result.Opcode != instructions.INS_LDA_INDY {
+        t.Errorf("Expected LDA ($20),Y at $FF00 but got opcode $%02X at $%04X", result.Opcode, result.PC)
+    }
+
+    if result.Mnemonic != "LDA" || result.Mode != instructions.IndirectIndexed {
+        t.Error("Expected LDA (indirect),Y but got: ", result.Mnemonic, result.Mode)
+    }
+
+    if len(result.Operand) != 1 || result.Operand[0] != 0x20 {
+        t.Error("Expected operand [0x20] but got: ", result.Operand)
+    }
+
+    if !result.HasEffectiveAddress || result.EffectiveAddress != 0x8102 {
+        t.Errorf("Expected effective address $8102 but got $%04X", result.EffectiveAddress)
+    }
+
+    // Page crossed
+    if result.Cycles != 6 {
+        t.Error("Expected cycles: 6 but got: ", result.Cycles)
+    }
+
+    if result.Before.A != 0 || result.Before.PC != 0xFF00 || result.Before.PS.N != cleared {
+        t.Error("Registers before are wrong: ", result.Before)
+    }
+
+    if result.After.A != 0x80 || result.After.PC != 0xFF02 || result.After.PS.N != set {
+        t.Error("Registers after are wrong: ", result.After)
+    }
+
+    if cpu.PC != 0xFF02 {
+        t.Error("Only one instruction should be executed, PC: ", cpu.PC)
+    }
+}
+
+func TestStepResolvesBranchTarget(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_BNE_REL
+    cpu.Memory.Data[0xFF01] = 0xFC
+
+    result, _ := cpu.Step()
+
+    if result.EffectiveAddress != 0xFEFE {
+        t.Errorf("Expected branch target $FEFE but got $%04X", result.EffectiveAddress)
+    }
+
+    // Branch taken to a new page
+    if result.Cycles != 4 {
+        t.Error("Expected cycles: 4 but got: ", result.Cycles)
+    }
+}
+
+func TestStepHasNoEffectiveAddressForImpliedAndImmediate(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_INX_IMP
+    cpu.Memory.Data[0xFF01] = instructions.INS_LDX_IM
+    cpu.Memory.Data[0xFF02] = 0x10
+
+    for _, expected := range []string{"INX", "LDX"} {
+
+        result, _ := cpu.Step()
+
+        if result.Mnemonic != expected || result.HasEffectiveAddress {
+            t.Error("Expected ", expected, "without effective address but got: ", result.Mnemonic, result.HasEffectiveAddress)
+        }
+    }
+}
+
+func TestStepServicesPendingInterruptFirst(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_NOP_IMP
+    cpu.Memory.Data[0xFFFA] = 0x00
+    cpu.Memory.Data[0xFFFB] = 0x80
+    cpu.Memory.Data[0x8000] = instructions.INS_LDA_IM
+    cpu.Memory.Data[0x8001] = 0x42
+
+    cpu.AssertNMI()
+
+    result, err := cpu.Step()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if !result.Interrupted || result.PC != 0x8000 || result.Mnemonic != "LDA" {
+        t.Errorf("Expected the first instruction of the handler, got %v at $%04X", result.Mnemonic, result.PC)
+    }
+
+    if expectedCycles := 7+2; result.Cycles != expectedCycles {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", result.Cycles)
+    }
+
+    if cpu.A != 0x42 {
+        t.Error("A should be 0x42 but got: ", cpu.A)
+    }
+}
+
+func TestStepReportsFaults(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+    cpu.Strict = true
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_SLO_ZP
+
+    result, err := cpu.Step()
+
+    if !errors.Is(err, ErrUnknownOpcode) {
+        t.Error("Expected unknown opcode but got: ", err)
+    }
+
+    if result.Mnemonic != "SLO" || result.After.PC != 0xFF00 {
+        t.Error("Expected SLO left at $FF00, got: ", result.Mnemonic, result.After.PC)
+    }
+}
+
+func TestStepDecodesCMOSOpcodes(t *testing.T){
+
+    cpu := Init65C02()
+    cpu.Reset(0xFF00)
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_BBS3_ZPREL
+    cpu.Memory.Data[0xFF01] = 0x10
+    cpu.Memory.Data[0xFF02] = 0x05
+
+    result, _ := cpu.Step()
+
+    if result.Mnemonic != "BBS3" || result.Mode != instructions.ZeroPageRelative || len(result.Operand) != 2 {
+        t.Error("Expected BBS3 with two operand bytes but got: ", result.Mnemonic, result.Mode, result.Operand)
+    }
+
+    if result.EffectiveAddress != 0x0010 {
+        t.Errorf("Expected effective address $0010 but got $%04X", result.EffectiveAddress)
+    }
+}
+
+// The effective address reads zero page pointers at $FF like the instruction does
+func TestStepWrapsZeroPagePointers(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_LDA_INDY
+    cpu.Memory.Data[0xFF01] = 0xFF
+    cpu.Memory.Data[0x00FF] = 0x34
+    cpu.Memory.Data[0x0000] = 0x12
+    cpu.Memory.Data[0x0100] = 0x56
+    cpu.Memory.Data[0x1234] = 0x42
+
+    result, _ := cpu.Step()
+
+    if result.EffectiveAddress != 0x1234 {
+        t.Errorf("Expected effective address $1234 but got $%04X", result.EffectiveAddress)
+    }
+
+    if cpu.A != 0x42 {
+        t.Error("A should be 0x42 but got: ", cpu.A)
+    }
+}
@@ -52,3 +52,116 @@ func TestNOPDoesNotAffectProcessorStatus(t *testing.T){
     }
 }
 
+
+func TestBRKPushesReturnAddressAndStatusAndJumpsToIRQVector(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+    cpu.PS.C = set
+    cpu.PS.N = set
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_BRK_IMP
+    cpu.Memory.Data[0xFFFE] = 0x00
+    cpu.Memory.Data[0xFFFF] = 0x80
+
+    expectedCycles := 7
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.PC != 0x8000 {
+        t.Error("PC should be 0x8000 but got: ", cpu.PC)
+    }
+
+    if cpu.SP != 0xFA {
+        t.Error("SP should be 0xFA but got: ", cpu.SP)
+    }
+
+    // Return address is BRK address + 2
+    if cpu.Memory.Data[0x01FD] != 0xFF || cpu.Memory.Data[0x01FC] != 0x02 {
+        t.Error("Pushed return address: ", uint16(cpu.Memory.Data[0x01FC]) | (uint16(cpu.Memory.Data[0x01FD]) << 8), "but want: 0xFF02")
+    }
+
+    // N, U, B and C set: 10110001
+    if cpu.Memory.Data[0x01FB] != 0xB1 {
+        t.Error("Pushed PS should be 0xB1 but got: ", cpu.Memory.Data[0x01FB])
+    }
+
+    if cpu.PS.I != set {
+        t.Error("Interrupt disable flag should be set instead is clear")
+    }
+
+    if cpu.PS.B != cleared {
+        t.Error("Break command flag should only be set in the pushed PS")
+    }
+}
+
+func TestRTIRestoresStatusAndProgramCounter(t *testing.T){
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+
+    // Stack as left by an interrupt: PC 0x1234 and PS with N, V and C set
+    cpu.Memory.Data[0x01FD] = 0x12
+    cpu.Memory.Data[0x01FC] = 0x34
+    cpu.Memory.Data[0x01FB] = 0xC1
+    cpu.SP = 0xFA
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_RTI_IMP
+
+    expectedCycles := 6
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.PC != 0x1234 {
+        t.Error("PC should be 0x1234 but got: ", cpu.PC)
+    }
+
+    if cpu.SP != 0xFD {
+        t.Error("SP should be 0xFD but got: ", cpu.SP)
+    }
+
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.N, &cpu.PS.V, &cpu.PS.C)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.Z, &cpu.PS.I, &cpu.PS.D, &cpu.PS.B)
+}
+
+func TestBRKAndRTIReturnAfterThePaddingByte(t *testing.T){
+
+    want := byte(0x42)
+
+    cpu := Init6502()
+    cpu.Reset(0xFF00)
+    cpu.PS.C = set
+
+    cpu.Memory.Data[0xFF00] = instructions.INS_BRK_IMP
+    cpu.Memory.Data[0xFF01] = 0xFF // padding byte, never executed
+    cpu.Memory.Data[0xFF02] = instructions.INS_LDA_IM
+    cpu.Memory.Data[0xFF03] = want
+    cpu.Memory.Data[0xFFFE] = 0x00
+    cpu.Memory.Data[0xFFFF] = 0x80
+    cpu.Memory.Data[0x8000] = instructions.INS_RTI_IMP
+
+    expectedCycles := 7+6+2
+    cyclesUsed := cpu.Execute(expectedCycles)
+
+    if expectedCycles != cyclesUsed {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != want {
+        t.Error("A should be ", want, "but got: ", cpu.A)
+    }
+
+    if cpu.SP != 0xFD {
+        t.Error("SP should be 0xFD but got: ", cpu.SP)
+    }
+
+    // RTI restores the I flag as it was before the BRK
+    CheckIfFollowingFlagsAreSet(t, &cpu.PS.C)
+    CheckIfFollowingFlagsAreCleared(t, &cpu.PS.I)
+}
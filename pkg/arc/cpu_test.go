@@ -99,8 +99,7 @@ func TestPopByteFromStack(t *testing.T){
     cpu.Memory.Data[0x01FD] = 0x32
     cpu.SP--
 
-    expectedCycles := 1
-    data := cpu.PopByteFromStack(&expectedCycles)
+    data := cpu.PopByteFromStack()
 
     if cpu.SP != 0xFD {
         t.Error("SP not incremented correctly, got: ", cpu.SP, "but want: 0xFD")
@@ -121,8 +120,7 @@ func TestPopWordFromStack(t *testing.T){
     cpu.Memory.Data[0x01FC] = 0x32
     cpu.SP -=2
 
-    expectedCycles := 2
-    data := cpu.PopWordFromStack(&expectedCycles)
+    data := cpu.PopWordFromStack()
 
     if cpu.SP != 0xFD {
         t.Error("SP not incremented correctly, got: ", cpu.SP, "but want: 0xFD")
@@ -139,8 +137,7 @@ func TestPushByteToStack(t *testing.T){
     cpu := Init6502()
 
     // MSB first since it's higher memory address
-    expectedCycles := 1
-    cpu.PushByteToStack(&expectedCycles, 0x3F)
+    cpu.PushByteToStack(0x3F)
 
     if cpu.SP != 0xFC {
         t.Error("SP not decremented correctly, got: ", cpu.SP, "but want: 0xFC")
@@ -156,8 +153,7 @@ func TestPushWordToStack(t *testing.T){
     cpu := Init6502()
 
     // MSB first since it's higher memory address
-    expectedCycles := 1
-    cpu.PushWordToStack(&expectedCycles, 0x333F)
+    cpu.PushWordToStack(0x333F)
 
     if cpu.SP != 0xFB {
         t.Error("SP not decremented correctly, got: ", cpu.SP, "but want: 0xFB")
@@ -0,0 +1,395 @@
+package arc
+
+import (
+	"fmt"
+)
+
+// BreakpointKind tells what stops the CPU at a breakpoint.
+type BreakpointKind int
+
+const (
+    // BreakOnExecute stops before the instruction at an address is executed.
+    BreakOnExecute BreakpointKind = iota
+
+    // WatchRead, WatchWrite and WatchAccess stop after the instruction that
+    // reads, writes, or does either to an address. Opcode and operand fetches
+    // aren't reads, use BreakOnExecute for them.
+    WatchRead
+    WatchWrite
+    WatchAccess
+)
+
+func (kind BreakpointKind) String() string{
+
+    switch kind {
+    case BreakOnExecute:
+        return "breakpoint"
+    case WatchRead:
+        return "read watchpoint"
+    case WatchWrite:
+        return "write watchpoint"
+    case WatchAccess:
+        return "watchpoint"
+    }
+
+    return fmt.Sprintf("BreakpointKind(%d)", int(kind))
+}
+
+// Breakpoint stops the CPU when it executes, reads or writes an address of its range.
+// Kind, Start and End are set by Debugger.Add and shouldn't be changed afterwards,
+// the other fields can be changed at any time between two calls to Run.
+type Breakpoint struct {
+
+    // ID identifies the breakpoint in its Debugger, starting from 1.
+    ID int
+
+    Kind BreakpointKind
+
+    // Start and End are the first and last address of the range, both included.
+    Start uint16
+    End uint16
+
+    // Condition, when set, must hold for the breakpoint to be hit.
+    // It's evaluated before the instruction for execution breakpoints,
+    // and right after the access for watchpoints.
+    Condition *Condition
+
+    // Enabled is false for breakpoints that are never hit.
+    Enabled bool
+
+    // Ignore is the number of hits that don't stop the CPU.
+    Ignore int
+
+    // Hits counts the times the breakpoint was hit, ignored hits included.
+    Hits int
+}
+
+func (b *Breakpoint) String() string{
+
+    text := fmt.Sprintf("%v %d at $%04X", b.Kind, b.ID, b.Start)
+    if b.End != b.Start {
+        text += fmt.Sprintf("-$%04X", b.End)
+    }
+
+    if b.Condition != nil {
+        text += " if " + b.Condition.String()
+    }
+
+    return text
+}
+
+// Hit is the error returned by Run when it stops at a breakpoint.
+// The CPU is left ready to go on: for an execution breakpoint the instruction
+// hasn't been executed yet, and the next Run doesn't stop at it again;
+// for a watchpoint the instruction has completed.
+type Hit struct {
+    Breakpoint *Breakpoint
+
+    // PC is the address of the instruction about to be executed for an execution breakpoint,
+    // or the address of the instruction that accessed the memory for a watchpoint.
+    PC uint16
+
+    // Address and Value describe the access that hit a watchpoint.
+    Address uint16
+    Value byte
+    Write bool
+
+    // Cycles is the number of cycles used by Run before stopping.
+    Cycles int
+}
+
+func (hit *Hit) Error() string{
+
+    b := hit.Breakpoint
+
+    if b.Kind == BreakOnExecute {
+        return fmt.Sprintf("breakpoint %d at $%04X, after %d cycles", b.ID, hit.PC, hit.Cycles)
+    }
+
+    access := "read"
+    if hit.Write {
+        access = "written"
+    }
+
+    return fmt.Sprintf("%v %d: $%02X %s at $%04X by the instruction at $%04X, after %d cycles",
+        b.Kind, b.ID, hit.Value, access, hit.Address, hit.PC, hit.Cycles)
+}
+
+// addressSet is a bitmap of the 64K addresses, to tell quickly if an address has breakpoints.
+type addressSet [MaxMem / 64]uint64
+
+func (s *addressSet) add(start, end uint16){
+
+    for address := int(start); address <= int(end); address++ {
+        s[address >> 6] |= 1 << (address & 63)
+    }
+}
+
+func (s *addressSet) has(address uint16) bool{
+    return s[address >> 6] & (1 << (address & 63)) != 0
+}
+
+// Debugger holds the breakpoints of a CPU, which checks them while it runs when
+// its Debugger field is set.
+// Watchpoints slow down memory accesses, but a CPU without Debugger,
+// or whose Debugger has no watchpoints, runs at full speed.
+type Debugger struct {
+    breakpoints []*Breakpoint
+    nextID int
+
+    // Addresses having breakpoints of each kind, built from the breakpoints
+    executes addressSet
+    reads addressSet
+    writes addressSet
+    watching bool
+
+    // Stop found during the current Run, and the one of the last Run
+    pending *Hit
+    last *Hit
+
+    // Execution breakpoints at this address aren't checked by the next instruction,
+    // so the CPU can go on from where it stopped.
+    resume bool
+    resumePC uint16
+
+    // Bus installed during Run to catch the accesses to watched addresses,
+    // and the one it replaced.
+    watcher watchBus
+    bus Bus
+}
+
+func NewDebugger() *Debugger{
+    return &Debugger{nextID: 1}
+}
+
+// Add adds an enabled breakpoint of kind on the addresses from start to end.
+func (d *Debugger) Add(kind BreakpointKind, start, end uint16) (*Breakpoint, error){
+
+    if kind < BreakOnExecute || kind > WatchAccess {
+        return nil, fmt.Errorf("unknown breakpoint kind %d", int(kind))
+    }
+
+    if end < start {
+        return nil, fmt.Errorf("range $%04X-$%04X ends before it starts", start, end)
+    }
+
+    if d.nextID == 0 {
+        d.nextID = 1
+    }
+
+    b := &Breakpoint{ID: d.nextID, Kind: kind, Start: start, End: end, Enabled: true}
+    d.nextID++
+
+    d.breakpoints = append(d.breakpoints, b)
+    d.index()
+
+    return b, nil
+}
+
+// Break adds an execution breakpoint at address.
+func (d *Debugger) Break(address uint16) *Breakpoint{
+
+    b, _ := d.Add(BreakOnExecute, address, address)
+
+    return b
+}
+
+// Delete removes the breakpoint with the given ID, it returns false if there's none.
+func (d *Debugger) Delete(id int) bool{
+
+    for i, b := range d.breakpoints {
+        if b.ID == id {
+            d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i + 1:]...)
+            d.index()
+            return true
+        }
+    }
+
+    return false
+}
+
+// Breakpoints returns the breakpoints, in the order they were added.
+func (d *Debugger) Breakpoints() []*Breakpoint{
+    return append([]*Breakpoint(nil), d.breakpoints...)
+}
+
+// LastHit returns the breakpoint hit that stopped the last Run, or nil if it didn't stop at one.
+// It's how callers of Execute, which doesn't return errors, find out about hits.
+func (d *Debugger) LastHit() *Hit{
+    return d.last
+}
+
+// index rebuilds the address sets from the breakpoints.
+func (d *Debugger) index(){
+
+    d.executes = addressSet{}
+    d.reads = addressSet{}
+    d.writes = addressSet{}
+    d.watching = false
+
+    for _, b := range d.breakpoints {
+
+        switch b.Kind {
+        case BreakOnExecute:
+            d.executes.add(b.Start, b.End)
+
+        case WatchRead:
+            d.reads.add(b.Start, b.End)
+
+        case WatchWrite:
+            d.writes.add(b.Start, b.End)
+
+        case WatchAccess:
+            d.reads.add(b.Start, b.End)
+            d.writes.add(b.Start, b.End)
+        }
+
+        d.watching = d.watching || b.Kind != BreakOnExecute
+    }
+}
+
+// attach prepares a Run of cpu, installing the watching Bus if needed.
+func (d *Debugger) attach(cpu *CPU){
+
+    d.pending = nil
+    d.last = nil
+    d.bus = cpu.Bus
+
+    if d.watching {
+        d.watcher = watchBus{Bus: cpu.bus(), cpu: cpu, debugger: d}
+        cpu.Bus = &d.watcher
+    }
+}
+
+
+// hit counts a hit of b if its condition holds, and returns true if it stops the CPU.
+func (d *Debugger) hit(cpu *CPU, b *Breakpoint) bool{
+
+    if !b.Enabled {
+        return false
+    }
+
+    if b.Condition != nil && b.Condition.expression(cpu, cpu.AddressSpace().Read) == 0 {
+        return false
+    }
+
+    b.Hits++
+
+    return b.Hits > b.Ignore
+}
+
+// breakAt is called before executing the instruction at PC,
+// it returns true if an execution breakpoint stops the CPU there.
+func (d *Debugger) breakAt(cpu *CPU) bool{
+
+    pc := cpu.PC
+
+    if d.resume {
+        d.resume = false
+        if pc == d.resumePC {
+            return false
+        }
+    }
+
+    if !d.executes.has(pc) {
+        return false
+    }
+
+    for _, b := range d.breakpoints {
+
+        if b.Kind != BreakOnExecute || pc < b.Start || pc > b.End {
+            continue
+        }
+
+        if d.hit(cpu, b) {
+            d.pending = &Hit{Breakpoint: b, PC: pc}
+            d.resume = true
+            d.resumePC = pc
+            return true
+        }
+    }
+
+    return false
+}
+
+// skip makes the next instruction ignore the execution breakpoints at pc.
+func (d *Debugger) skip(pc uint16){
+    d.resume = true
+    d.resumePC = pc
+}
+
+// access is called by the watching Bus after an access to a watched address.
+// Only the first hit of an instruction is reported.
+func (d *Debugger) access(cpu *CPU, address uint16, value byte, write bool){
+
+    for _, b := range d.breakpoints {
+
+        if b.Kind == BreakOnExecute || address < b.Start || address > b.End {
+            continue
+        }
+
+        if (write && b.Kind == WatchRead) || (!write && b.Kind == WatchWrite) {
+            continue
+        }
+
+        if d.hit(cpu, b) && d.pending == nil {
+            d.pending = &Hit{Breakpoint: b, PC: cpu.instructionPC, Address: address, Value: value, Write: write}
+        }
+    }
+}
+
+// detach restores the Bus of cpu at the end of Run. It returns the hit
+// stopping the Run, if any, and remembers it.
+func (d *Debugger) detach(cpu *CPU, cycles int) *Hit{
+
+    cpu.Bus = d.bus
+
+    hit := d.pending
+    d.pending = nil
+
+    if hit != nil {
+        hit.Cycles = cycles
+        d.last = hit
+    }
+
+    return hit
+}
+
+// watchBus wraps the Bus of the CPU while it runs with watchpoints.
+type watchBus struct {
+    Bus
+
+    cpu *CPU
+    debugger *Debugger
+}
+
+func (w *watchBus) Read(address uint16) byte{
+
+    value := w.Bus.Read(address)
+
+    // Fetches of opcodes and operands aren't data reads
+    if w.debugger.reads.has(address) && !w.cpu.fetching {
+        w.debugger.access(w.cpu, address, value, false)
+    }
+
+    return value
+}
+
+func (w *watchBus) Write(address uint16, value byte){
+
+    w.Bus.Write(address, value)
+
+    if w.debugger.writes.has(address) {
+        w.debugger.access(w.cpu, address, value, true)
+    }
+}
+
+// TakeFault passes the faults of the wrapped Bus through.
+func (w *watchBus) TakeFault() (uint16, bool){
+
+    if bus, ok := w.Bus.(FaultingBus); ok {
+        return bus.TakeFault()
+    }
+
+    return 0, false
+}
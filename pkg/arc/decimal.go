@@ -0,0 +1,132 @@
+package arc
+
+// Decimal mode arithmetic, used by ADC and SBC when the D flag is set.
+// Each nibble of the operands is treated as a decimal digit, so 0x29 + 0x01 = 0x30.
+
+// The NMOS 6502 computes the flags while it's still correcting the result,
+// which gives a few documented quirks:
+//  - Z is set from the binary result, as if the D flag was clear.
+//  - N and V are set from the result after the low digit is corrected,
+//    but before the high digit is.
+//  - Invalid BCD digits (A to F) are not rejected, they go through the same
+//    correction steps and give predictable, if meaningless, results.
+// The 65C02 fixes N and Z, which are set from the final result,
+// and corrects the SBC result differently, which only makes a difference with invalid digits.
+// It takes a cycle more to do so, counted by ADC and SBC.
+// http://www.6502.org/tutorials/decimal_mode.html
+
+// addWithCarryDecimal adds memValue and the carry to the accumulator, as binary-coded decimals.
+func addWithCarryDecimal(cpu *CPU, memValue byte){
+
+    a := int(cpu.A)
+    m := int(memValue)
+    carry := int(cpu.PS.C)
+
+    // Add the low digits and correct them if the sum isn't a decimal digit.
+    // The correction propagates the carry into the high digit.
+    lo := (a & 0x0F) + (m & 0x0F) + carry
+    if lo >= 0x0A {
+        lo = ((lo + 0x06) & 0x0F) + 0x10
+    }
+
+    result := (a & 0xF0) + (m & 0xF0) + lo
+
+    // N and V come from the partially corrected result
+    cpu.PS.N = uint((result >> 7) & 0x01)
+
+    if ((a ^ m) & 0x80) == 0 && ((a ^ result) & 0x80) != 0 {
+        cpu.PS.V = set
+    }else{
+        cpu.PS.V = cleared
+    }
+
+    // Correct the high digit
+    if result >= 0xA0 {
+        result += 0x60
+    }
+
+    if result >= 0x100 {
+        cpu.PS.C = set
+    }else{
+        cpu.PS.C = cleared
+    }
+
+    // Z comes from the binary sum
+    if byte(a + m + carry) == 0 {
+        cpu.PS.Z = set
+    }else{
+        cpu.PS.Z = cleared
+    }
+
+    cpu.A = byte(result)
+
+    if cpu.Variant == CMOS65C02 {
+        SetZeroAndNegativeFlags(cpu, cpu.A)
+    }
+}
+
+// subtractWithCarryDecimal subtracts memValue and the borrow (the inverted carry) from the accumulator,
+// as binary-coded decimals.
+// Unlike ADC, all the flags are set from the binary subtraction.
+func subtractWithCarryDecimal(cpu *CPU, memValue byte){
+
+    a := int(cpu.A)
+    m := int(memValue)
+    borrow := 1 - int(cpu.PS.C)
+
+    binary := a - m - borrow
+
+    if binary >= 0 {
+        cpu.PS.C = set
+    }else{
+        cpu.PS.C = cleared
+    }
+
+    if ((a ^ m) & 0x80) != 0 && ((a ^ binary) & 0x80) != 0 {
+        cpu.PS.V = set
+    }else{
+        cpu.PS.V = cleared
+    }
+
+    SetZeroAndNegativeFlags(cpu, byte(binary))
+
+    if cpu.Variant == CMOS65C02 {
+        subtractWithCarryDecimalCMOS(cpu, a, m, borrow)
+        return
+    }
+
+    // Subtract the low digits and correct them if a borrow occurred.
+    // The correction propagates the borrow into the high digit.
+    lo := (a & 0x0F) - (m & 0x0F) - borrow
+    if lo < 0 {
+        lo = ((lo - 0x06) & 0x0F) - 0x10
+    }
+
+    result := (a & 0xF0) - (m & 0xF0) + lo
+
+    // Correct the high digit
+    if result < 0 {
+        result -= 0x60
+    }
+
+    cpu.A = byte(result)
+}
+
+// subtractWithCarryDecimalCMOS corrects the binary subtraction as a whole, and then
+// sets N and Z from the result.
+func subtractWithCarryDecimalCMOS(cpu *CPU, a, m, borrow int){
+
+    lo := (a & 0x0F) - (m & 0x0F) - borrow
+    result := a - m - borrow
+
+    if result < 0 {
+        result -= 0x60
+    }
+
+    if lo < 0 {
+        result -= 0x06
+    }
+
+    cpu.A = byte(result)
+    SetZeroAndNegativeFlags(cpu, cpu.A)
+}
@@ -0,0 +1,381 @@
+package arc
+
+import (
+	"emulator/pkg/instructions"
+	"strings"
+)
+
+// The CPU decodes opcodes through a 256-entry dispatch table per variant, built once
+// from the opcode tables of the instructions package. Each entry holds the handler of the
+// instruction, composed from its addressing mode and its operation, and the cycles it takes.
+
+// handler executes an instruction, once its opcode has been fetched.
+type handler func(cpu *CPU)
+
+type dispatchEntry struct {
+    execute handler
+
+    // Base cycles, and whether crossing a page costs one more, from the opcode table.
+    cycles int
+    pageCross bool
+
+    // Illegal opcodes of the NMOS 6502 are rejected when the CPU is Strict.
+    illegal bool
+}
+
+var nmosDispatch = buildDispatchTable(&instructions.NMOSOpcodes, NMOS6502)
+var cmosDispatch = buildDispatchTable(&instructions.CMOSOpcodes, CMOS65C02)
+
+// dispatchTable returns the dispatch table of the CPU variant.
+func (cpu *CPU) dispatchTable() *[256]dispatchEntry{
+
+    if cpu.Variant == CMOS65C02 {
+        return &cmosDispatch
+    }
+
+    return &nmosDispatch
+}
+
+func buildDispatchTable(opcodes *[256]instructions.Opcode, variant Variant) (table [256]dispatchEntry){
+
+    for code, opcode := range opcodes {
+
+        table[code] = dispatchEntry{
+            execute: newHandler(byte(code), opcode, variant),
+            cycles: opcode.Cycles,
+            pageCross: opcode.PageCross,
+            illegal: variant == NMOS6502 && opcode.Status == instructions.Illegal,
+        }
+    }
+
+    return
+}
+
+// Instructions that read a value from memory, or from the operand in immediate mode.
+var readOperations = map[string]func(cpu *CPU, value byte){
+    "LDA": func(cpu *CPU, value byte){ cpu.A = value; SetZeroAndNegativeFlags(cpu, cpu.A) },
+    "LDX": func(cpu *CPU, value byte){ cpu.X = value; SetZeroAndNegativeFlags(cpu, cpu.X) },
+    "LDY": func(cpu *CPU, value byte){ cpu.Y = value; SetZeroAndNegativeFlags(cpu, cpu.Y) },
+    "AND": func(cpu *CPU, value byte){ cpu.A = cpu.A & value; SetZeroAndNegativeFlags(cpu, cpu.A) },
+    "ORA": func(cpu *CPU, value byte){ cpu.A = cpu.A | value; SetZeroAndNegativeFlags(cpu, cpu.A) },
+    "EOR": func(cpu *CPU, value byte){ cpu.A = cpu.A ^ value; SetZeroAndNegativeFlags(cpu, cpu.A) },
+    "CMP": func(cpu *CPU, value byte){ compareRegisterWithValueAndSetFlags(cpu, cpu.A, value) },
+    "CPX": func(cpu *CPU, value byte){ compareRegisterWithValueAndSetFlags(cpu, cpu.X, value) },
+    "CPY": func(cpu *CPU, value byte){ compareRegisterWithValueAndSetFlags(cpu, cpu.Y, value) },
+    "ADC": AddWithCarryAndSetSignOverflow,
+    "SBC": SubtractWithCarryAndSetSignOverflow,
+    "BIT": testBits,
+
+    // The value is read and discarded
+    "NOP": func(cpu *CPU, value byte){},
+
+    "LAX": loadAccumulatorAndX,
+    "LAS": loadAccumulatorXAndStackPointer,
+    "ANC": andThenCopyNegativeToCarry,
+    "ALR": andThenShiftRight,
+    "ARR": andRotateRight,
+    "SBX": andThenSubtractFromX,
+    "ANE": andWithXUnstable,
+    "LXA": loadAccumulatorAndXUnstable,
+
+    // Same as the documented SBC immediate
+    "USBC": SubtractWithCarryAndSetSignOverflow,
+}
+
+// Instructions that write a value to memory, without reading it first.
+var writeOperations = map[string]func(cpu *CPU) byte{
+    "STA": func(cpu *CPU) byte{ return cpu.A },
+    "STX": func(cpu *CPU) byte{ return cpu.X },
+    "STY": func(cpu *CPU) byte{ return cpu.Y },
+    "STZ": func(cpu *CPU) byte{ return 0 },
+
+    // Store A AND X, no flags are affected
+    "SAX": func(cpu *CPU) byte{ return cpu.A & cpu.X },
+}
+
+// Read-modify-write instructions, which work on the accumulator or on memory.
+var modifyOperations = map[string]func(cpu *CPU, value byte) byte{
+    "ASL": ArithmeticShiftLeft,
+    "LSR": LogicalShiftRight,
+    "ROL": RotateLeft,
+    "ROR": RotateRight,
+    "INC": func(cpu *CPU, value byte) byte{ value++; SetZeroAndNegativeFlags(cpu, value); return value },
+    "DEC": func(cpu *CPU, value byte) byte{ value--; SetZeroAndNegativeFlags(cpu, value); return value },
+    "TRB": testAndResetBits,
+    "TSB": testAndSetBits,
+    "SLO": shiftLeftThenOr,
+    "RLA": rotateLeftThenAnd,
+    "SRE": shiftRightThenEor,
+    "RRA": rotateRightThenAdd,
+    "DCP": decrementThenCompare,
+    "ISC": incrementThenSubtract,
+}
+
+// Instructions with a single addressing mode, which get their own handler.
+var fixedHandlers = map[string]handler{
+
+    // Register transfers
+    "TAX": func(cpu *CPU){ cpu.X = cpu.A; SetZeroAndNegativeFlags(cpu, cpu.X) },
+    "TAY": func(cpu *CPU){ cpu.Y = cpu.A; SetZeroAndNegativeFlags(cpu, cpu.Y) },
+    "TXA": func(cpu *CPU){ cpu.A = cpu.X; SetZeroAndNegativeFlags(cpu, cpu.A) },
+    "TYA": func(cpu *CPU){ cpu.A = cpu.Y; SetZeroAndNegativeFlags(cpu, cpu.A) },
+    "TSX": func(cpu *CPU){ cpu.X = cpu.SP; SetZeroAndNegativeFlags(cpu, cpu.X) },
+    "TXS": func(cpu *CPU){ cpu.SP = cpu.X },
+
+    // Stack operations
+    "PHA": func(cpu *CPU){ cpu.PushByteToStack(cpu.A) },
+    "PHP": func(cpu *CPU){ cpu.PushByteToStack(cpu.PSToByte()) },
+    "PHX": func(cpu *CPU){ cpu.PushByteToStack(cpu.X) },
+    "PHY": func(cpu *CPU){ cpu.PushByteToStack(cpu.Y) },
+    "PLA": func(cpu *CPU){ cpu.A = cpu.PopByteFromStack(); SetZeroAndNegativeFlags(cpu, cpu.A) },
+    "PLX": func(cpu *CPU){ cpu.X = cpu.PopByteFromStack(); SetZeroAndNegativeFlags(cpu, cpu.X) },
+    "PLY": func(cpu *CPU){ cpu.Y = cpu.PopByteFromStack(); SetZeroAndNegativeFlags(cpu, cpu.Y) },
+    "PLP": func(cpu *CPU){ cpu.PS = cpu.ByteToPS(cpu.PopByteFromStack()) },
+
+    // Increments & decrements of the registers
+    "INX": func(cpu *CPU){ cpu.X++; SetZeroAndNegativeFlags(cpu, cpu.X) },
+    "INY": func(cpu *CPU){ cpu.Y++; SetZeroAndNegativeFlags(cpu, cpu.Y) },
+    "DEX": func(cpu *CPU){ cpu.X--; SetZeroAndNegativeFlags(cpu, cpu.X) },
+    "DEY": func(cpu *CPU){ cpu.Y--; SetZeroAndNegativeFlags(cpu, cpu.Y) },
+
+    // Status flag changes
+    "CLC": func(cpu *CPU){ cpu.PS.C = cleared },
+    "CLD": func(cpu *CPU){ cpu.PS.D = cleared },
+    "CLI": func(cpu *CPU){ cpu.PS.I = cleared },
+    "CLV": func(cpu *CPU){ cpu.PS.V = cleared },
+    "SEC": func(cpu *CPU){ cpu.PS.C = set },
+    "SED": func(cpu *CPU){ cpu.PS.D = set },
+    "SEI": func(cpu *CPU){ cpu.PS.I = set },
+
+    // Branches
+    "BEQ": func(cpu *CPU){ cpu.BranchIf(cpu.PS.Z, set) },
+    "BNE": func(cpu *CPU){ cpu.BranchIf(cpu.PS.Z, cleared) },
+    "BCS": func(cpu *CPU){ cpu.BranchIf(cpu.PS.C, set) },
+    "BCC": func(cpu *CPU){ cpu.BranchIf(cpu.PS.C, cleared) },
+    "BMI": func(cpu *CPU){ cpu.BranchIf(cpu.PS.N, set) },
+    "BPL": func(cpu *CPU){ cpu.BranchIf(cpu.PS.N, cleared) },
+    "BVS": func(cpu *CPU){ cpu.BranchIf(cpu.PS.V, set) },
+    "BVC": func(cpu *CPU){ cpu.BranchIf(cpu.PS.V, cleared) },
+
+    // Branch always, its cycles in the table already count the branch
+    "BRA": func(cpu *CPU){ cpu.jumpRelative(cpu.FetchSignedByte()) },
+
+    // Jumps & calls
+    "JSR": jumpToSubroutine,
+    "RTS": func(cpu *CPU){ cpu.PC = cpu.PopWordFromStack() + 1 },
+
+    // System functions
+    "BRK": forceInterrupt,
+    "RTI": returnFromInterrupt,
+    "JAM": jam,
+
+    // Stop the clock until the next Reset, like a JAM on the NMOS 6502
+    "STP": func(cpu *CPU){ cpu.jammed = true },
+
+    // Sleep until an interrupt line is asserted
+    "WAI": func(cpu *CPU){ cpu.waiting = true },
+}
+
+// newHandler returns the handler of an opcode, from its mnemonic and addressing mode.
+// It panics if the instruction is unknown, since that's a mistake in the opcode tables.
+func newHandler(code byte, opcode instructions.Opcode, variant Variant) handler{
+
+    mnemonic, mode := opcode.Mnemonic, opcode.Mode
+
+    switch {
+    case mnemonic == "JMP":
+        return jumpHandler(mode, variant)
+
+    case mnemonic == "NOP" && mode == instructions.Implied:
+        return func(cpu *CPU){}
+
+    case mnemonic == "BIT" && mode == instructions.Immediate:
+        return bitImmediate
+
+    case mnemonic == "SHA" || mnemonic == "SHX" || mnemonic == "SHY" || mnemonic == "TAS":
+        return storeHighByteHandler(mnemonic, mode)
+
+    // The bit number is in the high nibble of the opcode
+    case strings.HasPrefix(mnemonic, "RMB"):
+        return modifyHandler(mode, resetBit((code >> 4) & 0x07))
+
+    case strings.HasPrefix(mnemonic, "SMB"):
+        return modifyHandler(mode, setBit((code >> 4) & 0x07))
+
+    case strings.HasPrefix(mnemonic, "BBR"):
+        return branchOnBit((code >> 4) & 0x07, cleared)
+
+    case strings.HasPrefix(mnemonic, "BBS"):
+        return branchOnBit((code >> 4) & 0x07, set)
+    }
+
+    if operation, ok := readOperations[mnemonic]; ok {
+        return readHandler(mode, operation)
+    }
+
+    if operation, ok := writeOperations[mnemonic]; ok {
+        return writeHandler(mode, operation)
+    }
+
+    if operation, ok := modifyOperations[mnemonic]; ok {
+
+        if mode == instructions.Accumulator {
+            return func(cpu *CPU){ cpu.A = operation(cpu, cpu.A) }
+        }
+
+        return modifyHandler(mode, operation)
+    }
+
+    if handler, ok := fixedHandlers[mnemonic]; ok {
+        return handler
+    }
+
+    panic("arc: no handler for " + mnemonic + " " + mode.String())
+}
+
+// The handlers below call the addressing helpers directly, one closure per mode,
+// so the Go compiler can inline them: going through a table of helpers
+// costs an indirect call for every instruction.
+
+func readHandler(mode instructions.Mode, operation func(cpu *CPU, value byte)) handler{
+
+    switch mode {
+    case instructions.Immediate:
+        return func(cpu *CPU){ operation(cpu, cpu.FetchByte()) }
+    case instructions.ZeroPage:
+        return func(cpu *CPU){ operation(cpu, cpu.read(cpu.AddressZeroPage())) }
+    case instructions.ZeroPageX:
+        return func(cpu *CPU){ operation(cpu, cpu.read(cpu.AddressZeroPageX())) }
+    case instructions.ZeroPageY:
+        return func(cpu *CPU){ operation(cpu, cpu.read(cpu.AddressZeroPageY())) }
+    case instructions.Absolute:
+        return func(cpu *CPU){ operation(cpu, cpu.read(cpu.AddressAbsolute())) }
+    case instructions.AbsoluteX:
+        return func(cpu *CPU){ operation(cpu, cpu.read(cpu.AddressAbsoluteX())) }
+    case instructions.AbsoluteY:
+        return func(cpu *CPU){ operation(cpu, cpu.read(cpu.AddressAbsoluteY())) }
+    case instructions.IndexedIndirect:
+        return func(cpu *CPU){ operation(cpu, cpu.read(cpu.AddressIndirectX())) }
+    case instructions.IndirectIndexed:
+        return func(cpu *CPU){ operation(cpu, cpu.read(cpu.AddressIndirectY())) }
+    case instructions.ZeroPageIndirect:
+        return func(cpu *CPU){ operation(cpu, cpu.read(cpu.addressIndirectZeroPage())) }
+    }
+
+    panic("arc: no addressing helper for " + mode.String())
+}
+
+func writeHandler(mode instructions.Mode, operation func(cpu *CPU) byte) handler{
+
+    switch mode {
+    case instructions.ZeroPage:
+        return func(cpu *CPU){ cpu.write(cpu.AddressZeroPage(), operation(cpu)) }
+    case instructions.ZeroPageX:
+        return func(cpu *CPU){ cpu.write(cpu.AddressZeroPageX(), operation(cpu)) }
+    case instructions.ZeroPageY:
+        return func(cpu *CPU){ cpu.write(cpu.AddressZeroPageY(), operation(cpu)) }
+    case instructions.Absolute:
+        return func(cpu *CPU){ cpu.write(cpu.AddressAbsolute(), operation(cpu)) }
+    case instructions.AbsoluteX:
+        return func(cpu *CPU){ cpu.write(cpu.AddressAbsoluteX(), operation(cpu)) }
+    case instructions.AbsoluteY:
+        return func(cpu *CPU){ cpu.write(cpu.AddressAbsoluteY(), operation(cpu)) }
+    case instructions.IndexedIndirect:
+        return func(cpu *CPU){ cpu.write(cpu.AddressIndirectX(), operation(cpu)) }
+    case instructions.IndirectIndexed:
+        return func(cpu *CPU){ cpu.write(cpu.AddressIndirectY(), operation(cpu)) }
+    case instructions.ZeroPageIndirect:
+        return func(cpu *CPU){ cpu.write(cpu.addressIndirectZeroPage(), operation(cpu)) }
+    }
+
+    panic("arc: no addressing helper for " + mode.String())
+}
+
+func modifyHandler(mode instructions.Mode, operation func(cpu *CPU, value byte) byte) handler{
+
+    switch mode {
+    case instructions.ZeroPage:
+        return func(cpu *CPU){ cpu.readModifyWrite(cpu.AddressZeroPage(), operation) }
+    case instructions.ZeroPageX:
+        return func(cpu *CPU){ cpu.readModifyWrite(cpu.AddressZeroPageX(), operation) }
+    case instructions.Absolute:
+        return func(cpu *CPU){ cpu.readModifyWrite(cpu.AddressAbsolute(), operation) }
+    case instructions.AbsoluteX:
+        return func(cpu *CPU){ cpu.readModifyWrite(cpu.AddressAbsoluteX(), operation) }
+    case instructions.AbsoluteY:
+        return func(cpu *CPU){ cpu.readModifyWrite(cpu.AddressAbsoluteY(), operation) }
+    case instructions.IndexedIndirect:
+        return func(cpu *CPU){ cpu.readModifyWrite(cpu.AddressIndirectX(), operation) }
+    case instructions.IndirectIndexed:
+        return func(cpu *CPU){ cpu.readModifyWrite(cpu.AddressIndirectY(), operation) }
+    }
+
+    panic("arc: no addressing helper for " + mode.String())
+}
+
+// readModifyWrite reads the value at address, runs operation on it and writes
+// the result back.
+func (cpu *CPU) readModifyWrite(address uint16, operation func(cpu *CPU, value byte) byte){
+
+    cpu.write(address, operation(cpu, cpu.read(address)))
+}
+
+func jumpHandler(mode instructions.Mode, variant Variant) handler{
+
+    switch mode {
+    case instructions.Absolute:
+        return func(cpu *CPU){ cpu.PC = cpu.AddressAbsolute() }
+
+    case instructions.Indirect:
+
+        // The page wrapping bug of the NMOS 6502 is fixed on the 65C02, at the cost of one cycle
+        if variant == CMOS65C02 {
+            return func(cpu *CPU){ cpu.PC = cpu.ReadWord(cpu.AddressAbsolute()) }
+        }
+
+        return jumpIndirectNMOS
+
+    case instructions.AbsoluteIndexedIndirect:
+
+        // Jump to the address read from the absolute address + X, useful for jump tables
+        return func(cpu *CPU){ cpu.PC = cpu.ReadWord(cpu.AddressAbsolute() + uint16(cpu.X)) }
+    }
+
+    panic("arc: no handler for JMP " + mode.String())
+}
+
+// The NMOS 6502 doesn't carry into the high byte when it reads the
+// second byte of the pointer: JMP ($10FF) reads the target from $10FF and $1000.
+func jumpIndirectNMOS(cpu *CPU){
+
+    targetAddress := cpu.AddressAbsolute()
+
+    lo := uint16(cpu.read(targetAddress))
+    hi := uint16(cpu.read((targetAddress & 0xFF00) | ((targetAddress + 1) & 0x00FF)))
+
+    cpu.PC = lo | (hi << 8)
+}
+
+// JSR pushes the address of its last byte, RTS adds one to it when returning.
+func jumpToSubroutine(cpu *CPU){
+
+    targetAddress := cpu.FetchWord()
+
+    cpu.PushWordToStack(cpu.PC - 1)
+
+    cpu.PC = targetAddress
+}
+
+// BRK skips the padding byte that follows it, so RTI returns after it.
+func forceInterrupt(cpu *CPU){
+
+    cpu.PC++
+
+    cpu.pushInterruptFrame(cpu.PSToByte() | breakBit | unusedBit, IRQVector)
+}
+
+func returnFromInterrupt(cpu *CPU){
+
+    cpu.PS = cpu.ByteToPS(cpu.PopByteFromStack())
+    cpu.PC = cpu.PopWordFromStack()
+}
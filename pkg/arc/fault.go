@@ -0,0 +1,84 @@
+package arc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of fault that stop the CPU.
+// Use errors.Is on the error returned by Run to tell them apart.
+var (
+    // The opcode fetched is not an instruction of the CPU variant,
+    // or it's an illegal opcode and the CPU is Strict.
+    ErrUnknownOpcode = errors.New("unknown opcode")
+
+    // The Bus refused an access, see FaultingBus.
+    ErrAddressFault = errors.New("address fault")
+
+    // The CPU executed a JAM (or STP on the 65C02) and stays stuck until the next Reset.
+    ErrJammed = errors.New("cpu jammed")
+)
+
+// FaultingBus is a Bus that can refuse some accesses, like writes to a ROM.
+// The CPU checks it after every instruction, and stops with an address fault
+// on the refused address.
+type FaultingBus interface {
+    Bus
+
+    // TakeFault returns the address of the first access refused since the last call, and forgets it.
+    TakeFault() (address uint16, faulted bool)
+}
+
+// Fault is the error returned by Run when the guest program makes the CPU stop.
+type Fault struct {
+
+    // Err is the kind of the fault, one of the Err* values.
+    Err error
+
+    // PC is the address of the instruction that caused the fault.
+    PC uint16
+
+    // Opcode is the opcode of the instruction that caused the fault.
+    Opcode byte
+
+    // Address is the memory address involved in an address fault.
+    Address uint16
+
+    // Cycles is the number of cycles used by Run before the fault.
+    Cycles int
+}
+
+func (f *Fault) Error() string{
+
+    if f.Err == ErrAddressFault {
+        return fmt.Sprintf("%v at $%04X: opcode $%02X at $%04X, after %d cycles", f.Err, f.Address, f.Opcode, f.PC, f.Cycles)
+    }
+
+    return fmt.Sprintf("%v: opcode $%02X at $%04X, after %d cycles", f.Err, f.Opcode, f.PC, f.Cycles)
+}
+
+func (f *Fault) Unwrap() error{
+    return f.Err
+}
+
+// raiseAddressFault records an address fault for the current instruction,
+// unless it already faulted.
+func (cpu *CPU) raiseAddressFault(address uint16){
+
+    if cpu.fault == nil {
+        cpu.fault = &Fault{Err: ErrAddressFault, Address: address}
+    }
+}
+
+// checkBusFault turns an access refused by the Bus into an address fault.
+func (cpu *CPU) checkBusFault(){
+
+    bus, ok := cpu.Bus.(FaultingBus)
+    if !ok {
+        return
+    }
+
+    if address, faulted := bus.TakeFault(); faulted {
+        cpu.raiseAddressFault(address)
+    }
+}
@@ -1,59 +1,35 @@
 package arc
 
-import (
-	"log"
-)
+// FetchByte reads the byte located at the PC address and increases the program counter.
+// Past the last byte of memory, the PC wraps around to $0000 like on the real CPU.
+func (cpu *CPU) FetchByte() byte{
 
-// FetchByte reads the byte located at the PC address
-// It increases the program counter and decrements clock cycles by 1
-// It return an error if PC exceeds max memory (65535 B)
-func (cpu *CPU) FetchByte(cycles *int) byte{
-
-    // Exceeding max memory halts the cpu (Fatal log)
-    if cpu.PC > MaxMem-1 {
-        log.Fatalf("Program Counter exceeded max memory")
-    }
-    data := cpu.Memory.Data[cpu.PC] 
+    // Watchpoints don't take fetches for data reads
+    cpu.fetching = true
+    data := cpu.read(cpu.PC)
+    cpu.fetching = false
 
     cpu.PC++
-    *cycles--
 
     return data
 }
 
-func (cpu *CPU) FetchSignedByte(cycles *int) int8{
-
-    // Exceeding max memory halts the cpu (Fatal log)
-    if cpu.PC > MaxMem-1 {
-        log.Fatalf("Program Counter exceeded max memory")
-    }
-    data := int8(cpu.Memory.Data[cpu.PC])
-
-    cpu.PC++
-    *cycles--
+func (cpu *CPU) FetchSignedByte() int8{
 
-    return data
+    return int8(cpu.FetchByte())
 }
 
-// FetchWord consumes 2 clock cycles
-func (cpu *CPU) FetchWord(cycles *int) uint16{
-
-    if cpu.PC > MaxMem-1 {
-        log.Fatalf("Program Counter exceeded max memory")
-    }
+// FetchWord reads the two bytes located at the PC address
+func (cpu *CPU) FetchWord() uint16{
 
     // 6502 is little endian so first byte is the least significant byte of the data
     // Fetch low byte of address
-    data := uint16(cpu.Memory.Data[cpu.PC])
-    cpu.PC++
-    *cycles--
+    data := uint16(cpu.FetchByte())
 
     // second byte is the msb
     // e.g. data = 00000000 10011010 << 8 = 10011010 00000000
     // Fetch high byte of address
-    data = data | (uint16(cpu.Memory.Data[cpu.PC]) << 8 )
-    cpu.PC++
-    *cycles--
+    data = data | (uint16(cpu.FetchByte()) << 8 )
 
     return data
 }
@@ -0,0 +1,274 @@
+package arc
+
+import (
+	"fmt"
+)
+
+// History records the execution of a CPU, which can then go back in time with Rewind
+// and RewindToWrite. The CPU records while it runs when its History field is set.
+//
+// Each step, an instruction or the servicing of an interrupt, is recorded as the registers
+// before it and the memory writes it made. Every interval steps, a snapshot of the whole
+// CPU is taken too. Going back restores the last snapshot before the step to go back to,
+// and replays the writes from there.
+//
+// Only the last steps are kept, the oldest ones are dropped when there's no room left.
+// Since going back needs a snapshot, between size-interval and size steps can be rewound.
+//
+// Changes made to the memory between two runs, other than by the CPU, aren't recorded:
+// call Checkpoint after them, or rewinding past them loses them.
+type History struct {
+
+    // Ring buffer of the steps, from first, count long
+    steps []historyStep
+    first int
+    count int
+
+    interval int
+
+    // Steps since the last snapshot, and whether the next step takes one anyway
+    sinceSnapshot int
+    checkpoint bool
+
+    // Bus installed during Run to record the writes, and the one it replaced
+    recorder historyBus
+    bus Bus
+}
+
+// historyStep is the state of the CPU before a step, and the writes the step made.
+type historyStep struct {
+    registers Registers
+    irq bool
+    nmi bool
+    nmiPending bool
+    cycles uint64
+
+    writes []historyWrite
+
+    // snapshot is the state before the step when hasSnapshot is set.
+    // It's kept when the step is dropped, to be reused.
+    snapshot *Snapshot
+    hasSnapshot bool
+}
+
+type historyWrite struct {
+    address uint16
+    value byte
+}
+
+// DefaultHistorySize and DefaultSnapshotInterval make a History of about ten megabytes.
+const (
+    DefaultHistorySize = 100_000
+    DefaultSnapshotInterval = 2_000
+)
+
+// NewHistory returns a History keeping the last size steps, taking a snapshot every interval steps.
+// Shorter intervals make going back faster, but use more memory and slow down the CPU.
+func NewHistory(size, interval int) *History{
+
+    if size < 1 {
+        size = 1
+    }
+
+    if interval < 1 || interval > size {
+        interval = size
+    }
+
+    return &History{steps: make([]historyStep, size), interval: interval, checkpoint: true}
+}
+
+// Len returns the number of steps that can be rewound.
+func (h *History) Len() int{
+
+    for i := 0; i < h.count; i++ {
+        if h.step(i).hasSnapshot {
+            return h.count - i
+        }
+    }
+
+    return 0
+}
+
+// Clear forgets every step, to start recording again after a new program was loaded for instance.
+func (h *History) Clear(){
+
+    h.first = 0
+    h.count = 0
+    h.sinceSnapshot = 0
+    h.checkpoint = true
+}
+
+// Checkpoint makes the next step take a snapshot, so that the changes made to the memory
+// since the last run are kept when going back to the steps that follow.
+func (h *History) Checkpoint(){
+    h.checkpoint = true
+}
+
+// step returns the i-th step, from the oldest one.
+func (h *History) step(i int) *historyStep{
+    return &h.steps[(h.first + i) % len(h.steps)]
+}
+
+// attach installs the recording Bus for a Run of cpu.
+func (h *History) attach(cpu *CPU){
+
+    h.bus = cpu.Bus
+    h.recorder = historyBus{Bus: cpu.bus(), history: h}
+    cpu.Bus = &h.recorder
+}
+
+// detach restores the Bus of cpu at the end of Run.
+func (h *History) detach(cpu *CPU){
+    cpu.Bus = h.bus
+}
+
+// record adds a step with the current state of cpu, which has executed cycles since the last Reset.
+func (h *History) record(cpu *CPU, cycles uint64){
+
+    if h.count == len(h.steps) {
+        h.first = (h.first + 1) % len(h.steps)
+        h.count--
+    }
+
+    s := h.step(h.count)
+    h.count++
+
+    s.registers = cpu.Registers()
+    s.irq = cpu.irq
+    s.nmi = cpu.nmi
+    s.nmiPending = cpu.nmiPending
+    s.cycles = cycles
+    s.writes = s.writes[:0]
+    s.hasSnapshot = false
+
+    if h.checkpoint || h.sinceSnapshot >= h.interval {
+
+        if s.snapshot == nil {
+            s.snapshot = &Snapshot{}
+        }
+
+        cpu.save(s.snapshot)
+        s.hasSnapshot = true
+
+        h.checkpoint = false
+        h.sinceSnapshot = 0
+    }
+
+    h.sinceSnapshot++
+}
+
+// write records a write of the last step.
+func (h *History) write(address uint16, value byte){
+
+    if h.count > 0 {
+        s := h.step(h.count - 1)
+        s.writes = append(s.writes, historyWrite{address, value})
+    }
+}
+
+// rewind puts cpu back in the state before the i-th step, and forgets that step and the following ones.
+func (h *History) rewind(cpu *CPU, i int) error{
+
+    last := i
+    for last >= 0 && !h.step(last).hasSnapshot {
+        last--
+    }
+
+    if last < 0 {
+        return fmt.Errorf("the history doesn't go back that far")
+    }
+
+    if err := cpu.Restore(h.step(last).snapshot); err != nil {
+        return err
+    }
+
+    for j := last; j < i; j++ {
+        for _, w := range h.step(j).writes {
+            cpu.write(w.address, w.value)
+        }
+    }
+
+    s := h.step(i)
+
+    r := s.registers
+    cpu.PC, cpu.SP, cpu.A, cpu.X, cpu.Y, cpu.PS = r.PC, r.SP, r.A, r.X, r.Y, r.PS
+
+    cpu.irq = s.irq
+    cpu.nmi = s.nmi
+    cpu.nmiPending = s.nmiPending
+    cpu.cycles = s.cycles
+
+    h.count = i
+    h.sinceSnapshot = i - last
+
+    return nil
+}
+
+// Rewind puts the CPU back in the state it was n steps ago, a step being an instruction
+// or the servicing of an interrupt. The steps going forward again are recorded anew.
+// The CPU must have a History, which recorded enough steps.
+func (cpu *CPU) Rewind(n int) error{
+
+    h := cpu.History
+    if h == nil {
+        return fmt.Errorf("the CPU has no history")
+    }
+
+    if n < 0 || n > h.Len() {
+        return fmt.Errorf("can't rewind %d steps, the history has %d", n, h.Len())
+    }
+
+    if n == 0 {
+        return nil
+    }
+
+    return h.rewind(cpu, h.count - n)
+}
+
+// RewindToWrite puts the CPU back in the state before the last step writing address,
+// whose instruction is then about to be executed again. It returns the number of steps rewound.
+func (cpu *CPU) RewindToWrite(address uint16) (int, error){
+
+    h := cpu.History
+    if h == nil {
+        return 0, fmt.Errorf("the CPU has no history")
+    }
+
+    oldest := h.count - h.Len()
+
+    for i := h.count - 1; i >= oldest; i-- {
+
+        for _, w := range h.step(i).writes {
+
+            if w.address == address {
+                n := h.count - i
+                return n, h.rewind(cpu, i)
+            }
+        }
+    }
+
+    return 0, fmt.Errorf("no write to $%04X in the last %d steps", address, h.Len())
+}
+
+// historyBus wraps the Bus of the CPU while it runs with a History, to record the writes.
+type historyBus struct {
+    Bus
+
+    history *History
+}
+
+func (b *historyBus) Write(address uint16, value byte){
+
+    b.Bus.Write(address, value)
+    b.history.write(address, value)
+}
+
+// TakeFault passes the faults of the wrapped Bus through.
+func (b *historyBus) TakeFault() (uint16, bool){
+
+    if bus, ok := b.Bus.(FaultingBus); ok {
+        return bus.TakeFault()
+    }
+
+    return 0, false
+}
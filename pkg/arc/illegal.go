@@ -0,0 +1,230 @@
+package arc
+
+import "emulator/pkg/instructions"
+
+// The NMOS illegal opcodes, see the "Illegal opcodes" section of the instructions package.
+// Their handlers are built by the dispatch table like the documented ones,
+// from the operations below.
+
+// Value ORed with the accumulator by the unstable ANE and LXA instructions.
+const unstableMagic = 0xEE
+
+// storeHighByteHandler returns the handler of SHA, SHX, SHY and TAS, which store
+// their value through storeAndHighByte.
+func storeHighByteHandler(mnemonic string, mode instructions.Mode) handler{
+
+    value := func(cpu *CPU) byte{ return cpu.A & cpu.X }
+
+    switch mnemonic {
+    case "SHX":
+        value = func(cpu *CPU) byte{ return cpu.X }
+
+    case "SHY":
+        value = func(cpu *CPU) byte{ return cpu.Y }
+
+    case "TAS":
+
+        // SP = A AND X, then store like SHA does
+        value = func(cpu *CPU) byte{ cpu.SP = cpu.A & cpu.X; return cpu.SP }
+    }
+
+    switch mode {
+    case instructions.AbsoluteX:
+        return func(cpu *CPU){
+            baseAddress := cpu.AddressAbsolute()
+            cpu.storeAndHighByte(value(cpu), baseAddress, cpu.X)
+        }
+
+    case instructions.AbsoluteY:
+        return func(cpu *CPU){
+            baseAddress := cpu.AddressAbsolute()
+            cpu.storeAndHighByte(value(cpu), baseAddress, cpu.Y)
+        }
+
+    case instructions.IndirectIndexed:
+        return func(cpu *CPU){
+            baseAddress := cpu.ReadWord(cpu.AddressZeroPage())
+            cpu.storeAndHighByte(value(cpu), baseAddress, cpu.Y)
+        }
+    }
+
+    panic("arc: no handler for " + mnemonic + " " + mode.String())
+}
+
+// Leave the PC on the JAM opcode, that's where the CPU got stuck
+func jam(cpu *CPU){
+
+    cpu.PC--
+    cpu.jammed = true
+}
+
+// LAX: load the same value in both A and X
+func loadAccumulatorAndX(cpu *CPU, value byte){
+
+    cpu.A = value
+    cpu.X = value
+    SetZeroAndNegativeFlags(cpu, cpu.A)
+}
+
+// LAS: A, X and SP = value AND SP
+func loadAccumulatorXAndStackPointer(cpu *CPU, value byte){
+
+    cpu.SP = value & cpu.SP
+    cpu.A = cpu.SP
+    cpu.X = cpu.SP
+    SetZeroAndNegativeFlags(cpu, cpu.A)
+}
+
+// ANC: AND, then copy the negative flag into the carry flag
+func andThenCopyNegativeToCarry(cpu *CPU, value byte){
+
+    cpu.A = cpu.A & value
+    SetZeroAndNegativeFlags(cpu, cpu.A)
+
+    cpu.PS.C = cpu.PS.N
+}
+
+// ALR: AND, then LSR the accumulator
+func andThenShiftRight(cpu *CPU, value byte){
+
+    cpu.A = LogicalShiftRight(cpu, cpu.A & value)
+}
+
+// SBX: X = (A AND X) - value, without borrow.
+// Flags are set like CMP does.
+func andThenSubtractFromX(cpu *CPU, value byte){
+
+    compareRegisterWithValueAndSetFlags(cpu, cpu.A & cpu.X, value)
+    cpu.X = (cpu.A & cpu.X) - value
+}
+
+// ANE: the magic constant depends on the chip, 0xEE is the most common value.
+func andWithXUnstable(cpu *CPU, value byte){
+
+    cpu.A = (cpu.A | unstableMagic) & cpu.X & value
+    SetZeroAndNegativeFlags(cpu, cpu.A)
+}
+
+// LXA: like ANE, without the AND with X, then copy A into X
+func loadAccumulatorAndXUnstable(cpu *CPU, value byte){
+
+    cpu.A = (cpu.A | unstableMagic) & value
+    cpu.X = cpu.A
+    SetZeroAndNegativeFlags(cpu, cpu.A)
+}
+
+// storeAndHighByte stores value AND (high byte of the base address + 1) at base address + index.
+// When the page is crossed the high byte of the target address gets replaced by the stored value,
+// since the CPU is still computing it while it drives the bus.
+func (cpu *CPU) storeAndHighByte(value byte, baseAddress uint16, index byte){
+
+    targetAddress := baseAddress + uint16(index)
+    value = value & (byte(baseAddress >> 8) + 1)
+
+    if (targetAddress >> 8) != (baseAddress >> 8) {
+        targetAddress = (uint16(value) << 8) | (targetAddress & 0xFF)
+    }
+
+    cpu.write(targetAddress, value)
+}
+
+// SLO: ASL the value, then ORA it with the accumulator
+func shiftLeftThenOr(cpu *CPU, value byte) byte{
+
+    value = ArithmeticShiftLeft(cpu, value)
+
+    cpu.A = cpu.A | value
+    SetZeroAndNegativeFlags(cpu, cpu.A)
+
+    return value
+}
+
+// RLA: ROL the value, then AND it with the accumulator
+func rotateLeftThenAnd(cpu *CPU, value byte) byte{
+
+    value = RotateLeft(cpu, value)
+
+    cpu.A = cpu.A & value
+    SetZeroAndNegativeFlags(cpu, cpu.A)
+
+    return value
+}
+
+// SRE: LSR the value, then EOR it with the accumulator
+func shiftRightThenEor(cpu *CPU, value byte) byte{
+
+    value = LogicalShiftRight(cpu, value)
+
+    cpu.A = cpu.A ^ value
+    SetZeroAndNegativeFlags(cpu, cpu.A)
+
+    return value
+}
+
+// RRA: ROR the value, then ADC it to the accumulator, using the carry from the rotation
+func rotateRightThenAdd(cpu *CPU, value byte) byte{
+
+    value = RotateRight(cpu, value)
+
+    AddWithCarryAndSetSignOverflow(cpu, value)
+
+    return value
+}
+
+// DCP: DEC the value, then CMP it with the accumulator
+func decrementThenCompare(cpu *CPU, value byte) byte{
+
+    value--
+
+    compareRegisterWithValueAndSetFlags(cpu, cpu.A, value)
+
+    return value
+}
+
+// ISC: INC the value, then SBC it from the accumulator
+func incrementThenSubtract(cpu *CPU, value byte) byte{
+
+    value++
+
+    SubtractWithCarryAndSetSignOverflow(cpu, value)
+
+    return value
+}
+
+// andRotateRight runs ARR: AND value with the accumulator, then rotate it right.
+// C is bit 6 of the result and V is bit 6 XOR bit 5.
+// In decimal mode the result also gets a BCD correction, with N, Z and V
+// still computed on the binary result.
+func andRotateRight(cpu *CPU, value byte){
+
+    and := cpu.A & value
+    result := (and >> 1) | byte(cpu.PS.C << 7)
+
+    if cpu.PS.D == cleared {
+
+        cpu.A = result
+        SetZeroAndNegativeFlags(cpu, cpu.A)
+
+        cpu.PS.C = uint((result >> 6) & 0x01)
+        cpu.PS.V = uint(((result >> 6) ^ (result >> 5)) & 0x01)
+        return
+    }
+
+    SetZeroAndNegativeFlags(cpu, result)
+    cpu.PS.V = uint(((and ^ result) >> 6) & 0x01)
+
+    // Correct the low digit
+    if (and & 0x0F) + (and & 0x01) > 0x05 {
+        result = (result & 0xF0) | ((result + 0x06) & 0x0F)
+    }
+
+    // Correct the high digit
+    if uint(and & 0xF0) + uint(and & 0x10) > 0x50 {
+        result = result + 0x60
+        cpu.PS.C = set
+    }else{
+        cpu.PS.C = cleared
+    }
+
+    cpu.A = result
+}
@@ -0,0 +1,63 @@
+package arc
+
+// AssertIRQ pulls the IRQ line low.
+// The line is level triggered: the interrupt is serviced between instructions
+// for as long as the line stays asserted and the interrupt disable flag is clear.
+// The device that asserted it is responsible for releasing it, usually when the
+// handler acknowledges the interrupt.
+func (cpu *CPU) AssertIRQ(){
+    cpu.irq = true
+}
+
+// ReleaseIRQ releases the IRQ line.
+func (cpu *CPU) ReleaseIRQ(){
+    cpu.irq = false
+}
+
+// AssertNMI pulls the NMI line low.
+// The line is edge triggered: only the transition from released to asserted
+// requests an interrupt, keeping the line asserted doesn't trigger it again.
+// NMI can't be masked by the interrupt disable flag.
+func (cpu *CPU) AssertNMI(){
+
+    if !cpu.nmi {
+        cpu.nmiPending = true
+    }
+    cpu.nmi = true
+}
+
+// ReleaseNMI releases the NMI line, so that the next AssertNMI triggers a new interrupt.
+func (cpu *CPU) ReleaseNMI(){
+    cpu.nmi = false
+}
+
+// Cycles taken by the hardware interrupt sequence, like BRK.
+const interruptCycles = 7
+
+// serviceInterrupt runs the hardware interrupt sequence, jumping to the handler pointed by vector.
+// The PC is pushed as is, since it already points to the instruction that was about to be executed,
+// followed by the PS with the break bit clear.
+// It takes interruptCycles, two of them are internal cycles where the CPU
+// reads the next opcode and discards it.
+func (cpu *CPU) serviceInterrupt(vector uint16){
+
+    cpu.pushInterruptFrame((cpu.PSToByte() | unusedBit) &^ breakBit, vector)
+}
+
+// pushInterruptFrame pushes the PC and the given PS byte, disables interrupts and loads
+// the PC from vector. It's shared by BRK and hardware interrupts, which only differ in
+// the break bit of the pushed PS.
+func (cpu *CPU) pushInterruptFrame(ps byte, vector uint16){
+
+    cpu.PushWordToStack(cpu.PC)
+    cpu.PushByteToStack(ps)
+
+    cpu.PS.I = set
+
+    // The 65C02 also leaves decimal mode, so handlers don't need a CLD
+    if cpu.Variant == CMOS65C02 {
+        cpu.PS.D = cleared
+    }
+
+    cpu.PC = cpu.ReadWord(vector)
+}
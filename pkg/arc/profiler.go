@@ -0,0 +1,66 @@
+package arc
+
+// Profiler counts the executions and the cycles of the instruction at every address,
+// while the CPU runs with its Profiler field set. The profile package makes reports of them.
+//
+// The cycles of an instruction include its page crossing and branch penalties.
+// The cycles of the interrupt sequences aren't those of any instruction, they're
+// counted apart, so the cycles add up to those executed by the CPU.
+type Profiler struct {
+    Executions [MaxMem]uint64
+    Cycles [MaxMem]uint64
+
+    InterruptCycles uint64
+
+    // Calls, when set, follows the subroutine calls too.
+    Calls *CallGraph
+}
+
+func NewProfiler() *Profiler{
+    return &Profiler{}
+}
+
+// Clear resets the counts, to profile another part of a program for instance.
+func (p *Profiler) Clear(){
+
+    calls := p.Calls
+    *p = Profiler{Calls: calls}
+
+    if calls != nil {
+        calls.Clear()
+    }
+}
+
+// TotalCycles returns the cycles counted, interrupt sequences included.
+func (p *Profiler) TotalCycles() uint64{
+
+    total := p.InterruptCycles
+    for _, cycles := range p.Cycles {
+        total += cycles
+    }
+
+    return total
+}
+
+// count counts an execution of the instruction at pc, which took cycles,
+// sp being the stack pointer before it.
+func (p *Profiler) count(cpu *CPU, pc uint16, sp byte, cycles int){
+
+    p.Executions[pc]++
+    p.Cycles[pc] += uint64(cycles)
+
+    if p.Calls != nil {
+        p.Calls.step(cpu, pc, sp, cycles)
+    }
+}
+
+// interrupt counts an interrupt sequence, which interrupted the instruction at pc
+// with sp as the stack pointer.
+func (p *Profiler) interrupt(cpu *CPU, pc uint16, sp byte){
+
+    p.InterruptCycles += interruptCycles
+
+    if p.Calls != nil {
+        p.Calls.interrupt(cpu, pc, sp, interruptCycles)
+    }
+}
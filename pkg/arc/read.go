@@ -1,35 +1,19 @@
 package arc
 
-import "log"
+// ReadByteAt reads a piece of memory, without increasing the PC.
+func (cpu *CPU) ReadByteAt(address uint16) byte{
 
-// ReadByte reads a piece of memory, without increasing the PC.
-// It takes a clock cycle
-func (cpu *CPU) ReadByte( cycles *int, address uint16) byte{
-
-    if address > MaxMem-1 {
-        log.Fatalf("Program Counter exceeded max memory")
-    }
-    data := cpu.Memory.Data[address] 
-
-    *cycles--
-
-    return data
+    return cpu.read(address)
 }
 
-func (cpu *CPU) ReadWord( cycles *int, address uint16) uint16{
-
-    if address > MaxMem-1 {
-        log.Fatalf("Program Counter exceeded max memory")
-    }
+func (cpu *CPU) ReadWord(address uint16) uint16{
 
     // Read low byte of address (LSB)
-    data := uint16(cpu.Memory.Data[address])
-    *cycles--
+    data := uint16(cpu.read(address))
 
     // Read high byte of address (MSB)
     // e.g. data = 00000000 10011010 << 8 = 10011010 00000000
-    data = data | (uint16(cpu.Memory.Data[address+1]) << 8 )
-    *cycles--
+    data = data | (uint16(cpu.read(address+1)) << 8 )
 
     return data
 
@@ -37,30 +21,26 @@ func (cpu *CPU) ReadWord( cycles *int, address uint16) uint16{
 
 // TODO: for now we write MSB first and decrement the SP
 // That follows 6502 little endian architecture. Don't know if it's correct.
-func (cpu *CPU) ReadByteFromStack(cycles *int) byte{
+func (cpu *CPU) ReadByteFromStack() byte{
 
     cpu.SP++
-    data := cpu.Memory.Data[cpu.SPTo16Address(cpu.SP)] 
-
-    *cycles--
+    data := cpu.read(cpu.SPTo16Address(cpu.SP)) 
 
     return data
 }
 
 // TODO: does this need to be Fetch instead of Read?
 // Do we need to increment the PC here?
-func (cpu *CPU) ReadWordFromStack( cycles *int) uint16{
+func (cpu *CPU) ReadWordFromStack() uint16{
 
     // Read low byte of address (LSB)
     cpu.SP++
-    data := uint16(cpu.Memory.Data[cpu.SPTo16Address(cpu.SP)])
-    *cycles--
+    data := uint16(cpu.read(cpu.SPTo16Address(cpu.SP)))
     cpu.SP++
 
     // Read high byte of address (MSB)
     // e.g. data = 00000000 10011010 << 8 = 10011010 00000000
-    data = data | (uint16(cpu.Memory.Data[cpu.SPTo16Address(cpu.SP)]) << 8 )
-    *cycles--
+    data = data | (uint16(cpu.read(cpu.SPTo16Address(cpu.SP))) << 8 )
 
     return data
 
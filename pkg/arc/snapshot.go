@@ -0,0 +1,241 @@
+package arc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// Snapshot is the whole state of a CPU at some point in time, so that it can be
+// restored later, or saved to a file and restored by another process.
+// Long test scenarios can start from a prepared state instead of preparing it each time.
+//
+// The memory is the address space of the CPU. With a Bus, it's read through its Dump
+// method when it has one, like memmap.Map, and restored through its Load method, so ROMs
+// are restored too. Buses without them are read through Read, which can have side effects
+// like acknowledging a device register, and written through Write. Devices behind the Bus
+// must save their own state.
+type Snapshot struct {
+    Variant Variant
+    Registers Registers
+
+    // Interrupt lines, and whether an NMI edge is waiting to be serviced.
+    IRQ bool
+    NMI bool
+    NMIPending bool
+
+    Jammed bool
+    Waiting bool
+
+    // Cycles executed since the last Reset.
+    Cycles uint64
+
+    Memory [MaxMem]byte
+}
+
+// loader is implemented by buses which can write to their ROMs, like memmap.Map.
+type loader interface {
+    Load(address uint16, data []byte)
+}
+
+// dumper is implemented by buses which can be read without side effects, like memmap.Map.
+type dumper interface {
+    Dump(address uint16, data []byte)
+}
+
+// Snapshot returns the current state of the CPU.
+func (cpu *CPU) Snapshot() *Snapshot{
+
+    s := &Snapshot{}
+    cpu.save(s)
+
+    return s
+}
+
+// save fills s with the current state of the CPU, reusing its memory. It's safe
+// during Run, since the memory is read around the Bus installed by the Debugger or the History.
+func (cpu *CPU) save(s *Snapshot){
+
+    s.Variant = cpu.Variant
+    s.Registers = cpu.Registers()
+    s.IRQ = cpu.irq
+    s.NMI = cpu.nmi
+    s.NMIPending = cpu.nmiPending
+    s.Jammed = cpu.jammed
+    s.Waiting = cpu.waiting
+    s.Cycles = cpu.cycles
+
+    switch bus := cpu.AddressSpace().(type) {
+    case *Memory:
+        s.Memory = bus.Data
+
+    case dumper:
+        bus.Dump(0, s.Memory[:])
+
+    default:
+        for address := range s.Memory {
+            s.Memory[address] = bus.Read(uint16(address))
+        }
+    }
+}
+
+// Restore puts the CPU back in the state of s. The CPU must be of the same Variant.
+func (cpu *CPU) Restore(s *Snapshot) error{
+
+    if s.Variant != cpu.Variant {
+        return fmt.Errorf("can't restore a snapshot of %v on %v", s.Variant, cpu.Variant)
+    }
+
+    r := s.Registers
+    cpu.PC, cpu.SP, cpu.A, cpu.X, cpu.Y, cpu.PS = r.PC, r.SP, r.A, r.X, r.Y, r.PS
+
+    cpu.irq = s.IRQ
+    cpu.nmi = s.NMI
+    cpu.nmiPending = s.NMIPending
+    cpu.jammed = s.Jammed
+    cpu.waiting = s.Waiting
+    cpu.cycles = s.Cycles
+    cpu.fault = nil
+
+    switch bus := cpu.Bus.(type) {
+    case nil:
+        cpu.Memory.Data = s.Memory
+
+    case loader:
+        bus.Load(0, s.Memory[:])
+
+    default:
+        for address, value := range s.Memory {
+            bus.Write(uint16(address), value)
+        }
+    }
+
+    return nil
+}
+
+// Snapshot files start with a magic number and the version of the format,
+// followed by the fields of the Snapshot in a fixed layout, little endian:
+//
+//	variant   1 byte
+//	PC        2 bytes
+//	SP A X Y  1 byte each
+//	PS        1 byte, with the flags in the order of the status register
+//	state     1 byte of flags: IRQ, NMI, NMI pending, jammed and waiting from bit 0
+//	cycles    8 bytes
+//	memory    65536 bytes
+var snapshotMagic = []byte("SNP\x1A")
+
+const snapshotVersion = 1
+
+const snapshotSize = 4 + 1 + 1 + 2 + 4 + 1 + 1 + 8 + MaxMem
+
+// Bits of the state byte.
+const (
+    stateIRQ = 1 << iota
+    stateNMI
+    stateNMIPending
+    stateJammed
+    stateWaiting
+)
+
+// MarshalBinary encodes the snapshot in the format of snapshot files.
+func (s *Snapshot) MarshalBinary() ([]byte, error){
+
+    data := make([]byte, 0, snapshotSize)
+
+    data = append(data, snapshotMagic...)
+    data = append(data, snapshotVersion, byte(s.Variant))
+
+    r := &s.Registers
+    data = binary.LittleEndian.AppendUint16(data, r.PC)
+    data = append(data, r.SP, r.A, r.X, r.Y, r.PS.toByte())
+
+    var state byte
+    for flag, set := range map[byte]bool{stateIRQ: s.IRQ, stateNMI: s.NMI, stateNMIPending: s.NMIPending, stateJammed: s.Jammed, stateWaiting: s.Waiting} {
+        if set {
+            state |= flag
+        }
+    }
+
+    data = append(data, state)
+    data = binary.LittleEndian.AppendUint64(data, s.Cycles)
+    data = append(data, s.Memory[:]...)
+
+    return data, nil
+}
+
+// UnmarshalBinary decodes a snapshot encoded by MarshalBinary.
+func (s *Snapshot) UnmarshalBinary(data []byte) error{
+
+    if !bytes.HasPrefix(data, snapshotMagic) {
+        return fmt.Errorf("not a snapshot")
+    }
+
+    data = data[len(snapshotMagic):]
+
+    if len(data) > 0 && data[0] != snapshotVersion {
+        return fmt.Errorf("snapshot version %d, only version %d is supported", data[0], snapshotVersion)
+    }
+
+    if len(data) != snapshotSize - len(snapshotMagic) {
+        return fmt.Errorf("corrupted snapshot: %d bytes instead of %d", len(data) + len(snapshotMagic), snapshotSize)
+    }
+
+    variant := Variant(data[1])
+    if variant != NMOS6502 && variant != CMOS65C02 {
+        return fmt.Errorf("corrupted snapshot: unknown variant %d", variant)
+    }
+
+    state := data[9]
+
+    *s = Snapshot{
+        Variant: variant,
+        Registers: Registers{
+            PC: binary.LittleEndian.Uint16(data[2:]),
+            SP: data[4],
+            A: data[5],
+            X: data[6],
+            Y: data[7],
+            PS: statusFromByte(data[8]),
+        },
+        IRQ: state & stateIRQ != 0,
+        NMI: state & stateNMI != 0,
+        NMIPending: state & stateNMIPending != 0,
+        Jammed: state & stateJammed != 0,
+        Waiting: state & stateWaiting != 0,
+        Cycles: binary.LittleEndian.Uint64(data[10:]),
+    }
+
+    copy(s.Memory[:], data[18:])
+
+    return nil
+}
+
+// WriteFile saves the snapshot to a file.
+func (s *Snapshot) WriteFile(path string) error{
+
+    data, err := s.MarshalBinary()
+    if err != nil {
+        return err
+    }
+
+    return os.WriteFile(path, data, 0644)
+}
+
+// ReadSnapshot reads a snapshot file written by WriteFile.
+func ReadSnapshot(path string) (*Snapshot, error){
+
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    s := &Snapshot{}
+
+    if err := s.UnmarshalBinary(data); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+
+    return s, nil
+}
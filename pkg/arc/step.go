@@ -0,0 +1,181 @@
+package arc
+
+import (
+	"emulator/pkg/instructions"
+)
+
+// Registers is a copy of the registers of the CPU at some point in time.
+type Registers struct {
+    PC uint16
+    SP byte
+    A byte
+    X byte
+    Y byte
+    PS ProcessorStatus
+}
+
+// Registers returns a copy of the current registers.
+func (cpu *CPU) Registers() Registers{
+
+    return Registers{PC: cpu.PC, SP: cpu.SP, A: cpu.A, X: cpu.X, Y: cpu.Y, PS: cpu.PS}
+}
+
+// StepResult describes the instruction executed by Step.
+type StepResult struct {
+
+    // PC is the address of the instruction.
+    PC uint16
+
+    Opcode byte
+    Mnemonic string
+    Mode instructions.Mode
+
+    // Operand holds the bytes following the opcode, as many as the addressing mode uses.
+    Operand []byte
+
+    // EffectiveAddress is the address the instruction reads, writes or jumps to,
+    // it's only meaningful when HasEffectiveAddress is true.
+    // For BBR and BBS it's the zero page address being tested.
+    EffectiveAddress uint16
+    HasEffectiveAddress bool
+
+    // Interrupted is true when an interrupt was serviced before the instruction,
+    // which then is the first one of the handler.
+    Interrupted bool
+
+    // Cycles taken by the step, interrupt sequence included.
+    Cycles int
+
+    Before Registers
+    After Registers
+}
+
+// Step executes exactly one instruction, and returns what it did.
+// A pending interrupt is serviced first, as part of the same step.
+// A CPU waiting for an interrupt (WAI) doesn't execute anything: the returned result
+// has no Mnemonic and takes no cycles.
+// Faults and watchpoints are reported like Run does, but the execution breakpoints
+// at the instruction are ignored.
+func (cpu *CPU) Step() (result StepResult, err error){
+
+    result.Before = cpu.Registers()
+
+    defer func(){
+        result.After = cpu.Registers()
+    }()
+
+    if cpu.waiting && !cpu.irq && !cpu.nmiPending {
+        return
+    }
+
+    // Run with a budget of one cycle executes a single instruction,
+    // or services a single interrupt.
+    if !cpu.jammed && (cpu.nmiPending || (cpu.irq && cpu.PS.I == cleared)) {
+
+        result.Interrupted = true
+
+        result.Cycles, err = cpu.Run(1)
+        if err != nil {
+            return
+        }
+    }
+
+    cpu.decode(&result)
+
+    // Step always executes the instruction, watchpoints can still stop it
+    if cpu.Debugger != nil {
+        cpu.Debugger.skip(cpu.PC)
+    }
+
+    cycles, err := cpu.Run(1)
+    result.Cycles += cycles
+
+    return
+}
+
+// opcodes returns the table used to decode the opcodes of the CPU variant.
+func (cpu *CPU) opcodes() *[256]instructions.Opcode{
+    return instructions.Table(cpu.Variant == CMOS65C02)
+}
+
+// decode fills the description of the instruction at PC in result, without executing it.
+func (cpu *CPU) decode(result *StepResult){
+
+    bus := cpu.bus()
+
+    result.PC = cpu.PC
+    result.Opcode = bus.Read(cpu.PC)
+
+    opcode := cpu.opcodes()[result.Opcode]
+    result.Mnemonic = opcode.Mnemonic
+    result.Mode = opcode.Mode
+
+    result.Operand = make([]byte, opcode.Size - 1)
+    for i := range result.Operand {
+        result.Operand[i] = bus.Read(cpu.PC + 1 + uint16(i))
+    }
+
+    result.EffectiveAddress, result.HasEffectiveAddress = cpu.effectiveAddress(result.PC, opcode.Mode, result.Operand)
+}
+
+// effectiveAddress computes the address an instruction at pc is going to use,
+// reading pointers the same way the instruction does.
+func (cpu *CPU) effectiveAddress(pc uint16, mode instructions.Mode, operand []byte) (uint16, bool){
+
+    bus := cpu.bus()
+
+    readWord := func(address uint16) uint16{
+        return uint16(bus.Read(address)) | uint16(bus.Read(address + 1)) << 8
+    }
+
+    var word uint16
+    if len(operand) == 2 {
+        word = uint16(operand[0]) | uint16(operand[1]) << 8
+    }
+
+    switch mode {
+    case instructions.ZeroPage, instructions.ZeroPageRelative:
+        return uint16(operand[0]), true
+
+    case instructions.ZeroPageX:
+        return uint16(operand[0] + cpu.X), true
+
+    case instructions.ZeroPageY:
+        return uint16(operand[0] + cpu.Y), true
+
+    case instructions.Relative:
+        return pc + 2 + uint16(int8(operand[0])), true
+
+    case instructions.Absolute:
+        return word, true
+
+    case instructions.AbsoluteX:
+        return word + uint16(cpu.X), true
+
+    case instructions.AbsoluteY:
+        return word + uint16(cpu.Y), true
+
+    case instructions.Indirect:
+
+        // Same page wrap bug as JMP ($xxFF) on the NMOS 6502
+        if cpu.Variant == NMOS6502 {
+            return uint16(bus.Read(word)) | uint16(bus.Read((word & 0xFF00) | ((word + 1) & 0x00FF))) << 8, true
+        }
+
+        return readWord(word), true
+
+    case instructions.IndexedIndirect:
+        return cpu.readZeroPageWord(operand[0] + cpu.X), true
+
+    case instructions.IndirectIndexed:
+        return cpu.readZeroPageWord(operand[0]) + uint16(cpu.Y), true
+
+    case instructions.ZeroPageIndirect:
+        return cpu.readZeroPageWord(operand[0]), true
+
+    case instructions.AbsoluteIndexedIndirect:
+        return readWord(word + uint16(cpu.X)), true
+    }
+
+    return 0, false
+}
@@ -1,53 +1,36 @@
 package arc
 
-import "log"
-
 // Write one byte to memory
-func (cpu *CPU) WriteByte(cycles *int, b byte ,address uint16){
-    
-    if address > MaxMem-1 {
-        log.Fatalf("Program Counter exceeded max memory")
-    }
-    cpu.Memory.Data[address] = b
-    *cycles--
+func (cpu *CPU) WriteByteAt(b byte ,address uint16){
+
+    cpu.write(address, b)
 }
 
 // Write two bytes to memory
-func (cpu *CPU) WriteWord(cycles *int, word ,address uint16){
-
-    if address > MaxMem-1 {
-        log.Fatalf("Program Counter exceeded max memory")
-    }
+func (cpu *CPU) WriteWord(word ,address uint16){
 
     // Little endian: we store LSB first
-    cpu.Memory.Data[address] = byte(word & 0xFF)
-    *cycles--
-
+    cpu.write(address, byte(word & 0xFF))
 
     // Store MSB
-    cpu.Memory.Data[address+1] = byte(word >> 8)
-    *cycles--
-
+    cpu.write(address+1, byte(word >> 8))
 }
 
 // Write one byte to memory
-func (cpu *CPU) WriteByteToStack(cycles *int, b byte){
+func (cpu *CPU) WriteByteToStack(b byte){
     
-    cpu.Memory.Data[cpu.SPTo16Address(cpu.SP)] = b
+    cpu.write(cpu.SPTo16Address(cpu.SP), b)
     cpu.SP--
-    *cycles--
 }
 
 // TODO: for now we write MSB first and then LSB.
 // That follows 6502 little endian architecture. Don't know if it's correct.
-func (cpu *CPU) WriteWordToStack(cycles *int, word uint16){
+func (cpu *CPU) WriteWordToStack(word uint16){
 
     // Store MSB
-    cpu.Memory.Data[cpu.SPTo16Address(cpu.SP)] = byte(word >> 8)
+    cpu.write(cpu.SPTo16Address(cpu.SP), byte(word >> 8))
     cpu.SP--
-    *cycles--
 
-    cpu.Memory.Data[cpu.SPTo16Address(cpu.SP)] = byte(word & 0xFF)
-    *cycles--
+    cpu.write(cpu.SPTo16Address(cpu.SP), byte(word & 0xFF))
     cpu.SP--
 }
@@ -0,0 +1,714 @@
+// Package asm is a two-pass 6502 assembler. It turns source like
+//
+//	        .org $1000
+//	start:  LDA #$FF
+//	        STA $90
+//	        JMP start
+//
+// into machine code, in the format CPU.LoadProgram accepts.
+//
+// The first pass computes the address of every label, the second one
+// encodes the instructions, so labels can be used before they are defined.
+//
+// Directives:
+//
+//	.org address            *= address works too
+//	.byte values            .db, values can also be "strings"
+//	.word values            .dw
+//	name = value            .equ works too
+//	.cpu 6502               or 65C02
+//	.macro name a, b        up to .endmacro, local labels start with @
+//	.if value               with .elseif, .else and .endif, or .ifdef and .ifndef name
+//	.include "file"
+//	.incbin "file", offset, length
+//	.res count, fill        reserves count bytes, filled with zeros by default
+//
+// Expressions use the operators of C, plus < and > for the low and high byte of a word.
+//
+// AssembleObject assembles a module into an Object instead, for the linker of package link
+// to place in memory. Objects have no .org: their code goes in segments, whose addresses
+// are chosen by the linker, and they share symbols with the other modules.
+//
+//	.segment "NAME"         CODE is the default, labels of ZEROPAGE are zero page addresses
+//	.export names
+//	.import names           .importzp for zero page addresses
+package asm
+
+import (
+	"bytes"
+	"emulator/pkg/instructions"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Error is an error in the source, with the line it was found at.
+type Error struct {
+    File string
+    Line int
+    Err error
+}
+
+func (err *Error) Error() string{
+
+    if err.File != "" {
+        return fmt.Sprintf("%s:%d: %v", err.File, err.Line, err.Err)
+    }
+
+    return fmt.Sprintf("line %d: %v", err.Line, err.Err)
+}
+
+func (err *Error) Unwrap() error{
+    return err.Err
+}
+
+// Program is the result of the assembly.
+type Program struct {
+
+    // Code starts at Origin, the lowest address of the program.
+    // The gaps left between .org blocks are filled with zeros.
+    Origin uint16
+    Code []byte
+
+    // Symbols holds the value of every label and constant.
+    Symbols map[string]uint16
+}
+
+// Binary returns the program in the format CPU.LoadProgram accepts:
+// the load address, little endian, followed by the code.
+func (program *Program) Binary() []byte{
+
+    binary := []byte{byte(program.Origin), byte(program.Origin >> 8)}
+
+    return append(binary, program.Code...)
+}
+
+// Assembler holds the options of the assembly.
+type Assembler struct {
+
+    // CMOS accepts the instructions of the 65C02, the .cpu directive can also change it.
+    CMOS bool
+}
+
+// Assemble assembles source for the NMOS 6502.
+func Assemble(source string) (*Program, error){
+
+    assembler := &Assembler{}
+
+    return assembler.Assemble("", source)
+}
+
+// Assemble assembles source, name is the file name used in errors.
+func (assembler *Assembler) Assemble(name string, source string) (*Program, error){
+
+    a, err := assembler.run(name, source, false)
+    if err != nil {
+        return nil, err
+    }
+
+    return a.program()
+}
+
+// AssembleFile assembles the source file at path. Files included by
+// .include and .incbin are looked up relative to the file including them.
+func (assembler *Assembler) AssembleFile(path string) (*Program, error){
+
+    source, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    return assembler.Assemble(path, string(source))
+}
+
+// AssembleObject assembles a module for the linker, name is the name of the module.
+func (assembler *Assembler) AssembleObject(name string, source string) (*Object, error){
+
+    a, err := assembler.run(name, source, true)
+    if err != nil {
+        return nil, err
+    }
+
+    return a.object()
+}
+
+// AssembleObjectFile assembles the source file at path into an object.
+func (assembler *Assembler) AssembleObjectFile(path string) (*Object, error){
+
+    source, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    return assembler.AssembleObject(path, string(source))
+}
+
+// run loads the source and runs both passes over it.
+func (assembler *Assembler) run(name string, source string, object bool) (*assembly, error){
+
+    a := &assembly{
+        assembler: assembler,
+        name: name,
+        relocatable: object,
+        symbols: map[string]value{},
+        modes: map[int]instructions.Mode{},
+        conditions: map[int]bool{},
+        macros: map[string]*macro{},
+        imports: map[string]bool{},
+        constants: map[string]value{},
+        declared: map[string]bool{},
+    }
+
+    if err := a.load(splitLines(name, source), expansion{}, 0); err != nil {
+        return nil, err
+    }
+
+    for a.pass = 1; a.pass <= 2; a.pass++ {
+
+        a.start()
+
+        for index, s := range a.statements {
+
+            if err := a.assemble(index, s); err != nil {
+                return nil, a.lineError(s, err)
+            }
+        }
+
+        if len(a.branches) > 0 {
+            open := a.branches[len(a.branches) - 1]
+            return nil, &Error{File: open.file, Line: open.line, Err: fmt.Errorf("missing .endif")}
+        }
+    }
+
+    return a, nil
+}
+
+// block is the code assembled after an .org directive, or in a segment of an object.
+type block struct {
+    origin int
+    code []byte
+
+    segment string
+    relocations []Relocation
+
+    // Where the block starts in the source, for errors
+    file string
+    line int
+}
+
+// assembly is the state of the assembler during a pass.
+type assembly struct {
+    assembler *Assembler
+
+    // Name of the module, and whether it's assembled into an object
+    name string
+    relocatable bool
+
+    statements []statement
+
+    pass int
+    opcodes opcodeSet
+
+    // Statement being assembled
+    current statement
+
+    // Address of the next byte, it's an int so running past $FFFF can be detected.
+    // In objects, it's the offset from the start of the current segment.
+    pc int
+
+    // Segment being assembled, and where each of the others is at, for objects
+    segment string
+    segments map[string]int
+
+    symbols map[string]value
+
+    // Symbols imported, and whether they're zero page addresses, in the order of
+    // the source. Exports are the .export statements, checked at the end.
+    imports map[string]bool
+    importOrder []string
+    exports []statement
+
+    // Addressing mode of every instruction, chosen by the first pass,
+    // so that both passes agree on the size of the instructions.
+    modes map[int]instructions.Mode
+
+    blocks []block
+
+    macros map[string]*macro
+
+    // Conditional blocks being assembled, innermost last, and the result of every
+    // condition, computed by the first pass so both passes take the same branches.
+    branches []branch
+    conditions map[int]bool
+
+    // Conditional blocks being loaded, and what load knows of the symbols defined
+    // so far to compute their conditions, see loadBranch.
+    loading []loadBranch
+    constants map[string]value
+    declared map[string]bool
+
+    // Last label which is not local, it's the scope of the local labels that follow
+    scope string
+}
+
+// start resets the state at the beginning of a pass. Symbols are kept.
+func (a *assembly) start(){
+
+    a.pc = 0
+    a.blocks = nil
+    a.branches = nil
+    a.scope = ""
+    a.opcodes = nmosOpcodes
+    a.segment = ""
+    a.segments = map[string]int{}
+
+    if a.relocatable {
+        a.segment = "CODE"
+    }
+
+    if a.assembler.CMOS {
+        a.opcodes = cmosOpcodes
+    }
+}
+
+// qualify returns the full name of a symbol. Local labels, which start with @,
+// belong to the last label that isn't local, or to the macro expansion they're in:
+// @loop after the label copy is copy@loop.
+func (a *assembly) qualify(name string, s statement) string{
+
+    if !strings.HasPrefix(name, "@") {
+        return name
+    }
+
+    if s.scope != "" {
+        return s.scope + name
+    }
+
+    return a.scope + name
+}
+
+// lookup returns the value of a symbol, seen from the current statement.
+func (a *assembly) lookup(name string) (value, bool){
+
+    symbol, ok := a.symbols[a.qualify(name, a.current)]
+    return symbol, ok
+}
+
+// here is the address of the next byte, relative to the current segment in objects.
+func (a *assembly) here() value{
+    return value{n: a.pc, segment: a.segment}
+}
+
+// evaluate computes an expression. Unknown symbols are only allowed in the first pass.
+func (a *assembly) evaluate(text string) (value, bool, error){
+
+    result, known, err := evaluate(text, a.here(), a.lookup)
+    if err != nil {
+        return value{}, false, err
+    }
+
+    if !known && a.pass == 2 {
+        return value{}, false, fmt.Errorf("undefined symbol in %q", text)
+    }
+
+    return result, known, nil
+}
+
+// evaluateNow computes the operand of directives that need its value right away, like .if:
+// it can't use symbols defined after it, nor addresses only the linker knows.
+func (a *assembly) evaluateNow(text string, operation string) (int, error){
+
+    result, known, err := a.evaluate(text)
+    if err != nil {
+        return 0, err
+    }
+
+    if !known {
+        return 0, fmt.Errorf("%s uses a symbol defined after it", operation)
+    }
+
+    if result.relocatable() {
+        return 0, fmt.Errorf("%s needs a value known before linking, %s is relocatable", operation, text)
+    }
+
+    return result.n, nil
+}
+
+// define sets the value of a symbol. Symbols are defined in the first pass,
+// except for constants that use symbols defined after them.
+func (a *assembly) define(name string, symbol value) error{
+
+    previous, ok := a.symbols[name]
+
+    if ok && a.pass == 1 {
+        return fmt.Errorf("symbol %s already defined", name)
+    }
+
+    if ok && previous != symbol {
+        return fmt.Errorf("symbol %s changed value between passes, from $%04X to $%04X", name, previous.n, symbol.n)
+    }
+
+    a.symbols[name] = symbol
+
+    return nil
+}
+
+func (a *assembly) assemble(index int, s statement) error{
+
+    a.current = s
+
+    // Inside a conditional block that is not assembled, only the nesting matters
+    if handled, err := a.conditional(index, s); handled || err != nil {
+        return err
+    }
+
+    if s.label != "" && s.operation != "=" && s.operation != ".equ" {
+
+        if !strings.HasPrefix(s.label, "@") && s.scope == "" {
+            a.scope = s.label
+        }
+
+        if err := a.define(a.qualify(s.label, s), a.here()); err != nil {
+            return err
+        }
+    }
+
+    switch s.operation {
+    case "":
+        return nil
+
+    case "=", ".equ":
+        return a.constant(s)
+
+    case ".org":
+        return a.org(s.operand, s)
+
+    case ".byte", ".db":
+        return a.data(s.operand, 1)
+
+    case ".word", ".dw":
+        return a.data(s.operand, 2)
+
+    case ".incbin":
+        return a.incbin(s)
+
+    case ".cpu":
+        return a.cpu(s.operand)
+
+    case ".res":
+        return a.reserve(s.operand)
+
+    case ".segment", ".import", ".importzp", ".export":
+
+        if !a.relocatable {
+            return fmt.Errorf("%s is only available when assembling objects for the linker", s.operation)
+        }
+
+        return a.module(s)
+    }
+
+    if strings.HasPrefix(s.operation, ".") {
+        return fmt.Errorf("unknown directive %s", s.operation)
+    }
+
+    return a.instruction(index, s)
+}
+
+func (a *assembly) constant(s statement) error{
+
+    if s.label == "" {
+        return fmt.Errorf("constant without a name")
+    }
+
+    // Constants using symbols defined after them are only defined by the second pass
+    result, known, err := a.evaluate(s.operand)
+    if err != nil || !known {
+        return err
+    }
+
+    return a.define(a.qualify(s.label, s), result)
+}
+
+func (a *assembly) org(operand string, s statement) error{
+
+    if a.relocatable {
+        return fmt.Errorf(".org can't be used in objects, the linker places their segments")
+    }
+
+    value, err := a.evaluateNow(operand, ".org")
+    if err != nil {
+        return err
+    }
+
+    if value < 0 || value > 0xFFFF {
+        return fmt.Errorf(".org address $%X out of range", value)
+    }
+
+    a.pc = value
+    a.blocks = append(a.blocks, block{origin: value, file: s.file, line: s.line})
+
+    return nil
+}
+
+func (a *assembly) cpu(operand string) error{
+
+    switch strings.ToLower(strings.Trim(operand, "\"")) {
+    case "6502":
+        a.opcodes = nmosOpcodes
+    case "65c02":
+        a.opcodes = cmosOpcodes
+    default:
+        return fmt.Errorf("unknown cpu %s, use 6502 or 65C02", operand)
+    }
+
+    return nil
+}
+
+// emit appends bytes at the current address. The first pass only counts them.
+func (a *assembly) emit(bytes ...byte) error{
+
+    if a.pc + len(bytes) > 0x10000 {
+        return fmt.Errorf("code runs past $FFFF")
+    }
+
+    if a.pass == 2 {
+        current := a.block()
+        current.code = append(current.code, bytes...)
+    }
+
+    a.pc += len(bytes)
+
+    return nil
+}
+
+// block returns the block being assembled by the second pass.
+func (a *assembly) block() *block{
+
+    if a.relocatable {
+
+        for i := range a.blocks {
+            if a.blocks[i].segment == a.segment {
+                return &a.blocks[i]
+            }
+        }
+
+        a.blocks = append(a.blocks, block{segment: a.segment})
+
+    // Code before the first .org starts at $0000
+    }else if len(a.blocks) == 0 {
+        a.blocks = append(a.blocks, block{origin: 0})
+    }
+
+    return &a.blocks[len(a.blocks) - 1]
+}
+
+// data assembles .byte and .word, whose arguments are expressions or, for .byte, strings.
+func (a *assembly) data(operand string, size int) error{
+
+    if operand == "" {
+        return fmt.Errorf("missing data")
+    }
+
+    for _, argument := range splitArguments(operand) {
+
+        if size == 1 && len(argument) >= 2 && argument[0] == '"' && argument[len(argument) - 1] == '"' {
+
+            if err := a.emit([]byte(argument[1:len(argument) - 1])...); err != nil {
+                return err
+            }
+            continue
+        }
+
+        result, _, err := a.evaluate(argument)
+        if err != nil {
+            return err
+        }
+
+        if size == 1 {
+            err = a.emitByte(result)
+        }else{
+            err = a.emitWord(result)
+        }
+
+        if err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
+
+// incbin emits the content of a binary file, optionally from an offset and for a length:
+// .incbin "file", offset, length
+func (a *assembly) incbin(s statement) error{
+
+    data := s.data
+    arguments := splitArguments(s.operand)
+
+    if len(arguments) > 3 {
+        return fmt.Errorf(".incbin takes a file name, an offset and a length")
+    }
+
+    values := make([]int, len(arguments))
+
+    for i := 1; i < len(arguments); i++ {
+
+        value, err := a.evaluateNow(arguments[i], ".incbin")
+        if err != nil {
+            return err
+        }
+
+        values[i] = value
+    }
+
+    if len(values) > 1 {
+
+        if values[1] < 0 || values[1] > len(data) {
+            return fmt.Errorf(".incbin offset %d out of the %d bytes of the file", values[1], len(data))
+        }
+
+        data = data[values[1]:]
+    }
+
+    if len(values) > 2 {
+
+        if values[2] < 0 || values[2] > len(data) {
+            return fmt.Errorf(".incbin length %d larger than the %d bytes available", values[2], len(data))
+        }
+
+        data = data[:values[2]]
+    }
+
+    return a.emit(data...)
+}
+
+// reserve emits count bytes of a fill value: .res count, fill
+func (a *assembly) reserve(operand string) error{
+
+    arguments := splitArguments(operand)
+
+    if operand == "" || len(arguments) > 2 {
+        return fmt.Errorf(".res takes a count and a fill value")
+    }
+
+    count, err := a.evaluateNow(arguments[0], ".res")
+    if err != nil {
+        return err
+    }
+
+    if count < 0 || a.pc + count > 0x10000 {
+        return fmt.Errorf(".res count %d out of range", count)
+    }
+
+    fill := 0
+
+    if len(arguments) == 2 {
+
+        if fill, err = a.evaluateNow(arguments[1], ".res"); err != nil {
+            return err
+        }
+
+        if fill < -128 || fill > 0xFF {
+            return fmt.Errorf("value $%X doesn't fit in a byte", fill)
+        }
+    }
+
+    return a.emit(bytes.Repeat([]byte{byte(fill)}, count)...)
+}
+
+// emitByte emits a value that fits in a byte, negative values are two's complement.
+// Relocatable addresses need a relocation: their low or high byte, or a zero page address.
+func (a *assembly) emitByte(v value) error{
+
+    if v.relocatable() {
+
+        kind := RelocateZeroPage
+
+        switch {
+        case v.part == '<':
+            kind = RelocateLow
+        case v.part == '>':
+            kind = RelocateHigh
+        case !a.zeroPage(v):
+            return fmt.Errorf("relocatable address %s doesn't fit in a byte, use < or > for its low or high byte", describe(v))
+        }
+
+        a.relocate(kind, v)
+
+        return a.emit(0)
+    }
+
+    if a.pass == 2 && (v.n < -128 || v.n > 0xFF) {
+        return fmt.Errorf("value $%X doesn't fit in a byte", v.n)
+    }
+
+    return a.emit(byte(v.n))
+}
+
+func (a *assembly) emitWord(v value) error{
+
+    if v.relocatable() {
+
+        switch v.part {
+        case '<':
+            a.relocate(RelocateLow, v)
+        case '>':
+            a.relocate(RelocateHigh, v)
+        default:
+            a.relocate(RelocateWord, v)
+        }
+
+        return a.emit(0, 0)
+    }
+
+    if a.pass == 2 && (v.n < -32768 || v.n > 0xFFFF) {
+        return fmt.Errorf("value $%X doesn't fit in a word", v.n)
+    }
+
+    return a.emit(byte(v.n), byte(v.n >> 8))
+}
+
+// program merges the blocks assembled by the second pass.
+func (a *assembly) program() (*Program, error){
+
+    program := &Program{Symbols: map[string]uint16{}}
+
+    for name, symbol := range a.symbols {
+        program.Symbols[name] = uint16(symbol.n)
+    }
+
+    var blocks []block
+    for _, b := range a.blocks {
+        if len(b.code) > 0 {
+            blocks = append(blocks, b)
+        }
+    }
+
+    if len(blocks) == 0 {
+        return program, nil
+    }
+
+    sort.SliceStable(blocks, func(i, j int) bool{
+        return blocks[i].origin < blocks[j].origin
+    })
+
+    program.Origin = uint16(blocks[0].origin)
+
+    for _, b := range blocks {
+
+        offset := b.origin - int(program.Origin)
+
+        if offset < len(program.Code) {
+            return nil, &Error{File: b.file, Line: b.line, Err: fmt.Errorf("code at $%04X overlaps code assembled before", b.origin)}
+        }
+
+        for len(program.Code) < offset {
+            program.Code = append(program.Code, 0)
+        }
+
+        program.Code = append(program.Code, b.code...)
+    }
+
+    return program, nil
+}
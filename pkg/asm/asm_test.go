@@ -0,0 +1,226 @@
+package asm
+
+import (
+	"bytes"
+	"emulator/pkg/arc"
+	"emulator/pkg/disasm"
+	"emulator/pkg/instructions"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func CheckCode(t *testing.T, source string, expected ...byte){
+
+    t.Helper()
+
+    program, err := Assemble(source)
+    if err != nil {
+        t.Error("Couldn't assemble ", source, ": ", err)
+        return
+    }
+
+    if !bytes.Equal(program.Code, expected) {
+        t.Errorf("%q: expected % X but got % X", source, expected, program.Code)
+    }
+}
+
+func CheckError(t *testing.T, source string, line int, message string){
+
+    t.Helper()
+
+    _, err := Assemble(source)
+
+    var asmErr *Error
+    if !errors.As(err, &asmErr) {
+        t.Error("Expected an error at line ", line, " but got: ", err)
+        return
+    }
+
+    if asmErr.Line != line || !strings.Contains(err.Error(), message) {
+        t.Error("Expected an error containing ", message, " at line ", line, " but got: ", err)
+    }
+}
+
+// The program of cpu_first_program_test.go, written in assembly
+const firstProgram = `
+        .org $1000
+        LDA #$FF    ; load 255
+
+start   STA $90
+        STA $8000
+        EOR #$CC
+        JMP start
+`
+
+func TestAssembleFirstProgram(t *testing.T){
+
+    program, err := Assemble(firstProgram)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    expected := []byte{0x00, 0x10, 0xA9, 0xFF, 0x85, 0x90, 0x8D, 0x00, 0x80, 0x49, 0xCC, 0x4C, 0x02, 0x10}
+
+    if !bytes.Equal(program.Binary(), expected) {
+        t.Errorf("Expected % X but got % X", expected, program.Binary())
+    }
+
+    if program.Symbols["start"] != 0x1002 {
+        t.Error("start should be $1002 but got: ", program.Symbols["start"])
+    }
+
+    // And it runs on the CPU
+    cpu := arc.NewCPU(arc.NMOS6502)
+    cpu.LoadProgram(program.Binary())
+    cpu.Execute(2 + 3 + 4 + 2)
+
+    if cpu.Memory.Data[0x0090] != 0xFF || cpu.Memory.Data[0x8000] != 0xFF || cpu.A != 0x33 {
+        t.Error("Program didn't run as expected, A: ", cpu.A)
+    }
+}
+
+func TestAssembleAddressingModes(t *testing.T){
+
+    CheckCode(t, " NOP", 0xEA)
+    CheckCode(t, " ASL", 0x0A)
+    CheckCode(t, " ROL A", 0x2A)
+    CheckCode(t, " LDA #$10", 0xA9, 0x10)
+    CheckCode(t, " LDA $10", 0xA5, 0x10)
+    CheckCode(t, " LDA $10,X", 0xB5, 0x10)
+    CheckCode(t, " LDX $10,Y", 0xB6, 0x10)
+    CheckCode(t, " LDA $1234", 0xAD, 0x34, 0x12)
+    CheckCode(t, " LDA $1234,X", 0xBD, 0x34, 0x12)
+    CheckCode(t, " LDA $1234, y", 0xB9, 0x34, 0x12)
+    CheckCode(t, " JMP ($1234)", 0x6C, 0x34, 0x12)
+    CheckCode(t, " LDA ($10,X)", 0xA1, 0x10)
+    CheckCode(t, " lda ($10),y", 0xB1, 0x10)
+
+    // No absolute,Y for STX, and no zero page,Y for LDA
+    CheckCode(t, " STX $10,Y", 0x96, 0x10)
+    CheckCode(t, " LDA $10,Y", 0xB9, 0x10, 0x00)
+
+    // NMOS illegal opcodes
+    CheckCode(t, " LAX $10", 0xA7, 0x10)
+}
+
+func TestAssembleNumberFormats(t *testing.T){
+
+    CheckCode(t, " .byte $1F, %1010, 42, 'A', -1", 0x1F, 0x0A, 42, 'A', 0xFF)
+    CheckCode(t, " .word $1234, 1000", 0x34, 0x12, 0xE8, 0x03)
+    CheckCode(t, ` .byte "HI;", 0`, 'H', 'I', ';', 0x00)
+    CheckCode(t, " LDA #%11110000", 0xA9, 0xF0)
+}
+
+func TestAssembleForwardReferences(t *testing.T){
+
+    source := `
+        .org $0200
+        JMP end
+        LDA data        ; not known yet, so it's absolute even if it fits in the zero page
+        BNE end
+        .word end
+end:    RTS
+data = $10
+`
+    CheckCode(t, source,
+        0x4C, 0x0A, 0x02,
+        0xAD, 0x10, 0x00,
+        0xD0, 0x02,
+        0x0A, 0x02,
+        0x60)
+}
+
+func TestAssembleConstantsAndCurrentAddress(t *testing.T){
+
+    source := `
+port = $D000
+zp   = $80
+        .org $0300
+        STA port+1
+        LDA zp
+loop:   BNE *
+        .word * - 2
+`
+    CheckCode(t, source, 0x8D, 0x01, 0xD0, 0xA5, 0x80, 0xD0, 0xFE, 0x05, 0x03)
+}
+
+func TestAssembleFillsGapsBetweenOrgs(t *testing.T){
+
+    program, err := Assemble(" .org $0204\n RTS\n *= $0200\n NOP\n")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if program.Origin != 0x0200 || !bytes.Equal(program.Code, []byte{0xEA, 0x00, 0x00, 0x00, 0x60}) {
+        t.Errorf("Expected $0200: EA 00 00 00 60 but got $%04X: % X", program.Origin, program.Code)
+    }
+}
+
+func TestAssembleCMOS(t *testing.T){
+
+    assembler := &Assembler{CMOS: true}
+
+    program, err := assembler.Assemble("rom.s", " .org $0200\nloop: BRA loop\n STZ $10\n LDA ($10)\n BBS7 $12,loop\n JMP ($3000,X)\n")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    expected := []byte{0x80, 0xFE, 0x64, 0x10, 0xB2, 0x10, 0xFF, 0x12, 0xF7, 0x7C, 0x00, 0x30}
+
+    if !bytes.Equal(program.Code, expected) {
+        t.Errorf("Expected % X but got % X", expected, program.Code)
+    }
+
+    CheckCode(t, " .cpu 65C02\n PHX", 0xDA)
+}
+
+func TestAssembleErrorsHaveLineNumbers(t *testing.T){
+
+    CheckError(t, " NOP\n FOO $10", 2, "unknown instruction FOO")
+    CheckError(t, " NOP\n\n JMP nowhere", 3, "undefined symbol")
+    CheckError(t, "loop: NOP\nloop: NOP", 2, "already defined")
+    CheckError(t, " STZ $10", 1, "unknown instruction STZ")
+    CheckError(t, " JMP #$10", 1, "doesn't support the immediate addressing mode")
+    CheckError(t, " LDA ($1234),Y", 1, "not in the zero page")
+    CheckError(t, " .byte 256", 1, "doesn't fit in a byte")
+    CheckError(t, " .org $0200\nback: NOP\n .org $0300\n BEQ back", 4, "out of range")
+    CheckError(t, " .org $0200\n .byte 1, 2\n .org $0201\n NOP", 3, "overlaps")
+    CheckError(t, " .fill 10", 1, "unknown directive")
+
+    _, err := (&Assembler{}).Assemble("rom.s", "\n LDA #")
+    if err == nil || err.Error() != "rom.s:2: missing expression" {
+        t.Error("Expected rom.s:2: missing expression but got: ", err)
+    }
+}
+
+// Every instruction the disassembler prints must assemble back to the same bytes.
+func TestAssembleDisassembledOpcodes(t *testing.T){
+
+    for _, cmos := range []bool{false, true} {
+
+        assembler := &Assembler{CMOS: cmos}
+
+        for code, opcode := range instructions.Table(cmos) {
+
+            if opcode.Status == instructions.Illegal {
+                continue
+            }
+
+            memory := &arc.Memory{}
+            copy(memory.Data[0x0200:], []byte{byte(code), 0x34, 0x12})
+
+            ins := disasm.Decode(memory, 0x0200, cmos)
+
+            program, err := assembler.Assemble("", " .org $0200\n " + ins.String())
+            if err != nil {
+                t.Error(ins.String(), ": ", err)
+                continue
+            }
+
+            if !bytes.Equal(program.Code, ins.Bytes) {
+                t.Errorf("%s: expected % X but got % X", ins.String(), ins.Bytes, program.Code)
+            }
+        }
+    }
+}
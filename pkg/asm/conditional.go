@@ -0,0 +1,318 @@
+package asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// branch is a conditional block, from .if to .endif.
+type branch struct {
+
+    // Where the block starts, for errors
+    file string
+    line int
+
+    // Whether the block containing this one is assembled
+    parent bool
+
+    // Whether the current branch is assembled, and whether one of the branches was already
+    active bool
+    taken bool
+
+    seenElse bool
+}
+
+// assembling reports whether the current statement is in a branch being assembled.
+func (a *assembly) assembling() bool{
+    return len(a.branches) == 0 || a.branches[len(a.branches) - 1].active
+}
+
+// conditional handles .if, .ifdef, .ifndef, .elseif, .else and .endif. It returns true when
+// it handled the statement, or when the statement is in a branch that isn't assembled.
+func (a *assembly) conditional(index int, s statement) (bool, error){
+
+    switch s.operation {
+    case ".if", ".ifdef", ".ifndef", ".elseif", ".else", ".endif":
+
+        if s.label != "" {
+            return true, fmt.Errorf("%s can't have a label", s.operation)
+        }
+
+    default:
+        return !a.assembling(), nil
+    }
+
+    if s.operation == ".if" || s.operation == ".ifdef" || s.operation == ".ifndef" {
+
+        parent := a.assembling()
+        condition := false
+
+        if parent {
+
+            var err error
+            if condition, err = a.condition(index, s); err != nil {
+                return true, err
+            }
+        }
+
+        a.branches = append(a.branches, branch{file: s.file, line: s.line, parent: parent, active: parent && condition, taken: condition})
+
+        return true, nil
+    }
+
+    if len(a.branches) == 0 {
+        return true, fmt.Errorf("%s without .if", s.operation)
+    }
+
+    b := &a.branches[len(a.branches) - 1]
+
+    switch s.operation {
+    case ".elseif":
+
+        if b.seenElse {
+            return true, fmt.Errorf(".elseif after .else")
+        }
+
+        if !b.parent || b.taken {
+            b.active = false
+            return true, nil
+        }
+
+        condition, err := a.condition(index, s)
+        if err != nil {
+            return true, err
+        }
+
+        b.active, b.taken = condition, condition
+
+    case ".else":
+
+        if b.seenElse {
+            return true, fmt.Errorf(".else after .else")
+        }
+
+        b.seenElse = true
+        b.active = b.parent && !b.taken
+        b.taken = true
+
+    case ".endif":
+        a.branches = a.branches[:len(a.branches) - 1]
+    }
+
+    return true, nil
+}
+
+// condition evaluates the condition of s. The first pass does it, and the second one
+// reuses its result, so both assemble the same lines.
+func (a *assembly) condition(index int, s statement) (bool, error){
+
+    if a.pass == 2 {
+        return a.conditions[index], nil
+    }
+
+    var condition bool
+
+    switch s.operation {
+    case ".if", ".elseif":
+
+        value, err := a.evaluateNow(s.operand, s.operation)
+        if err != nil {
+            return false, err
+        }
+
+        condition = value != 0
+
+    // Symbols defined after the directive don't count, since the first pass hasn't seen them yet
+    case ".ifdef", ".ifndef":
+
+        name := strings.TrimSpace(s.operand)
+        if !isSymbol(name) {
+            return false, fmt.Errorf("%s needs a symbol, got %q", s.operation, name)
+        }
+
+        _, defined := a.symbols[a.qualify(name, s)]
+        condition = defined == (s.operation == ".ifdef")
+    }
+
+    a.conditions[index] = condition
+
+    return condition, nil
+}
+
+// loadBranch is a conditional block seen by load, which has to know which branches are
+// assembled before the passes run: macros, includes and macro calls of the branches that
+// aren't must be skipped. Only constants defined before the directive are known then.
+type loadBranch struct {
+
+    // Whether the block containing this one is loaded, and why it's not known, if so
+    parent bool
+    parentErr error
+
+    active bool
+    taken bool
+
+    // Why the condition of a branch can't be computed before the passes. The branches
+    // after it can't either.
+    err error
+}
+
+// loadState tells whether the lines being loaded are in a branch that is assembled,
+// or the reason it can't be known yet.
+func (a *assembly) loadState() (bool, error){
+
+    if len(a.loading) == 0 {
+        return true, nil
+    }
+
+    b := &a.loading[len(a.loading) - 1]
+
+    if !b.active {
+        return false, nil
+    }
+
+    if b.parentErr != nil {
+        return true, b.parentErr
+    }
+
+    return true, b.err
+}
+
+// loadConditional follows the conditional blocks for load. Errors in the directives are
+// left to the passes, which evaluate the conditions again, with the same result.
+func (a *assembly) loadConditional(s statement){
+
+    switch s.operation {
+    case ".if", ".ifdef", ".ifndef":
+
+        parent, parentErr := a.loadState()
+        b := loadBranch{parent: parent, parentErr: parentErr}
+
+        if parent {
+            b.active, b.err = true, a.loadCondition(s, &b.taken)
+            if b.err == nil {
+                b.active = b.taken
+            }
+        }
+
+        a.loading = append(a.loading, b)
+        return
+    }
+
+    if len(a.loading) == 0 {
+        return
+    }
+
+    b := &a.loading[len(a.loading) - 1]
+
+    switch {
+    case s.operation == ".endif":
+        a.loading = a.loading[:len(a.loading) - 1]
+
+    case !b.parent:
+
+    // The branches after one that can't be decided can't be either
+    case b.err != nil:
+        b.active = true
+
+    case s.operation == ".elseif" && !b.taken:
+
+        var condition bool
+
+        b.active, b.err = true, a.loadCondition(s, &condition)
+        if b.err == nil {
+            b.active, b.taken = condition, condition
+        }
+
+    case s.operation == ".else":
+        b.active = !b.taken
+        b.taken = true
+
+    default:
+        b.active = false
+    }
+}
+
+// loadCondition computes the condition of s with the constants defined before it,
+// or returns why it can't.
+func (a *assembly) loadCondition(s statement, condition *bool) error{
+
+    var err error
+
+    switch s.operation {
+    case ".if", ".elseif":
+
+        // The current address is only known by the passes
+        var result value
+        var known bool
+
+        result, known, err = evaluate(s.operand, value{imported: "*"}, a.loadLookup)
+
+        switch {
+        case err != nil:
+
+        case !known:
+            err = fmt.Errorf("it uses a symbol which isn't a constant defined before it")
+
+        case result.relocatable():
+            err = fmt.Errorf("it uses an address")
+
+        default:
+            *condition = result.n != 0
+        }
+
+    case ".ifdef", ".ifndef":
+
+        name := strings.TrimSpace(s.operand)
+        defined, ok := a.declared[name]
+
+        switch {
+        case !isSymbol(name) || strings.HasPrefix(name, "@"):
+            err = fmt.Errorf("%s needs a symbol which isn't local", s.operation)
+
+        case ok && !defined:
+            err = fmt.Errorf("%s may not be defined by then", name)
+
+        default:
+            *condition = ok == (s.operation == ".ifdef")
+        }
+    }
+
+    if err != nil {
+        return fmt.Errorf("%s at %s can't be decided before assembling: %w", s.operation, location(s.file, s.line), err)
+    }
+
+    return nil
+}
+
+// loadLookup returns the value of the constants load computed.
+func (a *assembly) loadLookup(name string) (value, bool){
+
+    symbol, ok := a.constants[name]
+    return symbol, ok
+}
+
+// declare records the symbol defined by s for the conditions load computes: its value when
+// it's a constant load can compute, and whether it's sure to be defined by then.
+func (a *assembly) declare(s statement, sure bool){
+
+    if s.label == "" || strings.HasPrefix(s.label, "@") {
+        return
+    }
+
+    if _, ok := a.declared[s.label]; ok {
+        return
+    }
+
+    if sure && (s.operation == "=" || s.operation == ".equ") {
+
+        result, known, err := evaluate(s.operand, value{imported: "*"}, a.loadLookup)
+
+        if err == nil && known && !result.relocatable() {
+            a.constants[s.label] = result
+        }else{
+            sure = false
+        }
+    }
+
+    a.declared[s.label] = sure
+}
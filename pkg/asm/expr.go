@@ -0,0 +1,288 @@
+package asm
+
+import (
+	"emulator/pkg/common"
+	"fmt"
+	"strings"
+)
+
+// value is the result of an expression. Objects are assembled before the linker places
+// their segments, so their addresses are relocatable: an offset from the start of a segment
+// of the module, or from an imported symbol. Absolute values have neither.
+type value struct {
+    n int
+
+    segment string
+    imported string
+
+    // '<' or '>' once the low or high byte of a relocatable value is taken
+    part byte
+}
+
+func absolute(n int) value{
+    return value{n: n}
+}
+
+func (v value) relocatable() bool{
+    return v.segment != "" || v.imported != ""
+}
+
+func (v value) sameBase(other value) bool{
+    return v.segment == other.segment && v.imported == other.imported && v.part == other.part
+}
+
+// combine applies a binary operator to values that may be relocatable. Only offsets can be
+// added to or subtracted from them, and the difference of two addresses of a segment is absolute.
+func combine(operator *common.BinaryOperator, x, y value) (value, error){
+
+    if !x.relocatable() && !y.relocatable() {
+        n, err := operator.Apply(x.n, y.n)
+        return absolute(n), err
+    }
+
+    if x.part != 0 || y.part != 0 {
+        return value{}, fmt.Errorf("can't compute with the low or high byte of a relocatable address, use <(address %s value)", operator.Symbol)
+    }
+
+    switch {
+    case operator.Symbol == "+" && !(x.relocatable() && y.relocatable()):
+
+        if y.relocatable() {
+            x, y = y, x
+        }
+
+        x.n += y.n
+        return x, nil
+
+    case operator.Symbol == "-" && !y.relocatable():
+        x.n -= y.n
+        return x, nil
+
+    case operator.Symbol == "-" && x.sameBase(y):
+        return absolute(x.n - y.n), nil
+    }
+
+    return value{}, fmt.Errorf("operator %s can't be applied to relocatable addresses", operator.Symbol)
+}
+
+// expression parses and evaluates an expression of the operand of a line.
+// Symbols not defined yet make the result unknown, rather than failing,
+// since they may be defined later in the source.
+type expression struct {
+    text string
+    pos int
+
+    lookup func(name string) (value, bool)
+    pc value
+
+    known bool
+}
+
+// evaluate computes the value of text. Known is false when it uses
+// a symbol that isn't defined (yet).
+func evaluate(text string, pc value, lookup func(name string) (value, bool)) (result value, known bool, err error){
+
+    e := &expression{text: text, lookup: lookup, pc: pc, known: true}
+
+    e.skipSpaces()
+    if e.pos == len(e.text) {
+        return value{}, false, fmt.Errorf("missing expression")
+    }
+
+    result, err = e.binary(0)
+    if err != nil {
+        return value{}, false, err
+    }
+
+    e.skipSpaces()
+    if e.pos != len(e.text) {
+        return value{}, false, fmt.Errorf("unexpected %q in expression %q", e.text[e.pos:], text)
+    }
+
+    return result, e.known, nil
+}
+
+func (e *expression) skipSpaces(){
+
+    for e.pos < len(e.text) && (e.text[e.pos] == ' ' || e.text[e.pos] == '\t') {
+        e.pos++
+    }
+}
+
+// binary parses the operators with precedence level or higher.
+func (e *expression) binary(level int) (value, error){
+
+    if level == len(common.BinaryOperators) {
+        return e.unary()
+    }
+
+    x, err := e.binary(level + 1)
+    if err != nil {
+        return value{}, err
+    }
+
+    for {
+        e.skipSpaces()
+
+        operator := e.matchOperator(common.BinaryOperators[level])
+        if operator == nil {
+            return x, nil
+        }
+
+        y, err := e.binary(level + 1)
+        if err != nil {
+            return value{}, err
+        }
+
+        if x, err = combine(operator, x, y); err != nil {
+            return value{}, err
+        }
+    }
+}
+
+// matchOperator consumes the operator at the current position if it belongs to operators.
+func (e *expression) matchOperator(operators []common.BinaryOperator) *common.BinaryOperator{
+
+    operator := common.MatchOperator(e.text[e.pos:], operators)
+    if operator != nil {
+        e.pos += len(operator.Symbol)
+    }
+
+    return operator
+}
+
+// unary parses the unary operators: - negates, ~ complements, ! is the logical not,
+// < and > take the low and high byte of a word.
+func (e *expression) unary() (value, error){
+
+    e.skipSpaces()
+
+    if e.pos == len(e.text) || !strings.ContainsRune("-~!<>", rune(e.text[e.pos])) {
+        return e.term()
+    }
+
+    operator := e.text[e.pos]
+    e.pos++
+
+    operand, err := e.unary()
+    if err != nil {
+        return value{}, err
+    }
+
+    // The bytes of relocatable addresses are only known once the linker placed them
+    if operand.relocatable() {
+
+        if (operator != '<' && operator != '>') || operand.part != 0 {
+            return value{}, fmt.Errorf("operator %c can't be applied to relocatable addresses", operator)
+        }
+
+        operand.part = operator
+        return operand, nil
+    }
+
+    n := operand.n
+
+    switch operator {
+    case '-':
+        n = -n
+    case '~':
+        n = ^n
+    case '!':
+        n = common.Truth(n == 0)
+    case '<':
+        n = n & 0xFF
+    case '>':
+        n = (n >> 8) & 0xFF
+    }
+
+    return absolute(n), nil
+}
+
+// term parses a number, a character, a symbol, * for the current address,
+// or an expression in parentheses.
+func (e *expression) term() (value, error){
+
+    e.skipSpaces()
+
+    if e.pos == len(e.text) {
+        return value{}, fmt.Errorf("missing value at the end of %q", e.text)
+    }
+
+    c := e.text[e.pos]
+
+    switch {
+    case c == '(':
+
+        e.pos++
+
+        result, err := e.binary(0)
+        if err != nil {
+            return value{}, err
+        }
+
+        e.skipSpaces()
+        if e.pos == len(e.text) || e.text[e.pos] != ')' {
+            return value{}, fmt.Errorf("missing ) in %q", e.text)
+        }
+        e.pos++
+
+        return result, nil
+
+    case c == '*':
+        e.pos++
+        return e.pc, nil
+
+    case c == '\'':
+
+        if e.pos + 2 >= len(e.text) || e.text[e.pos + 2] != '\'' {
+            return value{}, fmt.Errorf("invalid character in %q", e.text)
+        }
+
+        character := int(e.text[e.pos + 1])
+        e.pos += 3
+
+        return absolute(character), nil
+
+    case c == '$' || c == '%' || isDigit(c):
+
+        start := e.pos
+        e.pos++
+
+        for e.pos < len(e.text) && isSymbolChar(e.text[e.pos]) {
+            e.pos++
+        }
+
+        n, err := common.ParseNumber(e.text[start:e.pos])
+        return absolute(n), err
+
+    case isSymbolStart(c):
+
+        start := e.pos
+
+        for e.pos < len(e.text) && isSymbolChar(e.text[e.pos]) {
+            e.pos++
+        }
+
+        symbol, known := e.lookup(e.text[start:e.pos])
+        if !known {
+            e.known = false
+        }
+
+        return symbol, nil
+    }
+
+    return value{}, fmt.Errorf("unexpected %q in expression %q", e.text[e.pos:], e.text)
+}
+
+func isDigit(c byte) bool{
+    return c >= '0' && c <= '9'
+}
+
+// Symbols starting with @ are local, see assembly.qualify.
+func isSymbolStart(c byte) bool{
+    return c == '_' || c == '@' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isSymbolChar(c byte) bool{
+    return isSymbolStart(c) || isDigit(c)
+}
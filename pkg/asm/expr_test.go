@@ -0,0 +1,88 @@
+package asm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvaluateExpressions(t *testing.T){
+
+    symbols := map[string]int{"table": 0x1234, "count": 3}
+
+    lookup := func(name string) (value, bool){
+        n, ok := symbols[name]
+        return absolute(n), ok
+    }
+
+    tests := []struct {
+        text string
+        value int
+    }{
+        {"<table", 0x34},
+        {">table", 0x12},
+        {"<table+1", 0x35},
+        {"<(table+$FF)", 0x33},
+        {"2 + 3 * 4", 14},
+        {"(2 + 3) * 4", 20},
+        {"7 / 2", 3},
+        {"10 % count", 1},
+        {"%101 % 2", 1},
+        {"$F0 | $0F", 0xFF},
+        {"$FF & ~$0F", 0xF0},
+        {"$FF ^ $0F", 0xF0},
+        {"1 << 4", 16},
+        {"$100 >> 4", 16},
+        {"-1", -1},
+        {"- -1", 1},
+        {"'A' + 1", 66},
+        {"* + 2", 0x0202},
+        {"count > 2", 1},
+        {"count <= 2", 0},
+        {"count == 3 && table != 0", 1},
+        {"count < 2 || 0", 0},
+        {"!count", 0},
+        {"1 << 2 < 5", 1},
+    }
+
+    for _, test := range tests {
+
+        result, known, err := evaluate(test.text, absolute(0x0200), lookup)
+
+        if err != nil || !known || result != absolute(test.value) {
+            t.Errorf("%s: expected %d but got %+v, known: %v, err: %v", test.text, test.value, result, known, err)
+        }
+    }
+}
+
+func TestEvaluateUnknownSymbol(t *testing.T){
+
+    lookup := func(name string) (value, bool){ return value{}, false }
+
+    _, known, err := evaluate("later + 1", value{}, lookup)
+
+    if err != nil || known {
+        t.Error("Undefined symbols should make the value unknown, got known: ", known, ", err: ", err)
+    }
+}
+
+func TestEvaluateErrors(t *testing.T){
+
+    lookup := func(name string) (value, bool){ return value{}, true }
+
+    tests := map[string]string{
+        "1 / 0": "division by zero",
+        "(1 + 2": "missing )",
+        "1 +": "missing value",
+        "$": "invalid number",
+        "1 2": "unexpected",
+    }
+
+    for text, message := range tests {
+
+        _, _, err := evaluate(text, value{}, lookup)
+
+        if err == nil || !strings.Contains(err.Error(), message) {
+            t.Error(text, ": expected an error containing ", message, " but got: ", err)
+        }
+    }
+}
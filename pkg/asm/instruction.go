@@ -0,0 +1,232 @@
+package asm
+
+import (
+	"emulator/pkg/instructions"
+	"fmt"
+	"strings"
+)
+
+// operand is the operand of an instruction, split according to its syntax.
+type operand struct {
+
+    // Modes the syntax can stand for, the zero page one first when there's a choice.
+    modes []instructions.Mode
+
+    // Expressions of the operand, two for BBR and BBS.
+    expressions []string
+}
+
+// parseOperand recognises the syntax of the operand of an instruction:
+//
+//	(none)  A  #value  value  value,X  value,Y  (value)  (value,X)  (value),Y  value,target
+func parseOperand(text string) operand{
+
+    text = strings.TrimSpace(text)
+    upper := strings.ToUpper(strings.ReplaceAll(text, " ", ""))
+
+    switch {
+    case text == "":
+        return operand{modes: []instructions.Mode{instructions.Implied, instructions.Accumulator}}
+
+    case upper == "A":
+        return operand{modes: []instructions.Mode{instructions.Accumulator}}
+
+    case strings.HasPrefix(text, "#"):
+        return operand{modes: []instructions.Mode{instructions.Immediate}, expressions: []string{text[1:]}}
+
+    case strings.HasPrefix(upper, "(") && strings.HasSuffix(upper, ",X)"):
+        inner := text[1:strings.LastIndex(text, ",")]
+        return operand{modes: []instructions.Mode{instructions.IndexedIndirect, instructions.AbsoluteIndexedIndirect}, expressions: []string{inner}}
+
+    case strings.HasPrefix(upper, "(") && strings.HasSuffix(upper, "),Y"):
+        inner := text[1:strings.LastIndex(text, ")")]
+        return operand{modes: []instructions.Mode{instructions.IndirectIndexed}, expressions: []string{inner}}
+
+    // (value) is indirect only if the parenthesis opened first is the one closed last,
+    // ($10+2)*2 is a plain expression.
+    case strings.HasPrefix(text, "(") && closingParenthesis(text) == len(text) - 1:
+        return operand{modes: []instructions.Mode{instructions.ZeroPageIndirect, instructions.Indirect}, expressions: []string{text[1:len(text) - 1]}}
+    }
+
+    arguments := splitArguments(text)
+
+    if len(arguments) == 2 {
+
+        switch strings.ToUpper(arguments[1]) {
+        case "X":
+            return operand{modes: []instructions.Mode{instructions.ZeroPageX, instructions.AbsoluteX}, expressions: arguments[:1]}
+        case "Y":
+            return operand{modes: []instructions.Mode{instructions.ZeroPageY, instructions.AbsoluteY}, expressions: arguments[:1]}
+        }
+
+        return operand{modes: []instructions.Mode{instructions.ZeroPageRelative}, expressions: arguments}
+    }
+
+    return operand{modes: []instructions.Mode{instructions.ZeroPage, instructions.Absolute, instructions.Relative}, expressions: arguments}
+}
+
+// closingParenthesis returns the index of the parenthesis closing the one at the start of text.
+func closingParenthesis(text string) int{
+
+    depth := 0
+
+    for i := 0; i < len(text); i++ {
+
+        switch text[i] {
+        case '(':
+            depth++
+        case ')':
+            depth--
+            if depth == 0 {
+                return i
+            }
+        }
+    }
+
+    return -1
+}
+
+func (a *assembly) instruction(index int, s statement) error{
+
+    modes, ok := a.opcodes[s.operation]
+    if !ok {
+        return fmt.Errorf("unknown instruction %s", s.operation)
+    }
+
+    operand := parseOperand(s.operand)
+
+    // Values of the expressions, and whether they're all known
+    values := make([]value, len(operand.expressions))
+    known := true
+
+    for i, expression := range operand.expressions {
+
+        result, resultKnown, err := a.evaluate(expression)
+        if err != nil {
+            return err
+        }
+
+        values[i] = result
+        known = known && resultKnown
+    }
+
+    mode, ok := a.modes[index]
+    if !ok {
+
+        zeroPage := known && len(values) > 0 && a.fitsZeroPage(values[0])
+
+        var err error
+        mode, err = chooseMode(s.operation, modes, operand.modes, zeroPage)
+        if err != nil {
+            return err
+        }
+
+        a.modes[index] = mode
+    }
+
+    if err := a.emit(modes[mode]); err != nil {
+        return err
+    }
+
+    switch mode {
+    case instructions.Implied, instructions.Accumulator:
+        return nil
+
+    case instructions.Relative:
+        return a.emitOffset(values[0], a.pc + 1)
+
+    case instructions.ZeroPageRelative:
+
+        if err := a.emitByte(values[0]); err != nil {
+            return err
+        }
+
+        return a.emitOffset(values[1], a.pc + 1)
+
+    case instructions.Absolute, instructions.AbsoluteX, instructions.AbsoluteY,
+         instructions.Indirect, instructions.AbsoluteIndexedIndirect:
+        return a.emitWord(values[0])
+    }
+
+    if mode != instructions.Immediate && a.pass == 2 && !a.fitsZeroPage(values[0]) {
+
+        if values[0].relocatable() {
+            return fmt.Errorf("address %s is not in the zero page", describe(values[0]))
+        }
+
+        return fmt.Errorf("address $%X is not in the zero page", values[0].n)
+    }
+
+    return a.emitByte(values[0])
+}
+
+// fitsZeroPage reports whether an address is in the zero page. Relocatable addresses
+// are when they belong to the ZEROPAGE segment or come from .importzp.
+func (a *assembly) fitsZeroPage(address value) bool{
+
+    if address.relocatable() {
+        return a.zeroPage(address)
+    }
+
+    return address.n >= 0 && address.n <= 0xFF
+}
+
+// chooseMode picks the addressing mode of an instruction among the ones its operand
+// can stand for. The zero page is used when the address is known and fits,
+// or when the instruction has no absolute mode.
+func chooseMode(mnemonic string, available map[instructions.Mode]byte, candidates []instructions.Mode, zeroPage bool) (instructions.Mode, error){
+
+    var supported []instructions.Mode
+
+    for _, mode := range candidates {
+        if _, ok := available[mode]; ok {
+            supported = append(supported, mode)
+        }
+    }
+
+    switch len(supported) {
+    case 0:
+        return 0, fmt.Errorf("%s doesn't support the %s addressing mode", mnemonic, candidates[0])
+
+    case 1:
+        return supported[0], nil
+    }
+
+    // Both the zero page and the absolute mode are available
+    if zeroPage {
+        return supported[0], nil
+    }
+
+    return supported[1], nil
+}
+
+// emitOffset emits the branch offset to target, from the address of the next instruction.
+// In objects, branches can only go to the segment they're in, whose address doesn't matter.
+func (a *assembly) emitOffset(target value, next int) error{
+
+    if a.pass == 2 && !target.sameBase(value{segment: a.segment}) {
+
+        if target.relocatable() {
+            return fmt.Errorf("branch to %s, which is not in segment %s", describe(target), a.segment)
+        }
+
+        return fmt.Errorf("branch to $%04X, but the address of segment %s is only known once linked", target.n, a.segment)
+    }
+
+    offset := target.n - next
+
+    if a.pass == 2 && (offset < -128 || offset > 127) {
+        return fmt.Errorf("branch to $%04X is out of range by %d bytes", target.n, outOfRange(offset))
+    }
+
+    return a.emit(byte(offset))
+}
+
+func outOfRange(offset int) int{
+
+    if offset < 0 {
+        return -128 - offset
+    }
+
+    return offset - 127
+}
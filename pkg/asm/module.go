@@ -0,0 +1,163 @@
+package asm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// module handles the directives of objects: .segment, .import, .importzp and .export.
+func (a *assembly) module(s statement) error{
+
+    if s.label != "" {
+        return fmt.Errorf("%s can't have a label", s.operation)
+    }
+
+    if s.operation == ".segment" {
+
+        name := strings.Trim(strings.TrimSpace(s.operand), "\"")
+        if !isSymbol(name) || strings.HasPrefix(name, "@") {
+            return fmt.Errorf("invalid segment name %q", s.operand)
+        }
+
+        // Each segment continues where it was left
+        a.segments[a.segment] = a.pc
+        a.segment, a.pc = name, a.segments[name]
+
+        return nil
+    }
+
+    if s.operand == "" {
+        return fmt.Errorf("%s needs the names of the symbols", s.operation)
+    }
+
+    for _, name := range splitArguments(s.operand) {
+
+        if !isSymbol(name) || strings.HasPrefix(name, "@") {
+            return fmt.Errorf("%s needs the names of the symbols, got %q", s.operation, name)
+        }
+
+        if s.operation == ".export" {
+
+            // Exports are checked once all the symbols are defined
+            if a.pass == 1 {
+                export := s
+                export.operand = name
+                a.exports = append(a.exports, export)
+            }
+
+            continue
+        }
+
+        if err := a.define(name, value{imported: name}); err != nil {
+            return err
+        }
+
+        if a.pass == 1 {
+            a.imports[name] = s.operation == ".importzp"
+            a.importOrder = append(a.importOrder, name)
+        }
+    }
+
+    return nil
+}
+
+// relocate records that the bytes emitted next are the address v, which the linker computes.
+func (a *assembly) relocate(kind RelocationKind, v value){
+
+    if a.pass != 2 {
+        return
+    }
+
+    current := a.block()
+
+    current.relocations = append(current.relocations, Relocation{
+        Offset: a.pc,
+        Kind: kind,
+        Segment: v.segment,
+        Import: v.imported,
+        Addend: v.n,
+        File: a.current.file,
+        Line: a.current.line,
+    })
+}
+
+// zeroPage reports whether a relocatable address is in the zero page once linked:
+// a label of the ZEROPAGE segment, or a symbol imported with .importzp.
+func (a *assembly) zeroPage(v value) bool{
+
+    if v.part != 0 {
+        return false
+    }
+
+    return v.segment == ZeroPageSegment || (v.imported != "" && a.imports[v.imported])
+}
+
+// describe prints a relocatable address, like CODE+$10 or print-$1.
+func describe(v value) string{
+
+    base := v.segment
+    if v.imported != "" {
+        base = v.imported
+    }
+
+    switch {
+    case v.n > 0:
+        return fmt.Sprintf("%s+$%X", base, v.n)
+    case v.n < 0:
+        return fmt.Sprintf("%s-$%X", base, -v.n)
+    }
+
+    return base
+}
+
+// object collects the segments, symbols and imports assembled by the second pass.
+func (a *assembly) object() (*Object, error){
+
+    object := &Object{Name: a.name}
+
+    for _, b := range a.blocks {
+        if len(b.code) > 0 {
+            object.Segments = append(object.Segments, Segment{Name: b.segment, Code: b.code, Relocations: b.relocations})
+        }
+    }
+
+    exported := map[string]bool{}
+
+    for _, s := range a.exports {
+
+        symbol, ok := a.symbols[s.operand]
+
+        switch {
+        case !ok:
+            return nil, a.lineError(s, fmt.Errorf("exported symbol %s is not defined", s.operand))
+        case symbol.imported != "":
+            return nil, a.lineError(s, fmt.Errorf("%s is imported, it can't be exported too", s.operand))
+        case symbol.part != 0:
+            return nil, a.lineError(s, fmt.Errorf("%s is the low or high byte of an address, it can't be exported", s.operand))
+        case exported[s.operand]:
+            return nil, a.lineError(s, fmt.Errorf("%s is exported twice", s.operand))
+        }
+
+        exported[s.operand] = true
+    }
+
+    for name, symbol := range a.symbols {
+
+        if symbol.imported != "" || symbol.part != 0 {
+            continue
+        }
+
+        object.Symbols = append(object.Symbols, Symbol{Name: name, Segment: symbol.segment, Value: symbol.n, Exported: exported[name]})
+    }
+
+    sort.Slice(object.Symbols, func(i, j int) bool{
+        return object.Symbols[i].Name < object.Symbols[j].Name
+    })
+
+    for _, name := range a.importOrder {
+        object.Imports = append(object.Imports, Import{Name: name, ZeroPage: a.imports[name]})
+    }
+
+    return object, nil
+}
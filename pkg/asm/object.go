@@ -0,0 +1,339 @@
+package asm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ZeroPageSegment is the segment whose labels are zero page addresses. Instructions using
+// them get their zero page addressing modes, like with .importzp symbols.
+const ZeroPageSegment = "ZEROPAGE"
+
+// Object is a module assembled on its own. Its segments don't have an address yet,
+// the linker places them and patches the bytes listed by their relocations.
+type Object struct {
+    Name string
+
+    Segments []Segment
+
+    // Labels and constants defined by the module, sorted by name
+    Symbols []Symbol
+
+    // Symbols the module uses but other modules define
+    Imports []Import
+}
+
+// Segment is the code a module assembles in a segment, from .segment "NAME".
+type Segment struct {
+    Name string
+    Code []byte
+    Relocations []Relocation
+}
+
+// RelocationKind tells which bytes of an address a relocation patches.
+type RelocationKind byte
+
+const (
+    // RelocateWord patches two bytes with the address, little endian.
+    RelocateWord RelocationKind = iota
+
+    // RelocateZeroPage patches a byte with the address, which must be in the zero page.
+    RelocateZeroPage
+
+    // RelocateLow and RelocateHigh patch a byte with the low or the high byte of the address.
+    RelocateLow
+    RelocateHigh
+)
+
+func (kind RelocationKind) String() string{
+
+    switch kind {
+    case RelocateWord:
+        return "word"
+    case RelocateZeroPage:
+        return "zero page"
+    case RelocateLow:
+        return "low byte"
+    case RelocateHigh:
+        return "high byte"
+    }
+
+    return fmt.Sprintf("RelocationKind(%d)", byte(kind))
+}
+
+// Relocation is an address the assembler couldn't know. The address is the start of
+// Segment, in the same module, or the value of the imported symbol Import, plus Addend.
+type Relocation struct {
+
+    // Where the bytes to patch are, from the start of the segment
+    Offset int
+
+    Kind RelocationKind
+
+    Segment string
+    Import string
+    Addend int
+
+    // Where the address is used in the source, for errors
+    File string
+    Line int
+}
+
+// Symbol is a label or a constant of a module. Labels are relative to the start of
+// their segment, constants have no segment.
+type Symbol struct {
+    Name string
+    Segment string
+    Value int
+
+    // Exported symbols can be imported by the other modules
+    Exported bool
+}
+
+// Import is a symbol declared by .import, or .importzp when it's a zero page address.
+type Import struct {
+    Name string
+    ZeroPage bool
+}
+
+// Objects start with this, followed by the version of the format.
+var objectMagic = []byte("O65\x1A")
+
+const objectVersion = 1
+
+// MarshalBinary encodes the object in the format of object files.
+// Integers are varints, strings and byte slices are prefixed with their length.
+func (object *Object) MarshalBinary() ([]byte, error){
+
+    w := &objectWriter{}
+
+    w.Write(objectMagic)
+    w.uint(objectVersion)
+    w.string(object.Name)
+
+    w.uint(len(object.Segments))
+    for _, segment := range object.Segments {
+
+        w.string(segment.Name)
+        w.bytes(segment.Code)
+
+        w.uint(len(segment.Relocations))
+        for _, r := range segment.Relocations {
+            w.uint(r.Offset)
+            w.uint(int(r.Kind))
+            w.string(r.Segment)
+            w.string(r.Import)
+            w.int(r.Addend)
+            w.string(r.File)
+            w.uint(r.Line)
+        }
+    }
+
+    w.uint(len(object.Symbols))
+    for _, symbol := range object.Symbols {
+        w.string(symbol.Name)
+        w.string(symbol.Segment)
+        w.int(symbol.Value)
+        w.bool(symbol.Exported)
+    }
+
+    w.uint(len(object.Imports))
+    for _, i := range object.Imports {
+        w.string(i.Name)
+        w.bool(i.ZeroPage)
+    }
+
+    return w.Bytes(), nil
+}
+
+// UnmarshalBinary decodes an object encoded by MarshalBinary.
+func (object *Object) UnmarshalBinary(data []byte) error{
+
+    if !bytes.HasPrefix(data, objectMagic) {
+        return fmt.Errorf("not an object file")
+    }
+
+    r := &objectReader{data: data[len(objectMagic):]}
+
+    if version := r.uint(); r.err == nil && version != objectVersion {
+        return fmt.Errorf("object file version %d, only version %d is supported", version, objectVersion)
+    }
+
+    decoded := Object{Name: r.string()}
+
+    for n := r.count(); n > 0; n-- {
+
+        segment := Segment{Name: r.string(), Code: r.bytes()}
+
+        for n := r.count(); n > 0; n-- {
+            segment.Relocations = append(segment.Relocations, Relocation{
+                Offset: r.uint(),
+                Kind: RelocationKind(r.uint()),
+                Segment: r.string(),
+                Import: r.string(),
+                Addend: r.int(),
+                File: r.string(),
+                Line: r.uint(),
+            })
+        }
+
+        decoded.Segments = append(decoded.Segments, segment)
+    }
+
+    for n := r.count(); n > 0; n-- {
+        decoded.Symbols = append(decoded.Symbols, Symbol{Name: r.string(), Segment: r.string(), Value: r.int(), Exported: r.bool()})
+    }
+
+    for n := r.count(); n > 0; n-- {
+        decoded.Imports = append(decoded.Imports, Import{Name: r.string(), ZeroPage: r.bool()})
+    }
+
+    if r.err != nil {
+        return fmt.Errorf("corrupted object file: %w", r.err)
+    }
+
+    *object = decoded
+
+    return nil
+}
+
+// WriteFile saves the object to an object file.
+func (object *Object) WriteFile(path string) error{
+
+    data, err := object.MarshalBinary()
+    if err != nil {
+        return err
+    }
+
+    return os.WriteFile(path, data, 0644)
+}
+
+// ReadObject loads an object file.
+func ReadObject(path string) (*Object, error){
+
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    object := &Object{}
+
+    if err := object.UnmarshalBinary(data); err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+
+    return object, nil
+}
+
+type objectWriter struct {
+    bytes.Buffer
+}
+
+func (w *objectWriter) uint(n int){
+    w.Write(binary.AppendUvarint(nil, uint64(n)))
+}
+
+func (w *objectWriter) int(n int){
+    w.Write(binary.AppendVarint(nil, int64(n)))
+}
+
+func (w *objectWriter) bool(b bool){
+
+    if b {
+        w.WriteByte(1)
+    }else{
+        w.WriteByte(0)
+    }
+}
+
+func (w *objectWriter) bytes(data []byte){
+    w.uint(len(data))
+    w.Write(data)
+}
+
+func (w *objectWriter) string(text string){
+    w.bytes([]byte(text))
+}
+
+// objectReader decodes the fields of an object file. The first error
+// sticks, and makes the following reads return zero values.
+type objectReader struct {
+    data []byte
+    err error
+}
+
+func (r *objectReader) uint() int{
+
+    n, size := binary.Uvarint(r.data)
+    if r.err != nil || size <= 0 || n > 1 << 31 {
+        r.fail()
+        return 0
+    }
+
+    r.data = r.data[size:]
+    return int(n)
+}
+
+func (r *objectReader) int() int{
+
+    n, size := binary.Varint(r.data)
+    if r.err != nil || size <= 0 || n > 1 << 31 || n < -(1 << 31) {
+        r.fail()
+        return 0
+    }
+
+    r.data = r.data[size:]
+    return int(n)
+}
+
+// count reads the length of a list, which can't be more than the bytes left.
+func (r *objectReader) count() int{
+
+    n := r.uint()
+    if n > len(r.data) {
+        r.fail()
+        return 0
+    }
+
+    return n
+}
+
+func (r *objectReader) bool() bool{
+
+    if r.err != nil || len(r.data) == 0 || r.data[0] > 1 {
+        r.fail()
+        return false
+    }
+
+    b := r.data[0] == 1
+    r.data = r.data[1:]
+
+    return b
+}
+
+func (r *objectReader) bytes() []byte{
+
+    n := r.count()
+    if r.err != nil {
+        return nil
+    }
+
+    data := append([]byte(nil), r.data[:n]...)
+    r.data = r.data[n:]
+
+    return data
+}
+
+func (r *objectReader) string() string{
+    return string(r.bytes())
+}
+
+func (r *objectReader) fail(){
+
+    if r.err == nil {
+        r.err = io.ErrUnexpectedEOF
+    }
+}
@@ -0,0 +1,188 @@
+package asm
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const module = `
+        .import print
+        .importzp ptr
+        .export start, count
+
+count = 3
+
+        .segment "ZEROPAGE"
+index:  .res 1
+
+        .segment "CODE"
+start:  LDX #count
+@loop:  STX index
+        LDA #<message
+        STA ptr
+        LDA #>(message + 1)
+        JSR print
+        DEX
+        BNE @loop
+        RTS
+
+        .segment "RODATA"
+message: .byte "HI", 0
+        .word start + 2, * - message
+`
+
+func InitModule(t *testing.T) *Object{
+
+    t.Helper()
+
+    object, err := (&Assembler{}).AssembleObject("module.s", module)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return object
+}
+
+func TestAssembleObjectSegments(t *testing.T){
+
+    object := InitModule(t)
+
+    if len(object.Segments) != 3 {
+        t.Fatal("Expected 3 segments but got: ", object.Segments)
+    }
+
+    zeroPage, code, rodata := object.Segments[0], object.Segments[1], object.Segments[2]
+
+    if zeroPage.Name != ZeroPageSegment || len(zeroPage.Code) != 1 {
+        t.Error("Unexpected zero page segment: ", zeroPage)
+    }
+
+    // Addresses are left to zero, the linker writes them
+    expected := []byte{
+        0xA2, 0x03,
+        0x86, 0x00,
+        0xA9, 0x00,
+        0x85, 0x00,
+        0xA9, 0x00,
+        0x20, 0x00, 0x00,
+        0xCA,
+        0xD0, 0xF2,
+        0x60,
+    }
+
+    if code.Name != "CODE" || !bytes.Equal(code.Code, expected) {
+        t.Errorf("Expected % X but got % X", expected, code.Code)
+    }
+
+    relocations := []Relocation{
+        {Offset: 3, Kind: RelocateZeroPage, Segment: ZeroPageSegment, File: "module.s", Line: 13},
+        {Offset: 5, Kind: RelocateLow, Segment: "RODATA", File: "module.s", Line: 14},
+        {Offset: 7, Kind: RelocateZeroPage, Import: "ptr", File: "module.s", Line: 15},
+        {Offset: 9, Kind: RelocateHigh, Segment: "RODATA", Addend: 1, File: "module.s", Line: 16},
+        {Offset: 11, Kind: RelocateWord, Import: "print", File: "module.s", Line: 17},
+    }
+
+    if !reflect.DeepEqual(code.Relocations, relocations) {
+        t.Error("Unexpected relocations: ", code.Relocations)
+    }
+
+    // The difference of two addresses of the same segment is known
+    if !bytes.Equal(rodata.Code, []byte{'H', 'I', 0, 0x00, 0x00, 0x05, 0x00}) {
+        t.Errorf("Unexpected RODATA: % X", rodata.Code)
+    }
+
+    if len(rodata.Relocations) != 1 || rodata.Relocations[0] != (Relocation{Offset: 3, Kind: RelocateWord, Segment: "CODE", Addend: 2, File: "module.s", Line: 24}) {
+        t.Error("Unexpected RODATA relocations: ", rodata.Relocations)
+    }
+}
+
+func TestAssembleObjectSymbols(t *testing.T){
+
+    object := InitModule(t)
+
+    symbols := []Symbol{
+        {Name: "count", Value: 3, Exported: true},
+        {Name: "index", Segment: ZeroPageSegment},
+        {Name: "message", Segment: "RODATA"},
+        {Name: "start", Segment: "CODE", Exported: true},
+        {Name: "start@loop", Segment: "CODE", Value: 2},
+    }
+
+    if !reflect.DeepEqual(object.Symbols, symbols) {
+        t.Error("Unexpected symbols: ", object.Symbols)
+    }
+
+    imports := []Import{{Name: "print"}, {Name: "ptr", ZeroPage: true}}
+
+    if !reflect.DeepEqual(object.Imports, imports) {
+        t.Error("Unexpected imports: ", object.Imports)
+    }
+}
+
+func TestObjectFileRoundTrip(t *testing.T){
+
+    object := InitModule(t)
+
+    data, err := object.MarshalBinary()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    decoded := &Object{}
+    if err := decoded.UnmarshalBinary(data); err != nil {
+        t.Fatal(err)
+    }
+
+    if !reflect.DeepEqual(decoded, object) {
+        t.Errorf("Decoded object differs:\n%+v\n%+v", decoded, object)
+    }
+
+    // Truncated files are detected, wherever they're cut
+    for size := len(objectMagic); size < len(data); size++ {
+        if err := decoded.UnmarshalBinary(data[:size]); err == nil {
+            t.Fatal("Truncating the object to ", size, " bytes should fail")
+        }
+    }
+
+    if err := decoded.UnmarshalBinary([]byte("not an object")); err == nil {
+        t.Error("Expected an error for a file which is not an object")
+    }
+}
+
+func CheckObjectError(t *testing.T, source string, line int, message string){
+
+    t.Helper()
+
+    _, err := (&Assembler{}).AssembleObject("", source)
+
+    if err == nil || !strings.Contains(err.Error(), message) || !strings.HasPrefix(err.Error(), location("", line)) {
+        t.Error("Expected an error containing ", message, " at line ", line, " but got: ", err)
+    }
+}
+
+func TestAssembleObjectErrors(t *testing.T){
+
+    CheckObjectError(t, " .org $1000", 1, "the linker places their segments")
+    CheckObjectError(t, "data: .byte 1\n LDA #data", 2, "use < or > for its low or high byte")
+    CheckObjectError(t, " .import far\n BNE far", 2, "not in segment CODE")
+    CheckObjectError(t, "here:\n .segment \"DATA\"\n BNE here", 3, "not in segment DATA")
+    CheckObjectError(t, " BNE $1000", 1, "only known once linked")
+    CheckObjectError(t, " .export missing", 1, "exported symbol missing is not defined")
+    CheckObjectError(t, " .import shared\n .export shared", 2, "is imported")
+    CheckObjectError(t, "a: .word a * 2", 1, "operator * can't be applied")
+    CheckObjectError(t, "a: .word <a + 1", 1, "use <(address + value)")
+    CheckObjectError(t, "a: .res a", 1, ".res needs a value known before linking")
+    CheckObjectError(t, "a:\n .segment \"DATA\"\nb: .word b - a", 3, "operator - can't be applied")
+
+    CheckError(t, " .segment \"CODE\"", 1, "only available when assembling objects")
+    CheckError(t, " .import print", 1, "only available when assembling objects")
+}
+
+func TestReserve(t *testing.T){
+
+    CheckCode(t, " .res 3\n .res 2, $EA\n NOP", 0, 0, 0, 0xEA, 0xEA, 0xEA)
+    CheckError(t, " .res -1", 1, "out of range")
+    CheckError(t, " .res later\nlater = 2", 1, "defined after it")
+}
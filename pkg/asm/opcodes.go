@@ -0,0 +1,42 @@
+package asm
+
+import "emulator/pkg/instructions"
+
+// opcodeSet maps each mnemonic and addressing mode to its opcode.
+type opcodeSet map[string]map[instructions.Mode]byte
+
+var nmosOpcodes = newOpcodeSet(&instructions.NMOSOpcodes, false)
+var cmosOpcodes = newOpcodeSet(&instructions.CMOSOpcodes, true)
+
+// newOpcodeSet builds the set from an opcode table. The NMOS illegal opcodes can be
+// assembled, the reserved NOPs of the 65C02 can't since they are not instructions.
+// When an instruction has more than one opcode, the documented and then the lowest one is used.
+func newOpcodeSet(table *[256]instructions.Opcode, cmos bool) opcodeSet{
+
+    set := opcodeSet{}
+
+    for code, opcode := range table {
+
+        if cmos && opcode.Status == instructions.Illegal {
+            continue
+        }
+
+        modes := set[opcode.Mnemonic]
+        if modes == nil {
+            modes = map[instructions.Mode]byte{}
+            set[opcode.Mnemonic] = modes
+        }
+
+        if previous, ok := modes[opcode.Mode]; ok && table[previous].Status == instructions.Documented {
+            continue
+        }
+
+        if _, ok := modes[opcode.Mode]; ok && opcode.Status != instructions.Documented {
+            continue
+        }
+
+        modes[opcode.Mode] = byte(code)
+    }
+
+    return set
+}
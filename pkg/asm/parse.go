@@ -0,0 +1,171 @@
+package asm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// statement is a line of source split into its fields:
+//
+//	label:  operation  operand  ; comment
+//
+// Operation is an upper case mnemonic, a lower case directive starting with a dot,
+// or = for a constant definition like "size = 16".
+type statement struct {
+    file string
+    line int
+
+    label string
+    operation string
+    operand string
+
+    // Set on the statements of a macro expansion: the scope of its local labels,
+    // and where the macro was called from, for errors.
+    scope string
+    caller string
+
+    // Content of the file of an .incbin directive
+    data []byte
+}
+
+// parseLine splits a line of source into a statement.
+// A label either ends with a colon, or starts at the beginning of the line
+// and isn't a mnemonic, a macro or a directive.
+func parseLine(text string, isMacro func(name string) bool) (s statement, err error){
+
+    text = strings.TrimRight(stripComment(text), " \t\r")
+    if strings.TrimSpace(text) == "" {
+        return
+    }
+
+    indented := text[0] == ' ' || text[0] == '\t'
+    rest := strings.TrimSpace(text)
+
+    // *= $1000 is the same as .org $1000
+    if strings.HasPrefix(rest, "*") && strings.HasPrefix(strings.TrimSpace(rest[1:]), "=") {
+        s.operation = ".org"
+        s.operand = strings.TrimSpace(strings.TrimSpace(rest[1:])[1:])
+        return
+    }
+
+    word, after := splitWord(rest)
+
+    switch {
+    case strings.HasSuffix(word, ":"):
+        s.label = strings.TrimSuffix(word, ":")
+        rest = after
+
+    case !indented && !isMnemonic(word) && !isMacro(word) && !strings.HasPrefix(word, ".") && word != "=":
+        s.label = word
+        rest = after
+    }
+
+    if s.label != "" && !isSymbol(s.label) {
+        return s, fmt.Errorf("invalid label %q", s.label)
+    }
+
+    if rest == "" {
+        return
+    }
+
+    // Constants: name = value
+    if strings.HasPrefix(rest, "=") {
+        s.operation = "="
+        s.operand = strings.TrimSpace(rest[1:])
+        return
+    }
+
+    word, s.operand = splitWord(rest)
+
+    if strings.HasPrefix(word, ".") {
+        s.operation = strings.ToLower(word)
+    }else{
+        s.operation = strings.ToUpper(word)
+    }
+
+    return
+}
+
+// splitWord returns the first word of text and the rest, trimmed.
+func splitWord(text string) (word, rest string){
+
+    end := strings.IndexAny(text, " \t")
+    if end == -1 {
+        return text, ""
+    }
+
+    return text[:end], strings.TrimSpace(text[end:])
+}
+
+// stripComment removes what follows a semicolon, unless it's quoted.
+func stripComment(text string) string{
+
+    quote := byte(0)
+
+    for i := 0; i < len(text); i++ {
+
+        switch c := text[i]; {
+        case quote != 0 && c == quote:
+            quote = 0
+        case quote == 0 && (c == '"' || c == '\''):
+            quote = c
+        case quote == 0 && c == ';':
+            return text[:i]
+        }
+    }
+
+    return text
+}
+
+// splitArguments splits an operand on the commas that are not quoted or in parentheses.
+func splitArguments(operand string) []string{
+
+    var arguments []string
+
+    quote := byte(0)
+    depth := 0
+    start := 0
+
+    for i := 0; i < len(operand); i++ {
+
+        switch c := operand[i]; {
+        case quote != 0:
+            if c == quote {
+                quote = 0
+            }
+        case c == '"' || c == '\'':
+            quote = c
+        case c == '(':
+            depth++
+        case c == ')':
+            depth--
+        case c == ',' && depth == 0:
+            arguments = append(arguments, strings.TrimSpace(operand[start:i]))
+            start = i + 1
+        }
+    }
+
+    return append(arguments, strings.TrimSpace(operand[start:]))
+}
+
+func isMnemonic(word string) bool{
+
+    word = strings.ToUpper(word)
+
+    return nmosOpcodes[word] != nil || cmosOpcodes[word] != nil
+}
+
+func isSymbol(name string) bool{
+
+    if name == "" || !isSymbolStart(name[0]) {
+        return false
+    }
+
+    for i := 1; i < len(name); i++ {
+        if !isSymbolChar(name[i]) {
+            return false
+        }
+    }
+
+    return true
+}
@@ -0,0 +1,379 @@
+package asm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Includes and macro expansions can't nest deeper than this, which catches recursion.
+const maxDepth = 64
+
+// macro is a block of lines defined by .macro name param1, param2 ... .endmacro.
+type macro struct {
+    name string
+    parameters []string
+    body []sourceLine
+
+    // Number of expansions so far, which gives each of them its own local labels
+    expansions int
+}
+
+// sourceLine is a line of text, with where it comes from.
+type sourceLine struct {
+    file string
+    line int
+    text string
+}
+
+// expansion is the context of the lines being loaded when they come from a macro.
+type expansion struct {
+    scope string
+    caller string
+}
+
+func splitLines(file string, source string) []sourceLine{
+
+    var lines []sourceLine
+
+    for number, text := range strings.Split(source, "\n") {
+        lines = append(lines, sourceLine{file: file, line: number + 1, text: text})
+    }
+
+    return lines
+}
+
+// load parses lines into statements, before the passes run. It records macros and
+// expands their calls, reads included files, and loads the content of .incbin files,
+// except in the branches of conditional blocks that aren't assembled.
+func (a *assembly) load(lines []sourceLine, context expansion, depth int) error{
+
+    // Macro being recorded, and the line where its definition starts.
+    // Macros of branches that aren't assembled are skipped.
+    var recording *macro
+    var definition sourceLine
+    var skipping bool
+
+    for _, line := range lines {
+
+        s, err := parseLine(line.text, a.isMacro)
+        s.file, s.line = line.file, line.line
+        s.scope, s.caller = context.scope, context.caller
+
+        if err != nil {
+            return a.lineError(s, err)
+        }
+
+        if recording != nil {
+
+            switch s.operation {
+            case ".endmacro", ".endm":
+
+                if !skipping {
+                    a.macros[strings.ToUpper(recording.name)] = recording
+                }
+
+                recording = nil
+
+            case ".macro":
+                return a.lineError(s, fmt.Errorf("macros can't be defined inside macro %s", recording.name))
+
+            default:
+                recording.body = append(recording.body, line)
+            }
+
+            continue
+        }
+
+        switch s.operation {
+        case ".if", ".ifdef", ".ifndef", ".elseif", ".else", ".endif":
+            a.loadConditional(s)
+        }
+
+        loaded, undecided := a.loadState()
+
+        if loaded {
+            a.declare(s, undecided == nil)
+        }
+
+        if s.operation == ".macro" || s.operation == ".include" || s.operation == ".incbin" || a.isMacro(s.operation) {
+
+            if undecided != nil {
+                return a.lineError(s, fmt.Errorf("%s can't be in a conditional block the passes decide, %v", s.operation, undecided))
+            }
+
+            if !loaded && s.operation == ".macro" {
+                name, _ := splitWord(s.operand)
+                recording, definition, skipping = &macro{name: name}, line, true
+                continue
+            }
+
+            if !loaded {
+                continue
+            }
+        }
+
+        switch {
+        case s.operation == ".macro":
+
+            if recording, err = a.defineMacro(s); err != nil {
+                return a.lineError(s, err)
+            }
+
+            definition, skipping = line, false
+
+        case s.operation == ".endmacro" || s.operation == ".endm":
+            return a.lineError(s, fmt.Errorf("%s without .macro", s.operation))
+
+        case s.operation == ".include":
+
+            if err := a.include(s, depth); err != nil {
+                return err
+            }
+
+        case s.operation == ".incbin":
+
+            if s.data, err = a.readFile(s, s.operand); err != nil {
+                return a.lineError(s, err)
+            }
+
+            a.statements = append(a.statements, s)
+
+        case a.isMacro(s.operation):
+
+            if err := a.expand(s, depth); err != nil {
+                return err
+            }
+
+        default:
+            a.statements = append(a.statements, s)
+        }
+    }
+
+    if recording != nil {
+        return &Error{File: definition.file, Line: definition.line, Err: fmt.Errorf("missing .endmacro for macro %s", recording.name)}
+    }
+
+    return nil
+}
+
+// lineError wraps err with the location of s, and the macro call it comes from.
+func (a *assembly) lineError(s statement, err error) error{
+
+    if s.caller != "" {
+        err = fmt.Errorf("%w (%s)", err, s.caller)
+    }
+
+    return &Error{File: s.file, Line: s.line, Err: err}
+}
+
+func (a *assembly) isMacro(name string) bool{
+
+    _, ok := a.macros[strings.ToUpper(name)]
+    return ok
+}
+
+// label keeps the label of a line whose operation is replaced, like a macro call.
+func (a *assembly) label(s statement){
+
+    if s.label != "" {
+        a.statements = append(a.statements, statement{file: s.file, line: s.line, label: s.label, scope: s.scope, caller: s.caller})
+    }
+}
+
+func (a *assembly) defineMacro(s statement) (*macro, error){
+
+    if s.label != "" {
+        return nil, fmt.Errorf("the name of a macro goes after .macro")
+    }
+
+    name, rest := splitWord(s.operand)
+
+    if !isSymbol(name) || strings.HasPrefix(name, "@") {
+        return nil, fmt.Errorf("invalid macro name %q", name)
+    }
+
+    if isMnemonic(name) || a.isMacro(name) {
+        return nil, fmt.Errorf("macro %s is already an instruction or a macro", name)
+    }
+
+    m := &macro{name: name}
+
+    if rest != "" {
+
+        for _, parameter := range splitArguments(rest) {
+
+            if !isSymbol(parameter) {
+                return nil, fmt.Errorf("invalid macro parameter %q", parameter)
+            }
+
+            m.parameters = append(m.parameters, parameter)
+        }
+    }
+
+    return m, nil
+}
+
+// expand loads the body of a macro, with its parameters replaced by the arguments of the call.
+// Local labels of the body belong to the expansion, so the macro can be called more than once.
+func (a *assembly) expand(s statement, depth int) error{
+
+    a.label(s)
+
+    m := a.macros[strings.ToUpper(s.operation)]
+
+    if depth == maxDepth {
+        return a.lineError(s, fmt.Errorf("macros nested more than %d levels, is %s recursive?", maxDepth, m.name))
+    }
+
+    var arguments []string
+    if s.operand != "" {
+        arguments = splitArguments(s.operand)
+    }
+
+    if len(arguments) != len(m.parameters) {
+        return a.lineError(s, fmt.Errorf("macro %s takes %d arguments, got %d", m.name, len(m.parameters), len(arguments)))
+    }
+
+    m.expansions++
+
+    context := expansion{
+        scope: fmt.Sprintf("%s.%d", m.name, m.expansions),
+        caller: fmt.Sprintf("in macro %s called at %s", m.name, location(s.file, s.line)),
+    }
+
+    body := make([]sourceLine, len(m.body))
+
+    for i, line := range m.body {
+
+        text := line.text
+        for j, parameter := range m.parameters {
+            text = replaceWord(text, parameter, arguments[j])
+        }
+
+        body[i] = sourceLine{file: line.file, line: line.line, text: text}
+    }
+
+    return a.load(body, context, depth + 1)
+}
+
+// replaceWord replaces the symbols named word in text. Numbers, quoted text
+// and comments are left alone, so a parameter named AB doesn't change $AB.
+func replaceWord(text, word, replacement string) string{
+
+    var replaced strings.Builder
+
+    for i := 0; i < len(text); {
+
+        c := text[i]
+        start := i
+
+        switch {
+        case c == ';':
+            replaced.WriteString(text[i:])
+            return replaced.String()
+
+        case c == '"' || c == '\'':
+
+            end := strings.IndexByte(text[i + 1:], c)
+            if end == -1 {
+                i = len(text)
+            }else{
+                i += end + 2
+            }
+
+        case c == '$' || c == '%' || isDigit(c):
+
+            // $ and % are numbers only when digits follow, % alone is the modulo operator
+            digits := "0123456789ABCDEFabcdef"
+            if c == '%' {
+                digits = "01"
+            }
+
+            for i++; i < len(text) && strings.IndexByte(digits, text[i]) != -1; i++ {
+            }
+
+        case isSymbolStart(c):
+
+            for i++; i < len(text) && isSymbolChar(text[i]); i++ {
+            }
+
+            if text[start:i] == word {
+                replaced.WriteString(replacement)
+                continue
+            }
+
+        default:
+            i++
+        }
+
+        replaced.WriteString(text[start:i])
+    }
+
+    return replaced.String()
+}
+
+func location(file string, line int) string{
+
+    if file != "" {
+        return fmt.Sprintf("%s:%d", file, line)
+    }
+
+    return fmt.Sprintf("line %d", line)
+}
+
+func (a *assembly) include(s statement, depth int) error{
+
+    a.label(s)
+
+    if depth == maxDepth {
+        return a.lineError(s, fmt.Errorf("includes nested more than %d levels, is the file including itself?", maxDepth))
+    }
+
+    path, err := a.resolve(s, s.operand)
+    if err != nil {
+        return a.lineError(s, err)
+    }
+
+    source, err := os.ReadFile(path)
+    if err != nil {
+        return a.lineError(s, err)
+    }
+
+    context := expansion{scope: s.scope, caller: s.caller}
+
+    return a.load(splitLines(path, string(source)), context, depth + 1)
+}
+
+// readFile reads the file of an .incbin directive. Its offset and length
+// arguments are evaluated later, by the passes.
+func (a *assembly) readFile(s statement, operand string) ([]byte, error){
+
+    arguments := splitArguments(operand)
+
+    path, err := a.resolve(s, arguments[0])
+    if err != nil {
+        return nil, err
+    }
+
+    return os.ReadFile(path)
+}
+
+// resolve returns the path of a quoted file name, relative to the file being assembled.
+func (a *assembly) resolve(s statement, operand string) (string, error){
+
+    name := strings.TrimSpace(operand)
+
+    if len(name) < 2 || name[0] != '"' || name[len(name) - 1] != '"' {
+        return "", fmt.Errorf("%s needs a quoted file name", s.operation)
+    }
+
+    name = name[1:len(name) - 1]
+
+    if filepath.IsAbs(name) || s.file == "" {
+        return name, nil
+    }
+
+    return filepath.Join(filepath.Dir(s.file), name), nil
+}
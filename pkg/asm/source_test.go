@@ -0,0 +1,255 @@
+package asm
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMacroWithParametersAndLocalLabels(t *testing.T){
+
+    source := `
+        .macro inc16 address
+        INC address
+        BNE @done
+        INC address+1
+@done:
+        .endmacro
+
+        .org $0200
+        inc16 $10
+        inc16 $20
+`
+    CheckCode(t, source,
+        0xE6, 0x10, 0xD0, 0x02, 0xE6, 0x11,
+        0xE6, 0x20, 0xD0, 0x02, 0xE6, 0x21)
+}
+
+func TestMacroCallingMacro(t *testing.T){
+
+    source := `
+        .macro load value
+        LDA #value
+        .endmacro
+
+        .macro store2 value, first, second
+        load value
+        STA first
+        STA second
+        .endmacro
+
+start:  store2 <$1234, $10, $2000
+        JMP start
+`
+    CheckCode(t, source, 0xA9, 0x34, 0x85, 0x10, 0x8D, 0x00, 0x20, 0x4C, 0x00, 0x00)
+}
+
+func TestMacroErrors(t *testing.T){
+
+    CheckError(t, " .macro twice a\n NOP\n .endmacro\n\n twice", 5, "takes 1 arguments, got 0")
+    CheckError(t, " .macro open\n NOP\n", 1, "missing .endmacro")
+    CheckError(t, " .endmacro", 1, ".endmacro without .macro")
+    CheckError(t, " .macro LDA\n .endmacro", 1, "already an instruction")
+
+    // Errors in the body point to the body, and to the call
+    _, err := Assemble(" .macro bad\n LDA #$100\n .endmacro\n bad\n")
+    if err == nil || err.Error() != "line 2: value $100 doesn't fit in a byte (in macro bad called at line 4)" {
+        t.Error("Unexpected error: ", err)
+    }
+
+    CheckError(t, " .macro loop\n loop\n .endmacro\n loop", 2, "recursive")
+}
+
+func TestLocalLabelsBelongToTheLastGlobalLabel(t *testing.T){
+
+    source := `
+        .org $0200
+first:  LDX #2
+@loop:  DEX
+        BNE @loop
+second: LDY #2
+@loop:  DEY
+        BNE @loop
+`
+    program, err := Assemble(source)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    expected := []byte{0xA2, 0x02, 0xCA, 0xD0, 0xFD, 0xA0, 0x02, 0x88, 0xD0, 0xFD}
+
+    if !bytes.Equal(program.Code, expected) {
+        t.Errorf("Expected % X but got % X", expected, program.Code)
+    }
+
+    if program.Symbols["second@loop"] != 0x0207 {
+        t.Error("second@loop should be $0207 but got: ", program.Symbols["second@loop"])
+    }
+}
+
+func TestConditionalAssembly(t *testing.T){
+
+    source := `
+debug = 1
+level = 2
+        .if debug
+        .if level > 2
+        LDA #3
+        .elseif level == 2
+        LDA #2
+        .else
+        LDA #1
+        .endif
+        .else
+        BRK
+        .endif
+
+        .ifdef debug
+        NOP
+        .endif
+        .ifndef release
+        TAX
+        .endif
+        .if 0
+unused: .byte 1, 2, 3
+        .endif
+`
+    CheckCode(t, source, 0xA9, 0x02, 0xEA, 0xAA)
+
+    program, _ := Assemble(source)
+    if _, ok := program.Symbols["unused"]; ok {
+        t.Error("Labels of branches that are not assembled shouldn't be defined")
+    }
+}
+
+func TestConditionalErrors(t *testing.T){
+
+    CheckError(t, " NOP\n .else", 2, ".else without .if")
+    CheckError(t, " .if 1\n .else\n .else\n .endif", 3, ".else after .else")
+    CheckError(t, " .if 1\n NOP\n", 1, "missing .endif")
+    CheckError(t, " .if later\n .endif\nlater = 1", 1, "defined after it")
+}
+
+func TestConditionalMacrosAndIncludes(t *testing.T){
+
+    // The macro set of the CPU, and an include that only exists for the other one
+    source := `
+CMOS = 1
+        .if CMOS
+        .macro pushx
+        PHX
+        .endmacro
+        .else
+        .macro pushx
+        TXA
+        PHA
+        .endmacro
+        .include "missing.inc"
+        .incbin "missing.bin"
+        .endif
+
+        .cpu 65c02
+        pushx
+        .if 0
+        pushx
+        .endif
+`
+    CheckCode(t, source, 0xDA)
+
+    CheckCode(t, " .if 0\n .include \"missing.inc\"\n .endif\n NOP", 0xEA)
+    CheckCode(t, " .ifdef CMOS\n .include \"missing.inc\"\n .endif\n NOP", 0xEA)
+
+    // The conditions load can't compute can't decide what it loads
+    CheckError(t, " .if later\n .include \"missing.inc\"\n .endif\nlater = 1", 2, ".if at line 1 can't be decided before assembling")
+    CheckError(t, "start: NOP\n .if start\n .else\n .macro m\n .endmacro\n .endif", 4, ".macro can't be in a conditional block the passes decide")
+}
+
+func WriteFile(t *testing.T, dir, name string, data []byte) string{
+
+    path := filepath.Join(dir, name)
+
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := os.WriteFile(path, data, 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    return path
+}
+
+func TestIncludeAndIncbin(t *testing.T){
+
+    dir := t.TempDir()
+
+    WriteFile(t, dir, "lib/macros.s", []byte(" .macro clear address\n LDA #0\n STA address\n .endmacro\n"))
+    WriteFile(t, dir, "lib/data.bin", []byte{1, 2, 3, 4, 5})
+    WriteFile(t, dir, "lib/defs.s", []byte("port = $D000\n .include \"macros.s\"\n"))
+
+    main := WriteFile(t, dir, "main.s", []byte(`
+        .include "lib/defs.s"
+        .org $0200
+        clear port
+table:  .incbin "lib/data.bin", 1, 3
+rest:   .incbin "lib/data.bin", 4
+`))
+
+    program, err := (&Assembler{}).AssembleFile(main)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    expected := []byte{0xA9, 0x00, 0x8D, 0x00, 0xD0, 2, 3, 4, 5}
+
+    if !bytes.Equal(program.Code, expected) {
+        t.Errorf("Expected % X but got % X", expected, program.Code)
+    }
+
+    if program.Symbols["table"] != 0x0205 {
+        t.Error("table should be $0205 but got: ", program.Symbols["table"])
+    }
+}
+
+func TestIncludeErrorsPointToTheIncludedFile(t *testing.T){
+
+    dir := t.TempDir()
+
+    WriteFile(t, dir, "bad.s", []byte(" NOP\n LDA (1\n"))
+    main := WriteFile(t, dir, "main.s", []byte(" .include \"bad.s\"\n"))
+
+    _, err := (&Assembler{}).AssembleFile(main)
+
+    var asmErr *Error
+    if !errors.As(err, &asmErr) || asmErr.Line != 2 || !strings.HasSuffix(asmErr.File, "bad.s") {
+        t.Error("Expected an error at bad.s:2 but got: ", err)
+    }
+
+    CheckError(t, " .include \"missing.s\"", 1, "missing.s")
+    CheckError(t, " .include missing.s", 1, "quoted file name")
+}
+
+func TestReplaceWordLeavesNumbersAlone(t *testing.T){
+
+    tests := []struct {
+        text string
+        expected string
+    }{
+        {" LDA #AB+$AB ; AB", " LDA #$10+$AB ; AB"},
+        {" .byte \"AB\", AB%AB", " .byte \"AB\", $10%$10"},
+        {" STA AB,X", " STA $10,X"},
+        {" STA ABC", " STA ABC"},
+    }
+
+    for _, test := range tests {
+
+        replaced := replaceWord(test.text, "AB", "$10")
+
+        if replaced != test.expected {
+            t.Error("Expected ", test.expected, " but got: ", replaced)
+        }
+    }
+}
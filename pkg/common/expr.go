@@ -0,0 +1,189 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Expressions of the assembler and conditions of the breakpoints share their numbers
+// and their operators, so what works in one works in the other.
+
+// BinaryOperator is an operator of expressions, with the function computing its result.
+type BinaryOperator struct {
+    Symbol string
+    Apply func(x, y int) (int, error)
+}
+
+// ErrDivisionByZero is returned by / and % when the divisor is 0.
+var ErrDivisionByZero = errors.New("division by zero")
+
+// BinaryOperators are grouped by precedence, lowest first, as in C.
+// Comparisons and logical operators return 1 for true and 0 for false.
+// && and || are listed for their precedence, parsers short circuit them.
+var BinaryOperators = [][]BinaryOperator{
+    {
+        {"||", func(x, y int) (int, error){ return Truth(x != 0 || y != 0), nil }},
+    },
+    {
+        {"&&", func(x, y int) (int, error){ return Truth(x != 0 && y != 0), nil }},
+    },
+    {
+        {"|", func(x, y int) (int, error){ return x | y, nil }},
+    },
+    {
+        {"^", func(x, y int) (int, error){ return x ^ y, nil }},
+    },
+    {
+        {"&", func(x, y int) (int, error){ return x & y, nil }},
+    },
+    {
+        {"==", func(x, y int) (int, error){ return Truth(x == y), nil }},
+        {"!=", func(x, y int) (int, error){ return Truth(x != y), nil }},
+    },
+    {
+        {"<=", func(x, y int) (int, error){ return Truth(x <= y), nil }},
+        {">=", func(x, y int) (int, error){ return Truth(x >= y), nil }},
+        {"<", func(x, y int) (int, error){ return Truth(x < y), nil }},
+        {">", func(x, y int) (int, error){ return Truth(x > y), nil }},
+    },
+    {
+        {"<<", func(x, y int) (int, error){ return x << uint(y & 31), nil }},
+        {">>", func(x, y int) (int, error){ return x >> uint(y & 31), nil }},
+    },
+    {
+        {"+", func(x, y int) (int, error){ return x + y, nil }},
+        {"-", func(x, y int) (int, error){ return x - y, nil }},
+    },
+    {
+        {"*", func(x, y int) (int, error){ return x * y, nil }},
+        {"/", divide},
+        {"%", modulo},
+    },
+}
+
+// Every operator symbol, to match the longest one: < must not be taken for the start of <<.
+var operatorSymbols = func() (symbols []string){
+
+    for _, level := range BinaryOperators {
+        for _, operator := range level {
+            symbols = append(symbols, operator.Symbol)
+        }
+    }
+
+    return
+}()
+
+// Truth turns a condition into 1 or 0.
+func Truth(condition bool) int{
+
+    if condition {
+        return 1
+    }
+
+    return 0
+}
+
+func divide(x, y int) (int, error){
+
+    if y == 0 {
+        return 0, ErrDivisionByZero
+    }
+
+    return x / y, nil
+}
+
+func modulo(x, y int) (int, error){
+
+    if y == 0 {
+        return 0, ErrDivisionByZero
+    }
+
+    return x % y, nil
+}
+
+// MatchOperator returns the operator text starts with if it belongs to operators,
+// a level of BinaryOperators, or nil.
+func MatchOperator(text string, operators []BinaryOperator) *BinaryOperator{
+
+    longest := ""
+
+    for _, symbol := range operatorSymbols {
+
+        if len(symbol) > len(longest) && strings.HasPrefix(text, symbol) {
+            longest = symbol
+        }
+    }
+
+    for i := range operators {
+
+        if operators[i].Symbol == longest {
+            return &operators[i]
+        }
+    }
+
+    return nil
+}
+
+// ParseNumber reads $ hexadecimal, % binary and decimal numbers.
+func ParseNumber(text string) (int, error){
+
+    digits, base := text, 10
+
+    switch {
+    case strings.HasPrefix(text, "$"):
+        digits, base = text[1:], 16
+    case strings.HasPrefix(text, "%"):
+        digits, base = text[1:], 2
+    }
+
+    value, err := strconv.ParseUint(digits, base, 32)
+    if err != nil || digits == "" {
+        return 0, fmt.Errorf("invalid number %q", text)
+    }
+
+    return int(value), nil
+}
+
+// ParseAddress reads a 16 bit address written as $C000, 0xC000 or 49152.
+func ParseAddress(text string) (uint16, error){
+
+    digits, base := text, 10
+
+    if strings.HasPrefix(text, "$") {
+        digits, base = text[1:], 16
+    }else if strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X") {
+        digits, base = text[2:], 16
+    }
+
+    address, err := strconv.ParseUint(digits, base, 16)
+    if err != nil {
+        return 0, fmt.Errorf("invalid address %q", text)
+    }
+
+    return uint16(address), nil
+}
+
+// ParseAddressRange reads a range of addresses written start-end, like $E000-$FFFF.
+func ParseAddressRange(text string) (start, end uint16, err error){
+
+    bounds := strings.Split(text, "-")
+    if len(bounds) != 2 {
+        return 0, 0, fmt.Errorf("invalid range %q, expected start-end", text)
+    }
+
+    if start, err = ParseAddress(bounds[0]); err != nil {
+        return
+    }
+
+    if end, err = ParseAddress(bounds[1]); err != nil {
+        return
+    }
+
+    if end < start {
+        return 0, 0, fmt.Errorf("range %s ends before it starts", text)
+    }
+
+    return
+}
@@ -0,0 +1,57 @@
+package common
+
+import "testing"
+
+func TestParseNumberReadsHexadecimalBinaryAndDecimal(t *testing.T){
+
+    numbers := map[string]int{"$1F": 31, "%101": 5, "42": 42}
+
+    for text, expected := range numbers {
+
+        if n, err := ParseNumber(text); err != nil || n != expected {
+            t.Error(text, " should be ", expected, " but got: ", n, err)
+        }
+    }
+
+    for _, text := range []string{"$", "%2", "0x10", "12A"} {
+
+        if _, err := ParseNumber(text); err == nil {
+            t.Error(text, " should be an invalid number")
+        }
+    }
+}
+
+func TestMatchOperatorTakesTheLongestSymbol(t *testing.T){
+
+    // & is the level of && too, but && must not be read as &
+    if operator := MatchOperator("&& 1", BinaryOperators[4]); operator != nil {
+        t.Error("&& shouldn't match &, got: ", operator.Symbol)
+    }
+
+    if operator := MatchOperator("<= 1", BinaryOperators[6]); operator == nil || operator.Symbol != "<=" {
+        t.Error("Expected <= to match")
+    }
+
+    if _, err := MatchOperator("/", BinaryOperators[9]).Apply(1, 0); err != ErrDivisionByZero {
+        t.Error("Expected a division by zero but got: ", err)
+    }
+}
+
+func TestParseAddressRange(t *testing.T){
+
+    start, end, err := ParseAddressRange("$E000-0xFFFF")
+    if err != nil || start != 0xE000 || end != 0xFFFF {
+        t.Error("Expected $E000-$FFFF but got: ", start, end, err)
+    }
+
+    if address, err := ParseAddress("49152"); err != nil || address != 0xC000 {
+        t.Error("Expected $C000 but got: ", address, err)
+    }
+
+    for _, text := range []string{"$FFFF-$E000", "$E000", "$E000-$1FFFF", "C000-D000"} {
+
+        if _, _, err := ParseAddressRange(text); err == nil {
+            t.Error(text, " should be an invalid range")
+        }
+    }
+}
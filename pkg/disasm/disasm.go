@@ -0,0 +1,204 @@
+// Package disasm decodes 6502 machine code back into assembly text,
+// using the opcode tables of the instructions package.
+package disasm
+
+import (
+	"emulator/pkg/instructions"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Reader is the memory the code is read from. arc.Bus and *arc.Memory are Readers,
+// and so is the address space of a CPU, see arc.CPU.AddressSpace.
+type Reader interface {
+    Read(address uint16) byte
+}
+
+// Options tells how instructions are decoded and printed.
+type Options struct {
+
+    // CMOS decodes the opcodes of the 65C02 instead of the NMOS 6502 ones.
+    CMOS bool
+
+    // Bytes shows the raw bytes of each instruction after its address.
+    Bytes bool
+
+    // Cycles shows the cycles each instruction takes, with a + when
+    // crossing a page, or taking a branch, costs more.
+    Cycles bool
+}
+
+// Instruction is a decoded instruction.
+type Instruction struct {
+
+    Address uint16
+
+    // Bytes holds the opcode followed by the operand.
+    Bytes []byte
+
+    instructions.Opcode
+
+    // Target is the absolute address a branch jumps to, HasTarget is true for
+    // relative branches only.
+    Target uint16
+    HasTarget bool
+}
+
+// Decode decodes the instruction at address.
+func Decode(memory Reader, address uint16, cmos bool) Instruction{
+
+    code := memory.Read(address)
+    opcode := instructions.Table(cmos)[code]
+
+    ins := Instruction{Address: address, Opcode: opcode}
+
+    ins.Bytes = make([]byte, opcode.Size)
+    for i := range ins.Bytes {
+        ins.Bytes[i] = memory.Read(address + uint16(i))
+    }
+
+    // Branch offsets are relative to the next instruction
+    next := address + uint16(opcode.Size)
+
+    switch opcode.Mode {
+    case instructions.Relative:
+        ins.Target = next + uint16(int8(ins.Bytes[1]))
+        ins.HasTarget = true
+
+    case instructions.ZeroPageRelative:
+        ins.Target = next + uint16(int8(ins.Bytes[2]))
+        ins.HasTarget = true
+    }
+
+    return ins
+}
+
+// Range decodes the instructions starting from start up to end included.
+// The last instruction may extend past end.
+func Range(memory Reader, start, end uint16, cmos bool) []Instruction{
+
+    var list []Instruction
+
+    // Addresses are counted as int, so a range ending at $FFFF terminates
+    for address := int(start); address <= int(end); {
+
+        ins := Decode(memory, uint16(address), cmos)
+        list = append(list, ins)
+
+        address += len(ins.Bytes)
+    }
+
+    return list
+}
+
+// Operand returns the operand as written in assembly, e.g. ($20),Y.
+func (ins Instruction) Operand() string{
+
+    var byteOperand byte
+    var wordOperand uint16
+
+    if len(ins.Bytes) > 1 {
+        byteOperand = ins.Bytes[1]
+    }
+    if len(ins.Bytes) > 2 {
+        wordOperand = uint16(ins.Bytes[1]) | uint16(ins.Bytes[2]) << 8
+    }
+
+    switch ins.Mode {
+    case instructions.Accumulator:
+        return "A"
+    case instructions.Immediate:
+        return fmt.Sprintf("#$%02X", byteOperand)
+    case instructions.ZeroPage:
+        return fmt.Sprintf("$%02X", byteOperand)
+    case instructions.ZeroPageX:
+        return fmt.Sprintf("$%02X,X", byteOperand)
+    case instructions.ZeroPageY:
+        return fmt.Sprintf("$%02X,Y", byteOperand)
+    case instructions.Relative:
+        return fmt.Sprintf("$%04X", ins.Target)
+    case instructions.Absolute:
+        return fmt.Sprintf("$%04X", wordOperand)
+    case instructions.AbsoluteX:
+        return fmt.Sprintf("$%04X,X", wordOperand)
+    case instructions.AbsoluteY:
+        return fmt.Sprintf("$%04X,Y", wordOperand)
+    case instructions.Indirect:
+        return fmt.Sprintf("($%04X)", wordOperand)
+    case instructions.IndexedIndirect:
+        return fmt.Sprintf("($%02X,X)", byteOperand)
+    case instructions.IndirectIndexed:
+        return fmt.Sprintf("($%02X),Y", byteOperand)
+    case instructions.ZeroPageIndirect:
+        return fmt.Sprintf("($%02X)", byteOperand)
+    case instructions.AbsoluteIndexedIndirect:
+        return fmt.Sprintf("($%04X,X)", wordOperand)
+    case instructions.ZeroPageRelative:
+        return fmt.Sprintf("$%02X,$%04X", byteOperand, ins.Target)
+    }
+
+    return ""
+}
+
+// String returns the instruction as written in assembly, e.g. LDA ($20),Y.
+func (ins Instruction) String() string{
+
+    operand := ins.Operand()
+    if operand == "" {
+        return ins.Mnemonic
+    }
+
+    return ins.Mnemonic + " " + operand
+}
+
+// Format returns a listing line: the address, the raw bytes and the cycles if requested
+// by options, and the instruction.
+//
+//	$0200  B1 20     LDA ($20),Y   5+
+func (ins Instruction) Format(options Options) string{
+
+    var line strings.Builder
+
+    fmt.Fprintf(&line, "$%04X  ", ins.Address)
+
+    if options.Bytes {
+
+        raw := make([]string, len(ins.Bytes))
+        for i, value := range ins.Bytes {
+            raw[i] = fmt.Sprintf("%02X", value)
+        }
+
+        // Instructions are at most 3 bytes long
+        fmt.Fprintf(&line, "%-10s", strings.Join(raw, " "))
+    }
+
+    if !options.Cycles {
+        line.WriteString(ins.String())
+        return line.String()
+    }
+
+    // The longest instruction is BBR0 $12,$1234
+    fmt.Fprintf(&line, "%-14s", ins.String())
+
+    cycles := fmt.Sprint(ins.Cycles)
+    if ins.PageCross {
+        cycles += "+"
+    }
+    line.WriteString(cycles)
+
+    return line.String()
+}
+
+// Write disassembles the range from start to end included to w, one instruction per line.
+func Write(w io.Writer, memory Reader, start, end uint16, options Options) error{
+
+    for _, ins := range Range(memory, start, end, options.CMOS) {
+
+        if _, err := fmt.Fprintln(w, ins.Format(options)); err != nil {
+            return err
+        }
+    }
+
+    return nil
+}
@@ -0,0 +1,144 @@
+package disasm
+
+import (
+	"emulator/pkg/arc"
+	"emulator/pkg/instructions"
+	"strings"
+	"testing"
+)
+
+func InitMemory(address uint16, code ...byte) *arc.Memory{
+
+    memory := &arc.Memory{}
+    copy(memory.Data[address:], code)
+
+    return memory
+}
+
+func TestDecodeFormatsEveryAddressingMode(t *testing.T){
+
+    tests := []struct {
+        code []byte
+        cmos bool
+        text string
+    }{
+        {[]byte{instructions.INS_NOP_IMP}, false, "NOP"},
+        {[]byte{instructions.INS_ASL_ACC}, false, "ASL A"},
+        {[]byte{instructions.INS_LDA_IM, 0x0F}, false, "LDA #$0F"},
+        {[]byte{instructions.INS_LDA_ZP, 0x20}, false, "LDA $20"},
+        {[]byte{instructions.INS_LDA_ZPX, 0x20}, false, "LDA $20,X"},
+        {[]byte{instructions.INS_LDX_ZPY, 0x20}, false, "LDX $20,Y"},
+        {[]byte{instructions.INS_LDA_ABS, 0x34, 0x12}, false, "LDA $1234"},
+        {[]byte{instructions.INS_LDA_ABSX, 0x34, 0x12}, false, "LDA $1234,X"},
+        {[]byte{instructions.INS_LDA_ABSY, 0x34, 0x12}, false, "LDA $1234,Y"},
+        {[]byte{instructions.INS_JMP_IND, 0xFF, 0x10}, false, "JMP ($10FF)"},
+        {[]byte{instructions.INS_LDA_INDX, 0x20}, false, "LDA ($20,X)"},
+        {[]byte{instructions.INS_LDA_INDY, 0x20}, false, "LDA ($20),Y"},
+        {[]byte{instructions.INS_LDA_INDZP, 0x20}, true, "LDA ($20)"},
+        {[]byte{instructions.INS_JMP_INDX, 0x00, 0x30}, true, "JMP ($3000,X)"},
+        {[]byte{instructions.INS_LAX_ZPY, 0x20}, false, "LAX $20,Y"},
+    }
+
+    for _, test := range tests {
+
+        ins := Decode(InitMemory(0x0200, test.code...), 0x0200, test.cmos)
+
+        if ins.String() != test.text {
+            t.Error("Expected ", test.text, " but got: ", ins.String())
+        }
+
+        if len(ins.Bytes) != len(test.code) {
+            t.Error(test.text, " should be ", len(test.code), " bytes long but got: ", len(ins.Bytes))
+        }
+    }
+}
+
+func TestBranchTargetsAreResolved(t *testing.T){
+
+    // BNE back to $0200, then forward to $0210
+    memory := InitMemory(0x0200,
+        instructions.INS_NOP_IMP,
+        instructions.INS_BNE_REL, 0xFD,
+        instructions.INS_BEQ_REL, 0x0B)
+
+    list := Range(memory, 0x0200, 0x0204, false)
+
+    if len(list) != 3 {
+        t.Fatal("Expected 3 instructions but got: ", len(list))
+    }
+
+    if list[1].String() != "BNE $0200" || list[1].Target != 0x0200 || !list[1].HasTarget {
+        t.Error("Backward branch decoded as: ", list[1].String())
+    }
+
+    if list[2].String() != "BEQ $0210" {
+        t.Error("Forward branch decoded as: ", list[2].String())
+    }
+}
+
+func TestZeroPageRelativeBranchTarget(t *testing.T){
+
+    memory := InitMemory(0x0200, instructions.INS_BBS7_ZPREL, 0x12, 0x10)
+
+    ins := Decode(memory, 0x0200, true)
+
+    if ins.String() != "BBS7 $12,$0213" {
+        t.Error("Expected BBS7 $12,$0213 but got: ", ins.String())
+    }
+}
+
+func TestRangeStopsAtTheEndOfMemory(t *testing.T){
+
+    // LDA $0000 wraps past $FFFF, the range still ends
+    memory := InitMemory(0xFFFE, instructions.INS_NOP_IMP, instructions.INS_LDA_ABS)
+
+    list := Range(memory, 0xFFFE, 0xFFFF, false)
+
+    if len(list) != 2 {
+        t.Fatal("Expected 2 instructions but got: ", len(list))
+    }
+
+    if len(list[1].Bytes) != 3 {
+        t.Error("Last instruction should read its operand past $FFFF, got: ", list[1].Bytes)
+    }
+}
+
+func TestWriteShowsBytesAndCycles(t *testing.T){
+
+    memory := InitMemory(0x0200,
+        instructions.INS_LDA_INDY, 0x20,
+        instructions.INS_STA_ABS, 0x00, 0x03)
+
+    var out strings.Builder
+
+    err := Write(&out, memory, 0x0200, 0x0202, Options{Bytes: true, Cycles: true})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    expected := "$0200  B1 20     LDA ($20),Y   5+\n" +
+                "$0202  8D 00 03  STA $0300     4\n"
+
+    if out.String() != expected {
+        t.Error("Expected:\n", expected, "but got:\n", out.String())
+    }
+
+    out.Reset()
+    Write(&out, memory, 0x0200, 0x0201, Options{})
+
+    if out.String() != "$0200  LDA ($20),Y\n" {
+        t.Error("Plain listing should only have address and instruction, got: ", out.String())
+    }
+}
+
+func TestDisassembleCPUAddressSpace(t *testing.T){
+
+    cpu := arc.NewCPU(arc.CMOS65C02)
+    cpu.LoadProgram([]byte{0x00, 0x02, instructions.INS_BRA_REL, 0xFE})
+
+    ins := Decode(cpu.AddressSpace(), cpu.PC, true)
+
+    if ins.String() != "BRA $0200" {
+        t.Error("Expected BRA $0200 but got: ", ins.String())
+    }
+}
@@ -144,6 +144,30 @@ const (
     INS_DEY_IMP = 0x88
 
     // Shifts
+    INS_ASL_ACC = 0x0A
+    INS_ASL_ZP = 0x06
+    INS_ASL_ZPX = 0x16
+    INS_ASL_ABS = 0x0E
+    INS_ASL_ABSX = 0x1E
+
+    INS_LSR_ACC = 0x4A
+    INS_LSR_ZP = 0x46
+    INS_LSR_ZPX = 0x56
+    INS_LSR_ABS = 0x4E
+    INS_LSR_ABSX = 0x5E
+
+    INS_ROL_ACC = 0x2A
+    INS_ROL_ZP = 0x26
+    INS_ROL_ZPX = 0x36
+    INS_ROL_ABS = 0x2E
+    INS_ROL_ABSX = 0x3E
+
+    INS_ROR_ACC = 0x6A
+    INS_ROR_ZP = 0x66
+    INS_ROR_ZPX = 0x76
+    INS_ROR_ABS = 0x6E
+    INS_ROR_ABSX = 0x7E
+
     // Jump & Calls
     INS_JMP_ABS = 0x4C
     INS_JMP_IND = 0x6C
@@ -171,10 +195,222 @@ const (
     INS_SEI_IMP = 0x78
     
     // System Functions
-    // TODO:
     INS_BRK_IMP = 0x00
     INS_NOP_IMP = 0xEA
-    // TODO:
     INS_RTI_IMP = 0x40
 
+    // Illegal opcodes
+    // The NMOS 6502 doesn't reject the opcodes that are not documented,
+    // they run a combination of the logic of the documented ones.
+    // Most of them are stable and are commonly used by real world code.
+    // https://www.masswerk.at/nowgobang/2021/6502-illegal-opcodes
+
+    // Read-modify-write combined with an ALU operation on the accumulator
+    INS_SLO_ZP = 0x07
+    INS_SLO_ZPX = 0x17
+    INS_SLO_ABS = 0x0F
+    INS_SLO_ABSX = 0x1F
+    INS_SLO_ABSY = 0x1B
+    INS_SLO_INDX = 0x03
+    INS_SLO_INDY = 0x13
+
+    INS_RLA_ZP = 0x27
+    INS_RLA_ZPX = 0x37
+    INS_RLA_ABS = 0x2F
+    INS_RLA_ABSX = 0x3F
+    INS_RLA_ABSY = 0x3B
+    INS_RLA_INDX = 0x23
+    INS_RLA_INDY = 0x33
+
+    INS_SRE_ZP = 0x47
+    INS_SRE_ZPX = 0x57
+    INS_SRE_ABS = 0x4F
+    INS_SRE_ABSX = 0x5F
+    INS_SRE_ABSY = 0x5B
+    INS_SRE_INDX = 0x43
+    INS_SRE_INDY = 0x53
+
+    INS_RRA_ZP = 0x67
+    INS_RRA_ZPX = 0x77
+    INS_RRA_ABS = 0x6F
+    INS_RRA_ABSX = 0x7F
+    INS_RRA_ABSY = 0x7B
+    INS_RRA_INDX = 0x63
+    INS_RRA_INDY = 0x73
+
+    INS_DCP_ZP = 0xC7
+    INS_DCP_ZPX = 0xD7
+    INS_DCP_ABS = 0xCF
+    INS_DCP_ABSX = 0xDF
+    INS_DCP_ABSY = 0xDB
+    INS_DCP_INDX = 0xC3
+    INS_DCP_INDY = 0xD3
+
+    INS_ISC_ZP = 0xE7
+    INS_ISC_ZPX = 0xF7
+    INS_ISC_ABS = 0xEF
+    INS_ISC_ABSX = 0xFF
+    INS_ISC_ABSY = 0xFB
+    INS_ISC_INDX = 0xE3
+    INS_ISC_INDY = 0xF3
+
+    INS_SAX_ZP = 0x87
+    INS_SAX_ZPY = 0x97
+    INS_SAX_ABS = 0x8F
+    INS_SAX_INDX = 0x83
+
+    INS_LAX_ZP = 0xA7
+    INS_LAX_ZPY = 0xB7
+    INS_LAX_ABS = 0xAF
+    INS_LAX_ABSY = 0xBF
+    INS_LAX_INDX = 0xA3
+    INS_LAX_INDY = 0xB3
+
+    // Immediate ALU operations
+    INS_ANC_IM = 0x0B
+    INS_ANC_IM_2B = 0x2B
+    INS_ALR_IM = 0x4B
+    INS_ARR_IM = 0x6B
+    INS_SBX_IM = 0xCB
+    INS_USBC_IM = 0xEB
+
+    // Unstable: the result depends on the chip and on analog effects.
+    INS_ANE_IM = 0x8B
+    INS_LXA_IM = 0xAB
+    INS_SHA_ABSY = 0x9F
+    INS_SHA_INDY = 0x93
+    INS_SHX_ABSY = 0x9E
+    INS_SHY_ABSX = 0x9C
+    INS_TAS_ABSY = 0x9B
+    INS_LAS_ABSY = 0xBB
+
+    // NOPs with different lengths and timings
+    INS_NOP_IMP_1A = 0x1A
+    INS_NOP_IMP_3A = 0x3A
+    INS_NOP_IMP_5A = 0x5A
+    INS_NOP_IMP_7A = 0x7A
+    INS_NOP_IMP_DA = 0xDA
+    INS_NOP_IMP_FA = 0xFA
+
+    INS_NOP_IM_80 = 0x80
+    INS_NOP_IM_82 = 0x82
+    INS_NOP_IM_89 = 0x89
+    INS_NOP_IM_C2 = 0xC2
+    INS_NOP_IM_E2 = 0xE2
+
+    INS_NOP_ZP_04 = 0x04
+    INS_NOP_ZP_44 = 0x44
+    INS_NOP_ZP_64 = 0x64
+
+    INS_NOP_ZPX_14 = 0x14
+    INS_NOP_ZPX_34 = 0x34
+    INS_NOP_ZPX_54 = 0x54
+    INS_NOP_ZPX_74 = 0x74
+    INS_NOP_ZPX_D4 = 0xD4
+    INS_NOP_ZPX_F4 = 0xF4
+
+    INS_NOP_ABS_0C = 0x0C
+
+    INS_NOP_ABSX_1C = 0x1C
+    INS_NOP_ABSX_3C = 0x3C
+    INS_NOP_ABSX_5C = 0x5C
+    INS_NOP_ABSX_7C = 0x7C
+    INS_NOP_ABSX_DC = 0xDC
+    INS_NOP_ABSX_FC = 0xFC
+
+    // JAM halts the CPU, only a reset brings it back.
+    INS_JAM_02 = 0x02
+    INS_JAM_12 = 0x12
+    INS_JAM_22 = 0x22
+    INS_JAM_32 = 0x32
+    INS_JAM_42 = 0x42
+    INS_JAM_52 = 0x52
+    INS_JAM_62 = 0x62
+    INS_JAM_72 = 0x72
+    INS_JAM_92 = 0x92
+    INS_JAM_B2 = 0xB2
+    INS_JAM_D2 = 0xD2
+    INS_JAM_F2 = 0xF2
+
+    // 65C02
+    // The CMOS 65C02 reuses most of the illegal opcodes of the NMOS 6502
+    // for new instructions and addressing modes. These are only valid on the 65C02.
+    INS_BRA_REL = 0x80
+
+    INS_PHX_IMP = 0xDA
+    INS_PHY_IMP = 0x5A
+    INS_PLX_IMP = 0xFA
+    INS_PLY_IMP = 0x7A
+
+    INS_STZ_ZP = 0x64
+    INS_STZ_ZPX = 0x74
+    INS_STZ_ABS = 0x9C
+    INS_STZ_ABSX = 0x9E
+
+    INS_TRB_ZP = 0x14
+    INS_TRB_ABS = 0x1C
+    INS_TSB_ZP = 0x04
+    INS_TSB_ABS = 0x0C
+
+    // Zero page indirect: the effective address is read from the zero page, without any offset.
+    INS_ORA_INDZP = 0x12
+    INS_AND_INDZP = 0x32
+    INS_EOR_INDZP = 0x52
+    INS_ADC_INDZP = 0x72
+    INS_STA_INDZP = 0x92
+    INS_LDA_INDZP = 0xB2
+    INS_CMP_INDZP = 0xD2
+    INS_SBC_INDZP = 0xF2
+
+    INS_BIT_IM = 0x89
+    INS_BIT_ZPX = 0x34
+    INS_BIT_ABSX = 0x3C
+
+    INS_INC_ACC = 0x1A
+    INS_DEC_ACC = 0x3A
+
+    INS_JMP_INDX = 0x7C
+
+    INS_WAI_IMP = 0xCB
+    INS_STP_IMP = 0xDB
+
+    // Reset and set a bit of a zero page value
+    INS_RMB0_ZP = 0x07
+    INS_RMB1_ZP = 0x17
+    INS_RMB2_ZP = 0x27
+    INS_RMB3_ZP = 0x37
+    INS_RMB4_ZP = 0x47
+    INS_RMB5_ZP = 0x57
+    INS_RMB6_ZP = 0x67
+    INS_RMB7_ZP = 0x77
+
+    INS_SMB0_ZP = 0x87
+    INS_SMB1_ZP = 0x97
+    INS_SMB2_ZP = 0xA7
+    INS_SMB3_ZP = 0xB7
+    INS_SMB4_ZP = 0xC7
+    INS_SMB5_ZP = 0xD7
+    INS_SMB6_ZP = 0xE7
+    INS_SMB7_ZP = 0xF7
+
+    // Branch if a bit of a zero page value is reset or set.
+    // The zero page address is followed by the relative offset.
+    INS_BBR0_ZPREL = 0x0F
+    INS_BBR1_ZPREL = 0x1F
+    INS_BBR2_ZPREL = 0x2F
+    INS_BBR3_ZPREL = 0x3F
+    INS_BBR4_ZPREL = 0x4F
+    INS_BBR5_ZPREL = 0x5F
+    INS_BBR6_ZPREL = 0x6F
+    INS_BBR7_ZPREL = 0x7F
+
+    INS_BBS0_ZPREL = 0x8F
+    INS_BBS1_ZPREL = 0x9F
+    INS_BBS2_ZPREL = 0xAF
+    INS_BBS3_ZPREL = 0xBF
+    INS_BBS4_ZPREL = 0xCF
+    INS_BBS5_ZPREL = 0xDF
+    INS_BBS6_ZPREL = 0xEF
+    INS_BBS7_ZPREL = 0xFF
+
 )
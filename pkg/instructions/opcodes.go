@@ -0,0 +1,650 @@
+package instructions
+
+// Mode is the addressing mode of an instruction, which tells how its operand is found.
+type Mode int
+
+const (
+    Implied Mode = iota
+    Accumulator
+    Immediate
+    ZeroPage
+    ZeroPageX
+    ZeroPageY
+
+    // Relative is used by branches, the operand is a signed offset from the next instruction.
+    Relative
+    Absolute
+    AbsoluteX
+    AbsoluteY
+
+    // Indirect is only used by JMP ($1234).
+    Indirect
+
+    // IndexedIndirect is ($12,X), IndirectIndexed is ($12),Y.
+    IndexedIndirect
+    IndirectIndexed
+
+    // Modes added by the 65C02: ($12), JMP ($1234,X) and the zero page
+    // address followed by a branch offset of BBR and BBS.
+    ZeroPageIndirect
+    AbsoluteIndexedIndirect
+    ZeroPageRelative
+)
+
+var modeNames = [...]string{
+    Implied: "implied",
+    Accumulator: "accumulator",
+    Immediate: "immediate",
+    ZeroPage: "zero page",
+    ZeroPageX: "zero page,X",
+    ZeroPageY: "zero page,Y",
+    Relative: "relative",
+    Absolute: "absolute",
+    AbsoluteX: "absolute,X",
+    AbsoluteY: "absolute,Y",
+    Indirect: "indirect",
+    IndexedIndirect: "(indirect,X)",
+    IndirectIndexed: "(indirect),Y",
+    ZeroPageIndirect: "(zero page)",
+    AbsoluteIndexedIndirect: "(absolute,X)",
+    ZeroPageRelative: "zero page,relative",
+}
+
+func (mode Mode) String() string{
+    return modeNames[mode]
+}
+
+// OperandSize is the number of bytes following the opcode.
+// It's always the Size of the instruction minus one.
+func (mode Mode) OperandSize() int{
+
+    switch mode {
+    case Implied, Accumulator:
+        return 0
+    case Absolute, AbsoluteX, AbsoluteY, Indirect, AbsoluteIndexedIndirect, ZeroPageRelative:
+        return 2
+    }
+
+    return 1
+}
+
+// Status tells where an opcode comes from.
+type Status int
+
+const (
+    // Documented opcodes are part of the instruction set of the NMOS 6502.
+    Documented Status = iota
+
+    // Illegal opcodes are not documented by the manufacturer. On the NMOS 6502 they're
+    // side effects of the decoding logic, on the 65C02 they're NOPs.
+    Illegal
+
+    // CMOS opcodes are the instructions and addressing modes added by the 65C02.
+    CMOS
+)
+
+func (status Status) String() string{
+
+    switch status {
+    case Documented:
+        return "documented"
+    case Illegal:
+        return "illegal"
+    case CMOS:
+        return "65C02"
+    }
+
+    return "unknown"
+}
+
+// Opcode describes the instruction encoded by an opcode byte.
+type Opcode struct {
+    Mnemonic string
+    Mode Mode
+
+    // Size in bytes, opcode included.
+    Size int
+
+    // Cycles taken when no page is crossed and, for branches, when the branch isn't taken.
+    // A taken branch takes one more cycle, but BRA always branches and its Cycles count it.
+    Cycles int
+
+    // PageCross is true when the instruction takes one more cycle
+    // if the effective address, or the branch target, is on another page.
+    PageCross bool
+
+    Status Status
+}
+
+// NMOSOpcodes is the opcode table of the NMOS 6502, illegal opcodes included.
+// The cycles of JAM are 0 since it never completes.
+var NMOSOpcodes = [256]Opcode{
+    0x00: {"BRK", Implied, 1, 7, false, Documented},
+    0x01: {"ORA", IndexedIndirect, 2, 6, false, Documented},
+    0x02: {"JAM", Implied, 1, 0, false, Illegal},
+    0x03: {"SLO", IndexedIndirect, 2, 8, false, Illegal},
+    0x04: {"NOP", ZeroPage, 2, 3, false, Illegal},
+    0x05: {"ORA", ZeroPage, 2, 3, false, Documented},
+    0x06: {"ASL", ZeroPage, 2, 5, false, Documented},
+    0x07: {"SLO", ZeroPage, 2, 5, false, Illegal},
+    0x08: {"PHP", Implied, 1, 3, false, Documented},
+    0x09: {"ORA", Immediate, 2, 2, false, Documented},
+    0x0A: {"ASL", Accumulator, 1, 2, false, Documented},
+    0x0B: {"ANC", Immediate, 2, 2, false, Illegal},
+    0x0C: {"NOP", Absolute, 3, 4, false, Illegal},
+    0x0D: {"ORA", Absolute, 3, 4, false, Documented},
+    0x0E: {"ASL", Absolute, 3, 6, false, Documented},
+    0x0F: {"SLO", Absolute, 3, 6, false, Illegal},
+    0x10: {"BPL", Relative, 2, 2, true, Documented},
+    0x11: {"ORA", IndirectIndexed, 2, 5, true, Documented},
+    0x12: {"JAM", Implied, 1, 0, false, Illegal},
+    0x13: {"SLO", IndirectIndexed, 2, 8, false, Illegal},
+    0x14: {"NOP", ZeroPageX, 2, 4, false, Illegal},
+    0x15: {"ORA", ZeroPageX, 2, 4, false, Documented},
+    0x16: {"ASL", ZeroPageX, 2, 6, false, Documented},
+    0x17: {"SLO", ZeroPageX, 2, 6, false, Illegal},
+    0x18: {"CLC", Implied, 1, 2, false, Documented},
+    0x19: {"ORA", AbsoluteY, 3, 4, true, Documented},
+    0x1A: {"NOP", Implied, 1, 2, false, Illegal},
+    0x1B: {"SLO", AbsoluteY, 3, 7, false, Illegal},
+    0x1C: {"NOP", AbsoluteX, 3, 4, true, Illegal},
+    0x1D: {"ORA", AbsoluteX, 3, 4, true, Documented},
+    0x1E: {"ASL", AbsoluteX, 3, 7, false, Documented},
+    0x1F: {"SLO", AbsoluteX, 3, 7, false, Illegal},
+    0x20: {"JSR", Absolute, 3, 6, false, Documented},
+    0x21: {"AND", IndexedIndirect, 2, 6, false, Documented},
+    0x22: {"JAM", Implied, 1, 0, false, Illegal},
+    0x23: {"RLA", IndexedIndirect, 2, 8, false, Illegal},
+    0x24: {"BIT", ZeroPage, 2, 3, false, Documented},
+    0x25: {"AND", ZeroPage, 2, 3, false, Documented},
+    0x26: {"ROL", ZeroPage, 2, 5, false, Documented},
+    0x27: {"RLA", ZeroPage, 2, 5, false, Illegal},
+    0x28: {"PLP", Implied, 1, 4, false, Documented},
+    0x29: {"AND", Immediate, 2, 2, false, Documented},
+    0x2A: {"ROL", Accumulator, 1, 2, false, Documented},
+    0x2B: {"ANC", Immediate, 2, 2, false, Illegal},
+    0x2C: {"BIT", Absolute, 3, 4, false, Documented},
+    0x2D: {"AND", Absolute, 3, 4, false, Documented},
+    0x2E: {"ROL", Absolute, 3, 6, false, Documented},
+    0x2F: {"RLA", Absolute, 3, 6, false, Illegal},
+    0x30: {"BMI", Relative, 2, 2, true, Documented},
+    0x31: {"AND", IndirectIndexed, 2, 5, true, Documented},
+    0x32: {"JAM", Implied, 1, 0, false, Illegal},
+    0x33: {"RLA", IndirectIndexed, 2, 8, false, Illegal},
+    0x34: {"NOP", ZeroPageX, 2, 4, false, Illegal},
+    0x35: {"AND", ZeroPageX, 2, 4, false, Documented},
+    0x36: {"ROL", ZeroPageX, 2, 6, false, Documented},
+    0x37: {"RLA", ZeroPageX, 2, 6, false, Illegal},
+    0x38: {"SEC", Implied, 1, 2, false, Documented},
+    0x39: {"AND", AbsoluteY, 3, 4, true, Documented},
+    0x3A: {"NOP", Implied, 1, 2, false, Illegal},
+    0x3B: {"RLA", AbsoluteY, 3, 7, false, Illegal},
+    0x3C: {"NOP", AbsoluteX, 3, 4, true, Illegal},
+    0x3D: {"AND", AbsoluteX, 3, 4, true, Documented},
+    0x3E: {"ROL", AbsoluteX, 3, 7, false, Documented},
+    0x3F: {"RLA", AbsoluteX, 3, 7, false, Illegal},
+    0x40: {"RTI", Implied, 1, 6, false, Documented},
+    0x41: {"EOR", IndexedIndirect, 2, 6, false, Documented},
+    0x42: {"JAM", Implied, 1, 0, false, Illegal},
+    0x43: {"SRE", IndexedIndirect, 2, 8, false, Illegal},
+    0x44: {"NOP", ZeroPage, 2, 3, false, Illegal},
+    0x45: {"EOR", ZeroPage, 2, 3, false, Documented},
+    0x46: {"LSR", ZeroPage, 2, 5, false, Documented},
+    0x47: {"SRE", ZeroPage, 2, 5, false, Illegal},
+    0x48: {"PHA", Implied, 1, 3, false, Documented},
+    0x49: {"EOR", Immediate, 2, 2, false, Documented},
+    0x4A: {"LSR", Accumulator, 1, 2, false, Documented},
+    0x4B: {"ALR", Immediate, 2, 2, false, Illegal},
+    0x4C: {"JMP", Absolute, 3, 3, false, Documented},
+    0x4D: {"EOR", Absolute, 3, 4, false, Documented},
+    0x4E: {"LSR", Absolute, 3, 6, false, Documented},
+    0x4F: {"SRE", Absolute, 3, 6, false, Illegal},
+    0x50: {"BVC", Relative, 2, 2, true, Documented},
+    0x51: {"EOR", IndirectIndexed, 2, 5, true, Documented},
+    0x52: {"JAM", Implied, 1, 0, false, Illegal},
+    0x53: {"SRE", IndirectIndexed, 2, 8, false, Illegal},
+    0x54: {"NOP", ZeroPageX, 2, 4, false, Illegal},
+    0x55: {"EOR", ZeroPageX, 2, 4, false, Documented},
+    0x56: {"LSR", ZeroPageX, 2, 6, false, Documented},
+    0x57: {"SRE", ZeroPageX, 2, 6, false, Illegal},
+    0x58: {"CLI", Implied, 1, 2, false, Documented},
+    0x59: {"EOR", AbsoluteY, 3, 4, true, Documented},
+    0x5A: {"NOP", Implied, 1, 2, false, Illegal},
+    0x5B: {"SRE", AbsoluteY, 3, 7, false, Illegal},
+    0x5C: {"NOP", AbsoluteX, 3, 4, true, Illegal},
+    0x5D: {"EOR", AbsoluteX, 3, 4, true, Documented},
+    0x5E: {"LSR", AbsoluteX, 3, 7, false, Documented},
+    0x5F: {"SRE", AbsoluteX, 3, 7, false, Illegal},
+    0x60: {"RTS", Implied, 1, 6, false, Documented},
+    0x61: {"ADC", IndexedIndirect, 2, 6, false, Documented},
+    0x62: {"JAM", Implied, 1, 0, false, Illegal},
+    0x63: {"RRA", IndexedIndirect, 2, 8, false, Illegal},
+    0x64: {"NOP", ZeroPage, 2, 3, false, Illegal},
+    0x65: {"ADC", ZeroPage, 2, 3, false, Documented},
+    0x66: {"ROR", ZeroPage, 2, 5, false, Documented},
+    0x67: {"RRA", ZeroPage, 2, 5, false, Illegal},
+    0x68: {"PLA", Implied, 1, 4, false, Documented},
+    0x69: {"ADC", Immediate, 2, 2, false, Documented},
+    0x6A: {"ROR", Accumulator, 1, 2, false, Documented},
+    0x6B: {"ARR", Immediate, 2, 2, false, Illegal},
+    0x6C: {"JMP", Indirect, 3, 5, false, Documented},
+    0x6D: {"ADC", Absolute, 3, 4, false, Documented},
+    0x6E: {"ROR", Absolute, 3, 6, false, Documented},
+    0x6F: {"RRA", Absolute, 3, 6, false, Illegal},
+    0x70: {"BVS", Relative, 2, 2, true, Documented},
+    0x71: {"ADC", IndirectIndexed, 2, 5, true, Documented},
+    0x72: {"JAM", Implied, 1, 0, false, Illegal},
+    0x73: {"RRA", IndirectIndexed, 2, 8, false, Illegal},
+    0x74: {"NOP", ZeroPageX, 2, 4, false, Illegal},
+    0x75: {"ADC", ZeroPageX, 2, 4, false, Documented},
+    0x76: {"ROR", ZeroPageX, 2, 6, false, Documented},
+    0x77: {"RRA", ZeroPageX, 2, 6, false, Illegal},
+    0x78: {"SEI", Implied, 1, 2, false, Documented},
+    0x79: {"ADC", AbsoluteY, 3, 4, true, Documented},
+    0x7A: {"NOP", Implied, 1, 2, false, Illegal},
+    0x7B: {"RRA", AbsoluteY, 3, 7, false, Illegal},
+    0x7C: {"NOP", AbsoluteX, 3, 4, true, Illegal},
+    0x7D: {"ADC", AbsoluteX, 3, 4, true, Documented},
+    0x7E: {"ROR", AbsoluteX, 3, 7, false, Documented},
+    0x7F: {"RRA", AbsoluteX, 3, 7, false, Illegal},
+    0x80: {"NOP", Immediate, 2, 2, false, Illegal},
+    0x81: {"STA", IndexedIndirect, 2, 6, false, Documented},
+    0x82: {"NOP", Immediate, 2, 2, false, Illegal},
+    0x83: {"SAX", IndexedIndirect, 2, 6, false, Illegal},
+    0x84: {"STY", ZeroPage, 2, 3, false, Documented},
+    0x85: {"STA", ZeroPage, 2, 3, false, Documented},
+    0x86: {"STX", ZeroPage, 2, 3, false, Documented},
+    0x87: {"SAX", ZeroPage, 2, 3, false, Illegal},
+    0x88: {"DEY", Implied, 1, 2, false, Documented},
+    0x89: {"NOP", Immediate, 2, 2, false, Illegal},
+    0x8A: {"TXA", Implied, 1, 2, false, Documented},
+    0x8B: {"ANE", Immediate, 2, 2, false, Illegal},
+    0x8C: {"STY", Absolute, 3, 4, false, Documented},
+    0x8D: {"STA", Absolute, 3, 4, false, Documented},
+    0x8E: {"STX", Absolute, 3, 4, false, Documented},
+    0x8F: {"SAX", Absolute, 3, 4, false, Illegal},
+    0x90: {"BCC", Relative, 2, 2, true, Documented},
+    0x91: {"STA", IndirectIndexed, 2, 6, false, Documented},
+    0x92: {"JAM", Implied, 1, 0, false, Illegal},
+    0x93: {"SHA", IndirectIndexed, 2, 6, false, Illegal},
+    0x94: {"STY", ZeroPageX, 2, 4, false, Documented},
+    0x95: {"STA", ZeroPageX, 2, 4, false, Documented},
+    0x96: {"STX", ZeroPageY, 2, 4, false, Documented},
+    0x97: {"SAX", ZeroPageY, 2, 4, false, Illegal},
+    0x98: {"TYA", Implied, 1, 2, false, Documented},
+    0x99: {"STA", AbsoluteY, 3, 5, false, Documented},
+    0x9A: {"TXS", Implied, 1, 2, false, Documented},
+    0x9B: {"TAS", AbsoluteY, 3, 5, false, Illegal},
+    0x9C: {"SHY", AbsoluteX, 3, 5, false, Illegal},
+    0x9D: {"STA", AbsoluteX, 3, 5, false, Documented},
+    0x9E: {"SHX", AbsoluteY, 3, 5, false, Illegal},
+    0x9F: {"SHA", AbsoluteY, 3, 5, false, Illegal},
+    0xA0: {"LDY", Immediate, 2, 2, false, Documented},
+    0xA1: {"LDA", IndexedIndirect, 2, 6, false, Documented},
+    0xA2: {"LDX", Immediate, 2, 2, false, Documented},
+    0xA3: {"LAX", IndexedIndirect, 2, 6, false, Illegal},
+    0xA4: {"LDY", ZeroPage, 2, 3, false, Documented},
+    0xA5: {"LDA", ZeroPage, 2, 3, false, Documented},
+    0xA6: {"LDX", ZeroPage, 2, 3, false, Documented},
+    0xA7: {"LAX", ZeroPage, 2, 3, false, Illegal},
+    0xA8: {"TAY", Implied, 1, 2, false, Documented},
+    0xA9: {"LDA", Immediate, 2, 2, false, Documented},
+    0xAA: {"TAX", Implied, 1, 2, false, Documented},
+    0xAB: {"LXA", Immediate, 2, 2, false, Illegal},
+    0xAC: {"LDY", Absolute, 3, 4, false, Documented},
+    0xAD: {"LDA", Absolute, 3, 4, false, Documented},
+    0xAE: {"LDX", Absolute, 3, 4, false, Documented},
+    0xAF: {"LAX", Absolute, 3, 4, false, Illegal},
+    0xB0: {"BCS", Relative, 2, 2, true, Documented},
+    0xB1: {"LDA", IndirectIndexed, 2, 5, true, Documented},
+    0xB2: {"JAM", Implied, 1, 0, false, Illegal},
+    0xB3: {"LAX", IndirectIndexed, 2, 5, true, Illegal},
+    0xB4: {"LDY", ZeroPageX, 2, 4, false, Documented},
+    0xB5: {"LDA", ZeroPageX, 2, 4, false, Documented},
+    0xB6: {"LDX", ZeroPageY, 2, 4, false, Documented},
+    0xB7: {"LAX", ZeroPageY, 2, 4, false, Illegal},
+    0xB8: {"CLV", Implied, 1, 2, false, Documented},
+    0xB9: {"LDA", AbsoluteY, 3, 4, true, Documented},
+    0xBA: {"TSX", Implied, 1, 2, false, Documented},
+    0xBB: {"LAS", AbsoluteY, 3, 4, true, Illegal},
+    0xBC: {"LDY", AbsoluteX, 3, 4, true, Documented},
+    0xBD: {"LDA", AbsoluteX, 3, 4, true, Documented},
+    0xBE: {"LDX", AbsoluteY, 3, 4, true, Documented},
+    0xBF: {"LAX", AbsoluteY, 3, 4, true, Illegal},
+    0xC0: {"CPY", Immediate, 2, 2, false, Documented},
+    0xC1: {"CMP", IndexedIndirect, 2, 6, false, Documented},
+    0xC2: {"NOP", Immediate, 2, 2, false, Illegal},
+    0xC3: {"DCP", IndexedIndirect, 2, 8, false, Illegal},
+    0xC4: {"CPY", ZeroPage, 2, 3, false, Documented},
+    0xC5: {"CMP", ZeroPage, 2, 3, false, Documented},
+    0xC6: {"DEC", ZeroPage, 2, 5, false, Documented},
+    0xC7: {"DCP", ZeroPage, 2, 5, false, Illegal},
+    0xC8: {"INY", Implied, 1, 2, false, Documented},
+    0xC9: {"CMP", Immediate, 2, 2, false, Documented},
+    0xCA: {"DEX", Implied, 1, 2, false, Documented},
+    0xCB: {"SBX", Immediate, 2, 2, false, Illegal},
+    0xCC: {"CPY", Absolute, 3, 4, false, Documented},
+    0xCD: {"CMP", Absolute, 3, 4, false, Documented},
+    0xCE: {"DEC", Absolute, 3, 6, false, Documented},
+    0xCF: {"DCP", Absolute, 3, 6, false, Illegal},
+    0xD0: {"BNE", Relative, 2, 2, true, Documented},
+    0xD1: {"CMP", IndirectIndexed, 2, 5, true, Documented},
+    0xD2: {"JAM", Implied, 1, 0, false, Illegal},
+    0xD3: {"DCP", IndirectIndexed, 2, 8, false, Illegal},
+    0xD4: {"NOP", ZeroPageX, 2, 4, false, Illegal},
+    0xD5: {"CMP", ZeroPageX, 2, 4, false, Documented},
+    0xD6: {"DEC", ZeroPageX, 2, 6, false, Documented},
+    0xD7: {"DCP", ZeroPageX, 2, 6, false, Illegal},
+    0xD8: {"CLD", Implied, 1, 2, false, Documented},
+    0xD9: {"CMP", AbsoluteY, 3, 4, true, Documented},
+    0xDA: {"NOP", Implied, 1, 2, false, Illegal},
+    0xDB: {"DCP", AbsoluteY, 3, 7, false, Illegal},
+    0xDC: {"NOP", AbsoluteX, 3, 4, true, Illegal},
+    0xDD: {"CMP", AbsoluteX, 3, 4, true, Documented},
+    0xDE: {"DEC", AbsoluteX, 3, 7, false, Documented},
+    0xDF: {"DCP", AbsoluteX, 3, 7, false, Illegal},
+    0xE0: {"CPX", Immediate, 2, 2, false, Documented},
+    0xE1: {"SBC", IndexedIndirect, 2, 6, false, Documented},
+    0xE2: {"NOP", Immediate, 2, 2, false, Illegal},
+    0xE3: {"ISC", IndexedIndirect, 2, 8, false, Illegal},
+    0xE4: {"CPX", ZeroPage, 2, 3, false, Documented},
+    0xE5: {"SBC", ZeroPage, 2, 3, false, Documented},
+    0xE6: {"INC", ZeroPage, 2, 5, false, Documented},
+    0xE7: {"ISC", ZeroPage, 2, 5, false, Illegal},
+    0xE8: {"INX", Implied, 1, 2, false, Documented},
+    0xE9: {"SBC", Immediate, 2, 2, false, Documented},
+    0xEA: {"NOP", Implied, 1, 2, false, Documented},
+    0xEB: {"USBC", Immediate, 2, 2, false, Illegal},
+    0xEC: {"CPX", Absolute, 3, 4, false, Documented},
+    0xED: {"SBC", Absolute, 3, 4, false, Documented},
+    0xEE: {"INC", Absolute, 3, 6, false, Documented},
+    0xEF: {"ISC", Absolute, 3, 6, false, Illegal},
+    0xF0: {"BEQ", Relative, 2, 2, true, Documented},
+    0xF1: {"SBC", IndirectIndexed, 2, 5, true, Documented},
+    0xF2: {"JAM", Implied, 1, 0, false, Illegal},
+    0xF3: {"ISC", IndirectIndexed, 2, 8, false, Illegal},
+    0xF4: {"NOP", ZeroPageX, 2, 4, false, Illegal},
+    0xF5: {"SBC", ZeroPageX, 2, 4, false, Documented},
+    0xF6: {"INC", ZeroPageX, 2, 6, false, Documented},
+    0xF7: {"ISC", ZeroPageX, 2, 6, false, Illegal},
+    0xF8: {"SED", Implied, 1, 2, false, Documented},
+    0xF9: {"SBC", AbsoluteY, 3, 4, true, Documented},
+    0xFA: {"NOP", Implied, 1, 2, false, Illegal},
+    0xFB: {"ISC", AbsoluteY, 3, 7, false, Illegal},
+    0xFC: {"NOP", AbsoluteX, 3, 4, true, Illegal},
+    0xFD: {"SBC", AbsoluteX, 3, 4, true, Documented},
+    0xFE: {"INC", AbsoluteX, 3, 7, false, Documented},
+    0xFF: {"ISC", AbsoluteX, 3, 7, false, Illegal},
+}
+
+// CMOSOpcodes is the opcode table of the WDC 65C02.
+// The opcodes left undefined by WDC are NOPs, with the size and cycles they have on the chip.
+// In decimal mode ADC and SBC take one more cycle than listed.
+var CMOSOpcodes = [256]Opcode{
+    0x00: {"BRK", Implied, 1, 7, false, Documented},
+    0x01: {"ORA", IndexedIndirect, 2, 6, false, Documented},
+    0x02: {"NOP", Immediate, 2, 2, false, Illegal},
+    0x03: {"NOP", Implied, 1, 1, false, Illegal},
+    0x04: {"TSB", ZeroPage, 2, 5, false, CMOS},
+    0x05: {"ORA", ZeroPage, 2, 3, false, Documented},
+    0x06: {"ASL", ZeroPage, 2, 5, false, Documented},
+    0x07: {"RMB0", ZeroPage, 2, 5, false, CMOS},
+    0x08: {"PHP", Implied, 1, 3, false, Documented},
+    0x09: {"ORA", Immediate, 2, 2, false, Documented},
+    0x0A: {"ASL", Accumulator, 1, 2, false, Documented},
+    0x0B: {"NOP", Implied, 1, 1, false, Illegal},
+    0x0C: {"TSB", Absolute, 3, 6, false, CMOS},
+    0x0D: {"ORA", Absolute, 3, 4, false, Documented},
+    0x0E: {"ASL", Absolute, 3, 6, false, Documented},
+    0x0F: {"BBR0", ZeroPageRelative, 3, 5, true, CMOS},
+    0x10: {"BPL", Relative, 2, 2, true, Documented},
+    0x11: {"ORA", IndirectIndexed, 2, 5, true, Documented},
+    0x12: {"ORA", ZeroPageIndirect, 2, 5, false, CMOS},
+    0x13: {"NOP", Implied, 1, 1, false, Illegal},
+    0x14: {"TRB", ZeroPage, 2, 5, false, CMOS},
+    0x15: {"ORA", ZeroPageX, 2, 4, false, Documented},
+    0x16: {"ASL", ZeroPageX, 2, 6, false, Documented},
+    0x17: {"RMB1", ZeroPage, 2, 5, false, CMOS},
+    0x18: {"CLC", Implied, 1, 2, false, Documented},
+    0x19: {"ORA", AbsoluteY, 3, 4, true, Documented},
+    0x1A: {"INC", Accumulator, 1, 2, false, CMOS},
+    0x1B: {"NOP", Implied, 1, 1, false, Illegal},
+    0x1C: {"TRB", Absolute, 3, 6, false, CMOS},
+    0x1D: {"ORA", AbsoluteX, 3, 4, true, Documented},
+    0x1E: {"ASL", AbsoluteX, 3, 6, true, Documented},
+    0x1F: {"BBR1", ZeroPageRelative, 3, 5, true, CMOS},
+    0x20: {"JSR", Absolute, 3, 6, false, Documented},
+    0x21: {"AND", IndexedIndirect, 2, 6, false, Documented},
+    0x22: {"NOP", Immediate, 2, 2, false, Illegal},
+    0x23: {"NOP", Implied, 1, 1, false, Illegal},
+    0x24: {"BIT", ZeroPage, 2, 3, false, Documented},
+    0x25: {"AND", ZeroPage, 2, 3, false, Documented},
+    0x26: {"ROL", ZeroPage, 2, 5, false, Documented},
+    0x27: {"RMB2", ZeroPage, 2, 5, false, CMOS},
+    0x28: {"PLP", Implied, 1, 4, false, Documented},
+    0x29: {"AND", Immediate, 2, 2, false, Documented},
+    0x2A: {"ROL", Accumulator, 1, 2, false, Documented},
+    0x2B: {"NOP", Implied, 1, 1, false, Illegal},
+    0x2C: {"BIT", Absolute, 3, 4, false, Documented},
+    0x2D: {"AND", Absolute, 3, 4, false, Documented},
+    0x2E: {"ROL", Absolute, 3, 6, false, Documented},
+    0x2F: {"BBR2", ZeroPageRelative, 3, 5, true, CMOS},
+    0x30: {"BMI", Relative, 2, 2, true, Documented},
+    0x31: {"AND", IndirectIndexed, 2, 5, true, Documented},
+    0x32: {"AND", ZeroPageIndirect, 2, 5, false, CMOS},
+    0x33: {"NOP", Implied, 1, 1, false, Illegal},
+    0x34: {"BIT", ZeroPageX, 2, 4, false, CMOS},
+    0x35: {"AND", ZeroPageX, 2, 4, false, Documented},
+    0x36: {"ROL", ZeroPageX, 2, 6, false, Documented},
+    0x37: {"RMB3", ZeroPage, 2, 5, false, CMOS},
+    0x38: {"SEC", Implied, 1, 2, false, Documented},
+    0x39: {"AND", AbsoluteY, 3, 4, true, Documented},
+    0x3A: {"DEC", Accumulator, 1, 2, false, CMOS},
+    0x3B: {"NOP", Implied, 1, 1, false, Illegal},
+    0x3C: {"BIT", AbsoluteX, 3, 4, true, CMOS},
+    0x3D: {"AND", AbsoluteX, 3, 4, true, Documented},
+    0x3E: {"ROL", AbsoluteX, 3, 6, true, Documented},
+    0x3F: {"BBR3", ZeroPageRelative, 3, 5, true, CMOS},
+    0x40: {"RTI", Implied, 1, 6, false, Documented},
+    0x41: {"EOR", IndexedIndirect, 2, 6, false, Documented},
+    0x42: {"NOP", Immediate, 2, 2, false, Illegal},
+    0x43: {"NOP", Implied, 1, 1, false, Illegal},
+    0x44: {"NOP", ZeroPage, 2, 3, false, Illegal},
+    0x45: {"EOR", ZeroPage, 2, 3, false, Documented},
+    0x46: {"LSR", ZeroPage, 2, 5, false, Documented},
+    0x47: {"RMB4", ZeroPage, 2, 5, false, CMOS},
+    0x48: {"PHA", Implied, 1, 3, false, Documented},
+    0x49: {"EOR", Immediate, 2, 2, false, Documented},
+    0x4A: {"LSR", Accumulator, 1, 2, false, Documented},
+    0x4B: {"NOP", Implied, 1, 1, false, Illegal},
+    0x4C: {"JMP", Absolute, 3, 3, false, Documented},
+    0x4D: {"EOR", Absolute, 3, 4, false, Documented},
+    0x4E: {"LSR", Absolute, 3, 6, false, Documented},
+    0x4F: {"BBR4", ZeroPageRelative, 3, 5, true, CMOS},
+    0x50: {"BVC", Relative, 2, 2, true, Documented},
+    0x51: {"EOR", IndirectIndexed, 2, 5, true, Documented},
+    0x52: {"EOR", ZeroPageIndirect, 2, 5, false, CMOS},
+    0x53: {"NOP", Implied, 1, 1, false, Illegal},
+    0x54: {"NOP", ZeroPageX, 2, 4, false, Illegal},
+    0x55: {"EOR", ZeroPageX, 2, 4, false, Documented},
+    0x56: {"LSR", ZeroPageX, 2, 6, false, Documented},
+    0x57: {"RMB5", ZeroPage, 2, 5, false, CMOS},
+    0x58: {"CLI", Implied, 1, 2, false, Documented},
+    0x59: {"EOR", AbsoluteY, 3, 4, true, Documented},
+    0x5A: {"PHY", Implied, 1, 3, false, CMOS},
+    0x5B: {"NOP", Implied, 1, 1, false, Illegal},
+    0x5C: {"NOP", Absolute, 3, 8, false, Illegal},
+    0x5D: {"EOR", AbsoluteX, 3, 4, true, Documented},
+    0x5E: {"LSR", AbsoluteX, 3, 6, true, Documented},
+    0x5F: {"BBR5", ZeroPageRelative, 3, 5, true, CMOS},
+    0x60: {"RTS", Implied, 1, 6, false, Documented},
+    0x61: {"ADC", IndexedIndirect, 2, 6, false, Documented},
+    0x62: {"NOP", Immediate, 2, 2, false, Illegal},
+    0x63: {"NOP", Implied, 1, 1, false, Illegal},
+    0x64: {"STZ", ZeroPage, 2, 3, false, CMOS},
+    0x65: {"ADC", ZeroPage, 2, 3, false, Documented},
+    0x66: {"ROR", ZeroPage, 2, 5, false, Documented},
+    0x67: {"RMB6", ZeroPage, 2, 5, false, CMOS},
+    0x68: {"PLA", Implied, 1, 4, false, Documented},
+    0x69: {"ADC", Immediate, 2, 2, false, Documented},
+    0x6A: {"ROR", Accumulator, 1, 2, false, Documented},
+    0x6B: {"NOP", Implied, 1, 1, false, Illegal},
+    0x6C: {"JMP", Indirect, 3, 6, false, Documented},
+    0x6D: {"ADC", Absolute, 3, 4, false, Documented},
+    0x6E: {"ROR", Absolute, 3, 6, false, Documented},
+    0x6F: {"BBR6", ZeroPageRelative, 3, 5, true, CMOS},
+    0x70: {"BVS", Relative, 2, 2, true, Documented},
+    0x71: {"ADC", IndirectIndexed, 2, 5, true, Documented},
+    0x72: {"ADC", ZeroPageIndirect, 2, 5, false, CMOS},
+    0x73: {"NOP", Implied, 1, 1, false, Illegal},
+    0x74: {"STZ", ZeroPageX, 2, 4, false, CMOS},
+    0x75: {"ADC", ZeroPageX, 2, 4, false, Documented},
+    0x76: {"ROR", ZeroPageX, 2, 6, false, Documented},
+    0x77: {"RMB7", ZeroPage, 2, 5, false, CMOS},
+    0x78: {"SEI", Implied, 1, 2, false, Documented},
+    0x79: {"ADC", AbsoluteY, 3, 4, true, Documented},
+    0x7A: {"PLY", Implied, 1, 4, false, CMOS},
+    0x7B: {"NOP", Implied, 1, 1, false, Illegal},
+    0x7C: {"JMP", AbsoluteIndexedIndirect, 3, 6, false, CMOS},
+    0x7D: {"ADC", AbsoluteX, 3, 4, true, Documented},
+    0x7E: {"ROR", AbsoluteX, 3, 6, true, Documented},
+    0x7F: {"BBR7", ZeroPageRelative, 3, 5, true, CMOS},
+    0x80: {"BRA", Relative, 2, 3, true, CMOS},
+    0x81: {"STA", IndexedIndirect, 2, 6, false, Documented},
+    0x82: {"NOP", Immediate, 2, 2, false, Illegal},
+    0x83: {"NOP", Implied, 1, 1, false, Illegal},
+    0x84: {"STY", ZeroPage, 2, 3, false, Documented},
+    0x85: {"STA", ZeroPage, 2, 3, false, Documented},
+    0x86: {"STX", ZeroPage, 2, 3, false, Documented},
+    0x87: {"SMB0", ZeroPage, 2, 5, false, CMOS},
+    0x88: {"DEY", Implied, 1, 2, false, Documented},
+    0x89: {"BIT", Immediate, 2, 2, false, CMOS},
+    0x8A: {"TXA", Implied, 1, 2, false, Documented},
+    0x8B: {"NOP", Implied, 1, 1, false, Illegal},
+    0x8C: {"STY", Absolute, 3, 4, false, Documented},
+    0x8D: {"STA", Absolute, 3, 4, false, Documented},
+    0x8E: {"STX", Absolute, 3, 4, false, Documented},
+    0x8F: {"BBS0", ZeroPageRelative, 3, 5, true, CMOS},
+    0x90: {"BCC", Relative, 2, 2, true, Documented},
+    0x91: {"STA", IndirectIndexed, 2, 6, false, Documented},
+    0x92: {"STA", ZeroPageIndirect, 2, 5, false, CMOS},
+    0x93: {"NOP", Implied, 1, 1, false, Illegal},
+    0x94: {"STY", ZeroPageX, 2, 4, false, Documented},
+    0x95: {"STA", ZeroPageX, 2, 4, false, Documented},
+    0x96: {"STX", ZeroPageY, 2, 4, false, Documented},
+    0x97: {"SMB1", ZeroPage, 2, 5, false, CMOS},
+    0x98: {"TYA", Implied, 1, 2, false, Documented},
+    0x99: {"STA", AbsoluteY, 3, 5, false, Documented},
+    0x9A: {"TXS", Implied, 1, 2, false, Documented},
+    0x9B: {"NOP", Implied, 1, 1, false, Illegal},
+    0x9C: {"STZ", Absolute, 3, 4, false, CMOS},
+    0x9D: {"STA", AbsoluteX, 3, 5, false, Documented},
+    0x9E: {"STZ", AbsoluteX, 3, 5, false, CMOS},
+    0x9F: {"BBS1", ZeroPageRelative, 3, 5, true, CMOS},
+    0xA0: {"LDY", Immediate, 2, 2, false, Documented},
+    0xA1: {"LDA", IndexedIndirect, 2, 6, false, Documented},
+    0xA2: {"LDX", Immediate, 2, 2, false, Documented},
+    0xA3: {"NOP", Implied, 1, 1, false, Illegal},
+    0xA4: {"LDY", ZeroPage, 2, 3, false, Documented},
+    0xA5: {"LDA", ZeroPage, 2, 3, false, Documented},
+    0xA6: {"LDX", ZeroPage, 2, 3, false, Documented},
+    0xA7: {"SMB2", ZeroPage, 2, 5, false, CMOS},
+    0xA8: {"TAY", Implied, 1, 2, false, Documented},
+    0xA9: {"LDA", Immediate, 2, 2, false, Documented},
+    0xAA: {"TAX", Implied, 1, 2, false, Documented},
+    0xAB: {"NOP", Implied, 1, 1, false, Illegal},
+    0xAC: {"LDY", Absolute, 3, 4, false, Documented},
+    0xAD: {"LDA", Absolute, 3, 4, false, Documented},
+    0xAE: {"LDX", Absolute, 3, 4, false, Documented},
+    0xAF: {"BBS2", ZeroPageRelative, 3, 5, true, CMOS},
+    0xB0: {"BCS", Relative, 2, 2, true, Documented},
+    0xB1: {"LDA", IndirectIndexed, 2, 5, true, Documented},
+    0xB2: {"LDA", ZeroPageIndirect, 2, 5, false, CMOS},
+    0xB3: {"NOP", Implied, 1, 1, false, Illegal},
+    0xB4: {"LDY", ZeroPageX, 2, 4, false, Documented},
+    0xB5: {"LDA", ZeroPageX, 2, 4, false, Documented},
+    0xB6: {"LDX", ZeroPageY, 2, 4, false, Documented},
+    0xB7: {"SMB3", ZeroPage, 2, 5, false, CMOS},
+    0xB8: {"CLV", Implied, 1, 2, false, Documented},
+    0xB9: {"LDA", AbsoluteY, 3, 4, true, Documented},
+    0xBA: {"TSX", Implied, 1, 2, false, Documented},
+    0xBB: {"NOP", Implied, 1, 1, false, Illegal},
+    0xBC: {"LDY", AbsoluteX, 3, 4, true, Documented},
+    0xBD: {"LDA", AbsoluteX, 3, 4, true, Documented},
+    0xBE: {"LDX", AbsoluteY, 3, 4, true, Documented},
+    0xBF: {"BBS3", ZeroPageRelative, 3, 5, true, CMOS},
+    0xC0: {"CPY", Immediate, 2, 2, false, Documented},
+    0xC1: {"CMP", IndexedIndirect, 2, 6, false, Documented},
+    0xC2: {"NOP", Immediate, 2, 2, false, Illegal},
+    0xC3: {"NOP", Implied, 1, 1, false, Illegal},
+    0xC4: {"CPY", ZeroPage, 2, 3, false, Documented},
+    0xC5: {"CMP", ZeroPage, 2, 3, false, Documented},
+    0xC6: {"DEC", ZeroPage, 2, 5, false, Documented},
+    0xC7: {"SMB4", ZeroPage, 2, 5, false, CMOS},
+    0xC8: {"INY", Implied, 1, 2, false, Documented},
+    0xC9: {"CMP", Immediate, 2, 2, false, Documented},
+    0xCA: {"DEX", Implied, 1, 2, false, Documented},
+    0xCB: {"WAI", Implied, 1, 3, false, CMOS},
+    0xCC: {"CPY", Absolute, 3, 4, false, Documented},
+    0xCD: {"CMP", Absolute, 3, 4, false, Documented},
+    0xCE: {"DEC", Absolute, 3, 6, false, Documented},
+    0xCF: {"BBS4", ZeroPageRelative, 3, 5, true, CMOS},
+    0xD0: {"BNE", Relative, 2, 2, true, Documented},
+    0xD1: {"CMP", IndirectIndexed, 2, 5, true, Documented},
+    0xD2: {"CMP", ZeroPageIndirect, 2, 5, false, CMOS},
+    0xD3: {"NOP", Implied, 1, 1, false, Illegal},
+    0xD4: {"NOP", ZeroPageX, 2, 4, false, Illegal},
+    0xD5: {"CMP", ZeroPageX, 2, 4, false, Documented},
+    0xD6: {"DEC", ZeroPageX, 2, 6, false, Documented},
+    0xD7: {"SMB5", ZeroPage, 2, 5, false, CMOS},
+    0xD8: {"CLD", Implied, 1, 2, false, Documented},
+    0xD9: {"CMP", AbsoluteY, 3, 4, true, Documented},
+    0xDA: {"PHX", Implied, 1, 3, false, CMOS},
+    0xDB: {"STP", Implied, 1, 3, false, CMOS},
+    0xDC: {"NOP", Absolute, 3, 4, false, Illegal},
+    0xDD: {"CMP", AbsoluteX, 3, 4, true, Documented},
+    0xDE: {"DEC", AbsoluteX, 3, 7, false, Documented},
+    0xDF: {"BBS5", ZeroPageRelative, 3, 5, true, CMOS},
+    0xE0: {"CPX", Immediate, 2, 2, false, Documented},
+    0xE1: {"SBC", IndexedIndirect, 2, 6, false, Documented},
+    0xE2: {"NOP", Immediate, 2, 2, false, Illegal},
+    0xE3: {"NOP", Implied, 1, 1, false, Illegal},
+    0xE4: {"CPX", ZeroPage, 2, 3, false, Documented},
+    0xE5: {"SBC", ZeroPage, 2, 3, false, Documented},
+    0xE6: {"INC", ZeroPage, 2, 5, false, Documented},
+    0xE7: {"SMB6", ZeroPage, 2, 5, false, CMOS},
+    0xE8: {"INX", Implied, 1, 2, false, Documented},
+    0xE9: {"SBC", Immediate, 2, 2, false, Documented},
+    0xEA: {"NOP", Implied, 1, 2, false, Documented},
+    0xEB: {"NOP", Implied, 1, 1, false, Illegal},
+    0xEC: {"CPX", Absolute, 3, 4, false, Documented},
+    0xED: {"SBC", Absolute, 3, 4, false, Documented},
+    0xEE: {"INC", Absolute, 3, 6, false, Documented},
+    0xEF: {"BBS6", ZeroPageRelative, 3, 5, true, CMOS},
+    0xF0: {"BEQ", Relative, 2, 2, true, Documented},
+    0xF1: {"SBC", IndirectIndexed, 2, 5, true, Documented},
+    0xF2: {"SBC", ZeroPageIndirect, 2, 5, false, CMOS},
+    0xF3: {"NOP", Implied, 1, 1, false, Illegal},
+    0xF4: {"NOP", ZeroPageX, 2, 4, false, Illegal},
+    0xF5: {"SBC", ZeroPageX, 2, 4, false, Documented},
+    0xF6: {"INC", ZeroPageX, 2, 6, false, Documented},
+    0xF7: {"SMB7", ZeroPage, 2, 5, false, CMOS},
+    0xF8: {"SED", Implied, 1, 2, false, Documented},
+    0xF9: {"SBC", AbsoluteY, 3, 4, true, Documented},
+    0xFA: {"PLX", Implied, 1, 4, false, CMOS},
+    0xFB: {"NOP", Implied, 1, 1, false, Illegal},
+    0xFC: {"NOP", Absolute, 3, 4, false, Illegal},
+    0xFD: {"SBC", AbsoluteX, 3, 4, true, Documented},
+    0xFE: {"INC", AbsoluteX, 3, 7, false, Documented},
+    0xFF: {"BBS7", ZeroPageRelative, 3, 5, true, CMOS},
+}
+
+// Table returns the opcode table of the NMOS 6502, or of the 65C02 when cmos is true.
+func Table(cmos bool) *[256]Opcode{
+
+    if cmos {
+        return &CMOSOpcodes
+    }
+
+    return &NMOSOpcodes
+}
@@ -0,0 +1,48 @@
+package instructions
+
+import "testing"
+
+func TestOpcodeSizeMatchesMode(t *testing.T){
+
+    for _, table := range []*[256]Opcode{&NMOSOpcodes, &CMOSOpcodes} {
+        for code, opcode := range table {
+
+            if opcode.Size != opcode.Mode.OperandSize() + 1 {
+                t.Errorf("$%02X %v %v: size %d", code, opcode.Mnemonic, opcode.Mode, opcode.Size)
+            }
+        }
+    }
+}
+
+func TestOpcodeTablesAgreeWithConstants(t *testing.T){
+
+    tests := []struct {
+        table *[256]Opcode
+        code byte
+        mnemonic string
+        mode Mode
+        status Status
+    }{
+        {&NMOSOpcodes, INS_LDA_INDY, "LDA", IndirectIndexed, Documented},
+        {&NMOSOpcodes, INS_JMP_IND, "JMP", Indirect, Documented},
+        {&NMOSOpcodes, INS_ASL_ACC, "ASL", Accumulator, Documented},
+        {&NMOSOpcodes, INS_LAX_ZPY, "LAX", ZeroPageY, Illegal},
+        {&NMOSOpcodes, INS_NOP_ABSX_1C, "NOP", AbsoluteX, Illegal},
+        {&NMOSOpcodes, INS_JAM_02, "JAM", Implied, Illegal},
+        {&CMOSOpcodes, INS_STZ_ABSX, "STZ", AbsoluteX, CMOS},
+        {&CMOSOpcodes, INS_LDA_INDZP, "LDA", ZeroPageIndirect, CMOS},
+        {&CMOSOpcodes, INS_BBR0_ZPREL, "BBR0", ZeroPageRelative, CMOS},
+        {&CMOSOpcodes, INS_JMP_INDX, "JMP", AbsoluteIndexedIndirect, CMOS},
+        {&CMOSOpcodes, INS_NOP_IMP, "NOP", Implied, Documented},
+        {&CMOSOpcodes, INS_SLO_INDX, "NOP", Implied, Illegal},
+    }
+
+    for _, test := range tests {
+
+        opcode := test.table[test.code]
+
+        if opcode.Mnemonic != test.mnemonic || opcode.Mode != test.mode || opcode.Status != test.status {
+            t.Errorf("$%02X: expected %v %v %v but got %v %v %v", test.code, test.mnemonic, test.mode, test.status, opcode.Mnemonic, opcode.Mode, opcode.Status)
+        }
+    }
+}
@@ -0,0 +1,112 @@
+package link
+
+import (
+	"bufio"
+	"emulator/pkg/common"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Config is the memory layout of the machine the objects are linked for.
+// Segments are placed in its order, so it decides which ones come first in
+// a range shared by several, like CODE followed by RODATA in the ROM.
+type Config struct {
+    Segments []SegmentConfig
+}
+
+// SegmentConfig maps a segment to the range of addresses from Start to End, included.
+type SegmentConfig struct {
+    Name string
+    Start uint16
+    End uint16
+}
+
+func (segment SegmentConfig) String() string{
+    return fmt.Sprintf("%s $%04X-$%04X", segment.Name, segment.Start, segment.End)
+}
+
+// ParseConfig reads a memory layout, one segment per line with its range of addresses:
+//
+//	# name     range
+//	ZEROPAGE   $0000-$00FF
+//	CODE       $E000-$FFF9
+//	RODATA     $E000-$FFF9
+//	VECTORS    $FFFA-$FFFF
+//
+// Addresses are written $E000, 0xE000 or 57344. Comments start with # or ;.
+func ParseConfig(r io.Reader) (*Config, error){
+
+    config := &Config{}
+    seen := map[string]bool{}
+
+    scanner := bufio.NewScanner(r)
+
+    for line := 1; scanner.Scan(); line++ {
+
+        text := scanner.Text()
+        if comment := strings.IndexAny(text, "#;"); comment != -1 {
+            text = text[:comment]
+        }
+
+        fields := strings.Fields(text)
+        if len(fields) == 0 {
+            continue
+        }
+
+        segment, err := parseSegment(fields)
+        if err != nil {
+            return nil, fmt.Errorf("line %d: %w", line, err)
+        }
+
+        if seen[segment.Name] {
+            return nil, fmt.Errorf("line %d: segment %s is configured twice", line, segment.Name)
+        }
+
+        seen[segment.Name] = true
+        config.Segments = append(config.Segments, segment)
+    }
+
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+
+    return config, nil
+}
+
+// ReadConfig parses the config file at path.
+func ReadConfig(path string) (*Config, error){
+
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    config, err := ParseConfig(file)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %w", path, err)
+    }
+
+    return config, nil
+}
+
+func parseSegment(fields []string) (SegmentConfig, error){
+
+    // The range can also be written with spaces: CODE $E000 - $FFF9
+    if len(fields) > 2 {
+        fields = []string{fields[0], strings.Join(fields[1:], "")}
+    }
+
+    if len(fields) != 2 {
+        return SegmentConfig{}, fmt.Errorf("expected a segment name and a range like $E000-$FFFF")
+    }
+
+    start, end, err := common.ParseAddressRange(fields[1])
+    if err != nil {
+        return SegmentConfig{}, err
+    }
+
+    return SegmentConfig{Name: fields[0], Start: start, End: end}, nil
+}
@@ -0,0 +1,48 @@
+package link
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T){
+
+    config := InitLayout(t)
+
+    expected := []SegmentConfig{
+        {Name: "ZEROPAGE", Start: 0x0080, End: 0x00FF},
+        {Name: "CODE", Start: 0xE000, End: 0xFFF9},
+        {Name: "RODATA", Start: 0xE000, End: 0xFFF9},
+        {Name: "VECTORS", Start: 0xFFFA, End: 0xFFFF},
+    }
+
+    if !reflect.DeepEqual(config.Segments, expected) {
+        t.Error("Unexpected segments: ", config.Segments)
+    }
+
+    config, err := ParseConfig(strings.NewReader("DATA 0x0200 - 1023"))
+    if err != nil || config.Segments[0] != (SegmentConfig{Name: "DATA", Start: 0x0200, End: 0x03FF}) {
+        t.Error("Unexpected config: ", config, err)
+    }
+}
+
+func TestParseConfigErrors(t *testing.T){
+
+    tests := map[string]string{
+        "CODE":                          "line 1: expected a segment name and a range",
+        "CODE $E000":                    "invalid range",
+        "CODE $E000-$G000":              "invalid address",
+        "CODE $F000-$E000":              "ends before it starts",
+        "CODE $E000-$EFFF\nCODE $F000-$FFFF": "line 2: segment CODE is configured twice",
+    }
+
+    for text, message := range tests {
+
+        _, err := ParseConfig(strings.NewReader(text))
+
+        if err == nil || !strings.Contains(err.Error(), message) {
+            t.Error(text, ": expected an error containing ", message, " but got: ", err)
+        }
+    }
+}
@@ -0,0 +1,429 @@
+// Package link links the objects assembled by package asm into an image of memory.
+//
+// The Config maps each segment to a range of addresses. The segments of the modules
+// are placed one after the other in their range, in the order of the objects given,
+// the symbols a module exports are resolved for the modules importing them, and the
+// addresses the assembler couldn't know are patched. The Image can then be loaded
+// into the memory of the emulator, and its map lists where everything went.
+package link
+
+import (
+	"emulator/pkg/arc"
+	"emulator/pkg/asm"
+	"fmt"
+	"sort"
+)
+
+// Image is the result of the link: the code of every module at its address.
+type Image struct {
+
+    // Chunks in the order they were placed, which is the order of the config
+    Chunks []Chunk
+
+    // Symbols of every module, sorted by value
+    Symbols []Symbol
+}
+
+// Chunk is a segment of a module, placed at its address.
+type Chunk struct {
+    Segment string
+    Module string
+    Address uint16
+    Data []byte
+}
+
+// End returns the address of the last byte of the chunk.
+func (chunk Chunk) End() uint16{
+    return chunk.Address + uint16(len(chunk.Data)) - 1
+}
+
+// Symbol is a label or a constant of a module, with its final value.
+type Symbol struct {
+    Name string
+    Module string
+
+    // Segment of a label, empty for constants
+    Segment string
+
+    Value int
+    Exported bool
+}
+
+// Link places the segments of objects in memory according to config, and resolves
+// the symbols they share.
+func Link(config *Config, objects ...*asm.Object) (*Image, error){
+
+    l := &linker{config: config, objects: objects, image: &Image{}}
+
+    if err := l.check(); err != nil {
+        return nil, err
+    }
+
+    if err := l.place(); err != nil {
+        return nil, err
+    }
+
+    if err := l.resolve(); err != nil {
+        return nil, err
+    }
+
+    if err := l.relocate(); err != nil {
+        return nil, err
+    }
+
+    return l.image, nil
+}
+
+// placement identifies the segment of an object.
+type placement struct {
+    object int
+    segment string
+}
+
+// linker is the state of a link.
+type linker struct {
+    config *Config
+    objects []*asm.Object
+
+    // Address of every segment of every object, indexed like objects.
+    // Segments a module has no code in are there too, for its labels.
+    bases []map[string]int
+
+    // Chunk holding every segment of every object that has code
+    chunks map[placement]int
+
+    // Exported symbols, by name
+    exports map[string]Symbol
+
+    image *Image
+}
+
+// check verifies every segment the objects use is in the config.
+func (l *linker) check() error{
+
+    configured := map[string]bool{}
+    for _, segment := range l.config.Segments {
+        configured[segment.Name] = true
+    }
+
+    for _, object := range l.objects {
+
+        for _, segment := range object.Segments {
+            if !configured[segment.Name] {
+                return fmt.Errorf("%s: segment %s is not in the config", object.Name, segment.Name)
+            }
+        }
+
+        for _, symbol := range object.Symbols {
+            if symbol.Segment != "" && !configured[symbol.Segment] {
+                return fmt.Errorf("%s: segment %s of symbol %s is not in the config", object.Name, symbol.Segment, symbol.Name)
+            }
+        }
+    }
+
+    return nil
+}
+
+// place gives an address to the segments of every module. Segments configured with the
+// same range share it: the ones coming later in the config go after the earlier ones.
+func (l *linker) place() error{
+
+    l.chunks = map[placement]int{}
+    l.bases = make([]map[string]int, len(l.objects))
+    for i := range l.bases {
+        l.bases[i] = map[string]int{}
+    }
+
+    // Next free address of each range
+    next := map[[2]uint16]int{}
+
+    for _, config := range l.config.Segments {
+
+        key := [2]uint16{config.Start, config.End}
+
+        address, ok := next[key]
+        if !ok {
+            address = int(config.Start)
+        }
+
+        for i, object := range l.objects {
+
+            l.bases[i][config.Name] = address
+
+            segment := findSegment(object, config.Name)
+            if segment == nil {
+                continue
+            }
+
+            if end := address + len(segment.Code) - 1; end > int(config.End) {
+                return fmt.Errorf("%s: segment %s overflows its range $%04X-$%04X by %d bytes", object.Name, config.Name, config.Start, config.End, end - int(config.End))
+            }
+
+            l.chunks[placement{i, config.Name}] = len(l.image.Chunks)
+            l.image.Chunks = append(l.image.Chunks, Chunk{
+                Segment: config.Name,
+                Module: object.Name,
+                Address: uint16(address),
+                Data: append([]byte(nil), segment.Code...),
+            })
+
+            address += len(segment.Code)
+        }
+
+        next[key] = address
+    }
+
+    return l.checkOverlaps()
+}
+
+// checkOverlaps catches segments configured with ranges that overlap without being the same.
+func (l *linker) checkOverlaps() error{
+
+    chunks := append([]Chunk(nil), l.image.Chunks...)
+
+    sort.SliceStable(chunks, func(i, j int) bool{
+        return chunks[i].Address < chunks[j].Address
+    })
+
+    for i := 1; i < len(chunks); i++ {
+
+        previous, chunk := chunks[i - 1], chunks[i]
+
+        if chunk.Address <= previous.End() {
+            return fmt.Errorf("%s: segment %s at $%04X overlaps segment %s of %s, which ends at $%04X",
+                chunk.Module, chunk.Segment, chunk.Address, previous.Segment, previous.Module, previous.End())
+        }
+    }
+
+    return nil
+}
+
+func findSegment(object *asm.Object, name string) *asm.Segment{
+
+    for i := range object.Segments {
+        if object.Segments[i].Name == name {
+            return &object.Segments[i]
+        }
+    }
+
+    return nil
+}
+
+// resolve computes the value of every symbol, and checks every import is exported by a module.
+func (l *linker) resolve() error{
+
+    l.exports = map[string]Symbol{}
+
+    for i, object := range l.objects {
+
+        for _, s := range object.Symbols {
+
+            symbol := Symbol{Name: s.Name, Module: object.Name, Segment: s.Segment, Value: s.Value, Exported: s.Exported}
+
+            if s.Segment != "" {
+                symbol.Value += l.bases[i][s.Segment]
+            }
+
+            l.image.Symbols = append(l.image.Symbols, symbol)
+
+            if !s.Exported {
+                continue
+            }
+
+            if other, ok := l.exports[s.Name]; ok {
+                return fmt.Errorf("symbol %s is exported by both %s and %s", s.Name, other.Module, object.Name)
+            }
+
+            l.exports[s.Name] = symbol
+        }
+    }
+
+    sort.SliceStable(l.image.Symbols, func(i, j int) bool{
+
+        if l.image.Symbols[i].Value != l.image.Symbols[j].Value {
+            return l.image.Symbols[i].Value < l.image.Symbols[j].Value
+        }
+
+        return l.image.Symbols[i].Name < l.image.Symbols[j].Name
+    })
+
+    for _, object := range l.objects {
+
+        for _, i := range object.Imports {
+
+            symbol, ok := l.exports[i.Name]
+
+            if !ok {
+                return fmt.Errorf("%s: %s is imported, but no module exports it", object.Name, i.Name)
+            }
+
+            if i.ZeroPage && (symbol.Value < 0 || symbol.Value > 0xFF) {
+                return fmt.Errorf("%s: %s is imported as a zero page address, but %s puts it at $%04X", object.Name, i.Name, symbol.Module, symbol.Value)
+            }
+        }
+    }
+
+    return nil
+}
+
+// relocate patches the addresses the assembler couldn't know.
+func (l *linker) relocate() error{
+
+    for i, object := range l.objects {
+
+        for _, segment := range object.Segments {
+
+            chunk := &l.image.Chunks[l.chunks[placement{i, segment.Name}]]
+
+            for _, r := range segment.Relocations {
+
+                address := r.Addend
+
+                if r.Import != "" {
+                    address += l.exports[r.Import].Value
+                }else{
+                    address += l.bases[i][r.Segment]
+                }
+
+                if err := patch(chunk.Data, r, address); err != nil {
+                    return fmt.Errorf("%s: %w", relocationLocation(object, r), err)
+                }
+            }
+        }
+    }
+
+    return nil
+}
+
+// patch writes the address, or the part of it the relocation asks for, in data.
+func patch(data []byte, r asm.Relocation, address int) error{
+
+    size := 1
+    if r.Kind == asm.RelocateWord {
+        size = 2
+    }
+
+    if r.Offset < 0 || r.Offset + size > len(data) {
+        return fmt.Errorf("relocation at offset %d is outside of segment %s", r.Offset, r.Segment)
+    }
+
+    switch r.Kind {
+    case asm.RelocateWord:
+
+        if address < 0 || address > 0xFFFF {
+            return fmt.Errorf("address $%X doesn't fit in a word", address)
+        }
+
+        data[r.Offset] = byte(address)
+        data[r.Offset + 1] = byte(address >> 8)
+
+    case asm.RelocateZeroPage:
+
+        if address < 0 || address > 0xFF {
+            return fmt.Errorf("address $%04X is not in the zero page", address)
+        }
+
+        data[r.Offset] = byte(address)
+
+    case asm.RelocateLow:
+        data[r.Offset] = byte(address)
+
+    case asm.RelocateHigh:
+        data[r.Offset] = byte(address >> 8)
+
+    default:
+        return fmt.Errorf("unknown relocation %v", r.Kind)
+    }
+
+    return nil
+}
+
+// relocationLocation is where the relocated address is used in the source.
+func relocationLocation(object *asm.Object, r asm.Relocation) string{
+
+    if r.File != "" {
+        return fmt.Sprintf("%s:%d", r.File, r.Line)
+    }
+
+    return fmt.Sprintf("%s line %d", object.Name, r.Line)
+}
+
+// Lookup returns the value of a symbol exported by one of the modules.
+func (image *Image) Lookup(name string) (uint16, bool){
+
+    for _, symbol := range image.Symbols {
+        if symbol.Exported && symbol.Name == name {
+            return uint16(symbol.Value), true
+        }
+    }
+
+    return 0, false
+}
+
+// loader is implemented by buses that can load data where writes are refused, like ROMs.
+type loader interface {
+    Load(address uint16, data []byte)
+}
+
+// Load copies the image to memory, at the addresses of the config. Buses
+// with a Load method, like the ones of package memmap, can fill their ROMs.
+func (image *Image) Load(bus arc.Bus){
+
+    for _, chunk := range image.Chunks {
+
+        if l, ok := bus.(loader); ok {
+            l.Load(chunk.Address, chunk.Data)
+            continue
+        }
+
+        for i, value := range chunk.Data {
+            bus.Write(chunk.Address + uint16(i), value)
+        }
+    }
+}
+
+// ROM returns the content of the image from start to end included, like a ROM
+// to burn. The bytes the image doesn't cover are set to fill.
+func (image *Image) ROM(start, end uint16, fill byte) []byte{
+
+    rom := make([]byte, int(end) - int(start) + 1)
+    for i := range rom {
+        rom[i] = fill
+    }
+
+    for _, chunk := range image.Chunks {
+        for i, value := range chunk.Data {
+
+            address := int(chunk.Address) + i
+            if address >= int(start) && address <= int(end) {
+                rom[address - int(start)] = value
+            }
+        }
+    }
+
+    return rom
+}
+
+// Binary returns the image in the format CPU.LoadProgram accepts, from its lowest to its
+// highest address, with the gaps filled with zeros. It's empty if the image is.
+func (image *Image) Binary() []byte{
+
+    if len(image.Chunks) == 0 {
+        return nil
+    }
+
+    start, end := image.Chunks[0].Address, image.Chunks[0].End()
+
+    for _, chunk := range image.Chunks {
+
+        if chunk.Address < start {
+            start = chunk.Address
+        }
+
+        if chunk.End() > end {
+            end = chunk.End()
+        }
+    }
+
+    return append([]byte{byte(start), byte(start >> 8)}, image.ROM(start, end, 0)...)
+}
@@ -0,0 +1,272 @@
+package link
+
+import (
+	"bytes"
+	"emulator/pkg/arc"
+	"emulator/pkg/asm"
+	"emulator/pkg/memmap"
+	"strings"
+	"testing"
+)
+
+const layout = `
+# A machine with its ROM at the top of memory
+ZEROPAGE   $0080-$00FF
+CODE       $E000-$FFF9
+RODATA     $E000-$FFF9   ; after the code
+VECTORS    $FFFA-$FFFF
+`
+
+// The main module sets a pointer to its message, and calls print in the other module
+const mainModule = `
+        .import print
+        .export ptr, reset
+
+        .segment "ZEROPAGE"
+ptr:    .res 2
+
+        .segment "CODE"
+reset:  LDA #<message
+        STA ptr
+        LDA #>message
+        STA ptr+1
+        JSR print
+done:   JMP done
+
+        .segment "RODATA"
+message: .byte "HI!", 0
+
+        .segment "VECTORS"
+        .word reset, reset, reset
+`
+
+const printModule = `
+        .importzp ptr
+        .export print
+
+output = $0200
+
+print:  LDY #0
+@loop:  LDA (ptr),Y
+        BEQ @done
+        STA output,Y
+        INY
+        BNE @loop
+@done:  RTS
+`
+
+func InitLayout(t *testing.T) *Config{
+
+    t.Helper()
+
+    config, err := ParseConfig(strings.NewReader(layout))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return config
+}
+
+func InitObject(t *testing.T, name, source string) *asm.Object{
+
+    t.Helper()
+
+    object, err := (&asm.Assembler{}).AssembleObject(name, source)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return object
+}
+
+func InitImage(t *testing.T) *Image{
+
+    t.Helper()
+
+    image, err := Link(InitLayout(t), InitObject(t, "main.s", mainModule), InitObject(t, "print.s", printModule))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    return image
+}
+
+func TestLinkPlacesSegmentsInTheirRange(t *testing.T){
+
+    image := InitImage(t)
+
+    expected := []struct {
+        segment string
+        module string
+        address uint16
+        size int
+    }{
+        {"ZEROPAGE", "main.s", 0x0080, 2},
+        {"CODE", "main.s", 0xE000, 14},
+        {"CODE", "print.s", 0xE00E, 13},
+        {"RODATA", "main.s", 0xE01B, 4},
+        {"VECTORS", "main.s", 0xFFFA, 6},
+    }
+
+    if len(image.Chunks) != len(expected) {
+        t.Fatal("Expected ", len(expected), " chunks but got: ", image.Chunks)
+    }
+
+    for i, chunk := range image.Chunks {
+
+        e := expected[i]
+
+        if chunk.Segment != e.segment || chunk.Module != e.module || chunk.Address != e.address || len(chunk.Data) != e.size {
+            t.Errorf("Chunk %d: expected %s of %s at $%04X, %d bytes, but got %s of %s at $%04X, %d bytes",
+                i, e.segment, e.module, e.address, e.size, chunk.Segment, chunk.Module, chunk.Address, len(chunk.Data))
+        }
+    }
+
+    if address, ok := image.Lookup("print"); !ok || address != 0xE00E {
+        t.Errorf("print should be exported at $E00E but got $%04X, %v", address, ok)
+    }
+}
+
+func TestLinkPatchesRelocations(t *testing.T){
+
+    image := InitImage(t)
+
+    // LDA #<message, STA ptr, LDA #>message, STA ptr+1, JSR print
+    code := []byte{0xA9, 0x1B, 0x85, 0x80, 0xA9, 0xE0, 0x85, 0x81, 0x20, 0x0E, 0xE0}
+
+    if !bytes.Equal(image.Chunks[1].Data[:len(code)], code) {
+        t.Errorf("Expected % X but got % X", code, image.Chunks[1].Data[:len(code)])
+    }
+
+    // LDA (ptr),Y uses the zero page address of the other module
+    if image.Chunks[2].Data[2] != 0xB1 || image.Chunks[2].Data[3] != 0x80 {
+        t.Errorf("Expected B1 80 but got % X", image.Chunks[2].Data[2:4])
+    }
+
+    vectors := []byte{0x00, 0xE0, 0x00, 0xE0, 0x00, 0xE0}
+
+    if !bytes.Equal(image.Chunks[4].Data, vectors) {
+        t.Errorf("Expected % X but got % X", vectors, image.Chunks[4].Data)
+    }
+}
+
+func TestLinkedImageRunsOnTheCPU(t *testing.T){
+
+    image := InitImage(t)
+
+    // Reset clears the memory, so the image is loaded after it
+    vector := image.ROM(0xFFFC, 0xFFFD, 0)
+
+    cpu := arc.NewCPU(arc.NMOS6502)
+    cpu.Reset(uint16(vector[0]) | uint16(vector[1]) << 8)
+    image.Load(cpu.AddressSpace())
+
+    cpu.Execute(300)
+
+    if got := string(cpu.Memory.Data[0x0200:0x0203]); got != "HI!" {
+        t.Error("Expected HI! at $0200 but got: ", got)
+    }
+}
+
+func TestLoadFillsROMs(t *testing.T){
+
+    image := InitImage(t)
+
+    m, err := memmap.New(
+        memmap.Region{Kind: memmap.RAM, Start: 0x0000, End: 0x7FFF},
+        memmap.Region{Kind: memmap.ROM, Start: 0xE000, End: 0xFFFF, Data: make([]byte, 0x2000)},
+    )
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    image.Load(m)
+
+    if m.Read(0xFFFC) != 0x00 || m.Read(0xFFFD) != 0xE0 || m.Read(0xE000) != 0xA9 {
+        t.Error("The image should have been loaded in the ROM")
+    }
+}
+
+func TestImageROMAndBinary(t *testing.T){
+
+    image := InitImage(t)
+
+    rom := image.ROM(0xE000, 0xFFFF, 0xFF)
+
+    if len(rom) != 0x2000 || rom[0] != 0xA9 || rom[0x1000] != 0xFF || rom[0x1FFC] != 0x00 || rom[0x1FFD] != 0xE0 {
+        t.Error("Unexpected ROM of ", len(rom), " bytes")
+    }
+
+    binary := image.Binary()
+
+    if len(binary) != 2 + 0x10000 - 0x80 || binary[0] != 0x80 || binary[1] != 0x00 {
+        t.Errorf("Unexpected binary of %d bytes, starting with % X", len(binary), binary[:2])
+    }
+}
+
+func TestWriteMap(t *testing.T){
+
+    var out strings.Builder
+
+    if err := InitImage(t).WriteMap(&out); err != nil {
+        t.Fatal(err)
+    }
+
+    text := out.String()
+
+    for _, expected := range []string{
+        "CODE      print.s  $E00E  $E01A  $000D",
+        "$0080  ptr *",
+        "$0200  output",
+        "$E00E  print *",
+        "$E010  print@loop",
+        "(constant)",
+    } {
+        if !strings.Contains(text, expected) {
+            t.Errorf("The map should contain %q:\n%s", expected, text)
+        }
+    }
+}
+
+func CheckLinkError(t *testing.T, message string, sources ...string){
+
+    t.Helper()
+
+    var objects []*asm.Object
+
+    for i, source := range sources {
+        objects = append(objects, InitObject(t, []string{"a.s", "b.s"}[i], source))
+    }
+
+    _, err := Link(InitLayout(t), objects...)
+
+    if err == nil || !strings.Contains(err.Error(), message) {
+        t.Error("Expected an error containing ", message, " but got: ", err)
+    }
+}
+
+func TestLinkErrors(t *testing.T){
+
+    CheckLinkError(t, "a.s: missing is imported, but no module exports it", " .import missing\n JMP missing")
+    CheckLinkError(t, "exported by both a.s and b.s", " .export twice\ntwice: RTS", " .export twice\ntwice: RTS")
+    CheckLinkError(t, "segment BSS is not in the config", " .segment \"BSS\"\n .res 4")
+    CheckLinkError(t, "segment VECTORS overflows its range $FFFA-$FFFF by 2 bytes", " .segment \"VECTORS\"\n .res 8")
+    CheckLinkError(t, "far is imported as a zero page address", " .importzp far\n LDA far", " .export far\nfar: RTS")
+    CheckLinkError(t, "a.s:5: address $0100 is not in the zero page", " .segment \"ZEROPAGE\"\n .res 128\nfull:\n .segment \"CODE\"\n LDA full")
+}
+
+func TestLinkCatchesOverlappingRanges(t *testing.T){
+
+    config := &Config{Segments: []SegmentConfig{
+        {Name: "CODE", Start: 0x1000, End: 0x1FFF},
+        {Name: "DATA", Start: 0x1001, End: 0x1FFF},
+    }}
+
+    object := InitObject(t, "a.s", " NOP\n NOP\n .segment \"DATA\"\n .byte 1")
+
+    _, err := Link(config, object)
+
+    if err == nil || !strings.Contains(err.Error(), "overlaps segment CODE") {
+        t.Error("Expected an overlap error but got: ", err)
+    }
+}
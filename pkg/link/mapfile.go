@@ -0,0 +1,45 @@
+package link
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// WriteMap writes the map of the image: where the segments of every module were
+// placed, and the value of every symbol, sorted by address. Exported symbols are
+// marked with an asterisk.
+func (image *Image) WriteMap(w io.Writer) error{
+
+    table := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+    fmt.Fprintln(table, "Segments:")
+    fmt.Fprintln(table)
+    fmt.Fprintln(table, "Segment\tModule\tStart\tEnd\tSize")
+
+    for _, chunk := range image.Chunks {
+        fmt.Fprintf(table, "%s\t%s\t$%04X\t$%04X\t$%04X\n", chunk.Segment, chunk.Module, chunk.Address, chunk.End(), len(chunk.Data))
+    }
+
+    fmt.Fprintln(table)
+    fmt.Fprintln(table, "Symbols:")
+    fmt.Fprintln(table)
+    fmt.Fprintln(table, "Value\tName\tModule\tSegment")
+
+    for _, symbol := range image.Symbols {
+
+        name := symbol.Name
+        if symbol.Exported {
+            name += " *"
+        }
+
+        segment := symbol.Segment
+        if segment == "" {
+            segment = "(constant)"
+        }
+
+        fmt.Fprintf(table, "$%04X\t%s\t%s\t%s\n", uint16(symbol.Value), name, symbol.Module, segment)
+    }
+
+    return table.Flush()
+}
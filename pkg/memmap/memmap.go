@@ -0,0 +1,309 @@
+// Package memmap describes the address space of a machine built around the 6502:
+// which ranges are RAM, which are ROM loaded from files, which mirror other ranges
+// and which are not connected to anything.
+// A Map implements arc.Bus, so it can be wired to the CPU.
+package memmap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+const addressSpace = 0x10000
+
+// Kind is what a Region of the address space is connected to.
+type Kind int
+
+const (
+    // Unmapped addresses aren't connected to anything. Reading them returns the
+    // open bus value, the last byte that went through the data bus, and writes are lost.
+    Unmapped Kind = iota
+
+    // RAM can be read and written, it starts cleared.
+    RAM
+
+    // ROM can only be read. Writes are handled according to the ROMWrites policy.
+    ROM
+
+    // Mirror regions repeat another range of the address space, which can be RAM or ROM.
+    Mirror
+)
+
+func (kind Kind) String() string{
+
+    switch kind {
+    case Unmapped:
+        return "unmapped"
+    case RAM:
+        return "RAM"
+    case ROM:
+        return "ROM"
+    case Mirror:
+        return "mirror"
+    }
+
+    return fmt.Sprintf("Kind(%d)", int(kind))
+}
+
+// Region is a range of the address space, from Start to End included.
+type Region struct {
+
+    Kind Kind
+
+    Start uint16
+    End uint16
+
+    // Content of a ROM, read from File when Data is nil.
+    // It must fill the region exactly.
+    Data []byte
+    File string
+
+    // A Mirror repeats the Size bytes starting at Source.
+    // When Size is 0, the mirrored range is as large as the region.
+    Source uint16
+    Size int
+}
+
+func (region Region) size() int{
+    return int(region.End) - int(region.Start) + 1
+}
+
+// WritePolicy tells what happens when the CPU writes to a ROM.
+type WritePolicy int
+
+const (
+    // IgnoreROMWrites drops the write, like the hardware does.
+    IgnoreROMWrites WritePolicy = iota
+
+    // ReportROMWrites drops the write and reports it to the CPU,
+    // which stops with an address fault.
+    ReportROMWrites
+)
+
+// Errors returned by New when the layout is not valid.
+var (
+    ErrInvalidRange = errors.New("invalid range")
+    ErrOverlap = errors.New("overlapping regions")
+    ErrROMSize = errors.New("ROM size doesn't match region")
+    ErrInvalidMirror = errors.New("invalid mirror")
+)
+
+// Map is the address space of a machine, built from a list of regions.
+type Map struct {
+
+    // ROMWrites is the policy applied to writes to a ROM, IgnoreROMWrites when not set.
+    ROMWrites WritePolicy
+
+    // For every address, the index of its region in regions, or -1 if unmapped.
+    owner [addressSpace]int
+
+    regions []Region
+
+    // Backing store of RAM and ROM regions, mirrors resolve to it.
+    cells [addressSpace]byte
+
+    // Last byte that went through the data bus, returned by unmapped reads.
+    openBus byte
+
+    // First ROM write reported and not taken by the CPU yet.
+    refused bool
+    refusedAddress uint16
+}
+
+// New builds a Map from regions. Addresses not covered by any region are unmapped.
+// Regions can't overlap, and a Mirror can only repeat RAM or ROM.
+func New(regions ...Region) (*Map, error){
+
+    m := &Map{}
+
+    for address := range m.owner {
+        m.owner[address] = -1
+    }
+
+    // Mirrors are resolved at the end, so they can point to regions declared after them
+    for _, region := range regions {
+
+        if region.End < region.Start {
+            return nil, fmt.Errorf("%w: %v region $%04X-$%04X", ErrInvalidRange, region.Kind, region.Start, region.End)
+        }
+
+        for address := int(region.Start); address <= int(region.End); address++ {
+
+            if m.owner[address] != -1 {
+                other := m.regions[m.owner[address]]
+                return nil, fmt.Errorf("%w: %v region $%04X-$%04X and %v region $%04X-$%04X",
+                    ErrOverlap, region.Kind, region.Start, region.End, other.Kind, other.Start, other.End)
+            }
+
+            m.owner[address] = len(m.regions)
+        }
+
+        if region.Kind == ROM {
+
+            if err := m.loadROM(region); err != nil {
+                return nil, err
+            }
+        }
+
+        m.regions = append(m.regions, region)
+    }
+
+    for i, region := range m.regions {
+
+        if region.Kind != Mirror {
+            continue
+        }
+
+        if region.Size == 0 {
+            m.regions[i].Size = region.size()
+            region.Size = region.size()
+        }
+
+        if int(region.Source) + region.Size > addressSpace {
+            return nil, fmt.Errorf("%w: $%04X-$%04X mirrors %d bytes at $%04X", ErrInvalidMirror, region.Start, region.End, region.Size, region.Source)
+        }
+
+        for address := int(region.Source); address < int(region.Source) + region.Size; address++ {
+
+            if m.kindAt(uint16(address)) != RAM && m.kindAt(uint16(address)) != ROM {
+                return nil, fmt.Errorf("%w: $%04X-$%04X mirrors $%04X which is %v", ErrInvalidMirror, region.Start, region.End, address, m.kindAt(uint16(address)))
+            }
+        }
+    }
+
+    return m, nil
+}
+
+// loadROM copies the content of a ROM region to its cells.
+func (m *Map) loadROM(region Region) error{
+
+    data := region.Data
+
+    if data == nil {
+
+        var err error
+        data, err = os.ReadFile(region.File)
+        if err != nil {
+            return err
+        }
+    }
+
+    if len(data) != region.size() {
+        return fmt.Errorf("%w: %d bytes for ROM region $%04X-$%04X", ErrROMSize, len(data), region.Start, region.End)
+    }
+
+    copy(m.cells[region.Start:], data)
+
+    return nil
+}
+
+func (m *Map) kindAt(address uint16) Kind{
+
+    if m.owner[address] == -1 {
+        return Unmapped
+    }
+
+    return m.regions[m.owner[address]].Kind
+}
+
+// resolve follows mirrors, and returns the address actually accessed with its region kind.
+func (m *Map) resolve(address uint16) (uint16, Kind){
+
+    index := m.owner[address]
+    if index == -1 {
+        return address, Unmapped
+    }
+
+    region := &m.regions[index]
+    if region.Kind != Mirror {
+        return address, region.Kind
+    }
+
+    address = region.Source + uint16((int(address) - int(region.Start)) % region.Size)
+
+    return address, m.kindAt(address)
+}
+
+// Read returns the byte at address, or the open bus value if it's unmapped.
+func (m *Map) Read(address uint16) byte{
+
+    address, kind := m.resolve(address)
+
+    if kind != Unmapped {
+        m.openBus = m.cells[address]
+    }
+
+    return m.openBus
+}
+
+// Write stores value at address if it's RAM. Writes to unmapped addresses are lost,
+// writes to ROM follow the ROMWrites policy.
+func (m *Map) Write(address uint16, value byte){
+
+    m.openBus = value
+
+    resolved, kind := m.resolve(address)
+
+    switch kind {
+    case RAM:
+        m.cells[resolved] = value
+
+    case ROM:
+        if m.ROMWrites == ReportROMWrites && !m.refused {
+            m.refused = true
+            m.refusedAddress = address
+        }
+    }
+}
+
+// TakeFault returns the address of the first reported ROM write since the last call.
+// It makes Map an arc.FaultingBus.
+func (m *Map) TakeFault() (address uint16, faulted bool){
+
+    address, faulted = m.refusedAddress, m.refused
+    m.refused = false
+
+    return
+}
+
+// Load copies data to the cells starting at address, following mirrors.
+// Unlike Write, it can fill ROMs, for instance to patch them.
+// Unmapped addresses are skipped.
+func (m *Map) Load(address uint16, data []byte){
+
+    for i, value := range data {
+
+        resolved, kind := m.resolve(address + uint16(i))
+
+        if kind != Unmapped {
+            m.cells[resolved] = value
+        }
+    }
+}
+
+// Dump copies the bytes starting at address to data, following mirrors. Unlike Read,
+// it doesn't change the open bus value, which unmapped addresses give.
+// It lets arc.Snapshot read the whole address space without side effects.
+func (m *Map) Dump(address uint16, data []byte){
+
+    for i := range data {
+
+        resolved, kind := m.resolve(address + uint16(i))
+
+        if kind != Unmapped {
+            data[i] = m.cells[resolved]
+        }else{
+            data[i] = m.openBus
+        }
+    }
+}
+
+// Regions returns the layout the Map was built from.
+func (m *Map) Regions() []Region{
+
+    regions := make([]Region, len(m.regions))
+    copy(regions, m.regions)
+
+    return regions
+}
@@ -0,0 +1,229 @@
+package memmap
+
+import (
+	"emulator/pkg/arc"
+	"emulator/pkg/instructions"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// rom returns a ROM image of size bytes filled with value.
+func rom(size int, value byte) []byte{
+
+    data := make([]byte, size)
+    for i := range data {
+        data[i] = value
+    }
+
+    return data
+}
+
+func TestRAMCanBeReadAndWritten(t *testing.T){
+
+    m, err := New(Region{Kind: RAM, Start: 0x0000, End: 0x07FF})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    m.Write(0x0123, 0x42)
+
+    if m.Read(0x0123) != 0x42 {
+        t.Error("RAM at 0x0123 should be 0x42 but got: ", m.Read(0x0123))
+    }
+}
+
+func TestROMIsLoadedFromFileAndIsReadOnly(t *testing.T){
+
+    path := filepath.Join(t.TempDir(), "kernal.bin")
+    data := rom(0x100, 0xEA)
+    data[0x10] = 0x42
+
+    if err := os.WriteFile(path, data, 0o644); err != nil {
+        t.Fatal(err)
+    }
+
+    m, err := New(Region{Kind: ROM, Start: 0xFF00, End: 0xFFFF, File: path})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if m.Read(0xFF10) != 0x42 {
+        t.Error("ROM at 0xFF10 should be 0x42 but got: ", m.Read(0xFF10))
+    }
+
+    m.Write(0xFF10, 0x00)
+
+    if m.Read(0xFF10) != 0x42 {
+        t.Error("A write shouldn't change the ROM, got: ", m.Read(0xFF10))
+    }
+
+    if _, faulted := m.TakeFault(); faulted {
+        t.Error("ROM writes should be ignored by default")
+    }
+}
+
+func TestMirroredRegionRepeatsItsSource(t *testing.T){
+
+    // RAM of the NES, repeated four times in the first 8K
+    m, err := New(
+        Region{Kind: RAM, Start: 0x0000, End: 0x07FF},
+        Region{Kind: Mirror, Start: 0x0800, End: 0x1FFF, Source: 0x0000, Size: 0x0800},
+    )
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    m.Write(0x1805, 0x42)
+
+    if m.Read(0x0005) != 0x42 || m.Read(0x0805) != 0x42 || m.Read(0x1005) != 0x42 {
+        t.Error("Write to 0x1805 should be visible at 0x0005, 0x0805 and 0x1005")
+    }
+}
+
+func TestUnmappedReadsReturnOpenBus(t *testing.T){
+
+    m, err := New(Region{Kind: RAM, Start: 0x0000, End: 0x00FF})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    m.Write(0x0010, 0x42)
+    m.Read(0x0010)
+
+    if m.Read(0x8000) != 0x42 {
+        t.Error("Unmapped read should return the last value on the bus 0x42 but got: ", m.Read(0x8000))
+    }
+
+    m.Write(0x8000, 0x13)
+
+    if m.Read(0x0010) != 0x42 {
+        t.Error("Unmapped write shouldn't reach the RAM")
+    }
+}
+
+func TestDumpLeavesTheOpenBus(t *testing.T){
+
+    m, err := New(
+        Region{Kind: RAM, Start: 0x0000, End: 0x00FF},
+        Region{Kind: Mirror, Start: 0x0100, End: 0x01FF, Source: 0x0000},
+    )
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    m.Write(0x0010, 0x42)
+    m.Write(0x8000, 0x13)
+
+    data := make([]byte, 4)
+    m.Dump(0x010F, data[:2])
+    m.Dump(0x01FF, data[2:])
+
+    // Mirrored RAM, then the open bus past the mirror
+    if data[0] != 0x00 || data[1] != 0x42 || data[2] != 0x00 || data[3] != 0x13 {
+        t.Error("Dump should give the RAM through the mirror and the open bus, got: ", data)
+    }
+
+    // The open bus is still the last value written, not the last one dumped
+    if m.Read(0x8000) != 0x13 {
+        t.Error("Dump shouldn't change the open bus 0x13, got: ", m.Read(0x8000))
+    }
+
+    cpu := arc.NewCPU(arc.NMOS6502)
+    cpu.Bus = m
+    cpu.Snapshot()
+
+    if m.Read(0x8000) != 0x13 {
+        t.Error("A snapshot shouldn't change the open bus 0x13, got: ", m.Read(0x8000))
+    }
+}
+
+func TestInvalidLayoutsAreRejected(t *testing.T){
+
+    tests := []struct {
+        name string
+        regions []Region
+        err error
+    }{
+        {"overlap", []Region{{Kind: RAM, Start: 0x0000, End: 0x1000}, {Kind: RAM, Start: 0x1000, End: 0x2000}}, ErrOverlap},
+        {"inverted range", []Region{{Kind: RAM, Start: 0x2000, End: 0x1000}}, ErrInvalidRange},
+        {"ROM size", []Region{{Kind: ROM, Start: 0xF000, End: 0xFFFF, Data: rom(0x100, 0)}}, ErrROMSize},
+        {"mirror of unmapped", []Region{{Kind: Mirror, Start: 0x0800, End: 0x0FFF, Source: 0x0000}}, ErrInvalidMirror},
+    }
+
+    for _, test := range tests {
+
+        if _, err := New(test.regions...); !errors.Is(err, test.err) {
+            t.Error(test.name, ": expected error ", test.err, "but got: ", err)
+        }
+    }
+}
+
+func TestCPURunsFromROMWithRAMForStack(t *testing.T){
+
+    code := rom(0x1000, instructions.INS_NOP_IMP)
+    copy(code, []byte{
+        instructions.INS_LDA_IM, 0x42,
+        instructions.INS_PHA_IMP,
+    })
+
+    m, err := New(
+        Region{Kind: RAM, Start: 0x0000, End: 0x07FF},
+        Region{Kind: ROM, Start: 0xF000, End: 0xFFFF, Data: code},
+    )
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    cpu := arc.NewCPU(arc.NMOS6502)
+    cpu.Bus = m
+    cpu.PC = 0xF000
+
+    if _, err := cpu.Run(2+3); err != nil {
+        t.Fatal(err)
+    }
+
+    if m.Read(0x01FD) != 0x42 {
+        t.Error("Stack at 0x01FD should be 0x42 but got: ", m.Read(0x01FD))
+    }
+}
+
+func TestReportedROMWriteFaultsTheCPU(t *testing.T){
+
+    code := rom(0x1000, instructions.INS_NOP_IMP)
+    copy(code, []byte{
+        instructions.INS_LDA_IM, 0x42,
+        instructions.INS_STA_ABS, 0x00, 0xF8,
+        instructions.INS_LDA_IM, 0x00,
+    })
+
+    m, err := New(Region{Kind: ROM, Start: 0xF000, End: 0xFFFF, Data: code})
+    if err != nil {
+        t.Fatal(err)
+    }
+    m.ROMWrites = ReportROMWrites
+
+    cpu := arc.NewCPU(arc.NMOS6502)
+    cpu.Bus = m
+    cpu.PC = 0xF000
+
+    cyclesUsed, err := cpu.Run(100)
+
+    var fault *arc.Fault
+    if !errors.As(err, &fault) || !errors.Is(err, arc.ErrAddressFault) {
+        t.Fatal("Expected an address fault but got: ", err)
+    }
+
+    if fault.Address != 0xF800 || fault.PC != 0xF002 {
+        t.Errorf("Expected fault at $F800 by the instruction at $F002, got $%04X by $%04X", fault.Address, fault.PC)
+    }
+
+    if expectedCycles := 2+4; cyclesUsed != expectedCycles {
+        t.Error("Expected cycles: ", expectedCycles, "but got: ", cyclesUsed)
+    }
+
+    if cpu.A != 0x42 {
+        t.Error("The CPU should stop right after the write, A should be 0x42 but got: ", cpu.A)
+    }
+}
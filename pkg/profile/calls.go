@@ -0,0 +1,172 @@
+package profile
+
+import (
+	"bufio"
+	"emulator/pkg/arc"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Routine is the profile of a routine, whatever it was called from.
+type Routine struct {
+    Address uint16
+    Interrupt bool
+
+    // Symbol is the name of the routine, see Name.
+    Symbol string
+
+    Calls uint64
+
+    // Inclusive counts the cycles of the routine and of the routines it called,
+    // Exclusive only those of the routine itself. The cycles of a recursive call
+    // are only counted once.
+    Inclusive uint64
+    Exclusive uint64
+}
+
+// Name names the routine at address after its label, or its address without one.
+// Interrupt handlers are marked, since the same code can be called and interrupt.
+func Name(address uint16, interrupt bool, labels *Labels) string{
+
+    name, ok := labels.Lookup(address)
+    if !ok {
+        name = fmt.Sprintf("$%04X", address)
+    }
+
+    if interrupt {
+        name += " (interrupt)"
+    }
+
+    return name
+}
+
+type routineKey struct {
+    address uint16
+    interrupt bool
+}
+
+// Routines sums up the nodes of the call graph by routine, sorted by inclusive cycles.
+// The Root is the first one, as the routine the program started at.
+func Routines(g *arc.CallGraph, labels *Labels) []Routine{
+
+    if g.Root == nil {
+        return nil
+    }
+
+    routines := map[routineKey]*Routine{}
+    onPath := map[routineKey]int{}
+
+    var visit func(node *arc.CallNode)
+
+    visit = func(node *arc.CallNode){
+
+        key := routineKey{node.Routine, node.Interrupt}
+
+        r := routines[key]
+        if r == nil {
+            r = &Routine{Address: node.Routine, Interrupt: node.Interrupt, Symbol: Name(node.Routine, node.Interrupt, labels)}
+            routines[key] = r
+        }
+
+        r.Calls += node.Calls
+        r.Exclusive += node.Cycles
+
+        // Only the outermost call of a recursion counts
+        if onPath[key] == 0 {
+            r.Inclusive += node.InclusiveCycles()
+        }
+
+        onPath[key]++
+        for _, child := range node.Children {
+            visit(child)
+        }
+        onPath[key]--
+    }
+
+    visit(g.Root)
+
+    list := make([]Routine, 0, len(routines))
+    for _, r := range routines {
+        list = append(list, *r)
+    }
+
+    sort.Slice(list, func(i, j int) bool{
+
+        a, b := &list[i], &list[j]
+
+        if a.Inclusive != b.Inclusive {
+            return a.Inclusive > b.Inclusive
+        }
+
+        if a.Address != b.Address {
+            return a.Address < b.Address
+        }
+
+        return !a.Interrupt
+    })
+
+    return list
+}
+
+// WriteRoutines writes the first n routines as a table, all of them when n is 0.
+//
+//	routine  calls  inclusive  exclusive
+//	start    1      5000       200
+//	print    10     4800       4800
+func WriteRoutines(w io.Writer, routines []Routine, n int) error{
+
+    if n <= 0 || n > len(routines) {
+        n = len(routines)
+    }
+
+    table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+    fmt.Fprintln(table, "routine\tcalls\tinclusive\texclusive")
+
+    for _, r := range routines[:n] {
+        fmt.Fprintf(table, "%s\t%d\t%d\t%d\n", r.Symbol, r.Calls, r.Inclusive, r.Exclusive)
+    }
+
+    return table.Flush()
+}
+
+// WriteFolded writes the call graph in the folded stack format of flame graphs:
+// a line per path of calls, with the routines from the Root separated by semicolons,
+// followed by the cycles spent at the end of the path.
+//
+//	start;print;putc 1200
+//
+// https://github.com/brendangregg/FlameGraph reads it, and so does speedscope.
+func WriteFolded(w io.Writer, g *arc.CallGraph, labels *Labels) error{
+
+    if g.Root == nil {
+        return nil
+    }
+
+    out := bufio.NewWriter(w)
+
+    var path []string
+    var visit func(node *arc.CallNode)
+
+    visit = func(node *arc.CallNode){
+
+        path = append(path, strings.ReplaceAll(Name(node.Routine, node.Interrupt, labels), ";", ":"))
+
+        if node.Cycles > 0 {
+            fmt.Fprintf(out, "%s %d\n", strings.Join(path, ";"), node.Cycles)
+        }
+
+        for _, child := range node.Children {
+            visit(child)
+        }
+
+        path = path[:len(path) - 1]
+    }
+
+    visit(g.Root)
+
+    return out.Flush()
+}
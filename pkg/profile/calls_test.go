@@ -0,0 +1,116 @@
+package profile
+
+import (
+	"emulator/pkg/arc"
+	"strings"
+	"testing"
+)
+
+// recursion calls rec, which calls itself until X is 0, from $0200:
+//
+//	$0200  LDX #3
+//	$0202  JSR rec
+//	$0205  JMP $0205
+//
+//	$0300  rec: DEX
+//	$0301  BEQ $0306
+//	$0303  JSR rec
+//	$0306  RTS
+//
+// with an NMI handler at $0400, which is only RTI.
+func InitCallGraph(t *testing.T) (*arc.CPU, *arc.CallGraph){
+
+    t.Helper()
+
+    cpu := arc.NewCPU(arc.NMOS6502)
+    cpu.Reset(0x0200)
+    copy(cpu.Memory.Data[0x0200:], []byte{0xA2, 0x03, 0x20, 0x00, 0x03, 0x4C, 0x05, 0x02})
+    copy(cpu.Memory.Data[0x0300:], []byte{0xCA, 0xF0, 0x03, 0x20, 0x00, 0x03, 0x60})
+    cpu.Memory.Data[0x0400] = 0x40
+    cpu.Memory.Data[arc.NMIVector + 1] = 0x04
+
+    cpu.Profiler = arc.NewProfiler()
+    cpu.Profiler.Calls = arc.NewCallGraph()
+
+    if _, err := cpu.Run(2 + 6 + 16 + 16 + 11 + 3); err != nil {
+        t.Fatal(err)
+    }
+
+    cpu.AssertNMI()
+
+    if _, err := cpu.Run(7 + 6 + 3); err != nil {
+        t.Fatal(err)
+    }
+
+    return cpu, cpu.Profiler.Calls
+}
+
+func TestRoutines(t *testing.T){
+
+    _, g := InitCallGraph(t)
+    labels := NewLabels([]Label{{0x0200, "start"}, {0x0300, "rec"}})
+
+    routines := Routines(g, labels)
+
+    expected := []Routine{
+        {Address: 0x0200, Symbol: "start", Calls: 1, Inclusive: 2 + 6 + 43 + 3 + 13 + 3, Exclusive: 2 + 6 + 3 + 3},
+        {Address: 0x0300, Symbol: "rec", Calls: 3, Inclusive: 43, Exclusive: 43},
+        {Address: 0x0400, Interrupt: true, Symbol: "$0400 (interrupt)", Calls: 1, Inclusive: 13, Exclusive: 13},
+    }
+
+    if len(routines) != len(expected) {
+        t.Fatal("Unexpected routines: ", routines)
+    }
+
+    for i := range expected {
+        if routines[i] != expected[i] {
+            t.Errorf("Expected %+v but got %+v", expected[i], routines[i])
+        }
+    }
+
+    var out strings.Builder
+    WriteRoutines(&out, routines, 2)
+
+    CheckLines(t, out.String(),
+        "routine  calls  inclusive  exclusive",
+        "start    1      70         14",
+        "rec      3      43         43",
+    )
+}
+
+func TestWriteFolded(t *testing.T){
+
+    _, g := InitCallGraph(t)
+    labels := NewLabels([]Label{{0x0200, "start"}, {0x0300, "rec"}})
+
+    var out strings.Builder
+
+    if err := WriteFolded(&out, g, labels); err != nil {
+        t.Fatal(err)
+    }
+
+    CheckLines(t, out.String(),
+        "start 14",
+        "start;rec 16",
+        "start;rec;rec 16",
+        "start;rec;rec;rec 11",
+        "start;$0400 (interrupt) 13",
+    )
+
+    // An empty graph has no stacks
+    out.Reset()
+    WriteFolded(&out, arc.NewCallGraph(), nil)
+
+    if out.Len() != 0 || Routines(arc.NewCallGraph(), nil) != nil {
+        t.Error("Expected nothing for an empty graph: ", out.String())
+    }
+}
+
+func CheckLines(t *testing.T, text string, expected ...string){
+
+    t.Helper()
+
+    if lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n"); strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+        t.Errorf("Expected:\n%s\nbut got:\n%s", strings.Join(expected, "\n"), text)
+    }
+}
@@ -0,0 +1,170 @@
+package profile
+
+import (
+	"bufio"
+	"emulator/pkg/common"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Label names an address of a program.
+type Label struct {
+    Address uint16
+    Name string
+}
+
+// Labels resolves addresses to the labels of a program.
+type Labels struct {
+
+    // Sorted by address
+    labels []Label
+}
+
+// NewLabels returns the labels of a program. When several labels name the same
+// address, the first one is used.
+func NewLabels(labels []Label) *Labels{
+
+    l := &Labels{labels: append([]Label(nil), labels...)}
+
+    sort.SliceStable(l.labels, func(i, j int) bool{
+        return l.labels[i].Address < l.labels[j].Address
+    })
+
+    return l
+}
+
+// Resolve names address after the closest label at or before it, like loop+3.
+// It returns an empty string when there's no label before address.
+func (l *Labels) Resolve(address uint16) string{
+
+    label, ok := l.closest(address)
+
+    switch {
+    case !ok:
+        return ""
+
+    case label.Address == address:
+        return label.Name
+    }
+
+    return fmt.Sprintf("%s+%d", label.Name, address - label.Address)
+}
+
+// Lookup returns the label of address, without looking for one before it like Resolve.
+func (l *Labels) Lookup(address uint16) (string, bool){
+
+    label, ok := l.closest(address)
+    if !ok || label.Address != address {
+        return "", false
+    }
+
+    return label.Name, true
+}
+
+// closest returns the first label of the closest address at or before address.
+func (l *Labels) closest(address uint16) (Label, bool){
+
+    if l == nil {
+        return Label{}, false
+    }
+
+    // First label after address
+    i := sort.Search(len(l.labels), func(i int) bool{
+        return l.labels[i].Address > address
+    })
+
+    if i == 0 {
+        return Label{}, false
+    }
+
+    // Back to the first label of that address
+    for i > 1 && l.labels[i - 2].Address == l.labels[i - 1].Address {
+        i--
+    }
+
+    return l.labels[i - 1], true
+}
+
+// ReadLabels reads a label file, see ParseLabels.
+func ReadLabels(path string) (*Labels, error){
+
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    labels, err := ParseLabels(file)
+    if err != nil {
+        return nil, fmt.Errorf("%s: %v", path, err)
+    }
+
+    return labels, nil
+}
+
+// ParseLabels reads labels, one per line, written in one of these ways:
+//
+//	start = $0200           an assignment, like in the sources of the assembler
+//	al C:0200 .start        a label file of VICE, as written by ca65 and others
+//	$0200  start  main  CODE  the symbols of a map file written by the linker
+//
+// Constants of map files aren't labels, they're ignored, and so are the lines that
+// aren't labels, like the segments of map files. Comments start with a semicolon.
+func ParseLabels(r io.Reader) (*Labels, error){
+
+    var labels []Label
+
+    scanner := bufio.NewScanner(r)
+
+    for scanner.Scan() {
+
+        line := scanner.Text()
+        if comment := strings.Index(line, ";"); comment != -1 {
+            line = line[:comment]
+        }
+
+        fields := strings.Fields(line)
+
+        switch {
+        case len(fields) == 3 && fields[1] == "=":
+
+            address, err := common.ParseAddress(fields[2])
+            if err != nil {
+                continue
+            }
+
+            labels = append(labels, Label{address, fields[0]})
+
+        case len(fields) == 3 && fields[0] == "al":
+
+            address, err := common.ParseAddress("$" + strings.TrimPrefix(fields[1], "C:"))
+            if err != nil {
+                continue
+            }
+
+            labels = append(labels, Label{address, strings.TrimPrefix(fields[2], ".")})
+
+        case len(fields) >= 2 && strings.HasPrefix(fields[0], "$"):
+
+            if fields[len(fields) - 1] == "(constant)" {
+                continue
+            }
+
+            address, err := common.ParseAddress(fields[0])
+            if err != nil {
+                continue
+            }
+
+            labels = append(labels, Label{address, fields[1]})
+        }
+    }
+
+    if err := scanner.Err(); err != nil {
+        return nil, err
+    }
+
+    return NewLabels(labels), nil
+}
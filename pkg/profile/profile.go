@@ -0,0 +1,176 @@
+// Package profile makes reports of the counts of an arc.Profiler: where a program
+// spends its time, address by address, optionally named after the labels of the program.
+package profile
+
+import (
+	"emulator/pkg/arc"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Entry is the profile of the instruction at an address.
+type Entry struct {
+    Address uint16
+
+    // Symbol is the address resolved with the labels, empty without them.
+    Symbol string
+
+    Executions uint64
+    Cycles uint64
+}
+
+// Order sorts the entries of a Report.
+type Order int
+
+const (
+    // ByCycles puts the addresses where the most time is spent first.
+    ByCycles Order = iota
+
+    // ByExecutions puts the most executed addresses first.
+    ByExecutions
+
+    // ByAddress sorts the addresses in increasing order.
+    ByAddress
+)
+
+// ParseOrder reads an Order named cycles, executions or address.
+func ParseOrder(name string) (Order, error){
+
+    switch name {
+    case "cycles":
+        return ByCycles, nil
+    case "executions":
+        return ByExecutions, nil
+    case "address":
+        return ByAddress, nil
+    }
+
+    return 0, fmt.Errorf("unknown order %q, expected cycles, executions or address", name)
+}
+
+// Report lists the addresses executed during the profile.
+type Report struct {
+    Entries []Entry
+
+    // TotalCycles are the cycles of the profile, interrupt sequences included,
+    // which are counted in InterruptCycles.
+    TotalCycles uint64
+    InterruptCycles uint64
+}
+
+// NewReport makes a report of the addresses executed, sorted by cycles.
+// labels may be nil.
+func NewReport(p *arc.Profiler, labels *Labels) *Report{
+
+    r := &Report{InterruptCycles: p.InterruptCycles, TotalCycles: p.InterruptCycles}
+
+    for address, executions := range p.Executions {
+
+        if executions == 0 {
+            continue
+        }
+
+        r.Entries = append(r.Entries, Entry{
+            Address: uint16(address),
+            Symbol: labels.Resolve(uint16(address)),
+            Executions: executions,
+            Cycles: p.Cycles[address],
+        })
+
+        r.TotalCycles += p.Cycles[address]
+    }
+
+    r.Sort(ByCycles)
+
+    return r
+}
+
+// Sort sorts the entries. Equal counts are sorted by address.
+func (r *Report) Sort(order Order){
+
+    sort.SliceStable(r.Entries, func(i, j int) bool{
+
+        a, b := &r.Entries[i], &r.Entries[j]
+
+        switch {
+        case order == ByCycles && a.Cycles != b.Cycles:
+            return a.Cycles > b.Cycles
+
+        case order == ByExecutions && a.Executions != b.Executions:
+            return a.Executions > b.Executions
+        }
+
+        return a.Address < b.Address
+    })
+}
+
+// Top returns the first n entries, all of them when n is 0 or more than there are.
+func (r *Report) Top(n int) []Entry{
+
+    if n <= 0 || n > len(r.Entries) {
+        return r.Entries
+    }
+
+    return r.Entries[:n]
+}
+
+// share is the part of the cycles of the profile spent at an entry, in percents.
+func (r *Report) share(e *Entry) float64{
+
+    if r.TotalCycles == 0 {
+        return 0
+    }
+
+    return float64(e.Cycles) * 100 / float64(r.TotalCycles)
+}
+
+// WriteTable writes the first n entries as a table, all of them when n is 0.
+//
+//	address  symbol    executions  cycles  share
+//	$0205    loop+3    1000        3000    42.86%
+func (r *Report) WriteTable(w io.Writer, n int) error{
+
+    table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+    fmt.Fprintln(table, "address\tsymbol\texecutions\tcycles\tshare")
+
+    for _, e := range r.Top(n) {
+        fmt.Fprintf(table, "$%04X\t%s\t%d\t%d\t%.2f%%\n", e.Address, e.Symbol, e.Executions, e.Cycles, r.share(&e))
+    }
+
+    if err := table.Flush(); err != nil {
+        return err
+    }
+
+    _, err := fmt.Fprintf(w, "%d cycles, %d in interrupt sequences\n", r.TotalCycles, r.InterruptCycles)
+
+    return err
+}
+
+// WriteCSV writes every entry, in the current order, as CSV with a header line.
+// Addresses are written like in the table, so spreadsheets keep them as text.
+func (r *Report) WriteCSV(w io.Writer) error{
+
+    out := csv.NewWriter(w)
+
+    out.Write([]string{"address", "symbol", "executions", "cycles", "share"})
+
+    for _, e := range r.Entries {
+
+        out.Write([]string{
+            fmt.Sprintf("$%04X", e.Address),
+            e.Symbol,
+            strconv.FormatUint(e.Executions, 10),
+            strconv.FormatUint(e.Cycles, 10),
+            strconv.FormatFloat(r.share(&e), 'f', 4, 64),
+        })
+    }
+
+    out.Flush()
+
+    return out.Error()
+}
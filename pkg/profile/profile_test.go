@@ -0,0 +1,125 @@
+package profile
+
+import (
+	"emulator/pkg/arc"
+	"strings"
+	"testing"
+)
+
+// countdown loops 200 times on DEX, from $0200:
+//
+//	$0200  LDX #200
+//	$0202  DEX
+//	$0203  BNE $0202
+//	$0205  JMP $0205
+func InitProfile(t *testing.T) *arc.Profiler{
+
+    t.Helper()
+
+    cpu := arc.NewCPU(arc.NMOS6502)
+    cpu.Reset(0x0200)
+    copy(cpu.Memory.Data[0x0200:], []byte{0xA2, 0xC8, 0xCA, 0xD0, 0xFD, 0x4C, 0x05, 0x02})
+
+    cpu.Profiler = arc.NewProfiler()
+
+    if _, err := cpu.Run(1010); err != nil {
+        t.Fatal(err)
+    }
+
+    return cpu.Profiler
+}
+
+const labelFile = `
+; assignments, VICE labels and the symbols of a map file
+start = $0200
+al C:0202 .loop
+Symbols:
+
+Value  Name     Module  Segment
+$0205  done *   main    CODE
+$0202  COUNT    main    (constant)
+`
+
+func TestLabels(t *testing.T){
+
+    labels, err := ParseLabels(strings.NewReader(labelFile))
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for address, expected := range map[uint16]string{0x0100: "", 0x0200: "start", 0x0201: "start+1", 0x0203: "loop+1", 0x0210: "done+11"} {
+        if symbol := labels.Resolve(address); symbol != expected {
+            t.Errorf("$%04X should be %q but got %q", address, expected, symbol)
+        }
+    }
+
+    // The first label of an address is used
+    labels = NewLabels([]Label{{0x0300, "b"}, {0x0200, "a"}, {0x0300, "c"}})
+
+    if symbol := labels.Resolve(0x0302); symbol != "b+2" {
+        t.Error("Expected b+2 but got: ", symbol)
+    }
+}
+
+func TestReport(t *testing.T){
+
+    labels, _ := ParseLabels(strings.NewReader(labelFile))
+    report := NewReport(InitProfile(t), labels)
+
+    top := report.Top(2)
+
+    if len(report.Entries) != 4 || len(top) != 2 {
+        t.Fatal("Unexpected entries: ", report.Entries)
+    }
+
+    // 199 taken branches and the last one
+    if top[0] != (Entry{Address: 0x0203, Symbol: "loop+1", Executions: 200, Cycles: 199 * 3 + 2}) {
+        t.Error("Unexpected first entry: ", top[0])
+    }
+
+    report.Sort(ByExecutions)
+
+    if report.Entries[0].Address != 0x0202 || report.Entries[1].Address != 0x0203 {
+        t.Error("DEX and BNE are executed as much, they should be sorted by address: ", report.Entries)
+    }
+
+    report.Sort(ByAddress)
+
+    if report.Entries[0].Symbol != "start" || report.Entries[3].Symbol != "done" {
+        t.Error("Unexpected order: ", report.Entries)
+    }
+
+    if report.TotalCycles < 1000 || report.InterruptCycles != 0 {
+        t.Error("Unexpected total: ", report.TotalCycles)
+    }
+}
+
+func TestWriteReport(t *testing.T){
+
+    report := NewReport(InitProfile(t), nil)
+    report.Entries[0].Symbol = "loop, again"
+
+    var table, csv strings.Builder
+
+    if err := report.WriteTable(&table, 1); err != nil {
+        t.Fatal(err)
+    }
+
+    if err := report.WriteCSV(&csv); err != nil {
+        t.Fatal(err)
+    }
+
+    lines := strings.Split(table.String(), "\n")
+
+    if len(lines) != 4 || !strings.HasPrefix(lines[1], "$0203    loop, again  200") || !strings.Contains(lines[1], "  599     59.") {
+        t.Errorf("Unexpected table:\n%s", table.String())
+    }
+
+    if !strings.HasPrefix(csv.String(), "address,symbol,executions,cycles,share\n$0203,\"loop, again\",200,599,59.") {
+        t.Errorf("Unexpected CSV:\n%s", csv.String())
+    }
+
+    if _, err := ParseOrder("calls"); err == nil {
+        t.Error("Expected an error for an unknown order")
+    }
+}
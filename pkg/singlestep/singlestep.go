@@ -0,0 +1,339 @@
+// Package singlestep runs the SingleStepTests of Tom Harte, formerly ProcessorTests,
+// https://github.com/SingleStepTests/65x02, on the CPU.
+//
+// Each test is one instruction: the registers and the memory before and after it,
+// and the address, value and direction of the bus access of every cycle.
+// There's a file of 10000 tests for each opcode, named after it, like a9.json.
+//
+// The CPU emulates instructions rather than cycles, so it doesn't make the dummy reads
+// and writes of the real chip: bus mismatches are counted apart from the other ones.
+package singlestep
+
+import (
+	"emulator/pkg/arc"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+)
+
+// State is the state of the CPU before or after a test.
+// RAM lists the bytes of memory used by the test, as [address, value] pairs.
+type State struct {
+    PC uint16 `json:"pc"`
+    S byte `json:"s"`
+    A byte `json:"a"`
+    X byte `json:"x"`
+    Y byte `json:"y"`
+    P byte `json:"p"`
+    RAM [][2]int `json:"ram"`
+}
+
+// Cycle is the bus access of a cycle.
+type Cycle struct {
+    Address uint16
+    Value byte
+    Write bool
+}
+
+// UnmarshalJSON reads a cycle written [address, value, "read" or "write"].
+func (c *Cycle) UnmarshalJSON(data []byte) error{
+
+    var fields [3]interface{}
+    if err := json.Unmarshal(data, &fields); err != nil {
+        return err
+    }
+
+    address, ok1 := fields[0].(float64)
+    value, ok2 := fields[1].(float64)
+    direction, ok3 := fields[2].(string)
+
+    if !ok1 || !ok2 || !ok3 || (direction != "read" && direction != "write") {
+        return fmt.Errorf("invalid cycle %s", data)
+    }
+
+    *c = Cycle{Address: uint16(address), Value: byte(value), Write: direction == "write"}
+
+    return nil
+}
+
+func (c Cycle) String() string{
+
+    if c.Write {
+        return fmt.Sprintf("write $%02X to $%04X", c.Value, c.Address)
+    }
+
+    return fmt.Sprintf("read $%02X from $%04X", c.Value, c.Address)
+}
+
+// Test is a test of a single instruction.
+type Test struct {
+    Name string `json:"name"`
+    Initial State `json:"initial"`
+    Final State `json:"final"`
+    Cycles []Cycle `json:"cycles"`
+}
+
+// ReadFile reads the tests of a JSON file.
+func ReadFile(path string) ([]Test, error){
+
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    var tests []Test
+    if err := json.Unmarshal(data, &tests); err != nil {
+        return nil, fmt.Errorf("%s: %v", path, err)
+    }
+
+    return tests, nil
+}
+
+// Path returns the path of the file of the tests of opcode in dir.
+func Path(dir string, opcode byte) string{
+    return filepath.Join(dir, fmt.Sprintf("%02x.json", opcode))
+}
+
+// Result sums up the tests of an opcode.
+// A test counts once in each kind of mismatch it has.
+type Result struct {
+    Opcode byte
+    Tests int
+
+    // Passed counts the tests ending with the expected registers and memory,
+    // after the expected number of cycles. Bus mismatches don't matter.
+    Passed int
+
+    // Faults counts the tests the CPU stopped with a fault, like a JAM.
+    Faults int
+
+    Registers int
+    Memory int
+    Cycles int
+    Bus int
+
+    // Failures describes the first failing tests, and BusFailures the first bus mismatches.
+    Failures []string
+    BusFailures []string
+}
+
+// maxFailures is the number of failures a Result describes.
+const maxFailures = 3
+
+func (r *Result) fail(test *Test, format string, args ...interface{}){
+
+    if len(r.Failures) < maxFailures {
+        r.Failures = append(r.Failures, test.Name + ": " + fmt.Sprintf(format, args...))
+    }
+}
+
+// recordingBus is the memory of a test, recording every access.
+type recordingBus struct {
+    memory map[uint16]byte
+    cycles []Cycle
+}
+
+func (bus *recordingBus) Read(address uint16) byte{
+
+    value := bus.memory[address]
+    bus.cycles = append(bus.cycles, Cycle{Address: address, Value: value})
+
+    return value
+}
+
+func (bus *recordingBus) Write(address uint16, value byte){
+
+    bus.memory[address] = value
+    bus.cycles = append(bus.cycles, Cycle{Address: address, Value: value, Write: true})
+}
+
+// The break and unused bits aren't flags of the chip, they're ignored when comparing P.
+const flagsMask = 0xCF
+
+// Run runs tests, which should all be tests of the same opcode, on a CPU of variant.
+func Run(variant arc.Variant, tests []Test) Result{
+
+    result := Result{Tests: len(tests)}
+
+    cpu := arc.NewCPU(variant)
+    bus := &recordingBus{}
+    cpu.Bus = bus
+
+    for i := range tests {
+
+        test := &tests[i]
+
+        // A jammed or waiting CPU only leaves that state with a Reset
+        if cpu.Jammed() || cpu.Waiting() {
+            cpu.Reset(test.Initial.PC)
+        }
+
+        bus.memory = make(map[uint16]byte, len(test.Initial.RAM))
+        for _, pair := range test.Initial.RAM {
+            bus.memory[uint16(pair[0])] = byte(pair[1])
+        }
+        bus.cycles = bus.cycles[:0]
+
+        initial := &test.Initial
+        result.Opcode = bus.memory[initial.PC]
+
+        cpu.PC, cpu.SP, cpu.A, cpu.X, cpu.Y = initial.PC, initial.S, initial.A, initial.X, initial.Y
+        cpu.PS = cpu.ByteToPS(initial.P)
+
+        cycles, err := cpu.Run(1)
+
+        if err != nil {
+            result.Faults++
+            result.fail(test, "%v", err)
+            continue
+        }
+
+        passed := compare(&result, test, cpu, bus)
+
+        if cycles != len(test.Cycles) {
+            result.Cycles++
+            result.fail(test, "took %d cycles, expected %d", cycles, len(test.Cycles))
+            passed = false
+        }
+
+        if mismatch := compareCycles(bus.cycles, test.Cycles); mismatch != "" {
+
+            result.Bus++
+
+            if len(result.BusFailures) < maxFailures {
+                result.BusFailures = append(result.BusFailures, test.Name + ": " + mismatch)
+            }
+        }
+
+        if passed {
+            result.Passed++
+        }
+    }
+
+    return result
+}
+
+// compare compares the registers and the memory with the final state of test,
+// counting the mismatches in result. It returns true if they match.
+func compare(result *Result, test *Test, cpu *arc.CPU, bus *recordingBus) bool{
+
+    final := &test.Final
+
+    registers := []struct {
+        name string
+        value, expected int
+    }{
+        {"PC", int(cpu.PC), int(final.PC)},
+        {"S", int(cpu.SP), int(final.S)},
+        {"A", int(cpu.A), int(final.A)},
+        {"X", int(cpu.X), int(final.X)},
+        {"Y", int(cpu.Y), int(final.Y)},
+        {"P", int(cpu.PSToByte() & flagsMask), int(final.P & flagsMask)},
+    }
+
+    passed := true
+
+    for _, r := range registers {
+
+        if r.value != r.expected {
+            result.fail(test, "%s is $%02X, expected $%02X", r.name, r.value, r.expected)
+            passed = false
+        }
+    }
+
+    if !passed {
+        result.Registers++
+    }
+
+    for _, pair := range final.RAM {
+
+        address, expected := uint16(pair[0]), byte(pair[1])
+
+        if value := bus.memory[address]; value != expected {
+            result.Memory++
+            result.fail(test, "$%04X is $%02X, expected $%02X", address, value, expected)
+            return false
+        }
+    }
+
+    return passed
+}
+
+// compareCycles describes the first difference between the bus accesses made and the expected ones,
+// it returns an empty string if there's none.
+func compareCycles(cycles, expected []Cycle) string{
+
+    for i := 0; i < len(cycles) && i < len(expected); i++ {
+        if cycles[i] != expected[i] {
+            return fmt.Sprintf("cycle %d: %v, expected %v", i + 1, cycles[i], expected[i])
+        }
+    }
+
+    switch {
+    case len(cycles) > len(expected):
+        return fmt.Sprintf("cycle %d: %v, expected no access", len(expected) + 1, cycles[len(expected)])
+
+    case len(cycles) < len(expected):
+        return fmt.Sprintf("cycle %d: no access, expected %v", len(cycles) + 1, expected[len(cycles)])
+    }
+
+    return ""
+}
+
+// RunFile runs the tests of the file of opcode in dir.
+func RunFile(variant arc.Variant, dir string, opcode byte) (Result, error){
+
+    tests, err := ReadFile(Path(dir, opcode))
+    if err != nil {
+        return Result{Opcode: opcode}, err
+    }
+
+    result := Run(variant, tests)
+    result.Opcode = opcode
+
+    return result, nil
+}
+
+// WriteSummary writes a table of the results, one line per opcode,
+// followed by the total and the failures of each opcode.
+//
+//	opcode  tests  passed  faults  registers  memory  cycles  bus
+//	$A9     10000  10000   0       0          0       0       0
+func WriteSummary(w io.Writer, results []Result){
+
+    table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+    fmt.Fprintln(table, "opcode\ttests\tpassed\tfaults\tregisters\tmemory\tcycles\tbus")
+
+    var total Result
+
+    for _, r := range results {
+
+        fmt.Fprintf(table, "$%02X\t%d\t%d\t%d\t%d\t%d\t%d\t%d\n", r.Opcode, r.Tests, r.Passed, r.Faults, r.Registers, r.Memory, r.Cycles, r.Bus)
+
+        total.Tests += r.Tests
+        total.Passed += r.Passed
+        total.Faults += r.Faults
+        total.Registers += r.Registers
+        total.Memory += r.Memory
+        total.Cycles += r.Cycles
+        total.Bus += r.Bus
+    }
+
+    fmt.Fprintf(table, "total\t%d\t%d\t%d\t%d\t%d\t%d\t%d\n", total.Tests, total.Passed, total.Faults, total.Registers, total.Memory, total.Cycles, total.Bus)
+    table.Flush()
+
+    for _, r := range results {
+
+        for _, failure := range r.Failures {
+            fmt.Fprintf(w, "$%02X %s\n", r.Opcode, failure)
+        }
+
+        for _, failure := range r.BusFailures {
+            fmt.Fprintf(w, "$%02X bus %s\n", r.Opcode, failure)
+        }
+    }
+}
@@ -0,0 +1,189 @@
+package singlestep
+
+import (
+	"emulator/pkg/arc"
+	"emulator/pkg/instructions"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// LDA ($20),Y crossing a page, as the tests describe it. The real chip reads $1103
+// before fixing the high byte of the address, the CPU only reads $1203.
+const indirectIndexed = `[{
+    "name": "b1 20 00",
+    "initial": {"pc": 512, "s": 253, "a": 0, "x": 0, "y": 4, "p": 36,
+        "ram": [[512, 177], [513, 32], [32, 255], [33, 17], [4611, 128]]},
+    "final": {"pc": 514, "s": 253, "a": 128, "x": 0, "y": 4, "p": 164,
+        "ram": [[512, 177], [513, 32], [32, 255], [33, 17], [4611, 128]]},
+    "cycles": [[512, 177, "read"], [513, 32, "read"], [32, 255, "read"], [33, 17, "read"],
+        [4355, 0, "read"], [4611, 128, "read"]]
+}]`
+
+func InitTests(t *testing.T, text string) []Test{
+
+    t.Helper()
+
+    var tests []Test
+    if err := json.Unmarshal([]byte(text), &tests); err != nil {
+        t.Fatal(err)
+    }
+
+    return tests
+}
+
+func TestRunComparesStates(t *testing.T){
+
+    tests := InitTests(t, indirectIndexed)
+
+    if tests[0].Cycles[4] != (Cycle{Address: 0x1103}) || tests[0].Initial.RAM[4] != [2]int{0x1203, 0x80} {
+        t.Fatal("Unexpected test: ", tests[0])
+    }
+
+    result := Run(arc.NMOS6502, tests)
+
+    if result.Opcode != 0xB1 || result.Tests != 1 || result.Passed != 1 || result.Bus != 1 {
+        t.Error("Expected a pass with a bus mismatch but got: ", result)
+    }
+
+    if len(result.BusFailures) != 1 || !strings.Contains(result.BusFailures[0], "cycle 5: read $80 from $1203, expected read $00 from $1103") {
+        t.Error("Unexpected bus failures: ", result.BusFailures)
+    }
+
+    // Wrong results are reported
+    tests[0].Final.A = 0x81
+    tests[0].Final.RAM[0][1] = 0
+    tests[0].Cycles = tests[0].Cycles[:5]
+
+    result = Run(arc.NMOS6502, tests)
+
+    if result.Passed != 0 || result.Registers != 1 || result.Memory != 1 || result.Cycles != 1 {
+        t.Error("Expected register, memory and cycle mismatches but got: ", result)
+    }
+
+    failures := strings.Join(result.Failures, "\n")
+
+    for _, expected := range []string{"b1 20 00: A is $80, expected $81", "$0200 is $B1, expected $00", "took 6 cycles, expected 5"} {
+        if !strings.Contains(failures, expected) {
+            t.Errorf("The failures should contain %q:\n%s", expected, failures)
+        }
+    }
+}
+
+func TestRunReportsFaults(t *testing.T){
+
+    tests := InitTests(t, `[
+        {"name": "02", "initial": {"pc": 512, "p": 32, "ram": [[512, 2]]}, "final": {"pc": 513, "p": 32, "ram": []}, "cycles": []},
+        {"name": "ea", "initial": {"pc": 512, "p": 32, "ram": [[512, 234]]}, "final": {"pc": 513, "p": 32, "ram": []},
+            "cycles": [[512, 234, "read"], [513, 0, "read"]]}
+    ]`)
+
+    result := Run(arc.NMOS6502, tests)
+
+    // The CPU is reset after the JAM, so the NOP passes
+    if result.Faults != 1 || result.Passed != 1 || !strings.Contains(result.Failures[0], "cpu jammed") {
+        t.Error("Expected a fault and a pass but got: ", result)
+    }
+}
+
+func TestWriteSummary(t *testing.T){
+
+    var out strings.Builder
+
+    WriteSummary(&out, []Result{
+        {Opcode: 0xA9, Tests: 10, Passed: 10},
+        {Opcode: 0xB1, Tests: 10, Passed: 9, Registers: 1, Bus: 4, Failures: []string{"b1 20 00: A is $80, expected $81"}},
+    })
+
+    for _, expected := range []string{
+        "opcode  tests  passed  faults  registers  memory  cycles  bus",
+        "$A9     10     10      0       0          0       0       0",
+        "total   20     19      0       1          0       0       4",
+        "$B1 b1 20 00: A is $80, expected $81",
+    } {
+        if !strings.Contains(out.String(), expected) {
+            t.Errorf("The summary should contain %q:\n%s", expected, out.String())
+        }
+    }
+}
+
+// The tests are several gigabytes, so they're not part of the repository.
+// They're read from the 65x02 directory of a clone of https://github.com/SingleStepTests/65x02,
+// set SINGLESTEPTESTS to its path, or copy 6502/v1 and wdc65c02/v1 to testdata.
+func testsDirectory() string{
+
+    if dir := os.Getenv("SINGLESTEPTESTS"); dir != "" {
+        return dir
+    }
+
+    return "testdata"
+}
+
+// RunDirectory runs the tests of every opcode found in dir in parallel, and logs the summary.
+// Mismatches of documented opcodes fail the test. The illegal opcodes of the NMOS 6502 are only
+// summarized, since some are unstable on the real chips, and so are bus mismatches.
+func RunDirectory(t *testing.T, variant arc.Variant, dir string){
+
+    if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+        t.Skipf("%s is missing, see testsDirectory", dir)
+    }
+
+    table := instructions.Table(variant == arc.CMOS65C02)
+    results := make([]*Result, 256)
+
+    t.Run("opcodes", func(t *testing.T){
+
+        for opcode := 0; opcode < 256; opcode++ {
+
+            opcode := byte(opcode)
+
+            if _, err := os.Stat(Path(dir, opcode)); err != nil {
+                continue
+            }
+
+            t.Run(Path("", opcode), func(t *testing.T){
+
+                t.Parallel()
+
+                result, err := RunFile(variant, dir, opcode)
+                if err != nil {
+                    t.Fatal(err)
+                }
+
+                results[opcode] = &result
+
+                unstable := variant == arc.NMOS6502 && table[opcode].Status == instructions.Illegal
+
+                if result.Passed != result.Tests && !unstable {
+                    t.Errorf("%d of %d tests failed:\n%s", result.Tests - result.Passed, result.Tests, strings.Join(result.Failures, "\n"))
+                }
+            })
+        }
+    })
+
+    var summary []Result
+    for _, result := range results {
+        if result != nil {
+            summary = append(summary, *result)
+        }
+    }
+
+    var out strings.Builder
+    WriteSummary(&out, summary)
+
+    t.Log("\n" + out.String())
+}
+
+func TestSingleStepTests(t *testing.T){
+
+    t.Run("6502", func(t *testing.T){
+        RunDirectory(t, arc.NMOS6502, filepath.Join(testsDirectory(), "6502", "v1"))
+    })
+
+    t.Run("65C02", func(t *testing.T){
+        RunDirectory(t, arc.CMOS65C02, filepath.Join(testsDirectory(), "wdc65c02", "v1"))
+    })
+}
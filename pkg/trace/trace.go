@@ -0,0 +1,105 @@
+// Package trace logs the instructions executed by a CPU, one line each, in the format
+// of the nestest log of Nintendulator that most 6502 emulators can produce:
+//
+//	C000  4C F5 C5  JMP $C5F5                       A:00 X:00 Y:00 P:24 SP:FD CYC:7
+//
+// That's the address, the raw bytes, the instruction, with a * before the illegal opcodes,
+// the registers before the instruction is executed and the cycles executed until then.
+// Unlike nestest, the values in memory aren't shown after the operand, so tools comparing
+// logs should compare the registers and the cycles, which is what matters anyway.
+package trace
+
+import (
+	"emulator/pkg/arc"
+	"emulator/pkg/disasm"
+	"emulator/pkg/instructions"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Range is a range of addresses, both included.
+type Range struct {
+    Start uint16
+    End uint16
+}
+
+// Options tells which instructions are traced and how.
+type Options struct {
+
+    // Ranges limits the trace to the instructions whose address is in one of them.
+    // Every instruction is traced when it's empty.
+    Ranges []Range
+
+    // CycleOffset is added to the cycles of the CPU. The nestest log starts at 7,
+    // the cycles of the reset sequence, which Reset doesn't take.
+    CycleOffset uint64
+}
+
+// Tracer writes a line for each instruction executed by the CPUs it's set on,
+// see arc.CPU.Tracer.
+type Tracer struct {
+    w io.Writer
+    options Options
+
+    // First error writing the trace, the following lines are dropped
+    err error
+}
+
+// New returns a Tracer writing to w.
+func New(w io.Writer, options Options) *Tracer{
+    return &Tracer{w: w, options: options}
+}
+
+// Trace writes the line of the instruction at the PC of cpu, if its address is traced.
+func (t *Tracer) Trace(cpu *arc.CPU, cycles uint64){
+
+    if t.err != nil || !t.traced(cpu.PC) {
+        return
+    }
+
+    _, t.err = fmt.Fprintln(t.w, Line(cpu, cycles + t.options.CycleOffset))
+}
+
+// Err returns the first error writing the trace.
+func (t *Tracer) Err() error{
+    return t.err
+}
+
+func (t *Tracer) traced(address uint16) bool{
+
+    if len(t.options.Ranges) == 0 {
+        return true
+    }
+
+    for _, r := range t.options.Ranges {
+        if address >= r.Start && address <= r.End {
+            return true
+        }
+    }
+
+    return false
+}
+
+// Line returns the trace line of the instruction at the PC of cpu, given the cycles executed until then.
+func Line(cpu *arc.CPU, cycles uint64) string{
+
+    cmos := cpu.Variant == arc.CMOS65C02
+    ins := disasm.Decode(cpu.AddressSpace(), cpu.PC, cmos)
+
+    raw := make([]string, len(ins.Bytes))
+    for i, value := range ins.Bytes {
+        raw[i] = fmt.Sprintf("%02X", value)
+    }
+
+    illegal := ' '
+    if ins.Status == instructions.Illegal {
+        illegal = '*'
+    }
+
+    // P is shown like PHP pushes it, but without the break bit, as nestest does
+    p := (cpu.PSToByte() | 0x20) &^ 0x10
+
+    return fmt.Sprintf("%04X  %-8s %c%-31s A:%02X X:%02X Y:%02X P:%02X SP:%02X CYC:%d",
+        cpu.PC, strings.Join(raw, " "), illegal, ins.String(), cpu.A, cpu.X, cpu.Y, p, cpu.SP, cycles)
+}
@@ -0,0 +1,94 @@
+package trace
+
+import (
+	"emulator/pkg/arc"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// InitCPU returns a CPU running, from $C000:
+//
+//	$C000  LDX #$02
+//	$C002  JSR $C008
+//	$C005  DEX
+//	$C006  BNE $C002
+//	$C008  NOP $10    (illegal)
+//	$C00A  RTS
+func InitCPU() *arc.CPU{
+
+    cpu := arc.NewCPU(arc.NMOS6502)
+    cpu.Reset(0xC000)
+
+    copy(cpu.Memory.Data[0xC000:], []byte{0xA2, 0x02, 0x20, 0x08, 0xC0, 0xCA, 0xD0, 0xFA, 0x04, 0x10, 0x60})
+
+    return cpu
+}
+
+func TestTraceLinesLikeNestest(t *testing.T){
+
+    cpu := InitCPU()
+
+    var out strings.Builder
+    cpu.Tracer = New(&out, Options{CycleOffset: 7})
+
+    cpu.Execute(2 + 6 + 3 + 6)
+
+    expected := "" +
+        "C000  A2 02     LDX #$02                        A:00 X:00 Y:00 P:20 SP:FD CYC:7\n" +
+        "C002  20 08 C0  JSR $C008                       A:00 X:02 Y:00 P:20 SP:FD CYC:9\n" +
+        "C008  04 10    *NOP $10                         A:00 X:02 Y:00 P:20 SP:FB CYC:15\n" +
+        "C00A  60        RTS                             A:00 X:02 Y:00 P:20 SP:FB CYC:18\n"
+
+    if out.String() != expected {
+        t.Errorf("Expected:\n%s\nbut got:\n%s", expected, out.String())
+    }
+
+    if cpu.Cycles() != 17 {
+        t.Error("The CPU should count 17 cycles but got: ", cpu.Cycles())
+    }
+}
+
+func TestTraceFiltersAddresses(t *testing.T){
+
+    cpu := InitCPU()
+
+    var out strings.Builder
+    cpu.Tracer = New(&out, Options{Ranges: []Range{{0xC005, 0xC006}, {0xC00A, 0xC00A}}})
+
+    // Until the loop ends, after the last BNE
+    cpu.Execute(41)
+
+    lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+
+    // The subroutine returns twice, DEX and BNE run twice
+    if len(lines) != 6 || !strings.HasPrefix(lines[0], "C00A") || !strings.HasPrefix(lines[1], "C005") || !strings.HasPrefix(lines[2], "C006") {
+        t.Error("Unexpected trace:\n", out.String())
+    }
+}
+
+type failingWriter struct {
+    writes int
+}
+
+func (w *failingWriter) Write(data []byte) (int, error){
+
+    w.writes++
+
+    return 0, errors.New("disk full")
+}
+
+func TestTraceStopsAtTheFirstError(t *testing.T){
+
+    cpu := InitCPU()
+
+    w := &failingWriter{}
+    tracer := New(w, Options{})
+    cpu.Tracer = tracer
+
+    cpu.Execute(20)
+
+    if w.writes != 1 || tracer.Err() == nil {
+        t.Error("Expected a single write and an error but got: ", w.writes, tracer.Err())
+    }
+}